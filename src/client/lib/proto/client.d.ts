@@ -378,7 +378,14 @@ export class Ok implements IOk {
 
 /** ErrorCode enum. */
 export enum ErrorCode {
-    NOT_FOUND = 0
+    NOT_FOUND = 0,
+    INVALID_REQUEST = 1,
+    INTERNAL_ERROR = 2,
+    UNAUTHENTICATED = 3,
+    FORBIDDEN = 4,
+    MESSAGE_TOO_LARGE = 5,
+    RATE_LIMITED = 6,
+    TOO_MANY_SUBSCRIPTIONS = 7
 }
 
 /** Represents a ClientRequest. */
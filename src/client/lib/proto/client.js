@@ -776,10 +776,24 @@ export const Ok = $root.Ok = (() => {
  * @exports ErrorCode
  * @enum {number}
  * @property {number} NOT_FOUND=0 NOT_FOUND value
+ * @property {number} INVALID_REQUEST=1 INVALID_REQUEST value
+ * @property {number} INTERNAL_ERROR=2 INTERNAL_ERROR value
+ * @property {number} UNAUTHENTICATED=3 UNAUTHENTICATED value
+ * @property {number} FORBIDDEN=4 FORBIDDEN value
+ * @property {number} MESSAGE_TOO_LARGE=5 MESSAGE_TOO_LARGE value
+ * @property {number} RATE_LIMITED=6 RATE_LIMITED value
+ * @property {number} TOO_MANY_SUBSCRIPTIONS=7 TOO_MANY_SUBSCRIPTIONS value
  */
 export const ErrorCode = $root.ErrorCode = (() => {
     const valuesById = {}, values = Object.create(valuesById);
     values[valuesById[0] = "NOT_FOUND"] = 0;
+    values[valuesById[1] = "INVALID_REQUEST"] = 1;
+    values[valuesById[2] = "INTERNAL_ERROR"] = 2;
+    values[valuesById[3] = "UNAUTHENTICATED"] = 3;
+    values[valuesById[4] = "FORBIDDEN"] = 4;
+    values[valuesById[5] = "MESSAGE_TOO_LARGE"] = 5;
+    values[valuesById[6] = "RATE_LIMITED"] = 6;
+    values[valuesById[7] = "TOO_MANY_SUBSCRIPTIONS"] = 7;
     return values;
 })();
 
@@ -1353,6 +1367,13 @@ export const ServerResponse = $root.ServerResponse = (() => {
             default:
                 return "error: enum value expected";
             case 0:
+            case 1:
+            case 2:
+            case 3:
+            case 4:
+            case 5:
+            case 6:
+            case 7:
                 break;
             }
         }
@@ -1399,6 +1420,34 @@ export const ServerResponse = $root.ServerResponse = (() => {
         case 0:
             message.error = 0;
             break;
+        case "INVALID_REQUEST":
+        case 1:
+            message.error = 1;
+            break;
+        case "INTERNAL_ERROR":
+        case 2:
+            message.error = 2;
+            break;
+        case "UNAUTHENTICATED":
+        case 3:
+            message.error = 3;
+            break;
+        case "FORBIDDEN":
+        case 4:
+            message.error = 4;
+            break;
+        case "MESSAGE_TOO_LARGE":
+        case 5:
+            message.error = 5;
+            break;
+        case "RATE_LIMITED":
+        case 6:
+            message.error = 6;
+            break;
+        case "TOO_MANY_SUBSCRIPTIONS":
+        case 7:
+            message.error = 7;
+            break;
         }
         if (object.ringView != null) {
             if (typeof object.ringView !== "object")
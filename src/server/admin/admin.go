@@ -0,0 +1,229 @@
+package admin
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"sdle-server/replication"
+	"sdle-server/ringview"
+)
+
+// Returned by TriggerAntiEntropy when a round is already running
+var ErrAlreadyRunning = errors.New("already running")
+
+type NodeInterface interface {
+	ID() string
+	Status() Status
+	GetRingView() *ringview.RingView
+	PreferenceList(key string) ringview.PreferenceList
+	HintStats() replication.HintStats
+
+	// Sizes of the local keys in the hash space [start, end]
+	LocalKeySizes(start uint64, end uint64) (map[string]int64, error)
+
+	// Delivers the hints for a node now, ignoring the retry backoff (every node with hints if nodeId is empty).
+	// Returns the nodes whose delivery was started.
+	DeliverHints(nodeId string) []string
+
+	// Starts a round of anti-entropy (the ownership scrub) in the background
+	TriggerAntiEntropy() error
+}
+
+// Status of a node, as seen by itself
+type Status struct {
+	ID            string   `json:"id"`
+	Tokens        []uint64 `json:"tokens"`
+	RingNodes     []string `json:"ring_nodes"`
+	Suspected     []string `json:"suspected"` // peers suspected to be down
+	N             int      `json:"n"`
+	W             int      `json:"w"`
+	R             int      `json:"r"`
+	Hints         int      `json:"hints"`
+	Subscriptions int      `json:"subscriptions"`
+	StoreLSMBytes int64    `json:"store_lsm_bytes"`
+	StoreLogBytes int64    `json:"store_vlog_bytes"`
+}
+
+type TokenRange struct {
+	Token uint64 `json:"token"`
+	Start uint64 `json:"start"`
+	End   uint64 `json:"end"`
+	Node  string `json:"node"`
+}
+
+type Ring struct {
+	Nodes  []string     `json:"nodes"`
+	Ranges []TokenRange `json:"ranges"` // sorted by token
+}
+
+type PreferenceList struct {
+	Key       string   `json:"key"`
+	Hash      uint64   `json:"hash"`
+	Nodes     []string `json:"nodes"` // coordinator first
+	Fallbacks []string `json:"fallbacks"`
+}
+
+type Hints struct {
+	Total     int            `json:"total"`
+	PerTarget map[string]int `json:"per_target"`
+	Bytes     int64          `json:"bytes"`
+	Merged    uint64         `json:"merged"`
+	Rejected  uint64         `json:"rejected"`
+	Expired   uint64         `json:"expired"`
+	Delivered uint64         `json:"delivered"`
+}
+
+type Key struct {
+	Key  string `json:"key"`
+	Size int64  `json:"size"` // key and value bytes
+}
+
+type Keys struct {
+	Start uint64 `json:"start"`
+	End   uint64 `json:"end"`
+	Keys  []Key  `json:"keys"` // sorted by key
+}
+
+type HintDelivery struct {
+	Targets []string `json:"targets"`
+}
+
+type Error struct {
+	Error string `json:"error"`
+}
+
+// Serves the admin API of a node under /admin/
+func NewHandler(node NodeInterface) http.Handler {
+	h := &handler{node: node}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/status", h.status)
+	mux.HandleFunc("GET /admin/ring", h.ring)
+	mux.HandleFunc("GET /admin/preference-list", h.preferenceList)
+	mux.HandleFunc("GET /admin/hints", h.hints)
+	mux.HandleFunc("GET /admin/keys", h.keys)
+	mux.HandleFunc("POST /admin/hints/deliver", h.deliverHints)
+	mux.HandleFunc("POST /admin/anti-entropy", h.antiEntropy)
+	return mux
+}
+
+type handler struct {
+	node NodeInterface
+}
+
+func (h *handler) status(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.node.Status())
+}
+
+func (h *handler) ring(w http.ResponseWriter, r *http.Request) {
+	ringView := h.node.GetRingView()
+
+	ring := Ring{Nodes: ringView.GetKnownIds(), Ranges: []TokenRange{}}
+	for token, tokenRange := range ringView.GetTokenRanges() {
+		ring.Ranges = append(ring.Ranges, TokenRange{Token: token, Start: tokenRange.Start, End: tokenRange.End, Node: tokenRange.NodeId})
+	}
+	slices.SortFunc(ring.Ranges, func(a, b TokenRange) int { return cmp.Compare(a.Token, b.Token) })
+	slices.Sort(ring.Nodes)
+
+	writeJSON(w, http.StatusOK, ring)
+}
+
+func (h *handler) preferenceList(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeJSON(w, http.StatusBadRequest, Error{Error: "missing key parameter"})
+		return
+	}
+
+	prefList := h.node.PreferenceList(key)
+	writeJSON(w, http.StatusOK, PreferenceList{
+		Key:       key,
+		Hash:      ringview.HashKey(key),
+		Nodes:     prefList.Nodes,
+		Fallbacks: prefList.Fallbacks,
+	})
+}
+
+func (h *handler) hints(w http.ResponseWriter, r *http.Request) {
+	stats := h.node.HintStats()
+	writeJSON(w, http.StatusOK, Hints{
+		Total:     stats.Total,
+		PerTarget: stats.PerTarget,
+		Bytes:     stats.Bytes,
+		Merged:    stats.Merged,
+		Rejected:  stats.Rejected,
+		Expired:   stats.Expired,
+		Delivered: stats.Delivered,
+	})
+}
+
+// Lists the local keys of a token range: either ?token=T (the range owned by the token) or ?start=S&end=E
+func (h *handler) keys(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var start, end uint64
+	if query.Has("token") {
+		token, err := strconv.ParseUint(query.Get("token"), 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, Error{Error: "invalid token parameter"})
+			return
+		}
+		tokenRange, ok := h.node.GetRingView().GetTokenRanges()[token]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, Error{Error: "unknown token"})
+			return
+		}
+		start, end = tokenRange.Start, tokenRange.End
+	} else {
+		var errStart, errEnd error
+		start, errStart = strconv.ParseUint(query.Get("start"), 10, 64)
+		end, errEnd = strconv.ParseUint(query.Get("end"), 10, 64)
+		if errStart != nil || errEnd != nil {
+			writeJSON(w, http.StatusBadRequest, Error{Error: "expected a token parameter, or start and end parameters"})
+			return
+		}
+	}
+
+	sizes, err := h.node.LocalKeySizes(start, end)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, Error{Error: err.Error()})
+		return
+	}
+
+	keys := Keys{Start: start, End: end, Keys: []Key{}}
+	for _, key := range slices.Sorted(maps.Keys(sizes)) {
+		keys.Keys = append(keys.Keys, Key{Key: key, Size: sizes[key]})
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+func (h *handler) deliverHints(w http.ResponseWriter, r *http.Request) {
+	targets := h.node.DeliverHints(r.URL.Query().Get("node"))
+	if targets == nil {
+		targets = []string{}
+	}
+	writeJSON(w, http.StatusAccepted, HintDelivery{Targets: targets})
+}
+
+func (h *handler) antiEntropy(w http.ResponseWriter, r *http.Request) {
+	if err := h.node.TriggerAntiEntropy(); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrAlreadyRunning) {
+			status = http.StatusConflict
+		}
+		writeJSON(w, status, Error{Error: err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
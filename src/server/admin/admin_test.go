@@ -0,0 +1,148 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sdle-server/replication"
+	"sdle-server/ringview"
+)
+
+type fakeNode struct {
+	ring        *ringview.RingView
+	keys        map[string]int64
+	delivered   []string
+	antiEntropy error
+}
+
+func (f *fakeNode) ID() string                      { return "node1:5000" }
+func (f *fakeNode) Status() Status                  { return Status{ID: "node1:5000", N: 3} }
+func (f *fakeNode) GetRingView() *ringview.RingView { return f.ring }
+func (f *fakeNode) HintStats() replication.HintStats {
+	return replication.HintStats{Total: 2, PerTarget: map[string]int{"node2:5001": 2}}
+}
+func (f *fakeNode) PreferenceList(key string) ringview.PreferenceList {
+	return f.ring.GetPreferenceList(key, 2)
+}
+func (f *fakeNode) LocalKeySizes(start uint64, end uint64) (map[string]int64, error) {
+	return f.keys, nil
+}
+func (f *fakeNode) DeliverHints(nodeId string) []string {
+	f.delivered = append(f.delivered, nodeId)
+	return []string{"node2:5001"}
+}
+func (f *fakeNode) TriggerAntiEntropy() error { return f.antiEntropy }
+
+func newFakeNode() *fakeNode {
+	return &fakeNode{
+		ring: ringview.NewFromTokenMap(map[uint64]string{100: "node1:5000", 200: "node2:5001", 300: "node3:5002"}),
+		keys: map[string]int64{"b": 20, "a": 10},
+	}
+}
+
+func request(t *testing.T, h http.Handler, method string, target string, body any) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+	if body != nil && rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), body); err != nil {
+			t.Fatalf("Expected a JSON body from %s %s, got %q", method, target, rec.Body.String())
+		}
+	}
+	return rec.Code
+}
+
+func TestHandler_Ring(t *testing.T) {
+	h := NewHandler(newFakeNode())
+
+	var ring Ring
+	if code := request(t, h, "GET", "/admin/ring", &ring); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if len(ring.Nodes) != 3 || len(ring.Ranges) != 3 {
+		t.Fatalf("Expected 3 nodes and 3 ranges, got %+v", ring)
+	}
+	expected := TokenRange{Token: 100, Start: 301, End: 100, Node: "node1:5000"}
+	if ring.Ranges[0] != expected {
+		t.Errorf("Expected the first range to be %+v, got %+v", expected, ring.Ranges[0])
+	}
+}
+
+func TestHandler_PreferenceList(t *testing.T) {
+	h := NewHandler(newFakeNode())
+
+	var prefList PreferenceList
+	if code := request(t, h, "GET", "/admin/preference-list?key=key1", &prefList); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if prefList.Key != "key1" || prefList.Hash != ringview.HashKey("key1") || len(prefList.Nodes) != 2 || len(prefList.Fallbacks) != 1 {
+		t.Errorf("Expected 2 replicas and 1 fallback for key1, got %+v", prefList)
+	}
+
+	if code := request(t, h, "GET", "/admin/preference-list", nil); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a key, got %d", code)
+	}
+}
+
+func TestHandler_Keys(t *testing.T) {
+	h := NewHandler(newFakeNode())
+
+	var keys Keys
+	if code := request(t, h, "GET", "/admin/keys?token=200", &keys); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if keys.Start != 101 || keys.End != 200 {
+		t.Errorf("Expected the range of token 200 to be [101, 200], got [%d, %d]", keys.Start, keys.End)
+	}
+	if len(keys.Keys) != 2 || keys.Keys[0] != (Key{Key: "a", Size: 10}) {
+		t.Errorf("Expected the keys sorted by name, got %+v", keys.Keys)
+	}
+
+	if code := request(t, h, "GET", "/admin/keys?start=5&end=10", &keys); code != http.StatusOK || keys.Start != 5 || keys.End != 10 {
+		t.Errorf("Expected an explicit range to be accepted, got %d %+v", code, keys)
+	}
+	if code := request(t, h, "GET", "/admin/keys?token=150", nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown token, got %d", code)
+	}
+	if code := request(t, h, "GET", "/admin/keys", nil); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a range, got %d", code)
+	}
+}
+
+func TestHandler_Hints(t *testing.T) {
+	node := newFakeNode()
+	h := NewHandler(node)
+
+	var hints Hints
+	if code := request(t, h, "GET", "/admin/hints", &hints); code != http.StatusOK || hints.PerTarget["node2:5001"] != 2 {
+		t.Errorf("Expected the hints per target, got %d %+v", code, hints)
+	}
+
+	if code := request(t, h, "GET", "/admin/hints/deliver", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected delivery to require POST, got %d", code)
+	}
+
+	var delivery HintDelivery
+	if code := request(t, h, "POST", "/admin/hints/deliver?node=node2:5001", &delivery); code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d", code)
+	}
+	if len(node.delivered) != 1 || node.delivered[0] != "node2:5001" || len(delivery.Targets) != 1 {
+		t.Errorf("Expected a delivery to node2:5001, got %v %+v", node.delivered, delivery)
+	}
+}
+
+func TestHandler_AntiEntropy(t *testing.T) {
+	node := newFakeNode()
+	h := NewHandler(node)
+
+	if code := request(t, h, "POST", "/admin/anti-entropy", nil); code != http.StatusAccepted {
+		t.Errorf("Expected 202, got %d", code)
+	}
+
+	node.antiEntropy = ErrAlreadyRunning
+	if code := request(t, h, "POST", "/admin/anti-entropy", nil); code != http.StatusConflict {
+		t.Errorf("Expected 409 while a round is running, got %d", code)
+	}
+}
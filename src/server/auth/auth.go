@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Minimum length, in bytes, of the key tokens are signed with
+const MinKeyLength = 32
+
+var (
+	ErrInvalidToken    = errors.New("invalid token")
+	ErrTokenExpired    = errors.New("token expired")
+	ErrUnauthenticated = errors.New("not authenticated")
+	ErrForbidden       = errors.New("forbidden")
+)
+
+// Claims of a token: the user it identifies and when it stops being accepted
+type Claims struct {
+	Subject string `json:"sub"`
+	Expires int64  `json:"exp"` // Unix time, in seconds
+}
+
+func (c Claims) ExpiresAt() time.Time {
+	return time.Unix(c.Expires, 0)
+}
+
+// Kinds of signed tokens. The kind is covered by the signature, so a token of one kind is never accepted as another.
+const (
+	kindSession    = "session"
+	kindCapability = "capability"
+)
+
+// Signs a token identifying a user until it expires
+func Sign(key []byte, subject string, expires time.Time) (string, error) {
+	if subject == "" {
+		return "", errors.New("token subject must not be empty")
+	}
+	return signClaims(key, kindSession, Claims{Subject: subject, Expires: expires.Unix()})
+}
+
+// Checks the signature and the expiry of a token, returning its claims
+func Verify(key []byte, token string, now time.Time) (Claims, error) {
+	var claims Claims
+	if err := verifyClaims(key, kindSession, token, &claims); err != nil {
+		return Claims{}, err
+	}
+	if claims.Subject == "" {
+		return Claims{}, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+
+	if !now.Before(claims.ExpiresAt()) {
+		return Claims{}, fmt.Errorf("%w at %s", ErrTokenExpired, claims.ExpiresAt().Format(time.RFC3339))
+	}
+	return claims, nil
+}
+
+// Signs the claims of a token. A token is "<claims>.<signature>", both base64url-encoded: the claims as JSON, and the
+// signature as the HMAC-SHA256 of its kind and claims under the key.
+func signClaims(key []byte, kind string, claims any) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(sign(key, kind, encoded)), nil
+}
+
+// Checks the signature of a token of the given kind, decoding its claims
+func verifyClaims(key []byte, kind string, token string, claims any) error {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return fmt.Errorf("%w: missing signature", ErrInvalidToken)
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, sign(key, kind, encoded)) {
+		return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return nil
+}
+
+func sign(key []byte, kind string, encoded string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(kind + "." + encoded))
+	return mac.Sum(nil)
+}
+
+// Reads the key tokens are signed with. Surrounding whitespace is not part of the key.
+func LoadKey(file string) ([]byte, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	key := bytes.TrimSpace(data)
+	if len(key) < MinKeyLength {
+		return nil, fmt.Errorf("%s: key has %d bytes, at least %d are required", file, len(key), MinKeyLength)
+	}
+	return key, nil
+}
+
+type userKey struct{}
+
+// Context carrying the authenticated user of a request
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// Authenticated user of a request, if any
+func User(ctx context.Context) (string, bool) {
+	user, ok := ctx.Value(userKey{}).(string)
+	return user, ok && user != ""
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestToken_RoundTrip(t *testing.T) {
+	now := time.Now()
+	token, err := Sign(testKey, "alice", now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Expected the token to be signed, got %v", err)
+	}
+
+	claims, err := Verify(testKey, token, now)
+	if err != nil {
+		t.Fatalf("Expected the token to verify, got %v", err)
+	}
+	if claims.Subject != "alice" || claims.Expires != now.Add(time.Hour).Unix() {
+		t.Errorf("Expected alice until %d, got %+v", now.Add(time.Hour).Unix(), claims)
+	}
+}
+
+func TestToken_Rejected(t *testing.T) {
+	now := time.Now()
+	token, err := Sign(testKey, "alice", now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Expected the token to be signed, got %v", err)
+	}
+	encoded, signature, _ := strings.Cut(token, ".")
+	forged, _ := Sign(testKey, "mallory", now.Add(time.Hour))
+	forgedClaims, _, _ := strings.Cut(forged, ".")
+
+	tests := []struct {
+		name  string
+		key   []byte
+		token string
+		now   time.Time
+		err   error
+	}{
+		{"other key", []byte("fedcba9876543210fedcba9876543210"), token, now, ErrInvalidToken},
+		{"no signature", testKey, encoded, now, ErrInvalidToken},
+		{"swapped claims", testKey, forgedClaims + "." + signature, now, ErrInvalidToken},
+		{"garbage", testKey, "not.a-token", now, ErrInvalidToken},
+		{"expired", testKey, token, now.Add(time.Hour), ErrTokenExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Verify(tt.key, tt.token, tt.now); !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, "key")
+	if err := os.WriteFile(file, append(testKey, '\n'), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	key, err := LoadKey(file)
+	if err != nil || string(key) != string(testKey) {
+		t.Errorf("Expected the key without the newline, got %q %v", key, err)
+	}
+
+	short := filepath.Join(dir, "short")
+	if err := os.WriteFile(short, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadKey(short); err == nil {
+		t.Errorf("Expected a short key to be rejected")
+	}
+}
+
+func TestCapability_RoundTrip(t *testing.T) {
+	now := time.Now()
+	c := NewCapability("list1", AccessRead, "alice", now.Add(time.Hour))
+	token, err := SignCapability(testKey, c)
+	if err != nil {
+		t.Fatalf("Expected the capability to be signed, got %v", err)
+	}
+
+	verified, err := VerifyCapability(testKey, token, now)
+	if err != nil {
+		t.Fatalf("Expected the capability to verify, got %v", err)
+	}
+	if verified != c || verified.CanWrite() {
+		t.Errorf("Expected %+v, got %+v", c, verified)
+	}
+
+	if _, err := VerifyCapability(testKey, token, now.Add(time.Hour)); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Expected the capability to expire, got %v", err)
+	}
+
+	forever, _ := SignCapability(testKey, NewCapability("list1", AccessWrite, "alice", time.Time{}))
+	if c, err := VerifyCapability(testKey, forever, now.AddDate(100, 0, 0)); err != nil || !c.CanWrite() {
+		t.Errorf("Expected a capability without expiry to stay valid, got %+v %v", c, err)
+	}
+}
+
+func TestCapability_NotASessionToken(t *testing.T) {
+	now := time.Now()
+	session, _ := Sign(testKey, "alice", now.Add(time.Hour))
+	capability, _ := SignCapability(testKey, NewCapability("list1", AccessWrite, "alice", time.Time{}))
+
+	if _, err := VerifyCapability(testKey, session, now); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected a session token not to be a capability, got %v", err)
+	}
+	if _, err := Verify(testKey, capability, now); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected a capability not to be a session token, got %v", err)
+	}
+	if _, err := SignCapability(testKey, Capability{ID: "c1", ListID: "list1", Access: "admin"}); err == nil {
+		t.Errorf("Expected an unknown access to be rejected")
+	}
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Access a capability grants to its list
+const (
+	AccessRead  = "read"
+	AccessWrite = "write" // also grants read access
+)
+
+// Share capability of a shopping list: whoever holds its token may access the list, without a session identity, until
+// it expires or is revoked
+type Capability struct {
+	ID      string `json:"jti"`           // random, identifies the capability when it is revoked
+	ListID  string `json:"list"`          // list the capability grants access to
+	Access  string `json:"access"`        // AccessRead or AccessWrite
+	Issuer  string `json:"iss"`           // user that shared the list
+	Expires int64  `json:"exp,omitempty"` // Unix time, in seconds (0 if the capability never expires)
+}
+
+// Creates a capability with a new ID. A zero expiry makes a capability that never expires.
+func NewCapability(listID string, access string, issuer string, expires time.Time) Capability {
+	c := Capability{ID: rand.Text(), ListID: listID, Access: access, Issuer: issuer}
+	if !expires.IsZero() {
+		c.Expires = expires.Unix()
+	}
+	return c
+}
+
+// When the capability expires (the zero time if it never does)
+func (c Capability) ExpiresAt() time.Time {
+	if c.Expires == 0 {
+		return time.Time{}
+	}
+	return time.Unix(c.Expires, 0)
+}
+
+// Whether the capability lets its holder write its list, and not only read it
+func (c Capability) CanWrite() bool {
+	return c.Access == AccessWrite
+}
+
+func (c Capability) validate() error {
+	switch {
+	case c.ID == "":
+		return errors.New("missing ID")
+	case c.ListID == "":
+		return errors.New("missing list")
+	case c.Access != AccessRead && c.Access != AccessWrite:
+		return fmt.Errorf("unknown access %q", c.Access)
+	}
+	return nil
+}
+
+// Signs the token of a capability
+func SignCapability(key []byte, c Capability) (string, error) {
+	if err := c.validate(); err != nil {
+		return "", fmt.Errorf("capability: %w", err)
+	}
+	return signClaims(key, kindCapability, c)
+}
+
+// Checks the signature and the expiry of a capability token, returning the capability. It is up to the caller to check
+// that the capability was not revoked.
+func VerifyCapability(key []byte, token string, now time.Time) (Capability, error) {
+	var c Capability
+	if err := verifyClaims(key, kindCapability, token, &c); err != nil {
+		return Capability{}, err
+	}
+	if err := c.validate(); err != nil {
+		return Capability{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if c.Expires != 0 && !now.Before(c.ExpiresAt()) {
+		return Capability{}, fmt.Errorf("%w at %s", ErrTokenExpired, c.ExpiresAt().Format(time.RFC3339))
+	}
+	return c, nil
+}
+
+type capabilityKey struct{}
+
+// Context carrying a verified capability presented with a request
+func WithCapability(ctx context.Context, c Capability) context.Context {
+	return context.WithValue(ctx, capabilityKey{}, c)
+}
+
+// Capability presented with a request, if any
+func CapabilityFrom(ctx context.Context) (Capability, bool) {
+	c, ok := ctx.Value(capabilityKey{}).(Capability)
+	return c, ok
+}
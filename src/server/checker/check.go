@@ -0,0 +1,276 @@
+package checker
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	generic "sdle-server/crdt/generic"
+	crdt "sdle-server/crdt/shopping"
+)
+
+type ViolationKind string
+
+const (
+	Divergence      ViolationKind = "divergence"       // replicas that received the same updates hold different states
+	LostWrite       ViolationKind = "lost-write"       // an acknowledged write is missing from a settled state
+	BrokenInvariant ViolationKind = "broken-invariant" // an item has a negative count or more acquired than needed
+)
+
+type Violation struct {
+	Kind         ViolationKind
+	List         string
+	Item         string
+	Message      string
+	History      []Operation   // minimal set of operations reproducing the violation
+	Observations []Observation // states in which the violation was seen
+}
+
+func (v Violation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s in %s: %s\n  history:\n", v.Kind, v.List, v.Message)
+	for _, op := range v.History {
+		fmt.Fprintf(&b, "    %s\n", op)
+	}
+	b.WriteString("  observed:\n")
+	for _, obs := range v.Observations {
+		fmt.Fprintf(&b, "    %s\n", obs)
+	}
+	return b.String()
+}
+
+// Verifies strong eventual consistency of a history: settled states of a list are equal, contain every acknowledged
+// write and observed items respect their invariants.
+//
+// Every edit clamps quantity and acquired against the state its client saw, so the invariants hold whenever the edits
+// of an item are causally ordered. Concurrent edits may legitimately break them (two clients acquiring the last unit
+// of an item), so items edited concurrently are not checked.
+func Check(h *History) []Violation {
+	ops := h.Operations()
+	observations := h.Observations()
+
+	violations := []Violation{}
+	violations = append(violations, checkInvariants(ops, observations)...)
+	violations = append(violations, checkDivergence(ops, observations)...)
+	violations = append(violations, checkLostWrites(ops, observations)...)
+	return violations
+}
+
+// Whether the update of an operation is part of a state. A put adds dots to the context; a remove only drops the dots
+// it saw, so it is part of a state that keeps none of them.
+func applied(op Operation, state *crdt.ShoppingList) bool {
+	if op.List != state.ListID() {
+		return false
+	}
+	if op.Kind == OpRemove {
+		return len(survivingDots(op, state)) == 0
+	}
+	return state.Context().Includes(op.Delta.Context())
+}
+
+// Dots of the item of a remove that the remove saw and a state still holds
+func survivingDots(op Operation, state *crdt.ShoppingList) []generic.Dot {
+	surviving := []generic.Dot{}
+	for _, dot := range ItemDots(state, op.Item) {
+		if op.Delta.Context().Knows(dot) {
+			surviving = append(surviving, dot)
+		}
+	}
+	return surviving
+}
+
+// Operations on a list whose updates are part of a state
+func includedOps(ops []Operation, state *crdt.ShoppingList) []Operation {
+	included := []Operation{}
+	for _, op := range ops {
+		if applied(op, state) {
+			included = append(included, op)
+		}
+	}
+	return included
+}
+
+func opsOnItem(ops []Operation, item string) []Operation {
+	result := []Operation{}
+	for _, op := range ops {
+		if op.Item == item {
+			result = append(result, op)
+		}
+	}
+	return result
+}
+
+// Joins the deltas of some operations into an empty list
+func replay(list string, ops []Operation) *crdt.ShoppingList {
+	replayed := crdt.NewShoppingList("checker", list)
+	for _, op := range ops {
+		replayed.Join(op.Delta)
+	}
+	return replayed
+}
+
+// Whether every pair of operations is causally ordered
+func sequential(ops []Operation) bool {
+	for i := range ops {
+		for j := i + 1; j < len(ops); j++ {
+			if !ops[i].HappenedBefore(ops[j]) && !ops[j].HappenedBefore(ops[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func checkInvariants(ops []Operation, observations []Observation) []Violation {
+	violations := []Violation{}
+	reported := map[string]bool{}
+
+	for _, obs := range observations {
+		counts := ItemCounts(obs.State)
+		for _, item := range slices.Sorted(maps.Keys(counts)) {
+			problem := counts[item].invariantProblem()
+			if problem == "" || reported[obs.List+"/"+item] {
+				continue
+			}
+
+			candidates := opsOnItem(includedOps(ops, obs.State), item)
+			if !sequential(candidates) {
+				continue
+			}
+			reported[obs.List+"/"+item] = true
+
+			// Shrink to the fewest updates of the item that still break the invariant when joined
+			history := candidates
+			if breaks := func(subset []Operation) bool {
+				return ItemCounts(replay(obs.List, subset))[item].invariantProblem() != ""
+			}; breaks(candidates) {
+				history = minimize(candidates, breaks)
+			}
+
+			violations = append(violations, Violation{
+				Kind:         BrokenInvariant,
+				List:         obs.List,
+				Item:         item,
+				Message:      fmt.Sprintf("item %s: %s", item, problem),
+				History:      history,
+				Observations: []Observation{obs},
+			})
+		}
+	}
+
+	return violations
+}
+
+// Once the cluster settled every replica received every update, so all final states of a list must be equal
+func checkDivergence(ops []Operation, observations []Observation) []Violation {
+	violations := []Violation{}
+	first := map[string]Observation{}
+	reported := map[string]bool{}
+
+	for _, obs := range observations {
+		if !obs.Final {
+			continue
+		}
+
+		expected, ok := first[obs.List]
+		if !ok {
+			first[obs.List] = obs
+			continue
+		}
+		if reported[obs.List] || CanonicalState(expected.State) == CanonicalState(obs.State) {
+			continue
+		}
+		reported[obs.List] = true
+
+		// Only the items that differ matter to reproduce the divergence
+		expectedItems, otherItems := CanonicalItems(expected.State), CanonicalItems(obs.State)
+		differing := []string{}
+		for _, item := range slices.Sorted(maps.Keys(mergeKeys(expectedItems, otherItems))) {
+			if expectedItems[item] != otherItems[item] {
+				differing = append(differing, item)
+			}
+		}
+
+		history := []Operation{}
+		for _, op := range ops {
+			if op.List == obs.List && slices.Contains(differing, op.Item) &&
+				applied(op, expected.State) != applied(op, obs.State) {
+				history = append(history, op)
+			}
+		}
+
+		violations = append(violations, Violation{
+			Kind:         Divergence,
+			List:         obs.List,
+			Item:         strings.Join(differing, ","),
+			Message:      fmt.Sprintf("settled states of %s and %s differ on %v", expected.Reader, obs.Reader, differing),
+			History:      history,
+			Observations: []Observation{expected, obs},
+		})
+	}
+
+	return violations
+}
+
+func checkLostWrites(ops []Operation, observations []Observation) []Violation {
+	violations := []Violation{}
+
+	for _, op := range ops {
+		if !op.Acked {
+			continue
+		}
+
+		for _, obs := range observations {
+			if !obs.Final || obs.List != op.List || applied(op, obs.State) {
+				continue
+			}
+
+			violations = append(violations, Violation{
+				Kind:         LostWrite,
+				List:         op.List,
+				Item:         op.Item,
+				Message:      fmt.Sprintf("acknowledged write #%d is missing from the state of %s", op.Index, obs.Reader),
+				History:      []Operation{op},
+				Observations: []Observation{obs},
+			})
+			break
+		}
+	}
+
+	return violations
+}
+
+func mergeKeys(a map[string]string, b map[string]string) map[string]string {
+	merged := maps.Clone(a)
+	maps.Copy(merged, b)
+	return merged
+}
+
+// Shrinks a failing set of operations to a smaller one that still fails, by repeatedly dropping chunks of it
+// (delta debugging). The result is 1-minimal: removing any single operation makes it pass.
+func minimize(ops []Operation, fails func([]Operation) bool) []Operation {
+	chunks := 2
+	for len(ops) >= 2 {
+		size := (len(ops) + chunks - 1) / chunks
+		reduced := false
+
+		for start := 0; start < len(ops); start += size {
+			complement := append(slices.Clone(ops[:start]), ops[min(start+size, len(ops)):]...)
+			if fails(complement) {
+				ops = complement
+				chunks = max(chunks-1, 2)
+				reduced = true
+				break
+			}
+		}
+
+		if !reduced {
+			if chunks >= len(ops) {
+				break
+			}
+			chunks = min(chunks*2, len(ops))
+		}
+	}
+	return ops
+}
@@ -0,0 +1,234 @@
+package checker
+
+import (
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"sdle-server/communication"
+	crdt "sdle-server/crdt/shopping"
+	"sdle-server/node"
+	"sdle-server/transport"
+)
+
+// Joins the deltas of some operations, as a replica that received them
+func stateOf(list string, ops ...Operation) *crdt.ShoppingList {
+	return replay(list, ops)
+}
+
+func TestCheck_ConsistentHistory(t *testing.T) {
+	h := NewHistory()
+	alice := crdt.NewShoppingList("alice", "list1")
+	bob := crdt.NewShoppingList("bob", "list1")
+
+	h.Ack(h.RecordPut("alice", alice, alice.PutItem("milk", "Milk", 2, 0), "milk", 2, 0))
+	h.Ack(h.RecordPut("bob", bob, bob.PutItem("eggs", "Eggs", 12, 0), "eggs", 12, 0))
+	h.Ack(h.RecordPut("alice", alice, alice.PutItem("milk", "Milk", 0, 1), "milk", 0, 1))
+	h.Ack(h.RecordRemove("bob", bob, bob.RemoveItem("eggs"), "eggs"))
+
+	ops := h.Operations()
+	h.Observe("alice", stateOf("list1", ops[0]))
+	h.ObserveFinal("node1", stateOf("list1", ops...))
+	h.ObserveFinal("node2", stateOf("list1", ops[3], ops[1], ops[2], ops[0]))
+
+	if violations := Check(h); len(violations) != 0 {
+		t.Errorf("Expected no violations, got %v", violations)
+	}
+}
+
+func TestCheck_LostWrite(t *testing.T) {
+	h := NewHistory()
+	alice := crdt.NewShoppingList("alice", "list1")
+
+	h.Ack(h.RecordPut("alice", alice, alice.PutItem("milk", "Milk", 2, 0), "milk", 2, 0))
+	h.Ack(h.RecordPut("alice", alice, alice.PutItem("eggs", "Eggs", 12, 0), "eggs", 12, 0))
+	// Not acknowledged: the client cannot expect it to survive
+	h.RecordPut("alice", alice, alice.PutItem("bread", "Bread", 1, 0), "bread", 1, 0)
+
+	ops := h.Operations()
+	h.ObserveFinal("node1", stateOf("list1", ops[0]))
+
+	violations := Check(h)
+	if len(violations) != 1 {
+		t.Fatalf("Expected 1 violation, got %v", violations)
+	}
+	if violations[0].Kind != LostWrite || violations[0].Item != "eggs" {
+		t.Errorf("Expected the write of eggs to be lost, got %s", violations[0])
+	}
+	if len(violations[0].History) != 1 || violations[0].History[0].Index != 1 {
+		t.Errorf("Expected the history to hold only the lost write, got %v", violations[0].History)
+	}
+}
+
+func TestCheck_LostRemove(t *testing.T) {
+	h := NewHistory()
+	alice := crdt.NewShoppingList("alice", "list1")
+
+	h.Ack(h.RecordPut("alice", alice, alice.PutItem("milk", "Milk", 2, 0), "milk", 2, 0))
+	h.Ack(h.RecordRemove("alice", alice, alice.RemoveItem("milk"), "milk"))
+
+	ops := h.Operations()
+	h.ObserveFinal("node1", stateOf("list1", ops[0]))
+
+	violations := Check(h)
+	if len(violations) != 1 || violations[0].Kind != LostWrite || violations[0].History[0].Kind != OpRemove {
+		t.Errorf("Expected the remove to be lost, got %v", violations)
+	}
+}
+
+func TestCheck_Divergence(t *testing.T) {
+	h := NewHistory()
+	alice := crdt.NewShoppingList("alice", "list1")
+	bob := crdt.NewShoppingList("bob", "list1")
+
+	h.RecordPut("alice", alice, alice.PutItem("milk", "Milk", 2, 0), "milk", 2, 0)
+	h.RecordPut("bob", bob, bob.PutItem("eggs", "Eggs", 12, 0), "eggs", 12, 0)
+
+	ops := h.Operations()
+	h.ObserveFinal("node1", stateOf("list1", ops...))
+	h.ObserveFinal("node2", stateOf("list1", ops[0]))
+
+	violations := Check(h)
+	if len(violations) != 1 {
+		t.Fatalf("Expected 1 violation, got %v", violations)
+	}
+	if violations[0].Kind != Divergence || violations[0].Item != "eggs" {
+		t.Errorf("Expected the replicas to diverge on eggs, got %s", violations[0])
+	}
+	if len(violations[0].History) != 1 || violations[0].History[0].Item != "eggs" {
+		t.Errorf("Expected the history to hold only the write of eggs, got %v", violations[0].History)
+	}
+}
+
+func TestCheck_BrokenInvariant(t *testing.T) {
+	h := NewHistory()
+	alice := crdt.NewShoppingList("alice", "list1")
+
+	h.Ack(h.RecordPut("alice", alice, alice.PutItem("milk", "Milk", 1, 0), "milk", 1, 0))
+	h.Ack(h.RecordPut("alice", alice, alice.PutItem("milk", "Milk", 0, 1), "milk", 0, 1))
+
+	// A replica that counts more acquired than the client ever did
+	broken := alice.ToProto()
+	acquired := broken.GetItems()["milk"].GetAcquired().GetDotKernel()
+	acquired.DotValues[0] = 3
+	observed, err := crdt.ShoppingListFromProto(broken, "node1")
+	if err != nil {
+		t.Fatalf("Expected the state to decode, got %v", err)
+	}
+	h.Observe("node1", observed)
+
+	violations := Check(h)
+	if len(violations) != 1 {
+		t.Fatalf("Expected 1 violation, got %v", violations)
+	}
+	if violations[0].Kind != BrokenInvariant || violations[0].Item != "milk" {
+		t.Errorf("Expected a broken invariant on milk, got %s", violations[0])
+	}
+	if !strings.Contains(violations[0].Message, "acquired 3 exceeds quantity 1") {
+		t.Errorf("Expected the message to explain the invariant, got %s", violations[0].Message)
+	}
+}
+
+func TestCheck_ConcurrentAcquiresAllowed(t *testing.T) {
+	h := NewHistory()
+	alice := crdt.NewShoppingList("alice", "list1")
+	bob := crdt.NewShoppingList("bob", "list1")
+
+	h.Ack(h.RecordPut("alice", alice, alice.PutItem("milk", "Milk", 1, 0), "milk", 1, 0))
+	bob.Join(alice)
+
+	// Both see one unit left and acquire it
+	h.Ack(h.RecordPut("alice", alice, alice.PutItem("milk", "Milk", 0, 1), "milk", 0, 1))
+	h.Ack(h.RecordPut("bob", bob, bob.PutItem("milk", "Milk", 0, 1), "milk", 0, 1))
+
+	alice.Join(bob)
+	h.ObserveFinal("node1", alice)
+
+	if counts := ItemCounts(alice)["milk"]; counts.Acquired <= counts.Quantity {
+		t.Fatalf("Expected the concurrent acquires to exceed the quantity, got %+v", counts)
+	}
+	if violations := Check(h); len(violations) != 0 {
+		t.Errorf("Expected concurrent acquires not to be reported, got %v", violations)
+	}
+}
+
+func TestMinimize(t *testing.T) {
+	ops := []Operation{}
+	for i := range 10 {
+		ops = append(ops, Operation{Index: i})
+	}
+
+	// Fails whenever operations 3 and 7 are both present
+	fails := func(subset []Operation) bool {
+		indexes := []int{}
+		for _, op := range subset {
+			indexes = append(indexes, op.Index)
+		}
+		return slices.Contains(indexes, 3) && slices.Contains(indexes, 7)
+	}
+
+	minimal := minimize(ops, fails)
+	if len(minimal) != 2 || minimal[0].Index != 3 || minimal[1].Index != 7 {
+		t.Errorf("Expected operations 3 and 7, got %v", minimal)
+	}
+}
+
+func TestRunCluster_InMemory(t *testing.T) {
+	ids := []string{"node1:5000", "node2:5001", "node3:5002"}
+	network := transport.NewMemoryNetwork()
+	baseDir := t.TempDir()
+	errCh := make(chan error, len(ids))
+
+	addrs := []string{}
+	for _, id := range ids {
+		memTransport, err := network.Listen(id)
+		if err != nil {
+			t.Fatalf("Expected no error listening on %s, got %v", id, err)
+		}
+		n, err := node.NewNodeWithTransport(id, baseDir, memTransport)
+		if err != nil {
+			t.Fatalf("Expected no error creating node %s, got %v", id, err)
+		}
+		n.Start(errCh)
+		t.Cleanup(func() { n.Stop() })
+
+		if err := n.JoinToRing(t.Context(), node.NodeIdToZMQAddr(ids[0])); err != nil {
+			t.Fatalf("Expected node %s to join the ring, got %v", id, err)
+		}
+
+		server := httptest.NewServer(communication.NewWebSocketHandler(n, n.Metrics()))
+		t.Cleanup(server.Close)
+		addrs = append(addrs, strings.TrimPrefix(server.URL, "http://"))
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	history, err := RunCluster(ClusterConfig{
+		Addrs:      addrs,
+		Seed:       1,
+		Clients:    4, // two clients share the first node
+		Lists:      2,
+		Operations: 10,
+		ReadRate:   0.3,
+		AckTimeout: 2 * time.Second,
+		SettleTime: 200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Expected the workload to run, got %v", err)
+	}
+
+	acked := 0
+	for _, op := range history.Operations() {
+		if op.Acked {
+			acked++
+		}
+	}
+	if acked == 0 {
+		t.Errorf("Expected some writes to be acknowledged")
+	}
+
+	for _, violation := range Check(history) {
+		t.Errorf("%s", violation)
+	}
+}
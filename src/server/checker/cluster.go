@@ -0,0 +1,333 @@
+package checker
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"sync"
+	"time"
+
+	crdt "sdle-server/crdt/shopping"
+	pb "sdle-server/proto"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/proto"
+)
+
+// Workload run against a cluster of nodes started separately (e.g. with the server binary)
+type ClusterConfig struct {
+	Addrs      []string      // WebSocket addresses of the nodes (host:port)
+	Seed       uint64        // seed of the operations of the clients
+	Clients    int           // number of concurrent clients, connected to the nodes in turn
+	Lists      int           // number of shopping lists shared by the clients
+	Operations int           // number of edits made by each client
+	ReadRate   float64       // probability that a client reads a list back after an edit
+	AckTimeout time.Duration // time a client waits for its write to be acknowledged
+	SettleTime time.Duration // time the cluster is given to converge before the final reads
+}
+
+// Items per list the clients pick from, kept small so that clients often edit the same items
+const clusterItemsPerList = 4
+
+// Runs the workload and returns its history. Each client subscribes to every list on its node: the node notifies it
+// of its own edit once the edit is stored (the acknowledgement), and of the edits of other clients of the node, which
+// it joins into its replica. After the clients finish, every node is asked for every list as the final states.
+func RunCluster(cfg ClusterConfig) (*History, error) {
+	if len(cfg.Addrs) == 0 || cfg.Clients < 1 || cfg.Lists < 1 {
+		return nil, errors.New("a cluster run needs at least one node, client and list")
+	}
+
+	history := NewHistory()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, cfg.Clients)
+	for i := range cfg.Clients {
+		c := &clusterClient{
+			id:      fmt.Sprintf("client%d", i+1),
+			addr:    cfg.Addrs[i%len(cfg.Addrs)],
+			cfg:     cfg,
+			rng:     rand.New(rand.NewPCG(cfg.Seed, uint64(i))),
+			history: history,
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.run(); err != nil {
+				errs <- fmt.Errorf("%s: %w", c.id, err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return history, err
+	}
+
+	time.Sleep(cfg.SettleTime)
+
+	for _, addr := range cfg.Addrs {
+		if err := observeFinal(addr, cfg.Lists, history); err != nil {
+			return history, fmt.Errorf("final read from %s: %w", addr, err)
+		}
+	}
+
+	return history, nil
+}
+
+type clusterClient struct {
+	id      string
+	addr    string
+	cfg     ClusterConfig
+	rng     *rand.Rand
+	history *History
+	conn    *websocket.Conn
+	lists   []*crdt.ShoppingList
+	reads   int
+
+	// Replica of the list of each subscription of the current connection. Nodes key subscriptions by message ID, so
+	// the IDs are unique to the client and the connection.
+	subscriptions map[string]*crdt.ShoppingList
+	connections   int
+}
+
+func dial(addr string) (*websocket.Conn, error) {
+	u := url.URL{Scheme: "ws", Host: addr, Path: "/ws"}
+	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	return conn, err
+}
+
+func listName(i int) string {
+	return fmt.Sprintf("checker-list%d", i+1)
+}
+
+func send(conn *websocket.Conn, req *pb.ClientRequest) error {
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(websocket.BinaryMessage, data)
+}
+
+// Waits for the next response, up to a deadline
+func receive(conn *websocket.Conn, deadline time.Time) (*pb.ServerResponse, error) {
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return nil, err
+	}
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	var resp pb.ServerResponse
+	if err := proto.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *clusterClient) run() error {
+	for i := range c.cfg.Lists {
+		c.lists = append(c.lists, crdt.NewShoppingList(c.id, listName(i)))
+	}
+
+	if err := c.connect(); err != nil {
+		return err
+	}
+	defer func() { c.conn.Close() }()
+
+	for range c.cfg.Operations {
+		list, delta, index := c.randomOperation()
+
+		if err := send(c.conn, &pb.ClientRequest{
+			MessageId:   fmt.Sprintf("%s-put-%d", c.id, index),
+			RequestType: &pb.ClientRequest_ShoppingList{ShoppingList: delta.ToProto()},
+		}); err != nil {
+			return err
+		}
+
+		acked, err := c.awaitAck(list, delta)
+		if err != nil {
+			return err
+		}
+		if acked {
+			c.history.Ack(index)
+		}
+
+		if c.rng.Float64() < c.cfg.ReadRate {
+			if err := c.read(list.ListID()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Connects to the node of the client and subscribes to every list. The node answers a subscription with its state of
+// the list, which is joined into the replica of the client.
+func (c *clusterClient) connect() error {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+
+	conn, err := dial(c.addr)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	c.connections++
+	c.subscriptions = make(map[string]*crdt.ShoppingList)
+
+	for _, list := range c.lists {
+		messageID := fmt.Sprintf("%s-sub-%d-%s", c.id, c.connections, list.ListID())
+		c.subscriptions[messageID] = list
+
+		err := send(conn, &pb.ClientRequest{
+			MessageId:   messageID,
+			RequestType: &pb.ClientRequest_SubscribeShoppingList{SubscribeShoppingList: &pb.SubscribeShoppingListRequest{Id: list.ListID()}},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func isTimeout(err error) bool {
+	var netErr interface{ Timeout() bool }
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// Edits a random list of the client and records the edit
+func (c *clusterClient) randomOperation() (*crdt.ShoppingList, *crdt.ShoppingList, int) {
+	list := c.lists[c.rng.IntN(len(c.lists))]
+	itemID := fmt.Sprintf("item%d", c.rng.IntN(clusterItemsPerList)+1)
+
+	switch c.rng.IntN(4) {
+	case 0:
+		delta := list.RemoveItem(itemID)
+		return list, delta, c.history.RecordRemove(c.id, list, delta, itemID)
+	case 1:
+		delta := list.PutItem(itemID, itemID, 0, 1)
+		return list, delta, c.history.RecordPut(c.id, list, delta, itemID, 0, 1)
+	default:
+		quantity := int64(c.rng.IntN(5) + 1)
+		delta := list.PutItem(itemID, itemID, quantity, 0)
+		return list, delta, c.history.RecordPut(c.id, list, delta, itemID, quantity, 0)
+	}
+}
+
+// Waits until the node notifies the client of its own delta, joining the notifications about other edits into the
+// replicas of the client. Returns false if the write was not acknowledged in time.
+func (c *clusterClient) awaitAck(list *crdt.ShoppingList, delta *crdt.ShoppingList) (bool, error) {
+	expected := CanonicalState(delta)
+	deadline := time.Now().Add(c.cfg.AckTimeout)
+
+	for {
+		resp, err := receive(c.conn, deadline)
+		if isTimeout(err) {
+			// A connection that timed out cannot be read anymore
+			return false, c.connect()
+		}
+		if err != nil {
+			return false, err
+		}
+
+		notified := c.joinNotification(resp)
+		if notified != nil && notified.ListID() == list.ListID() && CanonicalState(notified) == expected {
+			return true, nil
+		}
+	}
+}
+
+// Joins a subscription notification into the replica of its list, returning the notified state
+func (c *clusterClient) joinNotification(resp *pb.ServerResponse) *crdt.ShoppingList {
+	if resp.GetShoppingList() == nil {
+		return nil
+	}
+
+	notified, err := crdt.ShoppingListFromProto(resp.GetShoppingList(), c.id)
+	if err != nil {
+		// A notification the client cannot decode is not an acknowledgement; reads will show the state instead
+		return nil
+	}
+	if list, ok := c.subscriptions[resp.GetMessageId()]; ok {
+		list.Join(notified)
+	}
+	return notified
+}
+
+func (c *clusterClient) read(listID string) error {
+	c.reads++
+	messageID := fmt.Sprintf("%s-get-%d", c.id, c.reads)
+
+	if err := send(c.conn, &pb.ClientRequest{
+		MessageId:   messageID,
+		RequestType: &pb.ClientRequest_GetShoppingList_{GetShoppingList_: &pb.GetShoppingListRequest{Id: listID}},
+	}); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(c.cfg.AckTimeout)
+	for {
+		resp, err := receive(c.conn, deadline)
+		if isTimeout(err) {
+			// A read that times out observes nothing
+			return c.connect()
+		}
+		if err != nil {
+			return err
+		}
+		if resp.GetMessageId() != messageID {
+			c.joinNotification(resp)
+			continue
+		}
+
+		if resp.GetShoppingList() != nil {
+			observed, err := crdt.ShoppingListFromProto(resp.GetShoppingList(), c.id)
+			if err != nil {
+				return fmt.Errorf("read of %s from %s: %w", listID, c.addr, err)
+			}
+			c.history.Observe(c.id+"@"+c.addr, observed)
+		}
+		return nil
+	}
+}
+
+func observeFinal(addr string, lists int, history *History) error {
+	conn, err := dial(addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for i := range lists {
+		listID := listName(i)
+		if err := send(conn, &pb.ClientRequest{
+			MessageId:   "final-" + listID,
+			RequestType: &pb.ClientRequest_GetShoppingList_{GetShoppingList_: &pb.GetShoppingListRequest{Id: listID}},
+		}); err != nil {
+			return err
+		}
+
+		resp, err := receive(conn, time.Now().Add(10*time.Second))
+		if err != nil {
+			return err
+		}
+
+		// A list the node does not know is empty: any acknowledged write to it was lost
+		final := crdt.NewShoppingList("checker", listID)
+		if resp.GetShoppingList() != nil {
+			if final, err = crdt.ShoppingListFromProto(resp.GetShoppingList(), "checker"); err != nil {
+				return fmt.Errorf("final read of %s from %s: %w", listID, addr, err)
+			}
+		}
+		history.ObserveFinal(addr, final)
+	}
+
+	return nil
+}
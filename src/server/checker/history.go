@@ -0,0 +1,163 @@
+package checker
+
+import (
+	"fmt"
+	"sync"
+
+	generic "sdle-server/crdt/generic"
+	crdt "sdle-server/crdt/shopping"
+)
+
+type OpKind string
+
+const (
+	OpPut    OpKind = "put"    // adds or updates an item (name, quantity and acquired changes)
+	OpRemove OpKind = "remove" // removes an item
+)
+
+// A client edit of a shopping list and the delta it produced
+type Operation struct {
+	Index        int
+	Client       string
+	List         string
+	Kind         OpKind
+	Item         string
+	QuantityDiff int64
+	AcquiredDiff int64
+	Delta        *crdt.ShoppingList
+	Seen         *generic.DotContext // updates the client had seen when it made the edit, the edit included
+	Acked        bool                // the write was acknowledged to the client
+}
+
+// Whether the client that made the other operation had seen this one. A remove creates no dot, so whether another
+// client saw it cannot be told and it is only ordered with the operations of its own client.
+func (op Operation) HappenedBefore(other Operation) bool {
+	if op.Client == other.Client {
+		return op.Index < other.Index
+	}
+	if op.Kind == OpRemove {
+		return false
+	}
+	return other.Seen.Includes(op.Delta.Context())
+}
+
+func (op Operation) String() string {
+	ack := "unacked"
+	if op.Acked {
+		ack = "acked"
+	}
+
+	if op.Kind == OpRemove {
+		return fmt.Sprintf("#%d %s: remove %s from %s (%s)", op.Index, op.Client, op.Item, op.List, ack)
+	}
+	return fmt.Sprintf("#%d %s: put %s in %s, quantity %+d, acquired %+d (%s)",
+		op.Index, op.Client, op.Item, op.List, op.QuantityDiff, op.AcquiredDiff, ack)
+}
+
+// A state of a shopping list seen by a reader (a client read, or a replica inspected directly)
+type Observation struct {
+	Reader   string
+	List     string
+	State    *crdt.ShoppingList
+	AfterOps int  // number of operations recorded before the observation
+	Final    bool // observed after the cluster settled: every acknowledged write must be visible
+}
+
+func (obs Observation) String() string {
+	kind := "read"
+	if obs.Final {
+		kind = "final state"
+	}
+	return fmt.Sprintf("%s of %s by %s after op #%d: %s", kind, obs.List, obs.Reader, obs.AfterOps-1, obs.State)
+}
+
+// History of client operations and observed states. Safe for concurrent use, so clients running in parallel against
+// a real cluster can share it.
+type History struct {
+	mu           sync.Mutex
+	operations   []Operation
+	observations []Observation
+}
+
+func NewHistory() *History {
+	return &History{}
+}
+
+// Records a put of an item, given the replica of the client after the edit and the delta it produced. Both are
+// copied, so the caller may keep using them.
+func (h *History) RecordPut(client string, replica *crdt.ShoppingList, delta *crdt.ShoppingList, item string, quantityDiff int64, acquiredDiff int64) int {
+	return h.record(Operation{
+		Client:       client,
+		List:         delta.ListID(),
+		Kind:         OpPut,
+		Item:         item,
+		QuantityDiff: quantityDiff,
+		AcquiredDiff: acquiredDiff,
+		Delta:        delta.Clone(),
+		Seen:         replica.Context().Clone(),
+	})
+}
+
+func (h *History) RecordRemove(client string, replica *crdt.ShoppingList, delta *crdt.ShoppingList, item string) int {
+	return h.record(Operation{
+		Client: client,
+		List:   delta.ListID(),
+		Kind:   OpRemove,
+		Item:   item,
+		Delta:  delta.Clone(),
+		Seen:   replica.Context().Clone(),
+	})
+}
+
+func (h *History) record(op Operation) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	op.Index = len(h.operations)
+	h.operations = append(h.operations, op)
+	return op.Index
+}
+
+// Marks an operation as acknowledged to its client
+func (h *History) Ack(index int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.operations[index].Acked = true
+}
+
+func (h *History) Observe(reader string, state *crdt.ShoppingList) {
+	h.observe(reader, state, false)
+}
+
+// Records a state observed once the cluster settled
+func (h *History) ObserveFinal(reader string, state *crdt.ShoppingList) {
+	h.observe(reader, state, true)
+}
+
+func (h *History) observe(reader string, state *crdt.ShoppingList, final bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.observations = append(h.observations, Observation{
+		Reader:   reader,
+		List:     state.ListID(),
+		State:    state.Clone(),
+		AfterOps: len(h.operations),
+		Final:    final,
+	})
+}
+
+func (h *History) Operations() []Operation {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]Operation{}, h.operations...)
+}
+
+func (h *History) Observations() []Observation {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]Observation{}, h.observations...)
+}
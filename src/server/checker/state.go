@@ -0,0 +1,145 @@
+package checker
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	generic "sdle-server/crdt/generic"
+	crdt "sdle-server/crdt/shopping"
+	pb "sdle-server/proto"
+)
+
+// Quantity and acquired values of an item
+type Counts struct {
+	Quantity int64
+	Acquired int64
+}
+
+func (c Counts) invariantProblem() string {
+	switch {
+	case c.Quantity < 0:
+		return fmt.Sprintf("negative quantity %d", c.Quantity)
+	case c.Acquired < 0:
+		return fmt.Sprintf("negative acquired %d", c.Acquired)
+	case c.Acquired > c.Quantity:
+		return fmt.Sprintf("acquired %d exceeds quantity %d", c.Acquired, c.Quantity)
+	}
+	return ""
+}
+
+// Quantity and acquired values of every item of a list, read as signed sums so that underflows show
+func ItemCounts(list *crdt.ShoppingList) map[string]Counts {
+	counts := map[string]Counts{}
+	for itemID, item := range list.ToProto().GetItems() {
+		counts[itemID] = Counts{
+			Quantity: sum(item.GetQuantity().GetDotKernel().GetDotValues()),
+			Acquired: sum(item.GetAcquired().GetDotKernel().GetDotValues()),
+		}
+	}
+	return counts
+}
+
+func sum(values []int64) int64 {
+	total := int64(0)
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// Dots of every field of an item
+func ItemDots(list *crdt.ShoppingList, itemID string) []generic.Dot {
+	item, ok := list.ToProto().GetItems()[itemID]
+	if !ok {
+		return nil
+	}
+
+	dots := []generic.Dot{}
+	for _, keys := range [][]*pb.Dot{
+		item.GetName().GetDotKernel().GetDotKeys(),
+		item.GetQuantity().GetDotKernel().GetDotKeys(),
+		item.GetAcquired().GetDotKernel().GetDotKeys(),
+		item.GetDeleted().GetDotKernel().GetDotKeys(),
+	} {
+		for _, dot := range keys {
+			// The list was decoded, so its dots are valid
+			if d, err := generic.DotFromProto(dot); err == nil {
+				dots = append(dots, d)
+			}
+		}
+	}
+	return dots
+}
+
+// Renders a list in a form that does not depend on the order of its maps and repeated fields, so that equal states
+// render equally
+func CanonicalState(list *crdt.ShoppingList) string {
+	listProto := list.ToProto()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "name=%s ctx=%s items={", canonicalStrings(listProto.GetName().GetDotKernel()), CanonicalContext(list))
+	items := CanonicalItems(list)
+	for _, itemID := range slices.Sorted(maps.Keys(items)) {
+		fmt.Fprintf(&b, " %s:%s", itemID, items[itemID])
+	}
+	b.WriteString(" }")
+
+	return b.String()
+}
+
+// Canonical rendering of each item of a list
+func CanonicalItems(list *crdt.ShoppingList) map[string]string {
+	items := map[string]string{}
+	for itemID, item := range list.ToProto().GetItems() {
+		items[itemID] = fmt.Sprintf("[name=%s qty=%s acq=%s del=%s]",
+			canonicalStrings(item.GetName().GetDotKernel()),
+			canonicalInts(item.GetQuantity().GetDotKernel()),
+			canonicalInts(item.GetAcquired().GetDotKernel()),
+			canonicalDots(item.GetDeleted().GetDotKernel().GetDotKeys()))
+	}
+	return items
+}
+
+// Canonical rendering of the updates a list has seen
+func CanonicalContext(list *crdt.ShoppingList) string {
+	ctx := list.ToProto().GetDotContext()
+
+	parts := []string{}
+	for _, id := range slices.Sorted(maps.Keys(ctx.GetVersionVector())) {
+		parts = append(parts, fmt.Sprintf("%s:%d", id, ctx.GetVersionVector()[id]))
+	}
+	return "{" + strings.Join(parts, ",") + "}" + canonicalDots(ctx.GetDots())
+}
+
+func dotString(dot *pb.Dot) string {
+	return fmt.Sprintf("%s.%d", dot.GetId(), dot.GetSeq())
+}
+
+func canonicalDots(dots []*pb.Dot) string {
+	parts := []string{}
+	for _, dot := range dots {
+		parts = append(parts, dotString(dot))
+	}
+	slices.Sort(parts)
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func canonicalStrings(kernel *pb.StringDotKernel) string {
+	parts := []string{}
+	for i, dot := range kernel.GetDotKeys() {
+		parts = append(parts, dotString(dot)+"="+kernel.GetDotValues()[i])
+	}
+	slices.Sort(parts)
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func canonicalInts(kernel *pb.IntDotKernel) string {
+	parts := []string{}
+	for i, dot := range kernel.GetDotKeys() {
+		parts = append(parts, fmt.Sprintf("%s=%d", dotString(dot), kernel.GetDotValues()[i]))
+	}
+	slices.Sort(parts)
+	return "{" + strings.Join(parts, ",") + "}"
+}
@@ -0,0 +1,64 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Source of time for a node. Nodes use the real clock; the simulator swaps in a virtual one so that runs do not
+// depend on wall-clock time.
+type Clock interface {
+	Now() time.Time
+
+	// Fires after d has passed
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func Real() Clock {
+	return realClock{}
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Clock that only moves when told to. Waiting on After moves the clock forward instead of blocking, which is what a
+// single-threaded simulation needs: nothing else could advance the time while the caller waits.
+type Virtual struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewVirtual(start time.Time) *Virtual {
+	return &Virtual{now: start}
+}
+
+func (v *Virtual) Now() time.Time {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.now
+}
+
+func (v *Virtual) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- v.Advance(d)
+	return ch
+}
+
+// Moves the clock forward by d (negative durations are ignored) and returns the new time
+func (v *Virtual) Advance(d time.Duration) time.Time {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if d > 0 {
+		v.now = v.now.Add(d)
+	}
+	return v.now
+}
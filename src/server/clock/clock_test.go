@@ -0,0 +1,36 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVirtual_Advance(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	v := NewVirtual(start)
+
+	if !v.Now().Equal(start) {
+		t.Errorf("Expected the clock to start at %v, got %v", start, v.Now())
+	}
+
+	v.Advance(time.Minute)
+	v.Advance(-time.Hour)
+
+	if expected := start.Add(time.Minute); !v.Now().Equal(expected) {
+		t.Errorf("Expected %v after advancing, got %v", expected, v.Now())
+	}
+}
+
+func TestVirtual_AfterAdvancesTheClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	v := NewVirtual(start)
+
+	select {
+	case fired := <-v.After(time.Second):
+		if expected := start.Add(time.Second); !fired.Equal(expected) || !v.Now().Equal(expected) {
+			t.Errorf("Expected After to fire at %v, got %v (clock at %v)", expected, fired, v.Now())
+		}
+	default:
+		t.Errorf("Expected After to fire without blocking")
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"sdle-server/checker"
+)
+
+func main() {
+	nodes := flag.String("nodes", "localhost:8000,localhost:8001,localhost:8002", "comma-separated WebSocket addresses of the nodes")
+	seed := flag.Uint64("seed", uint64(time.Now().UnixNano()), "seed of the client operations")
+	clients := flag.Int("clients", 4, "number of concurrent clients")
+	lists := flag.Int("lists", 2, "number of shopping lists")
+	operations := flag.Int("ops", 50, "number of edits per client")
+	readRate := flag.Float64("read-rate", 0.2, "probability of reading a list back after an edit")
+	ackTimeout := flag.Duration("ack-timeout", 2*time.Second, "time to wait for a write to be acknowledged")
+	settleTime := flag.Duration("settle", 5*time.Second, "time given to the cluster to converge before the final reads")
+	flag.Parse()
+
+	fmt.Printf("Running %d clients against %s (seed %d)\n", *clients, *nodes, *seed)
+
+	history, err := checker.RunCluster(checker.ClusterConfig{
+		Addrs:      strings.Split(*nodes, ","),
+		Seed:       *seed,
+		Clients:    *clients,
+		Lists:      *lists,
+		Operations: *operations,
+		ReadRate:   *readRate,
+		AckTimeout: *ackTimeout,
+		SettleTime: *settleTime,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error running the workload:", err)
+		os.Exit(1)
+	}
+
+	violations := checker.Check(history)
+	fmt.Printf("Checked %d operations and %d observations\n", len(history.Operations()), len(history.Observations()))
+	for _, violation := range violations {
+		fmt.Println(violation)
+	}
+
+	if len(violations) > 0 {
+		fmt.Printf("%d violations found\n", len(violations))
+		os.Exit(1)
+	}
+	fmt.Println("No violations found")
+}
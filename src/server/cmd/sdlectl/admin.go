@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"sdle-server/admin"
+	"sdle-server/node"
+)
+
+// Sends a GET request to the admin API of a node and decodes its JSON response
+func (c *cli) adminGet(addr string, path string, query url.Values, result any) error {
+	u := url.URL{Scheme: "http", Host: addr, Path: path, RawQuery: query.Encode()}
+	client := http.Client{Timeout: c.timeout}
+
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr admin.Error
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("%s: %s", resp.Status, apiErr.Error)
+		}
+		return fmt.Errorf("%s %s: %s", path, addr, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+func (c *cli) status() error {
+	var status admin.Status
+	if err := c.adminGet(c.adminAddr, "/admin/status", nil, &status); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(status)
+	}
+
+	t := c.table()
+	fmt.Fprintf(t, "Node\t%s\n", status.ID)
+	fmt.Fprintf(t, "Tokens\t%d\n", len(status.Tokens))
+	fmt.Fprintf(t, "Ring nodes\t%v\n", status.RingNodes)
+	fmt.Fprintf(t, "Suspected\t%v\n", status.Suspected)
+	fmt.Fprintf(t, "Quorum\tN=%d W=%d R=%d\n", status.N, status.W, status.R)
+	fmt.Fprintf(t, "Hints\t%d\n", status.Hints)
+	fmt.Fprintf(t, "Subscriptions\t%d\n", status.Subscriptions)
+	fmt.Fprintf(t, "Store\t%d bytes (LSM), %d bytes (value log)\n", status.StoreLSMBytes, status.StoreLogBytes)
+	return t.Flush()
+}
+
+func (c *cli) ring() error {
+	var ring admin.Ring
+	if err := c.adminGet(c.adminAddr, "/admin/ring", nil, &ring); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(ring)
+	}
+
+	t := c.table()
+	fmt.Fprintln(t, "TOKEN\tSTART\tEND\tNODE")
+	for _, r := range ring.Ranges {
+		fmt.Fprintf(t, "%d\t%d\t%d\t%s\n", r.Token, r.Start, r.End, r.Node)
+	}
+	return t.Flush()
+}
+
+func (c *cli) hints() error {
+	var hints admin.Hints
+	if err := c.adminGet(c.adminAddr, "/admin/hints", nil, &hints); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(hints)
+	}
+
+	t := c.table()
+	fmt.Fprintf(t, "Stored\t%d (%d bytes)\n", hints.Total, hints.Bytes)
+	fmt.Fprintf(t, "Merged\t%d\n", hints.Merged)
+	fmt.Fprintf(t, "Rejected\t%d\n", hints.Rejected)
+	fmt.Fprintf(t, "Expired\t%d\n", hints.Expired)
+	fmt.Fprintf(t, "Delivered\t%d\n", hints.Delivered)
+	if len(hints.PerTarget) > 0 {
+		fmt.Fprintln(t, "\nINTENDED NODE\tHINTS")
+		for _, target := range slices.Sorted(maps.Keys(hints.PerTarget)) {
+			fmt.Fprintf(t, "%s\t%d\n", target, hints.PerTarget[target])
+		}
+	}
+	return t.Flush()
+}
+
+// A node responsible for a key, and whether it holds the key
+type replica struct {
+	Node     string `json:"node"`
+	Fallback bool   `json:"fallback"`
+	Holds    bool   `json:"holds"`
+	Size     int64  `json:"size,omitempty"`
+	Error    string `json:"error,omitempty"` // the node could not be asked
+}
+
+type replicas struct {
+	Key      string    `json:"key"`
+	Hash     uint64    `json:"hash"`
+	Replicas []replica `json:"replicas"`
+}
+
+// Shows the preference list of a key, as seen by the node, and asks each replica (and fallback) whether it holds the key
+func (c *cli) replicas(key string) error {
+	var prefList admin.PreferenceList
+	if err := c.adminGet(c.adminAddr, "/admin/preference-list", url.Values{"key": {key}}, &prefList); err != nil {
+		return err
+	}
+
+	result := replicas{Key: prefList.Key, Hash: prefList.Hash, Replicas: []replica{}}
+	for i, nodeId := range slices.Concat(prefList.Nodes, prefList.Fallbacks) {
+		r := replica{Node: nodeId, Fallback: i >= len(prefList.Nodes)}
+		if err := c.lookupKey(&r, key, prefList.Hash); err != nil {
+			r.Error = err.Error()
+		}
+		result.Replicas = append(result.Replicas, r)
+	}
+
+	if c.json {
+		return c.printJSON(result)
+	}
+
+	fmt.Fprintf(c.out, "Key %s (hash %d)\n\n", result.Key, result.Hash)
+	t := c.table()
+	fmt.Fprintln(t, "NODE\tROLE\tHOLDS KEY\tSIZE")
+	for _, r := range result.Replicas {
+		role := "replica"
+		if r.Fallback {
+			role = "fallback"
+		}
+		holds, size := "no", "-"
+		switch {
+		case r.Error != "":
+			holds = "unknown (" + r.Error + ")"
+		case r.Holds:
+			holds, size = "yes", strconv.FormatInt(r.Size, 10)
+		}
+		fmt.Fprintf(t, "%s\t%s\t%s\t%s\n", r.Node, role, holds, size)
+	}
+	return t.Flush()
+}
+
+func (c *cli) lookupKey(r *replica, key string, hash uint64) error {
+	// The admin API of a node is only served on its host, so remote nodes are reported as unknown
+	addr, err := node.AdminAddr(r.Node)
+	if err != nil {
+		return err
+	}
+
+	hashStr := strconv.FormatUint(hash, 10)
+	var keys admin.Keys
+	if err := c.adminGet(addr, "/admin/keys", url.Values{"start": {hashStr}, "end": {hashStr}}, &keys); err != nil {
+		return err
+	}
+
+	for _, k := range keys.Keys {
+		if k.Key == key {
+			r.Holds, r.Size = true, k.Size
+		}
+	}
+	return nil
+}
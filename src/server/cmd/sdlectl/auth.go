@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"sdle-server/auth"
+	pb "sdle-server/proto"
+)
+
+type tokenView struct {
+	User    string    `json:"user"`
+	Expires time.Time `json:"expires"`
+	Token   string    `json:"token"`
+}
+
+// Signs a session token for a user with the auth key of the cluster
+func (c *cli) signToken(user string, keyFile string, ttl time.Duration) error {
+	key, err := auth.LoadKey(keyFile)
+	if err != nil {
+		return err
+	}
+
+	expires := time.Now().Add(ttl).Truncate(time.Second)
+	token, err := auth.Sign(key, user, expires)
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		return c.printJSON(tokenView{User: user, Expires: expires, Token: token})
+	}
+	fmt.Fprintln(c.out, token)
+	return nil
+}
+
+// Authenticates a session with the token of the CLI
+func (s *session) authenticate(token string, deadline time.Time) error {
+	messageID, err := s.send(&pb.ClientRequest{
+		RequestType: &pb.ClientRequest_Authenticate{Authenticate: &pb.AuthenticateRequest{Token: token}},
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.await(messageID, deadline)
+	if err != nil {
+		return err
+	}
+	if resp.GetAuthenticated() == nil {
+		return fmt.Errorf("authentication failed: %s", resp.GetError())
+	}
+	return nil
+}
+
+// Sends a request about the ACL of a list and waits for the ACL in the response
+func (s *session) acl(req *pb.ClientRequest, listID string, deadline time.Time) (*pb.ListAcl, error) {
+	messageID, err := s.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.await(messageID, deadline)
+	if err != nil {
+		return nil, err
+	}
+	if resp.GetListAcl() == nil {
+		return nil, fmt.Errorf("ACL of list %s: %s", listID, resp.GetError())
+	}
+	return resp.GetListAcl(), nil
+}
+
+type aclView struct {
+	ListID  string   `json:"list_id"`
+	Owner   string   `json:"owner"`
+	Editors []string `json:"editors"`
+	Viewers []string `json:"viewers"`
+	Version uint64   `json:"version"`
+}
+
+// Prints the ACL of a list
+func (c *cli) getListAcl(listID string) error {
+	s, err := c.connect()
+	if err != nil {
+		return err
+	}
+	defer s.conn.Close()
+
+	acl, err := s.acl(&pb.ClientRequest{
+		RequestType: &pb.ClientRequest_GetListAcl{GetListAcl: &pb.GetListAclRequest{Id: listID}},
+	}, listID, time.Now().Add(c.timeout))
+	if err != nil {
+		return err
+	}
+	return c.printAcl(acl)
+}
+
+// Replaces the editors and viewers of a list (comma-separated user IDs)
+func (c *cli) setListAcl(listID string, editors string, viewers string) error {
+	s, err := c.connect()
+	if err != nil {
+		return err
+	}
+	defer s.conn.Close()
+
+	acl, err := s.acl(&pb.ClientRequest{
+		RequestType: &pb.ClientRequest_SetListAcl{SetListAcl: &pb.SetListAclRequest{
+			Id:      listID,
+			Editors: splitUsers(editors),
+			Viewers: splitUsers(viewers),
+		}},
+	}, listID, time.Now().Add(c.timeout))
+	if err != nil {
+		return err
+	}
+	return c.printAcl(acl)
+}
+
+func splitUsers(users string) []string {
+	if users == "" {
+		return nil
+	}
+	return strings.Split(users, ",")
+}
+
+func (c *cli) printAcl(acl *pb.ListAcl) error {
+	view := aclView{
+		ListID:  acl.GetListId(),
+		Owner:   acl.GetOwner(),
+		Editors: append([]string{}, acl.GetEditors()...),
+		Viewers: append([]string{}, acl.GetViewers()...),
+		Version: acl.GetVersion(),
+	}
+	if c.json {
+		return c.printJSON(view)
+	}
+
+	t := c.table()
+	fmt.Fprintf(t, "LIST\t%s\n", view.ListID)
+	fmt.Fprintf(t, "OWNER\t%s\n", view.Owner)
+	fmt.Fprintf(t, "EDITORS\t%s\n", strings.Join(view.Editors, ", "))
+	fmt.Fprintf(t, "VIEWERS\t%s\n", strings.Join(view.Viewers, ", "))
+	fmt.Fprintf(t, "VERSION\t%d\n", view.Version)
+	return t.Flush()
+}
+
+type shareView struct {
+	Capability string    `json:"capability"`
+	ID         string    `json:"id"`
+	ListID     string    `json:"list_id"`
+	Access     string    `json:"access"`
+	Expires    time.Time `json:"expires,omitzero"`
+}
+
+// Mints a share capability of a list, printing its token
+func (c *cli) share(listID string, access pb.ShareAccess, ttl time.Duration) error {
+	s, err := c.connect()
+	if err != nil {
+		return err
+	}
+	defer s.conn.Close()
+
+	messageID, err := s.send(&pb.ClientRequest{
+		RequestType: &pb.ClientRequest_CreateShareLink{CreateShareLink: &pb.CreateShareLinkRequest{
+			Id:         listID,
+			Access:     access,
+			TtlSeconds: int64(ttl / time.Second),
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := s.await(messageID, time.Now().Add(c.timeout))
+	if err != nil {
+		return err
+	}
+	link := resp.GetShareLink()
+	if link == nil {
+		return fmt.Errorf("sharing list %s: %s", listID, resp.GetError())
+	}
+
+	if c.json {
+		view := shareView{Capability: link.GetCapability(), ID: link.GetCapabilityId(), ListID: link.GetListId(),
+			Access: link.GetAccess().String()}
+		if link.GetExpires() != 0 {
+			view.Expires = time.Unix(link.GetExpires(), 0)
+		}
+		return c.printJSON(view)
+	}
+	fmt.Fprintln(c.out, link.GetCapability())
+	return nil
+}
+
+// Revokes a share capability
+func (c *cli) revoke(capability string) error {
+	s, err := c.connect()
+	if err != nil {
+		return err
+	}
+	defer s.conn.Close()
+
+	messageID, err := s.send(&pb.ClientRequest{
+		RequestType: &pb.ClientRequest_RevokeShareLink{RevokeShareLink: &pb.RevokeShareLinkRequest{Capability: capability}},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := s.await(messageID, time.Now().Add(c.timeout))
+	if err != nil {
+		return err
+	}
+	if resp.GetOk() == nil {
+		return fmt.Errorf("revoking capability: %s", resp.GetError())
+	}
+	return nil
+}
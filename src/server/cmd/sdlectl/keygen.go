@@ -0,0 +1,68 @@
+package main
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+type keypair struct {
+	Node          string `json:"node"`
+	PublicKey     string `json:"public_key"`
+	SecretKeyFile string `json:"secret_key_file"`
+}
+
+// Generates a CurveZMQ keypair for a node: the secret key is written to a new file, readable by its owner only, and
+// the public key is printed as a line of the public keys file of the cluster
+func (c *cli) keygen(nodeId string, secretKeyFile string) error {
+	publicKey, secretKey, err := newCurveKeypair()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(secretKeyFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(f, secretKey); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if c.json {
+		return c.printJSON(keypair{Node: nodeId, PublicKey: publicKey, SecretKeyFile: secretKeyFile})
+	}
+	fmt.Fprintf(c.out, "%s %s\n", nodeId, publicKey)
+	return nil
+}
+
+// Generates a Z85-encoded CurveZMQ keypair. CurveZMQ keys are X25519 keys, so they are generated without libzmq.
+func newCurveKeypair() (publicKey string, secretKey string, err error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return z85Encode(key.PublicKey().Bytes()), z85Encode(key.Bytes()), nil
+}
+
+const z85Alphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ.-:+=^!/*?&<>()[]{}@%$#"
+
+// Encodes data, whose length must be a multiple of 4, in Z85 (ZeroMQ RFC 32): every 4 bytes become 5 characters
+func z85Encode(data []byte) string {
+	encoded := make([]byte, 0, len(data)/4*5)
+	for i := 0; i+4 <= len(data); i += 4 {
+		value := binary.BigEndian.Uint32(data[i:])
+		var chunk [5]byte
+		for j := 4; j >= 0; j-- {
+			chunk[j] = z85Alphabet[value%85]
+			value /= 85
+		}
+		encoded = append(encoded, chunk[:]...)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"crypto/ecdh"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func z85Decode(s string) []byte {
+	data := make([]byte, 0, len(s)/5*4)
+	for i := 0; i+5 <= len(s); i += 5 {
+		var value uint32
+		for _, c := range []byte(s[i : i+5]) {
+			value = value*85 + uint32(strings.IndexByte(z85Alphabet, c))
+		}
+		data = binary.BigEndian.AppendUint32(data, value)
+	}
+	return data
+}
+
+func TestZ85Encode(t *testing.T) {
+	// Test vector of ZeroMQ RFC 32
+	if got := z85Encode([]byte{0x86, 0x4F, 0xD2, 0x6F, 0xB5, 0x59, 0xF7, 0x5B}); got != "HelloWorld" {
+		t.Errorf("Expected HelloWorld, got %s", got)
+	}
+}
+
+func TestNewCurveKeypair(t *testing.T) {
+	// Keypair of the CurveZMQ examples of libzmq: the public key derived from the secret key must match
+	secret, err := ecdh.X25519().NewPrivateKey(z85Decode("JTKVSB%%)wK0E.X)V>+}o?pNmC{O&4W4b!Ni{Lh6"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := z85Encode(secret.PublicKey().Bytes()); got != "rq:rM>}U?@Lns47E1%kR.o@n%FcmmsL/@{H8]yf7" {
+		t.Errorf("Expected the public key of the libzmq example, got %s", got)
+	}
+
+	publicKey, secretKey, err := newCurveKeypair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(publicKey) != 40 || len(secretKey) != 40 || publicKey == secretKey {
+		t.Errorf("Expected two distinct 40-character keys, got %q and %q", publicKey, secretKey)
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"sdle-server/checker"
+	crdt "sdle-server/crdt/shopping"
+	pb "sdle-server/proto"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/proto"
+)
+
+// Changes a replica of a list, returning the delta to send to the node
+type edit func(list *crdt.ShoppingList) (*crdt.ShoppingList, error)
+
+func setName(name string) edit {
+	return func(list *crdt.ShoppingList) (*crdt.ShoppingList, error) {
+		return list.SetName(name), nil
+	}
+}
+
+func addItem(itemID string, quantity int64) edit {
+	return func(list *crdt.ShoppingList) (*crdt.ShoppingList, error) {
+		return list.PutItem(itemID, itemName(list, itemID), quantity, 0), nil
+	}
+}
+
+func acquireItem(itemID string, acquired int64) edit {
+	return func(list *crdt.ShoppingList) (*crdt.ShoppingList, error) {
+		if !hasItem(list, itemID) {
+			return nil, fmt.Errorf("list %s has no item %s", list.ListID(), itemID)
+		}
+		return list.PutItem(itemID, itemName(list, itemID), 0, acquired), nil
+	}
+}
+
+func removeItem(itemID string) edit {
+	return func(list *crdt.ShoppingList) (*crdt.ShoppingList, error) {
+		if !hasItem(list, itemID) {
+			return nil, fmt.Errorf("list %s has no item %s", list.ListID(), itemID)
+		}
+		return list.RemoveItem(itemID), nil
+	}
+}
+
+func hasItem(list *crdt.ShoppingList, itemID string) bool {
+	item := list.GetItem(itemID)
+	return item != nil && !item.Deleted()
+}
+
+// Name of an item: the current one if the item exists, its ID otherwise
+func itemName(list *crdt.ShoppingList, itemID string) string {
+	if item := list.GetItem(itemID); item != nil && item.Name() != "" {
+		return item.Name()
+	}
+	return itemID
+}
+
+// WebSocket connection to a node
+type session struct {
+	conn       *websocket.Conn
+	replicaID  string // unique to the session, so the dots of its edits never clash with those of other clients
+	capability string // share capability sent with every request (none if empty)
+	requests   int
+}
+
+func (c *cli) connect() (*session, error) {
+	u := url.URL{Scheme: "ws", Host: c.addr, Path: "/ws"}
+	dialer := websocket.Dialer{HandshakeTimeout: c.timeout}
+	conn, _, err := dialer.Dial(u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &session{conn: conn, replicaID: "sdlectl-" + rand.Text()[:8], capability: c.capability}
+	if c.token != "" {
+		if err := s.authenticate(c.token, time.Now().Add(c.timeout)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Sends a request, returning its message ID
+func (s *session) send(req *pb.ClientRequest) (string, error) {
+	s.requests++
+	req.MessageId = fmt.Sprintf("%s-%d", s.replicaID, s.requests)
+	req.Capability = s.capability
+
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	return req.MessageId, s.conn.WriteMessage(websocket.BinaryMessage, data)
+}
+
+// Waits for the response to a request, up to a deadline, skipping the responses to other requests
+func (s *session) await(messageID string, deadline time.Time) (*pb.ServerResponse, error) {
+	for {
+		resp, err := s.receive(deadline)
+		if err != nil || resp.GetMessageId() == messageID {
+			return resp, err
+		}
+	}
+}
+
+// Waits for the next response, up to a deadline (none if zero)
+func (s *session) receive(deadline time.Time) (*pb.ServerResponse, error) {
+	if err := s.conn.SetReadDeadline(deadline); err != nil {
+		return nil, err
+	}
+	_, data, err := s.conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	var resp pb.ServerResponse
+	if err := proto.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+var errNotFound = errors.New("not found")
+
+// Reads a list from the node. Returns errNotFound if the node has no replica of it (or could not read it).
+func (s *session) get(listID string, deadline time.Time) (*crdt.ShoppingList, error) {
+	messageID, err := s.send(&pb.ClientRequest{
+		RequestType: &pb.ClientRequest_GetShoppingList_{GetShoppingList_: &pb.GetShoppingListRequest{Id: listID}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		resp, err := s.receive(deadline)
+		if err != nil {
+			return nil, err
+		}
+		if resp.GetMessageId() != messageID {
+			continue
+		}
+
+		if resp.GetShoppingList() == nil {
+			if resp.GetError() == pb.ErrorCode_NOT_FOUND {
+				return nil, fmt.Errorf("list %s: %w", listID, errNotFound)
+			}
+			return nil, fmt.Errorf("list %s: %s", listID, resp.GetError())
+		}
+		return crdt.ShoppingListFromProto(resp.GetShoppingList(), s.replicaID)
+	}
+}
+
+func (s *session) subscribe(listID string) (string, error) {
+	return s.send(&pb.ClientRequest{
+		RequestType: &pb.ClientRequest_SubscribeShoppingList{SubscribeShoppingList: &pb.SubscribeShoppingListRequest{Id: listID}},
+	})
+}
+
+func (c *cli) getList(listID string) error {
+	s, err := c.connect()
+	if err != nil {
+		return err
+	}
+	defer s.conn.Close()
+
+	list, err := s.get(listID, time.Now().Add(c.timeout))
+	if err != nil {
+		return err
+	}
+	return c.printList(list)
+}
+
+// Applies an edit to the current state of a list (an empty list if the node has none) and sends the delta. The edit is
+// acknowledged once the node notifies the subscribers of the list of the delta, which it does after a write quorum.
+func (c *cli) editList(listID string, e edit) error {
+	s, err := c.connect()
+	if err != nil {
+		return err
+	}
+	defer s.conn.Close()
+
+	deadline := time.Now().Add(c.timeout)
+
+	list, err := s.get(listID, deadline)
+	if errors.Is(err, errNotFound) {
+		list = crdt.NewShoppingList(s.replicaID, listID)
+	} else if err != nil {
+		return err
+	}
+
+	delta, err := e(list)
+	if err != nil {
+		return err
+	}
+
+	subscriptionID, err := s.subscribe(listID)
+	if err != nil {
+		return err
+	}
+	editID, err := s.send(&pb.ClientRequest{
+		RequestType: &pb.ClientRequest_ShoppingList{ShoppingList: delta.ToProto()},
+	})
+	if err != nil {
+		return err
+	}
+
+	expected := checker.CanonicalState(delta)
+	for {
+		resp, err := s.receive(deadline)
+		if isTimeout(err) {
+			return fmt.Errorf("the node did not acknowledge the edit within %s (it may still be applied)", c.timeout)
+		}
+		if err != nil {
+			return err
+		}
+
+		if resp.GetMessageId() == editID && resp.GetShoppingList() == nil {
+			return fmt.Errorf("edit rejected: %s", resp.GetError())
+		}
+		if resp.GetMessageId() == subscriptionID && resp.GetShoppingList() == nil {
+			return fmt.Errorf("subscription rejected: %s", resp.GetError())
+		}
+		if resp.GetMessageId() != subscriptionID || resp.GetShoppingList() == nil {
+			continue
+		}
+
+		notified, err := crdt.ShoppingListFromProto(resp.GetShoppingList(), s.replicaID)
+		if err != nil {
+			continue
+		}
+		list.Join(notified)
+		if checker.CanonicalState(notified) == expected {
+			return c.printList(list)
+		}
+	}
+}
+
+// Prints the state of a list, then every update the node notifies, until the connection closes
+func (c *cli) watchList(listID string) error {
+	s, err := c.connect()
+	if err != nil {
+		return err
+	}
+	defer s.conn.Close()
+
+	subscriptionID, err := s.subscribe(listID)
+	if err != nil {
+		return err
+	}
+
+	list := crdt.NewShoppingList(s.replicaID, listID)
+	for {
+		resp, err := s.receive(time.Time{})
+		if err != nil {
+			return err
+		}
+		if resp.GetMessageId() != subscriptionID {
+			continue
+		}
+		if resp.GetShoppingList() == nil {
+			return fmt.Errorf("subscription rejected: %s", resp.GetError())
+		}
+
+		notified, err := crdt.ShoppingListFromProto(resp.GetShoppingList(), s.replicaID)
+		if err != nil {
+			return fmt.Errorf("notification for list %s: %w", listID, err)
+		}
+		list.Join(notified)
+
+		if !c.json {
+			fmt.Fprintf(c.out, "--- %s\n", time.Now().Format(time.TimeOnly))
+		}
+		if err := c.printList(list); err != nil {
+			return err
+		}
+	}
+}
+
+func isTimeout(err error) bool {
+	var netErr interface{ Timeout() bool }
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+type itemView struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Quantity uint64 `json:"quantity"`
+	Acquired uint64 `json:"acquired"`
+}
+
+type listView struct {
+	ID    string     `json:"id"`
+	Name  string     `json:"name"`
+	Items []itemView `json:"items"` // sorted by ID
+}
+
+func (c *cli) printList(list *crdt.ShoppingList) error {
+	view := listView{ID: list.ListID(), Name: list.Name(), Items: []itemView{}}
+	for _, item := range list.Items() {
+		view.Items = append(view.Items, itemView{ID: item.ItemID(), Name: item.Name(), Quantity: item.Quantity(), Acquired: item.Acquired()})
+	}
+	slices.SortFunc(view.Items, func(a, b itemView) int { return strings.Compare(a.ID, b.ID) })
+
+	if c.json {
+		return c.printJSON(view)
+	}
+
+	fmt.Fprintf(c.out, "%s (%s)\n", view.Name, view.ID)
+	if len(view.Items) == 0 {
+		fmt.Fprintln(c.out, "No items")
+		return nil
+	}
+
+	t := c.table()
+	fmt.Fprintln(t, "ITEM\tNAME\tACQUIRED")
+	for _, item := range view.Items {
+		fmt.Fprintf(t, "%s\t%s\t%d/%d\n", item.ID, item.Name, item.Acquired, item.Quantity)
+	}
+	return t.Flush()
+}
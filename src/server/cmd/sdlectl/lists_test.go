@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"sdle-server/auth"
+	"sdle-server/communication"
+	"sdle-server/node"
+	"sdle-server/transport"
+)
+
+// Starts an in-memory cluster and returns a CLI talking to the WebSocket server of its first node. With an auth key,
+// the server requires sessions to authenticate.
+func newTestCLI(t *testing.T, authKey []byte) (*cli, *bytes.Buffer) {
+	ids := []string{"node1:5000", "node2:5001", "node3:5002"}
+	network := transport.NewMemoryNetwork()
+	baseDir := t.TempDir()
+	errCh := make(chan error, len(ids))
+
+	var addr string
+	for _, id := range ids {
+		memTransport, err := network.Listen(id)
+		if err != nil {
+			t.Fatalf("Expected no error listening on %s, got %v", id, err)
+		}
+		n, err := node.NewNodeWithTransport(id, baseDir, memTransport)
+		if err != nil {
+			t.Fatalf("Expected no error creating node %s, got %v", id, err)
+		}
+		n.Start(errCh)
+		t.Cleanup(func() { n.Stop() })
+
+		if err := n.JoinToRing(t.Context(), node.NodeIdToZMQAddr(ids[0])); err != nil {
+			t.Fatalf("Expected node %s to join the ring, got %v", id, err)
+		}
+
+		if addr == "" {
+			handler := communication.NewWebSocketHandler(n, n.Metrics())
+			if authKey != nil {
+				handler.RequireAuthentication(authKey)
+			}
+			server := httptest.NewServer(handler)
+			t.Cleanup(server.Close)
+			addr = strings.TrimPrefix(server.URL, "http://")
+		}
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	out := &bytes.Buffer{}
+	return &cli{addr: addr, json: true, timeout: 2 * time.Second, out: out}, out
+}
+
+func TestCLI_EditList(t *testing.T) {
+	c, out := newTestCLI(t, nil)
+
+	commands := [][]string{
+		{"list", "create", "list1", "Groceries"},
+		{"list", "add", "list1", "milk", "2"},
+		{"list", "add", "list1", "eggs"},
+		{"list", "acquire", "list1", "milk"},
+		{"list", "remove", "list1", "eggs"},
+	}
+	for _, args := range commands {
+		if err := c.run(args); err != nil {
+			t.Fatalf("Expected %v to succeed, got %v", args, err)
+		}
+	}
+
+	out.Reset()
+	if err := c.run([]string{"list", "get", "list1"}); err != nil {
+		t.Fatalf("Expected list get to succeed, got %v", err)
+	}
+	var view listView
+	if err := json.Unmarshal(out.Bytes(), &view); err != nil {
+		t.Fatalf("Expected a JSON list, got %q", out.String())
+	}
+	if view.Name != "Groceries" || len(view.Items) != 1 {
+		t.Fatalf("Expected Groceries with a single item, got %+v", view)
+	}
+	if item := view.Items[0]; item.ID != "milk" || item.Quantity != 2 || item.Acquired != 1 {
+		t.Errorf("Expected 1/2 milk, got %+v", item)
+	}
+}
+
+func TestCLI_InvalidCommands(t *testing.T) {
+	c, _ := newTestCLI(t, nil)
+
+	if err := c.run([]string{"list", "acquire", "list1", "milk"}); err == nil {
+		t.Errorf("Expected acquiring an item of a missing list to fail")
+	}
+	if err := c.run([]string{"list", "add", "list1", "milk", "-1"}); err == nil {
+		t.Errorf("Expected a negative amount to be rejected")
+	}
+	if err := c.run([]string{"frobnicate"}); err == nil {
+		t.Errorf("Expected an unknown command to be rejected")
+	}
+}
+
+func TestCLI_AuthenticatedSessions(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	keyFile := filepath.Join(t.TempDir(), "auth.key")
+	if err := os.WriteFile(keyFile, key, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, out := newTestCLI(t, key)
+
+	tokens := map[string]string{}
+	for _, user := range []string{"alice", "bob", "carol"} {
+		out.Reset()
+		if err := c.run([]string{"token", user, keyFile, "1h"}); err != nil {
+			t.Fatalf("Expected a token for %s, got %v", user, err)
+		}
+		var view tokenView
+		if err := json.Unmarshal(out.Bytes(), &view); err != nil || view.User != user {
+			t.Fatalf("Expected a JSON token for %s, got %q", user, out.String())
+		}
+		tokens[user] = view.Token
+	}
+
+	if err := c.run([]string{"list", "get", "list1"}); err == nil || !strings.Contains(err.Error(), "UNAUTHENTICATED") {
+		t.Errorf("Expected an unauthenticated session to be rejected, got %v", err)
+	}
+	c.token = "forged"
+	if err := c.run([]string{"list", "get", "list1"}); err == nil || !strings.Contains(err.Error(), "authentication failed") {
+		t.Errorf("Expected a forged token to be rejected, got %v", err)
+	}
+
+	c.token = tokens["alice"]
+	if err := c.run([]string{"list", "create", "list1", "Groceries"}); err != nil {
+		t.Fatalf("Expected alice to create the list, got %v", err)
+	}
+	out.Reset()
+	if err := c.run([]string{"list", "acl", "list1", "bob", "carol"}); err != nil {
+		t.Fatalf("Expected alice to share the list, got %v", err)
+	}
+	var acl aclView
+	if err := json.Unmarshal(out.Bytes(), &acl); err != nil || acl.Owner != "alice" || acl.Version != 2 {
+		t.Fatalf("Expected version 2 of the ACL, owned by alice, got %q", out.String())
+	}
+
+	c.token = tokens["bob"]
+	if err := c.run([]string{"list", "add", "list1", "milk"}); err != nil {
+		t.Errorf("Expected bob to edit the list, got %v", err)
+	}
+
+	c.token = tokens["carol"]
+	if err := c.run([]string{"list", "get", "list1"}); err != nil {
+		t.Errorf("Expected carol to read the list, got %v", err)
+	}
+	if err := c.run([]string{"list", "add", "list1", "eggs"}); err == nil || !strings.Contains(err.Error(), "FORBIDDEN") {
+		t.Errorf("Expected carol not to edit the list, got %v", err)
+	}
+}
+
+func TestCLI_ShareLinks(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	c, out := newTestCLI(t, key)
+
+	alice, err := auth.Sign(key, "alice", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.token = alice
+	for _, listID := range []string{"list1", "list2"} {
+		if err := c.run([]string{"list", "create", listID, "Groceries"}); err != nil {
+			t.Fatalf("Expected alice to create %s, got %v", listID, err)
+		}
+	}
+
+	capabilities := map[string]string{}
+	for _, access := range []string{"read", "write"} {
+		out.Reset()
+		if err := c.run([]string{"share", "list1", access, "1h"}); err != nil {
+			t.Fatalf("Expected alice to share the list, got %v", err)
+		}
+		var view shareView
+		if err := json.Unmarshal(out.Bytes(), &view); err != nil || view.Expires.IsZero() {
+			t.Fatalf("Expected an expiring JSON share link, got %q", out.String())
+		}
+		capabilities[access] = view.Capability
+	}
+
+	// Anonymous sessions access the list through the capabilities alone
+	anonymous := *c
+	anonymous.token = ""
+	anonymous.capability = capabilities["write"]
+	if err := anonymous.run([]string{"list", "add", "list1", "milk"}); err != nil {
+		t.Errorf("Expected the write capability to edit the list, got %v", err)
+	}
+	anonymous.capability = capabilities["read"]
+	if err := anonymous.run([]string{"list", "get", "list1"}); err != nil {
+		t.Errorf("Expected the read capability to read the list, got %v", err)
+	}
+	if err := anonymous.run([]string{"list", "add", "list1", "eggs"}); err == nil || !strings.Contains(err.Error(), "FORBIDDEN") {
+		t.Errorf("Expected the read capability not to edit the list, got %v", err)
+	}
+	if err := anonymous.run([]string{"list", "get", "list2"}); err == nil || !strings.Contains(err.Error(), "FORBIDDEN") {
+		t.Errorf("Expected the capability not to grant access to another list, got %v", err)
+	}
+
+	if err := c.run([]string{"revoke", capabilities["read"]}); err != nil {
+		t.Fatalf("Expected alice to revoke the capability, got %v", err)
+	}
+	if err := anonymous.run([]string{"list", "get", "list1"}); err == nil || !strings.Contains(err.Error(), "FORBIDDEN") {
+		t.Errorf("Expected the revoked capability to be denied, got %v", err)
+	}
+}
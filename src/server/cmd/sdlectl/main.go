@@ -0,0 +1,199 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"sdle-server/node"
+	pb "sdle-server/proto"
+)
+
+const usage = `usage: sdlectl [flags] <command> [arguments]
+
+Commands:
+  status                          status of the node
+  ring                            token ranges of the ring and their owners
+  replicas <key>                  nodes responsible for a key, and whether they hold it
+  replicas -list <list ID>        same, for the key of a shopping list
+  hints                           hints the node stores for other nodes
+  list get <list ID>              contents of a shopping list
+  list create <list ID> <name>    creates a shopping list (or renames it)
+  list add <list ID> <item> [n]   adds n units of an item (1 by default)
+  list acquire <list ID> <item> [n]
+                                  marks n units of an item as acquired (1 by default)
+  list remove <list ID> <item>    removes an item
+  list watch <list ID>            prints the updates of a shopping list until interrupted
+  list acl <list ID>              owner, editors and viewers of a shopping list
+  list acl <list ID> <editors> [viewers]
+                                  replaces the editors and viewers of a shopping list (comma-separated
+                                  user IDs, "" for none); only its owner may
+  share <list ID> [read|write] [ttl]
+                                  mints a share capability of a shopping list (read-only by default,
+                                  never expiring unless a ttl is given); only its owner may
+  revoke <capability>             revokes a share capability; only the owner of its list may
+  token <user> <key file> [ttl]   signs a session token for a user with the auth key of the cluster,
+                                  valid for ttl (24h by default)
+  keygen <node ID> <secret key file>
+                                  generates a CurveZMQ keypair for a node, writing the secret key to
+                                  the file and printing the public key line of the cluster keys file
+
+Flags:
+`
+
+func main() {
+	addr := flag.String("addr", "localhost:8000", "HTTP address (WebSocket) of the node")
+	adminAddr := flag.String("admin-addr", "localhost:9000", "address of the admin API of the node (served on the loopback interface of its host)")
+	jsonOutput := flag.Bool("json", false, "print JSON instead of human-readable output")
+	timeout := flag.Duration("timeout", 5*time.Second, "time to wait for the node to answer")
+	token := flag.String("token", os.Getenv("SDLE_TOKEN"), "session token to authenticate with (defaults to $SDLE_TOKEN)")
+	capability := flag.String("capability", os.Getenv("SDLE_CAPABILITY"), "share capability to access a list with (defaults to $SDLE_CAPABILITY)")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cli := &cli{addr: *addr, adminAddr: *adminAddr, json: *jsonOutput, timeout: *timeout, token: *token, capability: *capability, out: os.Stdout}
+	if err := cli.run(flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+type cli struct {
+	addr       string // HTTP address of the node
+	adminAddr  string // address of the admin API of the node
+	json       bool
+	timeout    time.Duration
+	token      string // session token (none if empty)
+	capability string // share capability (none if empty)
+	out        io.Writer
+}
+
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e) + " (run sdlectl -h for usage)"
+}
+
+func (c *cli) run(args []string) error {
+	switch args[0] {
+	case "status":
+		return c.status()
+	case "ring":
+		return c.ring()
+	case "replicas":
+		switch {
+		case len(args) == 2:
+			return c.replicas(args[1])
+		case len(args) == 3 && args[1] == "-list":
+			return c.replicas(node.ShoppingListKey(args[2]))
+		}
+		return usageError("expected replicas <key> or replicas -list <list ID>")
+	case "hints":
+		return c.hints()
+	case "list":
+		return c.list(args[1:])
+	case "keygen":
+		if len(args) != 3 {
+			return usageError("expected keygen <node ID> <secret key file>")
+		}
+		return c.keygen(args[1], args[2])
+	case "token":
+		if len(args) != 3 && len(args) != 4 {
+			return usageError("expected token <user> <key file> [ttl]")
+		}
+		ttl := 24 * time.Hour
+		if len(args) == 4 {
+			var err error
+			if ttl, err = time.ParseDuration(args[3]); err != nil || ttl <= 0 {
+				return usageError("expected a positive ttl, got " + strconv.Quote(args[3]))
+			}
+		}
+		return c.signToken(args[1], args[2], ttl)
+	case "share":
+		if len(args) < 2 || len(args) > 4 {
+			return usageError("expected share <list ID> [read|write] [ttl]")
+		}
+		access, ttl := pb.ShareAccess_SHARE_READ, time.Duration(0)
+		if len(args) >= 3 {
+			switch args[2] {
+			case "read":
+			case "write":
+				access = pb.ShareAccess_SHARE_WRITE
+			default:
+				return usageError("expected read or write access, got " + strconv.Quote(args[2]))
+			}
+		}
+		if len(args) == 4 {
+			var err error
+			if ttl, err = time.ParseDuration(args[3]); err != nil || ttl < time.Second {
+				return usageError("expected a ttl of at least 1s, got " + strconv.Quote(args[3]))
+			}
+		}
+		return c.share(args[1], access, ttl)
+	case "revoke":
+		if len(args) != 2 {
+			return usageError("expected revoke <capability>")
+		}
+		return c.revoke(args[1])
+	}
+	return usageError("unknown command " + strconv.Quote(args[0]))
+}
+
+func (c *cli) list(args []string) error {
+	if len(args) < 2 {
+		return usageError("expected list <get|create|add|acquire|remove|watch|acl> <list ID>")
+	}
+	command, listID, rest := args[0], args[1], args[2:]
+
+	amount := func() (int64, error) {
+		if len(rest) < 2 {
+			return 1, nil
+		}
+		n, err := strconv.ParseInt(rest[1], 10, 64)
+		if err != nil || n < 1 {
+			return 0, usageError("expected a positive amount, got " + strconv.Quote(rest[1]))
+		}
+		return n, nil
+	}
+
+	switch {
+	case command == "get" && len(rest) == 0:
+		return c.getList(listID)
+	case command == "watch" && len(rest) == 0:
+		return c.watchList(listID)
+	case command == "create" && len(rest) == 1:
+		return c.editList(listID, setName(rest[0]))
+	case command == "add" && (len(rest) == 1 || len(rest) == 2):
+		n, err := amount()
+		if err != nil {
+			return err
+		}
+		return c.editList(listID, addItem(rest[0], n))
+	case command == "acquire" && (len(rest) == 1 || len(rest) == 2):
+		n, err := amount()
+		if err != nil {
+			return err
+		}
+		return c.editList(listID, acquireItem(rest[0], n))
+	case command == "remove" && len(rest) == 1:
+		return c.editList(listID, removeItem(rest[0]))
+	case command == "acl" && len(rest) == 0:
+		return c.getListAcl(listID)
+	case command == "acl" && len(rest) == 1:
+		return c.setListAcl(listID, rest[0], "")
+	case command == "acl" && len(rest) == 2:
+		return c.setListAcl(listID, rest[0], rest[1])
+	}
+	return usageError("invalid list command")
+}
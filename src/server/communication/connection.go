@@ -0,0 +1,148 @@
+package communication
+
+import (
+	"errors"
+	"sdle-server/config"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Keepalive of WebSocket connections. The server pings every connection, and declares dead the ones it hears nothing
+// from (not even a pong) for PongTimeout, or that a write takes longer than WriteTimeout on.
+type Keepalive struct {
+	PingInterval time.Duration // 0 disables the pings and the read deadline
+	PongTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+func KeepaliveFromConfig(c config.Config) Keepalive {
+	return Keepalive{
+		PingInterval: c.ClientPingInterval,
+		PongTimeout:  c.ClientPongTimeout,
+		WriteTimeout: c.ClientWriteTimeout,
+	}
+}
+
+// WebSocket connection of a client, written to by its handler and by the notifications of its subscriptions. Writes
+// are serialized (gorilla/websocket supports a single writer) and bounded by the write timeout.
+//
+// Closing the connection drops its subscriptions at once, whoever declares it dead: the handler when a read fails,
+// the keepalive when a ping is not answered, or a notification whose write fails.
+type Connection struct {
+	conn         *websocket.Conn
+	writeTimeout time.Duration
+
+	writeMu sync.Mutex
+
+	mu            sync.Mutex
+	closed        bool
+	subscriptions map[subscription]struct{}
+	unsubscribe   func(listID string, messageID string, conn *Connection)
+	done          chan struct{}
+}
+
+// Subscription of a connection to a list. Message IDs are chosen by clients, which may reuse one across lists.
+type subscription struct {
+	messageID string
+	listID    string
+}
+
+var errConnectionClosed = errors.New("connection closed")
+
+func newConnection(conn *websocket.Conn, writeTimeout time.Duration, unsubscribe func(listID string, messageID string, conn *Connection)) *Connection {
+	return &Connection{
+		conn:          conn,
+		writeTimeout:  writeTimeout,
+		subscriptions: make(map[subscription]struct{}),
+		unsubscribe:   unsubscribe,
+		done:          make(chan struct{}),
+	}
+}
+
+// Sends a binary message. A write that fails declares the connection dead.
+func (c *Connection) WriteMessage(data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout)); err != nil {
+		c.Close()
+		return err
+	}
+	if err := c.conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+		c.Close()
+		return err
+	}
+	return nil
+}
+
+// Sends a control message (which, unlike data messages, may be sent concurrently with them)
+func (c *Connection) writeControl(messageType int, data []byte) error {
+	err := c.conn.WriteControl(messageType, data, time.Now().Add(c.writeTimeout))
+	if err != nil {
+		c.Close()
+	}
+	return err
+}
+
+// Records a subscription of the connection, to be dropped when it closes. Returns false if the connection is already
+// closed, in which case the caller drops the subscription itself.
+func (c *Connection) track(messageID string, listID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	c.subscriptions[subscription{messageID: messageID, listID: listID}] = struct{}{}
+	return true
+}
+
+// Number of subscriptions of the connection
+func (c *Connection) subscriptionCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.subscriptions)
+}
+
+// Closes the connection and drops its subscriptions. Closing the underlying connection also fails the pending read of
+// the handler, which then returns. Safe to call more than once, and from any goroutine.
+func (c *Connection) Close() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	subscriptions := c.subscriptions
+	c.subscriptions = nil
+	close(c.done)
+	c.mu.Unlock()
+
+	_ = c.conn.Close()
+	for sub := range subscriptions {
+		c.unsubscribe(sub.listID, sub.messageID, c)
+	}
+}
+
+// Closed when the connection closes
+func (c *Connection) Done() <-chan struct{} {
+	return c.done
+}
+
+// Pings the connection every interval until it closes. A ping that cannot be written declares the connection dead;
+// one that is not answered does so through the read deadline, which only pongs and messages extend.
+func (c *Connection) keepalive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := c.writeControl(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package communication
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sdle-server/metrics"
+	pb "sdle-server/proto"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/proto"
+)
+
+// Node that only keeps track of subscriptions
+type subscriptionNode struct {
+	NodeInterface
+	mu            sync.Mutex
+	subscriptions map[string]bool // list ID/message ID
+}
+
+func (n *subscriptionNode) ID() string {
+	return "node1"
+}
+
+func (n *subscriptionNode) SubscribeShoppingList(ctx context.Context, listID string, messageID string, conn *Connection) error {
+	if listID == "unavailable" {
+		return errors.New("list unavailable")
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.subscriptions[listID+"/"+messageID] = true
+	return nil
+}
+
+func (n *subscriptionNode) UnsubscribeShoppingList(listID string, messageID string, conn *Connection) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.subscriptions, listID+"/"+messageID)
+	return nil
+}
+
+func (n *subscriptionNode) GetShoppingList(ctx context.Context, id string) (*pb.ShoppingList, error) {
+	return &pb.ShoppingList{Id: id}, nil
+}
+
+func (n *subscriptionNode) count() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.subscriptions)
+}
+
+func TestConnection_DeadClientIsUnsubscribed(t *testing.T) {
+	node := &subscriptionNode{subscriptions: make(map[string]bool)}
+	handler := NewWebSocketHandler(node, metrics.NewRegistry())
+	handler.SetKeepalive(Keepalive{PingInterval: 20 * time.Millisecond, PongTimeout: 100 * time.Millisecond, WriteTimeout: time.Second})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	data, _ := proto.Marshal(&pb.ClientRequest{
+		MessageId:   "sub1",
+		RequestType: &pb.ClientRequest_SubscribeShoppingList{SubscribeShoppingList: &pb.SubscribeShoppingListRequest{Id: "list1"}},
+	})
+	if err := conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := conn.ReadMessage(); err != nil {
+		t.Fatalf("Expected the subscription to be answered, got %v", err)
+	}
+	if node.count() != 1 {
+		t.Fatalf("Expected 1 subscription, got %d", node.count())
+	}
+
+	// The client stops reading, so it answers no ping, and is declared dead once the pong timeout expires
+	deadline := time.Now().Add(2 * time.Second)
+	for node.count() != 0 || handler.disconnects.Value("dead") != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the dead client to be unsubscribed, got %d subscriptions and %v dead connections",
+				node.count(), handler.disconnects.Value("dead"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestConnection_CloseDropsSubscriptions(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if conn, err := upgrader.Upgrade(w, r, nil); err == nil {
+			conn.ReadMessage()
+		}
+	}))
+	defer server.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dropped []string
+	var c *Connection
+	c = newConnection(conn, time.Second, func(listID string, messageID string, unsubscribed *Connection) {
+		if unsubscribed != c {
+			t.Errorf("Expected the subscriptions of the closed connection to be dropped")
+		}
+		dropped = append(dropped, listID+"/"+messageID)
+	})
+	// The client reuses its message ID across lists
+	if !c.track("sub1", "list1") || !c.track("sub1", "list2") {
+		t.Fatalf("Expected an open connection to track subscriptions")
+	}
+
+	c.Close()
+	c.Close()
+	slices.Sort(dropped)
+	if !slices.Equal(dropped, []string{"list1/sub1", "list2/sub1"}) {
+		t.Errorf("Expected list1/sub1 and list2/sub1 to be dropped once, got %v", dropped)
+	}
+	if c.track("sub2", "list1") {
+		t.Errorf("Expected a closed connection not to track subscriptions")
+	}
+	if err := c.WriteMessage([]byte("x")); err == nil {
+		t.Errorf("Expected writes to a closed connection to fail")
+	}
+}
@@ -1,34 +1,108 @@
 package communication
 
 import (
-	"log"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"runtime/debug"
+	"sdle-server/auth"
+	"sdle-server/config"
 	crdt "sdle-server/crdt/shopping"
+	"sdle-server/logging"
+	"sdle-server/metrics"
 	pb "sdle-server/proto"
+	"sdle-server/replication"
+	"sdle-server/tracing"
+	"slices"
+	"time"
 
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/proto"
 )
 
 type WebSocketHandler struct {
-	upgrader websocket.Upgrader
-	node     NodeInterface
+	upgrader       websocket.Upgrader
+	node           NodeInterface
+	requestTimeout time.Duration // time the node spends on a request before giving up on it
+	logger         *slog.Logger
+
+	authKey        []byte   // key session tokens are signed with (nil if sessions need not authenticate)
+	allowedOrigins []string // origins browsers may connect from (any if empty)
+	limits         Limits
+	lists          *listLimiter // rate limits of the lists, shared by all connections
+	keepalive      Keepalive
+
+	connections     *metrics.Gauge
+	requests        *metrics.CounterVec   // client requests, by type
+	requestDuration *metrics.HistogramVec // time spent handling client requests, by type
+	errors          *metrics.CounterVec   // error responses sent to clients, by code
+	disconnects     *metrics.CounterVec   // connections closed by the server, by reason
 }
 
-func NewWebSocketHandler(node NodeInterface) *WebSocketHandler {
-	return &WebSocketHandler{
+// Creates the handler of the WebSocket endpoint, registering its metrics in the given registry
+func NewWebSocketHandler(node NodeInterface, registry *metrics.Registry) *WebSocketHandler {
+	h := &WebSocketHandler{
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
-			CheckOrigin: func(r *http.Request) bool {
-				// In real environments, we should check the origin here
-				// but since this project is going to be ran locally only,
-				// we can just allow all origins.
-				return true
-			},
 		},
-		node: node,
+		node:           node,
+		requestTimeout: config.DefaultConfig().ClientRequestTimeout,
+		logger:         slog.Default().With(logging.KeyNode, node.ID()),
+		connections:    registry.NewGauge("sdle_websocket_connections", "Open WebSocket connections."),
+		requests: registry.NewCounterVec("sdle_client_requests_total",
+			"Requests received from WebSocket clients, by request type.", "type"),
+		requestDuration: registry.NewHistogramVec("sdle_client_request_duration_seconds",
+			"Time spent handling requests of WebSocket clients.", metrics.LatencyBuckets, "type"),
+		errors: registry.NewCounterVec("sdle_client_errors_total",
+			"Error responses sent to WebSocket clients, by error code.", "code"),
+		disconnects: registry.NewCounterVec("sdle_websocket_disconnects_total",
+			"WebSocket connections closed by the server, by reason.", "reason"),
 	}
+	h.upgrader.CheckOrigin = h.checkOrigin
+	h.SetLimits(LimitsFromConfig(config.DefaultConfig()))
+	h.SetKeepalive(KeepaliveFromConfig(config.DefaultConfig()))
+	return h
+}
+
+// Replaces the keepalive of the connections opened from now on
+func (h *WebSocketHandler) SetKeepalive(keepalive Keepalive) {
+	h.keepalive = keepalive
+}
+
+// Replaces the limits on what clients may send. Connections opened before keep the rates they started with.
+func (h *WebSocketHandler) SetLimits(limits Limits) {
+	h.limits = limits
+	h.lists = newListLimiter(limits.ListRate, limits.ListBurst)
+}
+
+// Requires every connection to authenticate, with a token signed with the key (see the auth package), before any
+// other request
+func (h *WebSocketHandler) RequireAuthentication(key []byte) {
+	h.authKey = key
+}
+
+// Only accepts connections from browsers on the given origins (e.g. "https://lists.example.com"). Clients that send
+// no Origin header, such as sdlectl, are always accepted, and so is every origin if none is given.
+func (h *WebSocketHandler) AllowOrigins(origins []string) {
+	h.allowedOrigins = origins
+}
+
+func (h *WebSocketHandler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	return origin == "" || len(h.allowedOrigins) == 0 || slices.Contains(h.allowedOrigins, origin)
+}
+
+// State of a connection
+type session struct {
+	user       string       // authenticated user (empty until the connection authenticates)
+	expires    time.Time    // when the token of the user expires
+	requests   *tokenBucket // rate limit of the requests of the connection
+	violations *tokenBucket // requests rejected by the limits that the connection may still send this minute
 }
 
 func (h *WebSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -37,142 +111,531 @@ func (h *WebSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Could not open websocket connection", http.StatusBadRequest)
 		return
 	}
-	defer conn.Close()
 
-	log.Printf("WebSocket connection established from %s", r.RemoteAddr)
+	logger := h.logger.With("remote_addr", r.RemoteAddr)
+	logger.Info("WebSocket connection established")
+	h.connections.Inc()
+	defer h.connections.Dec()
+
+	// Closing the connection, whichever way it dies, drops the subscriptions made on it
+	c := newConnection(conn, h.keepalive.WriteTimeout, h.unsubscribe)
+	defer c.Close()
+	if h.keepalive.PingInterval > 0 {
+		h.extendReadDeadline(conn)
+		conn.SetPongHandler(func(string) error {
+			h.extendReadDeadline(conn)
+			return nil
+		})
+		go c.keepalive(h.keepalive.PingInterval)
+	}
+
+	now := time.Now()
+	sess := &session{
+		requests:   newTokenBucket(h.limits.ConnectionRate, h.limits.ConnectionBurst, now),
+		violations: newTokenBucket(float64(h.limits.MaxViolations)/60, h.limits.MaxViolations, now),
+	}
+	if h.limits.MaxMessageSize > 0 {
+		conn.SetReadLimit(h.limits.MaxMessageSize * hardMessageSizeFactor)
+	}
 
 	for {
-		messageType, message, err := conn.ReadMessage()
+		messageType, message, err := h.readMessage(conn)
+		if err == nil || errors.Is(err, errMessageTooLarge) {
+			h.extendReadDeadline(conn)
+		}
+		if errors.Is(err, errMessageTooLarge) {
+			logger.Warn("Rejecting oversized message", "max_size", h.limits.MaxMessageSize)
+			if err := h.writeError(c, logger, "", pb.ErrorCode_MESSAGE_TOO_LARGE); err != nil || !h.tolerate(c, sess, logger) {
+				break
+			}
+			continue
+		}
 		if err != nil {
-			log.Println("Error reading message:", err)
+			h.logClosed(c, logger, err)
 			break
 		}
 
 		if messageType != websocket.BinaryMessage {
-			log.Println("Received non-binary message, ignoring")
+			logger.Warn("Received non-binary message, ignoring")
 			continue
 		}
 
 		var req pb.ClientRequest
 		if err := proto.Unmarshal(message, &req); err != nil {
-			log.Println("Failed to unmarshal client request:", err)
+			logger.Warn("Failed to unmarshal client request", logging.Err(err))
 			continue
 		}
 
-		switch req.GetRequestType().(type) {
-		case *pb.ClientRequest_ShoppingList:
-			list := crdt.ShoppingListFromProto(req.GetShoppingList(), h.node.ID())
+		reqLogger := logger.With(logging.KeyRequest, requestTypeName(&req), logging.KeyMessageID, req.GetMessageId())
+		if code, ok := h.admit(&req, sess, c.subscriptionCount()); !ok {
+			reqLogger.Warn("Rejecting request over the limits", "code", code.String())
+			if err := h.writeError(c, reqLogger, req.MessageId, code); err != nil || !h.tolerate(c, sess, reqLogger) {
+				break
+			}
+			continue
+		}
 
-			if err := h.node.HandleShoppingList(list); err != nil {
-				log.Println("Error handling shopping list:", err)
+		// A request is abandoned when it takes too long, or when the client goes away
+		start := time.Now()
+		ctx, cancel := context.WithTimeout(r.Context(), h.requestTimeout)
+		ctx, span := h.startSpan(ctx, &req)
+		if span.SpanContext().IsValid() {
+			reqLogger = reqLogger.With(logging.KeyTrace, span.SpanContext().TraceID().String())
+		}
+		err = h.handleRequest(ctx, c, &req, sess, reqLogger)
+		span.End()
+		cancel()
+		if errors.Is(err, errOverLimits) {
+			if !h.tolerate(c, sess, reqLogger) {
+				break
 			}
+			continue
+		}
+		if err != nil {
+			reqLogger.Warn("Failed to write response", logging.Err(err))
+			h.disconnects.Inc("dead")
+			break
+		}
+		elapsed := time.Since(start)
+		h.requests.Inc(requestTypeName(&req))
+		h.requestDuration.ObserveDuration(elapsed, requestTypeName(&req))
+		reqLogger.Debug("Handled client request", logging.KeyDuration, elapsed)
+	}
+}
+
+func (h *WebSocketHandler) unsubscribe(listID string, messageID string, conn *Connection) {
+	if err := h.node.UnsubscribeShoppingList(listID, messageID, conn); err != nil {
+		h.logger.Warn("Failed to unsubscribe from shopping list", "list_id", listID, logging.KeyMessageID, messageID,
+			logging.Err(err))
+	}
+}
 
-		case *pb.ClientRequest_GetShoppingList_:
-			getShoppingListReq := req.GetGetShoppingList_()
+var (
+	errMessageTooLarge = errors.New("message too large")
+	errOverLimits      = errors.New("request over the limits") // the request was rejected, and counts as a violation
+)
 
-			list, err := h.node.GetShoppingList(getShoppingListReq.GetId())
-			if err != nil {
-				log.Println("Error getting shopping list:", err)
+// Pushes back the time by which the client must send something (a message or a pong) not to be declared dead
+func (h *WebSocketHandler) extendReadDeadline(conn *websocket.Conn) {
+	if h.keepalive.PingInterval > 0 {
+		_ = conn.SetReadDeadline(time.Now().Add(h.keepalive.PongTimeout))
+	}
+}
 
-				notFoundRes := &pb.ServerResponse{
-					MessageId: req.MessageId,
-					ResponseType: &pb.ServerResponse_Error{
-						Error: pb.ErrorCode_NOT_FOUND,
-					},
-				}
+// Logs why a connection stopped being read. Connections closed by the client are expected; those that timed out, or
+// that were closed because a write to them failed, are declared dead.
+func (h *WebSocketHandler) logClosed(c *Connection, logger *slog.Logger, err error) {
+	var netErr net.Error
+	timedOut := errors.As(err, &netErr) && netErr.Timeout()
+	select {
+	case <-c.Done():
+	default:
+		if !timedOut {
+			logger.Info("WebSocket connection closed", logging.Err(err))
+			return
+		}
+	}
+	logger.Warn("WebSocket connection declared dead", logging.Err(err))
+	h.disconnects.Inc("dead")
+}
 
-				resBytes, err := proto.Marshal(notFoundRes)
-				if err != nil {
-					log.Println("Failed to marshal not found response:", err)
-					continue
-				}
+// Reads the next message of a connection. A message over the maximum size is not kept: errMessageTooLarge is
+// returned, and the rest of the message is discarded by the next read.
+func (h *WebSocketHandler) readMessage(conn *websocket.Conn) (int, []byte, error) {
+	messageType, r, err := conn.NextReader()
+	if err != nil {
+		return 0, nil, err
+	}
+	if h.limits.MaxMessageSize <= 0 {
+		message, err := io.ReadAll(r)
+		return messageType, message, err
+	}
 
-				if err := conn.WriteMessage(websocket.BinaryMessage, resBytes); err != nil {
-					log.Println("Error writing message:", err)
-					break
-				}
+	message, err := io.ReadAll(io.LimitReader(r, h.limits.MaxMessageSize+1))
+	if err != nil {
+		return 0, nil, err
+	}
+	if int64(len(message)) > h.limits.MaxMessageSize {
+		return messageType, nil, errMessageTooLarge
+	}
+	return messageType, message, nil
+}
 
-				continue
-			}
+// Checks a request against the rate limit and the subscriptions of its connection. Returns the error code to reject
+// it with if it is over a limit. The rate limit of its list is only checked once the session may send the request,
+// so that anyone cannot use up the requests of the lists of others.
+func (h *WebSocketHandler) admit(req *pb.ClientRequest, sess *session, subscriptions int) (pb.ErrorCode, bool) {
+	if !sess.requests.allow(time.Now()) {
+		return pb.ErrorCode_RATE_LIMITED, false
+	}
+	if req.GetSubscribeShoppingList() != nil && h.limits.MaxSubscriptions > 0 && subscriptions >= h.limits.MaxSubscriptions {
+		return pb.ErrorCode_TOO_MANY_SUBSCRIPTIONS, false
+	}
+	return 0, true
+}
 
-			// Send the shopping list back to the client
-			serverResp := &pb.ServerResponse{
-				MessageId: req.MessageId,
-				ResponseType: &pb.ServerResponse_ShoppingList{
-					ShoppingList: list,
-				},
-			}
+// Records a message rejected by the limits. Returns false, after closing the connection, if the connection went over
+// the violations it may commit per minute.
+func (h *WebSocketHandler) tolerate(c *Connection, sess *session, logger *slog.Logger) bool {
+	if sess.violations.allow(time.Now()) {
+		return true
+	}
 
-			respBytes, err := proto.Marshal(serverResp)
-			if err != nil {
-				log.Println("Failed to marshal shopping list:", err)
-				continue
-			}
+	logger.Warn("Closing connection over the limits", "max_violations", h.limits.MaxViolations)
+	h.disconnects.Inc("violations")
+	message := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "too many requests over the limits")
+	_ = c.writeControl(websocket.CloseMessage, message)
+	return false
+}
 
-			if err := conn.WriteMessage(websocket.BinaryMessage, respBytes); err != nil {
-				log.Println("Error writing message:", err)
-				break
-			}
+// Handles one request of a client, returning an error only if the connection can no longer be written to, or
+// errOverLimits if the request was rejected by the rate limit of its list. A panic while handling the request is
+// answered with INTERNAL_ERROR, so it fails that request and not the connection.
+func (h *WebSocketHandler) handleRequest(ctx context.Context, conn *Connection, req *pb.ClientRequest, sess *session, logger *slog.Logger) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Panic handling client request", "panic", r, "stack", string(debug.Stack()))
+			err = h.writeError(conn, logger, req.GetMessageId(), pb.ErrorCode_INTERNAL_ERROR)
+		}
+	}()
 
-		case *pb.ClientRequest_SubscribeShoppingList:
-			subscribeReq := req.GetSubscribeShoppingList()
+	if req.GetAuthenticate() != nil {
+		return h.authenticate(conn, logger, req, sess)
+	}
 
-			err := h.node.SubscribeShoppingList(subscribeReq.GetId(), req.MessageId, conn)
-			if err != nil {
-				log.Println("Error handling subscribe shopping list:", err)
-			}
-			defer h.node.UnsubscribeShoppingList(subscribeReq.GetId(), req.MessageId)
+	// A capability stands in for the identity of the session on list requests
+	hasCapability := req.GetCapability() != ""
+	if hasCapability {
+		if h.authKey == nil {
+			logger.Warn("Capability presented, but no key is configured")
+			return h.writeError(conn, logger, req.MessageId, pb.ErrorCode_INVALID_REQUEST)
+		}
+		c, err := auth.VerifyCapability(h.authKey, req.GetCapability(), time.Now())
+		if err != nil {
+			logger.Warn("Rejecting capability", logging.Err(err))
+			return h.writeError(conn, logger, req.MessageId, pb.ErrorCode_UNAUTHENTICATED)
+		}
+		ctx = auth.WithCapability(ctx, c)
+		logger = logger.With("capability", c.ID)
+	}
 
-			// Synchronize client with local state
-			list, err := h.node.GetShoppingList(subscribeReq.GetId())
-			if err != nil {
-				log.Println("Error getting shopping list for synchronization:", err)
-				continue
-			}
+	authenticated := sess.user != "" && time.Now().Before(sess.expires)
+	if h.authKey != nil && !authenticated && !(hasCapability && isListRequest(req)) {
+		logger.Debug("Rejecting request of an unauthenticated session")
+		return h.writeError(conn, logger, req.MessageId, pb.ErrorCode_UNAUTHENTICATED)
+	}
+	if authenticated {
+		ctx = auth.WithUser(ctx, sess.user)
+		logger = logger.With(logging.KeyUser, sess.user)
+	}
 
-			syncResp := &pb.ServerResponse{
-				MessageId: req.MessageId,
-				ResponseType: &pb.ServerResponse_ShoppingList{
-					ShoppingList: list,
-				},
+	if listID := requestListID(req); listID != "" && !h.lists.allow(listID, time.Now()) {
+		logger.Warn("Rejecting request over the rate limit of its list", "list_id", listID)
+		if err := h.writeError(conn, logger, req.MessageId, pb.ErrorCode_RATE_LIMITED); err != nil {
+			return err
+		}
+		return errOverLimits
+	}
+
+	switch req.GetRequestType().(type) {
+	case *pb.ClientRequest_ShoppingList:
+		list, err := crdt.ShoppingListFromProto(req.GetShoppingList(), h.node.ID())
+		if err != nil {
+			logger.Warn("Rejecting malformed shopping list", logging.Err(err))
+			tracing.Fail(trace.SpanFromContext(ctx), err)
+			return h.writeError(conn, logger, req.MessageId, pb.ErrorCode_INVALID_REQUEST)
+		}
+
+		if err := h.node.HandleShoppingList(ctx, list); err != nil {
+			tracing.Fail(trace.SpanFromContext(ctx), err)
+			if code, denied := accessErrorCode(err); denied {
+				logger.Warn("Denied write of shopping list", logging.Err(err))
+				return h.writeError(conn, logger, req.MessageId, code)
 			}
+			logger.Error("Failed to handle shopping list", logging.Err(err))
+		}
+
+	case *pb.ClientRequest_GetShoppingList_:
+		getShoppingListReq := req.GetGetShoppingList_()
 
-			syncBytes, err := proto.Marshal(syncResp)
-			if err != nil {
-				log.Println("Failed to marshal synchronization shopping list:", err)
-				continue
+		list, err := h.node.GetShoppingList(ctx, getShoppingListReq.GetId())
+		if err != nil {
+			logger.Warn("Failed to get shopping list", logging.Err(err))
+			tracing.Fail(trace.SpanFromContext(ctx), err)
+			if code, denied := accessErrorCode(err); denied {
+				return h.writeError(conn, logger, req.MessageId, code)
 			}
+			return h.writeError(conn, logger, req.MessageId, pb.ErrorCode_NOT_FOUND)
+		}
 
-			if err := conn.WriteMessage(websocket.BinaryMessage, syncBytes); err != nil {
-				log.Println("Error writing synchronization message:", err)
-				break
+		// Send the shopping list back to the client
+		return h.writeResponse(conn, logger, &pb.ServerResponse{
+			MessageId: req.MessageId,
+			ResponseType: &pb.ServerResponse_ShoppingList{
+				ShoppingList: list,
+			},
+		})
+
+	case *pb.ClientRequest_SubscribeShoppingList:
+		subscribeReq := req.GetSubscribeShoppingList()
+
+		// Only a subscription the node recorded is tracked, to be dropped when the connection closes
+		if err := h.node.SubscribeShoppingList(ctx, subscribeReq.GetId(), req.MessageId, conn); err != nil {
+			if _, denied := accessErrorCode(err); denied {
+				logger.Warn("Denied subscription to shopping list", logging.Err(err))
+			} else {
+				logger.Error("Failed to subscribe to shopping list", logging.Err(err))
 			}
+			tracing.Fail(trace.SpanFromContext(ctx), err)
+			return h.writeError(conn, logger, req.MessageId, errorCode(err))
+		}
+		if !conn.track(req.MessageId, subscribeReq.GetId()) {
+			h.unsubscribe(subscribeReq.GetId(), req.MessageId, conn)
+			return errConnectionClosed
+		}
 
-		case *pb.ClientRequest_RingView:
-			ringView := h.node.GetRingView()
+		// Synchronize client with local state
+		list, err := h.node.GetShoppingList(ctx, subscribeReq.GetId())
+		if err != nil {
+			logger.Warn("Failed to get shopping list for synchronization", logging.Err(err))
+			return nil
+		}
+
+		return h.writeResponse(conn, logger, &pb.ServerResponse{
+			MessageId: req.MessageId,
+			ResponseType: &pb.ServerResponse_ShoppingList{
+				ShoppingList: list,
+			},
+		})
 
-			ringViewResp := &pb.ServerResponse{
-				MessageId: req.MessageId,
-				ResponseType: &pb.ServerResponse_RingView{
-					RingView: &pb.RingView{
-						TokenToNode: ringView.GetTokenToNode(),
-					},
+	case *pb.ClientRequest_RingView:
+		ringView := h.node.GetRingView()
+
+		return h.writeResponse(conn, logger, &pb.ServerResponse{
+			MessageId: req.MessageId,
+			ResponseType: &pb.ServerResponse_RingView{
+				RingView: &pb.RingView{
+					TokenToNode: ringView.GetTokenToNode(),
 				},
-			}
+			},
+		})
 
-			ringViewBytes, err := proto.Marshal(ringViewResp)
-			if err != nil {
-				log.Println("Failed to marshal ring view response:", err)
-				continue
-			}
+	case *pb.ClientRequest_GetListAcl:
+		acl, err := h.node.GetListAcl(ctx, req.GetGetListAcl().GetId())
+		if err != nil {
+			logger.Warn("Failed to get list ACL", logging.Err(err))
+			tracing.Fail(trace.SpanFromContext(ctx), err)
+			return h.writeError(conn, logger, req.MessageId, errorCode(err))
+		}
+		return h.writeResponse(conn, logger, &pb.ServerResponse{
+			MessageId:    req.MessageId,
+			ResponseType: &pb.ServerResponse_ListAcl{ListAcl: acl},
+		})
 
-			if err := conn.WriteMessage(websocket.BinaryMessage, ringViewBytes); err != nil {
-				log.Println("Error writing ring view message:", err)
-				break
-			}
+	case *pb.ClientRequest_SetListAcl:
+		setReq := req.GetSetListAcl()
+		if setReq.GetId() == "" {
+			return h.writeError(conn, logger, req.MessageId, pb.ErrorCode_INVALID_REQUEST)
+		}
+
+		acl, err := h.node.SetListAcl(ctx, setReq.GetId(), setReq.GetEditors(), setReq.GetViewers())
+		if err != nil {
+			logger.Warn("Failed to set list ACL", logging.Err(err))
+			tracing.Fail(trace.SpanFromContext(ctx), err)
+			return h.writeError(conn, logger, req.MessageId, errorCode(err))
+		}
+		return h.writeResponse(conn, logger, &pb.ServerResponse{
+			MessageId:    req.MessageId,
+			ResponseType: &pb.ServerResponse_ListAcl{ListAcl: acl},
+		})
+
+	case *pb.ClientRequest_CreateShareLink:
+		shareReq := req.GetCreateShareLink()
+		if h.authKey == nil || shareReq.GetId() == "" || shareReq.GetTtlSeconds() < 0 {
+			return h.writeError(conn, logger, req.MessageId, pb.ErrorCode_INVALID_REQUEST)
+		}
+
+		if err := h.node.ShareList(ctx, shareReq.GetId()); err != nil {
+			logger.Warn("Failed to share list", logging.Err(err))
+			tracing.Fail(trace.SpanFromContext(ctx), err)
+			return h.writeError(conn, logger, req.MessageId, errorCode(err))
+		}
+		link, err := newShareLink(h.authKey, shareReq, sess.user, time.Now())
+		if err != nil {
+			logger.Error("Failed to sign capability", logging.Err(err))
+			return h.writeError(conn, logger, req.MessageId, pb.ErrorCode_INTERNAL_ERROR)
+		}
+		logger.Info("List shared", "list_id", link.GetListId(), "capability", link.GetCapabilityId(),
+			"access", link.GetAccess().String())
+
+		return h.writeResponse(conn, logger, &pb.ServerResponse{
+			MessageId:    req.MessageId,
+			ResponseType: &pb.ServerResponse_ShareLink{ShareLink: link},
+		})
 
-		default:
-			log.Println("Unknown request type")
+	case *pb.ClientRequest_RevokeShareLink:
+		if h.authKey == nil {
+			return h.writeError(conn, logger, req.MessageId, pb.ErrorCode_INVALID_REQUEST)
+		}
+
+		// An expired capability can no longer be presented, so there is nothing to revoke
+		c, err := auth.VerifyCapability(h.authKey, req.GetRevokeShareLink().GetCapability(), time.Now())
+		if err != nil && !errors.Is(err, auth.ErrTokenExpired) {
+			logger.Warn("Rejecting revocation of an invalid capability", logging.Err(err))
+			return h.writeError(conn, logger, req.MessageId, pb.ErrorCode_INVALID_REQUEST)
+		}
+		if err == nil {
+			if err := h.node.RevokeCapability(ctx, c); err != nil {
+				logger.Warn("Failed to revoke capability", logging.Err(err))
+				tracing.Fail(trace.SpanFromContext(ctx), err)
+				return h.writeError(conn, logger, req.MessageId, errorCode(err))
+			}
 		}
+
+		return h.writeResponse(conn, logger, &pb.ServerResponse{
+			MessageId:    req.MessageId,
+			ResponseType: &pb.ServerResponse_Ok{Ok: &pb.Ok{}},
+		})
+
+	default:
+		logger.Warn("Unknown request type")
+	}
+
+	return nil
+}
+
+// List a request is about, empty if it is about none
+func requestListID(req *pb.ClientRequest) string {
+	switch req.GetRequestType().(type) {
+	case *pb.ClientRequest_ShoppingList:
+		return req.GetShoppingList().GetId()
+	case *pb.ClientRequest_GetShoppingList_:
+		return req.GetGetShoppingList_().GetId()
+	case *pb.ClientRequest_SubscribeShoppingList:
+		return req.GetSubscribeShoppingList().GetId()
+	case *pb.ClientRequest_GetListAcl:
+		return req.GetGetListAcl().GetId()
+	case *pb.ClientRequest_SetListAcl:
+		return req.GetSetListAcl().GetId()
+	case *pb.ClientRequest_CreateShareLink:
+		return req.GetCreateShareLink().GetId()
+	}
+	return ""
+}
+
+// Whether a request reads, subscribes to or writes a shopping list
+func isListRequest(req *pb.ClientRequest) bool {
+	switch req.GetRequestType().(type) {
+	case *pb.ClientRequest_ShoppingList, *pb.ClientRequest_GetShoppingList_, *pb.ClientRequest_SubscribeShoppingList:
+		return true
+	}
+	return false
+}
+
+// Mints and signs a share capability of a list on behalf of its owner
+func newShareLink(key []byte, req *pb.CreateShareLinkRequest, issuer string, now time.Time) (*pb.ShareLink, error) {
+	access := auth.AccessRead
+	if req.GetAccess() == pb.ShareAccess_SHARE_WRITE {
+		access = auth.AccessWrite
+	}
+	var expires time.Time
+	if req.GetTtlSeconds() > 0 {
+		expires = time.Unix(now.Unix()+req.GetTtlSeconds(), 0)
+	}
+
+	c := auth.NewCapability(req.GetId(), access, issuer, expires)
+	token, err := auth.SignCapability(key, c)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ShareLink{
+		Capability:   token,
+		CapabilityId: c.ID,
+		ListId:       c.ListID,
+		Access:       req.GetAccess(),
+		Expires:      c.Expires,
+	}, nil
+}
+
+// Identifies the user of a session with a token. A session that fails to authenticate loses its previous identity.
+func (h *WebSocketHandler) authenticate(conn *Connection, logger *slog.Logger, req *pb.ClientRequest, sess *session) error {
+	if h.authKey == nil {
+		logger.Warn("Authentication requested, but no key is configured")
+		return h.writeError(conn, logger, req.MessageId, pb.ErrorCode_INVALID_REQUEST)
+	}
+
+	claims, err := auth.Verify(h.authKey, req.GetAuthenticate().GetToken(), time.Now())
+	if err != nil {
+		*sess = session{}
+		logger.Warn("Rejecting session token", logging.Err(err))
+		return h.writeError(conn, logger, req.MessageId, pb.ErrorCode_UNAUTHENTICATED)
+	}
+
+	*sess = session{user: claims.Subject, expires: claims.ExpiresAt()}
+	logger.Info("Session authenticated", logging.KeyUser, claims.Subject)
+	return h.writeResponse(conn, logger, &pb.ServerResponse{
+		MessageId:    req.MessageId,
+		ResponseType: &pb.ServerResponse_Authenticated{Authenticated: &pb.Authenticated{UserId: claims.Subject}},
+	})
+}
+
+// Error code of a request denied because of the identity of the session, if it was
+func accessErrorCode(err error) (pb.ErrorCode, bool) {
+	switch {
+	case errors.Is(err, auth.ErrUnauthenticated):
+		return pb.ErrorCode_UNAUTHENTICATED, true
+	case errors.Is(err, auth.ErrForbidden):
+		return pb.ErrorCode_FORBIDDEN, true
+	}
+	return 0, false
+}
+
+// Error code of a failed request
+func errorCode(err error) pb.ErrorCode {
+	if code, denied := accessErrorCode(err); denied {
+		return code
+	}
+	if errors.Is(err, replication.ErrNotFound) {
+		return pb.ErrorCode_NOT_FOUND
+	}
+	return pb.ErrorCode_INTERNAL_ERROR
+}
+
+func (h *WebSocketHandler) writeError(conn *Connection, logger *slog.Logger, messageID string, code pb.ErrorCode) error {
+	h.errors.Inc(code.String())
+	return h.writeResponse(conn, logger, &pb.ServerResponse{
+		MessageId: messageID,
+		ResponseType: &pb.ServerResponse_Error{
+			Error: code,
+		},
+	})
+}
+
+func (h *WebSocketHandler) writeResponse(conn *Connection, logger *slog.Logger, resp *pb.ServerResponse) error {
+	respBytes, err := proto.Marshal(resp)
+	if err != nil {
+		logger.Error("Failed to marshal response", logging.Err(err))
+		return nil
+	}
+
+	return conn.WriteMessage(respBytes)
+}
+
+// Starts the root span of a client request. Its trace ID is derived from the message ID of the request, so the trace
+// of a request can be found from the message ID alone (requests without a message ID get a random trace).
+func (h *WebSocketHandler) startSpan(ctx context.Context, req *pb.ClientRequest) (context.Context, trace.Span) {
+	if req.GetMessageId() != "" {
+		ctx = tracing.WithTraceID(ctx, tracing.TraceIDFromMessageID(req.GetMessageId()))
+	}
+	return tracing.Start(ctx, "client "+requestTypeName(req), trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(tracing.AttrNode.String(h.node.ID()), tracing.AttrMessageID.String(req.GetMessageId())))
+}
+
+// Name of the type of a request, as declared in client.proto (e.g. "get_shopping_list")
+func requestTypeName(req *pb.ClientRequest) string {
+	msg := req.ProtoReflect()
+	if field := msg.WhichOneof(msg.Descriptor().Oneofs().ByName("request_type")); field != nil {
+		return string(field.Name())
 	}
+	return "unknown"
 }
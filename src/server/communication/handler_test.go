@@ -0,0 +1,134 @@
+package communication
+
+import (
+	"net/http/httptest"
+	"sdle-server/auth"
+	"sdle-server/metrics"
+	pb "sdle-server/proto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/proto"
+)
+
+// Sends a request and returns the response to it
+func roundTrip(t *testing.T, conn *websocket.Conn, req *pb.ClientRequest) *pb.ServerResponse {
+	t.Helper()
+	data, _ := proto.Marshal(req)
+	if err := conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+		t.Fatal(err)
+	}
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp pb.ServerResponse
+	if err := proto.Unmarshal(data, &resp); err != nil {
+		t.Fatal(err)
+	}
+	return &resp
+}
+
+func subscribe(messageID string, listID string) *pb.ClientRequest {
+	return &pb.ClientRequest{
+		MessageId:   messageID,
+		RequestType: &pb.ClientRequest_SubscribeShoppingList{SubscribeShoppingList: &pb.SubscribeShoppingListRequest{Id: listID}},
+	}
+}
+
+func getList(messageID string, listID string) *pb.ClientRequest {
+	return &pb.ClientRequest{
+		MessageId:   messageID,
+		RequestType: &pb.ClientRequest_GetShoppingList_{GetShoppingList_: &pb.GetShoppingListRequest{Id: listID}},
+	}
+}
+
+func TestHandler_ListRateNotSpentByUnauthenticatedSessions(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	handler := NewWebSocketHandler(&subscriptionNode{subscriptions: make(map[string]bool)}, metrics.NewRegistry())
+	handler.RequireAuthentication(key)
+	handler.SetLimits(Limits{ListRate: 0.001, ListBurst: 2})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	anonymous, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer anonymous.Close()
+	for i := range 5 {
+		if resp := roundTrip(t, anonymous, getList("anon", "list1")); resp.GetError() != pb.ErrorCode_UNAUTHENTICATED {
+			t.Fatalf("Expected request %d of the anonymous session to be UNAUTHENTICATED, got %v", i, resp)
+		}
+	}
+
+	alice, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Close()
+	token, _ := auth.Sign(key, "alice", time.Now().Add(time.Hour))
+	roundTrip(t, alice, &pb.ClientRequest{
+		MessageId:   "auth",
+		RequestType: &pb.ClientRequest_Authenticate{Authenticate: &pb.AuthenticateRequest{Token: token}},
+	})
+
+	for i := range 2 {
+		if resp := roundTrip(t, alice, getList("get", "list1")); resp.GetShoppingList() == nil {
+			t.Fatalf("Expected request %d of alice to be served, got %v", i, resp)
+		}
+	}
+	if resp := roundTrip(t, alice, getList("get", "list1")); resp.GetError() != pb.ErrorCode_RATE_LIMITED {
+		t.Errorf("Expected the list to be rate limited once its burst is used, got %v", resp)
+	}
+}
+
+func TestHandler_SubscriptionsCountedPerList(t *testing.T) {
+	node := &subscriptionNode{subscriptions: make(map[string]bool)}
+	handler := NewWebSocketHandler(node, metrics.NewRegistry())
+	handler.SetLimits(Limits{MaxSubscriptions: 2})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// The client reuses its message ID for every list
+	for _, listID := range []string{"list1", "list2"} {
+		if resp := roundTrip(t, conn, subscribe("sub", listID)); resp.GetShoppingList() == nil {
+			t.Fatalf("Expected the subscription to %s to be served, got %v", listID, resp)
+		}
+	}
+	if resp := roundTrip(t, conn, subscribe("sub", "list3")); resp.GetError() != pb.ErrorCode_TOO_MANY_SUBSCRIPTIONS {
+		t.Errorf("Expected a third subscription to be rejected, got %v", resp)
+	}
+	if node.count() != 2 {
+		t.Errorf("Expected 2 subscriptions, got %d", node.count())
+	}
+}
+
+func TestHandler_FailedSubscriptionNotTracked(t *testing.T) {
+	handler := NewWebSocketHandler(&subscriptionNode{subscriptions: make(map[string]bool)}, metrics.NewRegistry())
+	handler.SetLimits(Limits{MaxSubscriptions: 1})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if resp := roundTrip(t, conn, subscribe("sub1", "unavailable")); resp.GetError() != pb.ErrorCode_INTERNAL_ERROR {
+		t.Errorf("Expected the failed subscription to be answered with INTERNAL_ERROR, got %v", resp)
+	}
+	if resp := roundTrip(t, conn, subscribe("sub2", "list1")); resp.GetShoppingList() == nil {
+		t.Errorf("Expected the failed subscription not to count towards the limit, got %v", resp)
+	}
+}
@@ -0,0 +1,115 @@
+package communication
+
+import (
+	"sdle-server/config"
+	"sync"
+	"time"
+)
+
+// Limits on what WebSocket clients may send. A zero value disables a limit.
+type Limits struct {
+	MaxMessageSize   int64   // bytes
+	ConnectionRate   float64 // requests per second a connection may send on average
+	ConnectionBurst  int     // requests a connection may send at once
+	ListRate         float64 // requests per second for a single list, over all connections
+	ListBurst        int     // requests for a single list accepted at once
+	MaxSubscriptions int     // subscriptions a connection may hold
+	MaxViolations    int     // rejected requests per minute after which a connection is closed
+}
+
+func LimitsFromConfig(c config.Config) Limits {
+	return Limits{
+		MaxMessageSize:   c.MaxClientMessageSize,
+		ConnectionRate:   c.ClientRequestRate,
+		ConnectionBurst:  c.ClientRequestBurst,
+		ListRate:         c.ListRequestRate,
+		ListBurst:        c.ListRequestBurst,
+		MaxSubscriptions: c.MaxClientSubscriptions,
+		MaxViolations:    c.MaxClientViolations,
+	}
+}
+
+// Messages up to this many times the maximum size are read (and answered with MESSAGE_TOO_LARGE); larger ones close
+// the connection before they are read whole
+const hardMessageSizeFactor = 4
+
+// Token bucket: holds up to burst tokens, refilled at rate tokens per second, and every event takes one. A nil bucket
+// allows everything.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// Creates a full bucket, or nil if the rate or the burst is zero (no limit)
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	if rate <= 0 || burst <= 0 {
+		return nil
+	}
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: now}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = min(b.burst, b.tokens+elapsed*b.rate)
+	}
+	b.last = now
+}
+
+// Takes a token, if there is one
+func (b *tokenBucket) allow(now time.Time) bool {
+	if b == nil {
+		return true
+	}
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Number of lists above which the list limiter drops the buckets that refilled
+const listBucketsPruneThreshold = 1024
+
+// Token buckets of the lists, shared by all the connections of a node, so that a list cannot be flooded by spreading
+// requests over many connections
+type listLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   int
+	buckets map[string]*tokenBucket
+}
+
+// Creates a limiter of the requests per list, or nil if the rate or the burst is zero (no limit)
+func newListLimiter(rate float64, burst int) *listLimiter {
+	if rate <= 0 || burst <= 0 {
+		return nil
+	}
+	return &listLimiter{rate: rate, burst: burst, buckets: make(map[string]*tokenBucket)}
+}
+
+func (l *listLimiter) allow(listID string, now time.Time) bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// A full bucket behaves as a new one, so dropping it forgets nothing
+	if len(l.buckets) >= listBucketsPruneThreshold {
+		for id, b := range l.buckets {
+			if b.refill(now); b.tokens >= b.burst {
+				delete(l.buckets, id)
+			}
+		}
+	}
+
+	b, ok := l.buckets[listID]
+	if !ok {
+		b = newTokenBucket(l.rate, l.burst, now)
+		l.buckets[listID] = b
+	}
+	return b.allow(now)
+}
@@ -0,0 +1,67 @@
+package communication
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	b := newTokenBucket(2, 3, now)
+
+	for i := range 3 {
+		if !b.allow(now) {
+			t.Fatalf("Expected request %d of the burst to be allowed", i)
+		}
+	}
+	if b.allow(now) {
+		t.Errorf("Expected the bucket to be empty after the burst")
+	}
+
+	// 2 tokens per second: one more request every 500ms
+	if !b.allow(now.Add(500 * time.Millisecond)) {
+		t.Errorf("Expected a token after 500ms")
+	}
+	if b.allow(now.Add(600 * time.Millisecond)) {
+		t.Errorf("Expected no token after 600ms")
+	}
+
+	// The bucket never holds more than the burst
+	later := now.Add(time.Hour)
+	for range 3 {
+		b.allow(later)
+	}
+	if b.allow(later) {
+		t.Errorf("Expected the refill to stop at the burst")
+	}
+
+	var unlimited *tokenBucket = newTokenBucket(0, 10, now)
+	if unlimited != nil || !unlimited.allow(now) {
+		t.Errorf("Expected a zero rate to disable the limit")
+	}
+}
+
+func TestListLimiter(t *testing.T) {
+	now := time.Now()
+	l := newListLimiter(1, 2)
+
+	if !l.allow("list1", now) || !l.allow("list1", now) {
+		t.Fatalf("Expected the burst of list1 to be allowed")
+	}
+	if l.allow("list1", now) {
+		t.Errorf("Expected list1 to be limited")
+	}
+	if !l.allow("list2", now) {
+		t.Errorf("Expected list2 not to be limited by list1")
+	}
+
+	// Buckets that refilled are dropped once there are many lists
+	for i := range listBucketsPruneThreshold {
+		l.allow(fmt.Sprintf("other%d", i), now)
+	}
+	l.allow("list3", now.Add(time.Hour))
+	if len(l.buckets) != 1 {
+		t.Errorf("Expected only the bucket of list3 to be kept, got %d buckets", len(l.buckets))
+	}
+}
@@ -1,18 +1,23 @@
 package communication
 
 import (
+	"context"
+
+	"sdle-server/auth"
 	crdt "sdle-server/crdt/shopping"
 	pb "sdle-server/proto"
 	"sdle-server/ringview"
-
-	"github.com/gorilla/websocket"
 )
 
 type NodeInterface interface {
 	ID() string
-	HandleShoppingList(list *crdt.ShoppingList) error
-	GetShoppingList(id string) (*pb.ShoppingList, error)
-	SubscribeShoppingList(listID string, messageID string, conn *websocket.Conn) error
-	UnsubscribeShoppingList(listID string, messageID string) error
+	HandleShoppingList(ctx context.Context, list *crdt.ShoppingList) error
+	GetShoppingList(ctx context.Context, id string) (*pb.ShoppingList, error)
+	SubscribeShoppingList(ctx context.Context, listID string, messageID string, conn *Connection) error
+	UnsubscribeShoppingList(listID string, messageID string, conn *Connection) error
+	GetListAcl(ctx context.Context, listID string) (*pb.ListAcl, error)
+	SetListAcl(ctx context.Context, listID string, editors []string, viewers []string) (*pb.ListAcl, error)
+	ShareList(ctx context.Context, listID string) error
+	RevokeCapability(ctx context.Context, c auth.Capability) error
 	GetRingView() *ringview.RingView
 }
@@ -2,18 +2,74 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"sdle-server/logging"
 	"time"
 )
 
+const (
+	HintExpiryDrop     = "drop"     // discard the hint (the hinted replica is later relocated by the scrubber)
+	HintExpiryRedirect = "redirect" // deliver the hinted value to the reachable replicas of the key, then discard it
+)
+
 type Config struct {
-	N int // Replication factor
-	W int // Write quorum
-	R int // Read quorum
-	TokensPerNode int // Number of tokens per node
+	N             int    // Replication factor
+	W             int    // Write quorum
+	R             int    // Read quorum
+	TokensPerNode int    // Number of tokens per node
 	HashSpaceSize uint64 // Size of the hash space
 
 	RequestTimeout       time.Duration // Timeout for requests to other nodes
-	HintDeliveryInterval time.Duration // Interval between handoff tries
+	ClientRequestTimeout time.Duration // Time a node spends on a client request (including its hops) before giving up on it
+	HintDeliveryInterval time.Duration // Interval between handoff tries (hints are also delivered as soon as their target recovers)
+	HintBatchSize        int           // Maximum number of hints sent to a node in a single request
+	HintBatchRate        int           // Maximum number of hint batches sent per second to a single node
+	HintRetryBackoff     time.Duration // Initial delay before retrying delivery to a node that failed (doubles on every failure)
+	HintRetryMaxBackoff  time.Duration // Maximum delay between delivery retries to a node
+	FailureProbeInterval time.Duration // Interval between pings to nodes suspected to be down
+
+	HintTTL           time.Duration // Time after which an undelivered hint expires (0 means hints never expire)
+	HintExpiryPolicy  string        // What to do with expired hints (HintExpiryDrop or HintExpiryRedirect)
+	MaxHintsPerTarget int           // Maximum number of hints stored for a single node (0 means no limit)
+	MaxHints          int           // Maximum number of hints stored in a node (0 means no limit)
+
+	RebalanceInterval  time.Duration // Interval between rebalancing rounds (0 disables the rebalancer)
+	RebalanceThreshold float64       // Load factor (relative to the mean) above which a node gives away part of a range
+	RebalanceMinLoad   int64         // Minimum load of a node before it is considered for rebalancing
+	RebalanceDryRun    bool          // Only log the proposed token moves, without executing them
+	RebalanceKeyRate   int           // Maximum number of keys streamed per second while moving a token
+
+	ScrubInterval time.Duration // Interval between ownership scrubs of the local store (0 disables the scrubber)
+	ScrubKeyRate  int           // Maximum number of keys checked per second while scrubbing
+
+	LogLevel  string // Minimum level of the logged records (debug, info, warn or error)
+	LogFormat string // Format of the logged records (logging.FormatText or logging.FormatJSON)
+
+	TraceFile string // File the spans of the node are exported to, as OpenTelemetry JSON (empty disables tracing)
+
+	// CurveZMQ keys (see zmqtransport.LoadCurveKeys). Without them, any process that can reach the ZMQ port of a node can
+	// send it requests.
+	CurveSecretKeyFile  string // File holding the secret key of the node
+	CurvePublicKeysFile string // File holding the public keys of the nodes of the cluster
+
+	// Limits on WebSocket clients (0 disables a limit)
+	MaxClientMessageSize   int64   // Maximum size, in bytes, of a client message
+	ClientRequestRate      float64 // Requests per second a connection may send on average
+	ClientRequestBurst     int     // Requests a connection may send at once
+	ListRequestRate        float64 // Requests per second for a single list, over all the connections of a node
+	ListRequestBurst       int     // Requests for a single list a node accepts at once
+	MaxClientSubscriptions int     // Subscriptions a connection may hold
+	MaxClientViolations    int     // Requests rejected by the limits, per minute, after which a connection is closed
+
+	// Keepalive of WebSocket clients
+	ClientPingInterval time.Duration // Interval between pings sent to a client (0 disables the keepalive)
+	ClientPongTimeout  time.Duration // Time without a pong, or any message, after which a client is declared dead
+	ClientWriteTimeout time.Duration // Time a write to a client may take before the client is declared dead
+
+	// WebSocket access. Without an auth key, sessions are anonymous and can only access lists without an owner.
+	AuthKeyFile    string   // File holding the key client session tokens are signed with (see auth.LoadKey)
+	AllowedOrigins []string // Origins browsers may open WebSocket connections from (any if empty)
 }
 
 func DefaultConfig() Config {
@@ -24,10 +80,45 @@ func DefaultConfig() Config {
 		TokensPerNode:        3,
 		HashSpaceSize:        65536,
 		HintDeliveryInterval: 10 * time.Second,
+		HintBatchSize:        64,
+		HintBatchRate:        10,
+		HintRetryBackoff:     1 * time.Second,
+		HintRetryMaxBackoff:  2 * time.Minute,
 		RequestTimeout:       100 * time.Millisecond,
+		ClientRequestTimeout: 2 * time.Second,
+		FailureProbeInterval: 5 * time.Second,
+		HintTTL:              24 * time.Hour,
+		HintExpiryPolicy:     HintExpiryRedirect,
+		MaxHintsPerTarget:    10000,
+		MaxHints:             50000,
+		RebalanceInterval:    60 * time.Second,
+		RebalanceThreshold:   1.25,
+		RebalanceMinLoad:     64 * 1024,
+		RebalanceDryRun:      false,
+		RebalanceKeyRate:     200,
+		ScrubInterval:        5 * time.Minute,
+		ScrubKeyRate:         100,
+		LogLevel:             "info",
+		LogFormat:            logging.FormatText,
+
+		MaxClientMessageSize:   64 * 1024,
+		ClientRequestRate:      20,
+		ClientRequestBurst:     40,
+		ListRequestRate:        50,
+		ListRequestBurst:       100,
+		MaxClientSubscriptions: 100,
+		MaxClientViolations:    20,
+
+		ClientPingInterval: 20 * time.Second,
+		ClientPongTimeout:  60 * time.Second,
+		ClientWriteTimeout: 10 * time.Second,
 	}
 }
 
+// Highest rate, in events per second, of the rate-limited background tasks (token moves, scrubs and hint delivery).
+// They wait time.Second / rate between two events, which must not round down to zero.
+const MaxRate = 1_000_000
+
 func (c Config) Validate() error {
 	if c.N < 1 {
 		return errors.New("N must be at least 1")
@@ -38,5 +129,36 @@ func (c Config) Validate() error {
 	if c.R < 1 || c.R > c.N {
 		return errors.New("R must be between 1 and N")
 	}
+	if c.RequestTimeout <= 0 || c.ClientRequestTimeout <= 0 {
+		return errors.New("RequestTimeout and ClientRequestTimeout must be positive")
+	}
+	if c.HintExpiryPolicy != HintExpiryDrop && c.HintExpiryPolicy != HintExpiryRedirect {
+		return errors.New("HintExpiryPolicy must be either drop or redirect")
+	}
+	if c.HintBatchSize < 1 || c.HintBatchRate < 1 || c.HintBatchRate > MaxRate {
+		return fmt.Errorf("HintBatchSize and HintBatchRate must be at least 1, and HintBatchRate at most %d", MaxRate)
+	}
+	if c.RebalanceThreshold < 1 {
+		return errors.New("RebalanceThreshold must be at least 1")
+	}
+	if c.RebalanceKeyRate < 1 || c.RebalanceKeyRate > MaxRate {
+		return fmt.Errorf("RebalanceKeyRate must be between 1 and %d", MaxRate)
+	}
+	if c.ScrubKeyRate < 1 || c.ScrubKeyRate > MaxRate {
+		return fmt.Errorf("ScrubKeyRate must be between 1 and %d", MaxRate)
+	}
+	if c.MaxClientMessageSize < 0 || c.ClientRequestRate < 0 || c.ClientRequestBurst < 0 || c.ListRequestRate < 0 ||
+		c.ListRequestBurst < 0 || c.MaxClientSubscriptions < 0 || c.MaxClientViolations < 0 {
+		return errors.New("limits on WebSocket clients must not be negative")
+	}
+	if c.ClientWriteTimeout <= 0 {
+		return errors.New("ClientWriteTimeout must be positive")
+	}
+	if c.ClientPingInterval < 0 || c.ClientPingInterval > 0 && c.ClientPongTimeout <= c.ClientPingInterval {
+		return errors.New("ClientPongTimeout must be longer than ClientPingInterval")
+	}
+	if _, err := logging.New(io.Discard, c.LogLevel, c.LogFormat); err != nil {
+		return err
+	}
 	return nil
 }
@@ -0,0 +1,143 @@
+// Package crdttest checks the lattice laws of delta-state CRDTs on random histories. The histories are drawn from
+// fuzzer input, so the checks run as Go fuzz targets and as regular tests over their seed corpus.
+package crdttest
+
+import "testing"
+
+// Number of replicas taking part in a history
+const replicas = 3
+
+// Maximum number of steps of a history, so that long fuzzer inputs stay fast
+const maxSteps = 64
+
+type Lattice[T any] interface {
+	Join(other T)
+	Clone() T
+}
+
+// A CRDT under test
+type Subject[T Lattice[T]] struct {
+	New func(replicaID string) T
+	// Applies a random operation, drawn from src, to a replica and returns its delta
+	Mutate func(replicaID string, replica T, src *Source) T
+	// Whether two states are the same (same values under the same dots, and equivalent contexts)
+	Equal func(a T, b T) bool
+}
+
+// Random choices read from fuzzer input. Once the input is exhausted every choice is zero.
+type Source struct {
+	data []byte
+	pos  int
+}
+
+func NewSource(data []byte) *Source {
+	return &Source{data: data}
+}
+
+func (s *Source) Exhausted() bool {
+	return s.pos >= len(s.data)
+}
+
+func (s *Source) Byte() byte {
+	if s.Exhausted() {
+		return 0
+	}
+	b := s.data[s.pos]
+	s.pos++
+	return b
+}
+
+// A choice in [0, n)
+func (s *Source) Intn(n int) int {
+	return int(s.Byte()) % n
+}
+
+// A value in [-limit, limit]
+func (s *Source) Int64(limit int64) int64 {
+	return int64(s.Byte())%(2*limit+1) - limit
+}
+
+// Picks one of the given values
+func Pick[V any](s *Source, values ...V) V {
+	return values[s.Intn(len(values))]
+}
+
+// Runs a random history of operations and syncs between replicas, checking after every operation that its delta
+// brings an older copy of the replica to the same state, and that a replica that already has the full state is not
+// changed by the delta. At the end it checks that Join is commutative, associative and idempotent on the replicas.
+func CheckLaws[T Lattice[T]](t *testing.T, subject Subject[T], data []byte) {
+	t.Helper()
+
+	src := NewSource(data)
+	ids := []string{"a", "b", "c"}
+	states := make([]T, replicas)
+	for i, id := range ids {
+		states[i] = subject.New(id)
+	}
+
+	for step := 0; step < maxSteps && !src.Exhausted(); step++ {
+		i := src.Intn(replicas)
+
+		if src.Intn(4) == 0 {
+			// Sync: the replica joins the full state of another one
+			j := src.Intn(replicas)
+			states[i].Join(states[j].Clone())
+			continue
+		}
+
+		before := states[i].Clone()
+		delta := subject.Mutate(ids[i], states[i], src)
+
+		viaDelta := before.Clone()
+		viaDelta.Join(delta.Clone())
+		if !subject.Equal(viaDelta, states[i]) {
+			t.Fatalf("Expected joining the delta to give the new state at step %d\nbefore: %v\ndelta: %v\nvia delta: %v\nstate: %v",
+				step, before, delta, viaDelta, states[i])
+		}
+
+		j := src.Intn(replicas)
+		withState := join(states[j], states[i])
+		withBoth := join(join(states[j], delta), states[i])
+		if !subject.Equal(withState, withBoth) {
+			t.Fatalf("Expected the delta to be absorbed by the full state at step %d\nreplica: %v\ndelta: %v\nstate: %v",
+				step, states[j], delta, states[i])
+		}
+	}
+
+	a, b, c := states[0], states[1], states[2]
+
+	if ab, ba := join(a, b), join(b, a); !subject.Equal(ab, ba) {
+		t.Fatalf("Expected Join to be commutative\na: %v\nb: %v\na ⊔ b: %v\nb ⊔ a: %v", a, b, ab, ba)
+	}
+
+	if left, right := join(join(a, b), c), join(a, join(b, c)); !subject.Equal(left, right) {
+		t.Fatalf("Expected Join to be associative\na: %v\nb: %v\nc: %v\n(a ⊔ b) ⊔ c: %v\na ⊔ (b ⊔ c): %v", a, b, c, left, right)
+	}
+
+	for i, state := range states {
+		if twice := join(state, state); !subject.Equal(twice, state) {
+			t.Fatalf("Expected Join to be idempotent on replica %s\nstate: %v\nstate ⊔ state: %v", ids[i], state, twice)
+		}
+	}
+}
+
+// Joins copies of two states, leaving both untouched
+func join[T Lattice[T]](a T, b T) T {
+	joined := a.Clone()
+	joined.Join(b.Clone())
+	return joined
+}
+
+// Adds seed inputs to a fuzz target: a few fixed histories, which also run as regular tests
+func AddSeeds(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+	for seed := range 16 {
+		data := make([]byte, 96)
+		for i := range data {
+			// A fixed pseudo-random sequence, different for each seed
+			data[i] = byte((seed*131 + i*(2*seed+17) + i*i*7) % 251)
+		}
+		f.Add(data)
+	}
+}
@@ -91,11 +91,14 @@ func (cc *CCounter) ToProto() *g01.CCounter {
 	}
 }
 
-func CCounterFromProto(protoCounter *g01.CCounter, replicaId string, ctx *DotContext) *CCounter {
-	dotKernel := IntDotKernelFromProto(protoCounter.GetDotKernel(), ctx)
+func CCounterFromProto(protoCounter *g01.CCounter, replicaId string, ctx *DotContext) (*CCounter, error) {
+	dotKernel, err := IntDotKernelFromProto(protoCounter.GetDotKernel(), ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	return &CCounter{
 		replicaID: replicaId,
 		dotKernel: (*DotKernel[int64])(dotKernel),
-	}
+	}, nil
 }
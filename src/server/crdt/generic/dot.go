@@ -25,6 +25,23 @@ func (d Dot) ToProto() *g01.Dot {
 	}
 }
 
-func DotFromProto(protoDot *g01.Dot) Dot {
-	return NewDot(protoDot.GetId(), protoDot.GetSeq())
+func DotFromProto(protoDot *g01.Dot) (Dot, error) {
+	if err := validateReplicaID(protoDot.GetId()); err != nil {
+		return Dot{}, err
+	}
+	if protoDot.GetSeq() == 0 {
+		return Dot{}, fmt.Errorf("%w: dot of %s has sequence number 0", ErrInvalidDot, protoDot.GetId())
+	}
+
+	return NewDot(protoDot.GetId(), protoDot.GetSeq()), nil
+}
+
+func validateReplicaID(id string) error {
+	if id == "" {
+		return fmt.Errorf("%w: empty replica ID", ErrInvalidDot)
+	}
+	if len(id) > MaxReplicaIDLength {
+		return fmt.Errorf("%w: replica ID of %d bytes", ErrTooLarge, len(id))
+	}
+	return nil
 }
\ No newline at end of file
@@ -26,6 +26,57 @@ func (ctx *DotContext) Knows(dot Dot) bool {
 	return ctx.dotCloud.Contains(dot)
 }
 
+// Checks if joining other would leave a hole in the causal history: some dot of other whose predecessor (same id,
+// previous seq) is known neither by this context nor by other itself, or a compact entry of other that runs more than
+// one seq past what this context knows
+func (ctx *DotContext) HasGap(other *DotContext) bool {
+	// A compact entry claims every seq up to its own, so joining it would mark as known the seqs this context missed
+	// (only the next seq can come with other itself)
+	for id, otherSeq := range other.versionVector {
+		for seq := ctx.versionVector[id] + 1; seq < otherSeq; seq++ {
+			if !ctx.dotCloud.Contains(NewDot(id, seq)) {
+				return true
+			}
+		}
+	}
+
+	for dot := range other.dotCloud {
+		if dot.seq <= 1 {
+			continue
+		}
+
+		previous := NewDot(dot.id, dot.seq-1)
+		if !ctx.Knows(previous) && !other.Knows(previous) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Checks if every dot known by other is also known by this context (i.e. this context has seen every update other has)
+func (ctx *DotContext) Includes(other *DotContext) bool {
+	for id, otherSeq := range other.versionVector {
+		if ctx.versionVector[id] >= otherSeq {
+			continue
+		}
+
+		for seq := ctx.versionVector[id] + 1; seq <= otherSeq; seq++ {
+			if !ctx.dotCloud.Contains(NewDot(id, seq)) {
+				return false
+			}
+		}
+	}
+
+	for dot := range other.dotCloud {
+		if !ctx.Knows(dot) {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (ctx *DotContext) MakeDot(id string) Dot {
 	if localSeq, ok := ctx.versionVector[id]; ok {
 		localSeq++
@@ -144,17 +195,27 @@ func (ctx *DotContext) ToProto() *g01.DotContext {
 	}
 }
 
-func DotContextFromProto(protoCtx *g01.DotContext) *DotContext {
+func DotContextFromProto(protoCtx *g01.DotContext) (*DotContext, error) {
+	if len(protoCtx.GetVersionVector())+len(protoCtx.GetDots()) > MaxDots {
+		return nil, fmt.Errorf("%w: context with %d entries", ErrTooLarge, len(protoCtx.GetVersionVector())+len(protoCtx.GetDots()))
+	}
+
 	ctx := NewDotContext()
 
 	for id, seq := range protoCtx.GetVersionVector() {
+		if err := validateReplicaID(id); err != nil {
+			return nil, fmt.Errorf("version vector: %w", err)
+		}
 		ctx.versionVector[id] = seq
 	}
 
 	for _, protoDot := range protoCtx.GetDots() {
-		dot := DotFromProto(protoDot)
+		dot, err := DotFromProto(protoDot)
+		if err != nil {
+			return nil, fmt.Errorf("context: %w", err)
+		}
 		ctx.dotCloud.Add(dot)
 	}
 
-	return ctx
+	return ctx, nil
 }
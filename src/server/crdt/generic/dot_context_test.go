@@ -399,3 +399,90 @@ func TestDotContext_CloneIndependence(t *testing.T) {
 		t.Errorf("Expected clone to know Dot(node1, 2)")
 	}
 }
+
+func TestDotContext_HasGap(t *testing.T) {
+	ctx := NewDotContext()
+	ctx.MakeDot("a")
+	ctx.MakeDot("a")
+
+	// Next dot of a: no gap
+	delta := NewDotContext()
+	delta.InsertDot(NewDot("a", 3))
+	if ctx.HasGap(delta) {
+		t.Errorf("Expected no gap for the next dot of a known replica")
+	}
+
+	// Dot 5 of a, while 3 and 4 are unknown
+	delta = NewDotContext()
+	delta.InsertDot(NewDot("a", 5))
+	if !ctx.HasGap(delta) {
+		t.Errorf("Expected a gap when intermediate dots are missing")
+	}
+
+	// The delta carries the missing dots itself
+	delta.InsertDot(NewDot("a", 3))
+	delta.InsertDot(NewDot("a", 4))
+	if ctx.HasGap(delta) {
+		t.Errorf("Expected no gap when the delta includes the intermediate dots")
+	}
+
+	// The first dot of an unknown replica is compacted into the version vector, and is its next seq
+	delta = NewDotContext()
+	delta.InsertDot(NewDot("b", 1))
+	if ctx.HasGap(delta) {
+		t.Errorf("Expected no gap for the first dot of an unknown replica")
+	}
+
+	// A version vector that runs past the known seqs claims the ones in between
+	delta = NewDotContext()
+	delta.InsertDot(NewDot("a", 1))
+	delta.InsertDot(NewDot("a", 2))
+	delta.InsertDot(NewDot("a", 3))
+	if ctx.HasGap(delta) {
+		t.Errorf("Expected no gap for a version vector that reaches the next seq")
+	}
+	delta.InsertDot(NewDot("a", 4))
+	if !ctx.HasGap(delta) {
+		t.Errorf("Expected a gap for a version vector that runs past the next seq")
+	}
+	ctx.InsertDot(NewDot("a", 3))
+	if ctx.HasGap(delta) {
+		t.Errorf("Expected no gap once the seqs in between are known")
+	}
+
+	delta = NewDotContext()
+	delta.InsertDot(NewDot("b", 2))
+	if !ctx.HasGap(delta) {
+		t.Errorf("Expected a gap for the second dot of an unknown replica")
+	}
+}
+
+func TestDotContext_Includes(t *testing.T) {
+	ctx := NewDotContext()
+	ctx.MakeDot("a")
+	ctx.MakeDot("a")
+	ctx.InsertDot(NewDot("b", 3))
+
+	other := NewDotContext()
+	other.MakeDot("a")
+	other.InsertDot(NewDot("b", 3))
+	if !ctx.Includes(other) {
+		t.Errorf("Expected the context to include a subset of its dots")
+	}
+
+	other.MakeDot("a")
+	other.MakeDot("a")
+	if ctx.Includes(other) {
+		t.Errorf("Expected the context not to include a dot it never saw")
+	}
+
+	other = NewDotContext()
+	other.MakeDot("b")
+	if ctx.Includes(other) {
+		t.Errorf("Expected the context not to include b:1, since it only knows b:3")
+	}
+
+	if !ctx.Includes(NewDotContext()) {
+		t.Errorf("Expected every context to include the empty context")
+	}
+}
@@ -134,22 +134,9 @@ func (dk *Int64DotKernel) ToProto() *g01.IntDotKernel {
 	}
 }
 
-func IntDotKernelFromProto(protoDotKernel *g01.IntDotKernel, ctx *DotContext) *Int64DotKernel {
-	if len(protoDotKernel.GetDotKeys()) != len(protoDotKernel.GetDotValues()) {
-		panic("DotKernelFromProto: mismatched lengths of dot keys and values")
-	}
-
-	dk := NewDotKernel[int64]()
-
-	for i, protoDot := range protoDotKernel.GetDotKeys() {
-		dot := DotFromProto(protoDot)
-		value := protoDotKernel.GetDotValues()[i]
-		dk.dotValues[dot] = value
-	}
-
-	dk.SetContext(ctx)
-
-	return (*Int64DotKernel)(dk)
+func IntDotKernelFromProto(protoDotKernel *g01.IntDotKernel, ctx *DotContext) (*Int64DotKernel, error) {
+	dk, err := dotKernelFromProto(protoDotKernel.GetDotKeys(), protoDotKernel.GetDotValues(), ctx)
+	return (*Int64DotKernel)(dk), err
 }
 
 func (dk *StringDotKernel) ToProto() *g01.StringDotKernel {
@@ -168,22 +155,9 @@ func (dk *StringDotKernel) ToProto() *g01.StringDotKernel {
 	}
 }
 
-func StringDotKernelFromProto(protoDotKernel *g01.StringDotKernel, ctx *DotContext) *StringDotKernel {
-	if len(protoDotKernel.GetDotKeys()) != len(protoDotKernel.GetDotValues()) {
-		panic("StringDotKernelFromProto: mismatched lengths of dot keys and values")
-	}
-
-	dk := NewDotKernel[string]()
-
-	for i, protoDot := range protoDotKernel.GetDotKeys() {
-		dot := DotFromProto(protoDot)
-		value := protoDotKernel.GetDotValues()[i]
-		dk.dotValues[dot] = value
-	}
-
-	dk.SetContext(ctx)
-
-	return (*StringDotKernel)(dk)
+func StringDotKernelFromProto(protoDotKernel *g01.StringDotKernel, ctx *DotContext) (*StringDotKernel, error) {
+	dk, err := dotKernelFromProto(protoDotKernel.GetDotKeys(), protoDotKernel.GetDotValues(), ctx)
+	return (*StringDotKernel)(dk), err
 }
 
 func (dk *EmptyDotKernel) ToProto() *g01.EmptyDotKernel {
@@ -199,15 +173,39 @@ func (dk *EmptyDotKernel) ToProto() *g01.EmptyDotKernel {
 	}
 }
 
-func EmptyDotKernelFromProto(protoDotKernel *g01.EmptyDotKernel, ctx *DotContext) *EmptyDotKernel {
-	dk := NewDotKernel[struct{}]()
+func EmptyDotKernelFromProto(protoDotKernel *g01.EmptyDotKernel, ctx *DotContext) (*EmptyDotKernel, error) {
+	keys := protoDotKernel.GetDotKeys()
+	dk, err := dotKernelFromProto(keys, make([]struct{}, len(keys)), ctx)
+	return (*EmptyDotKernel)(dk), err
+}
 
-	for _, protoDot := range protoDotKernel.GetDotKeys() {
-		dot := DotFromProto(protoDot)
-		dk.dotValues[dot] = struct{}{}
+// Decodes the dots and values of a kernel sharing the given context. Every dot must be valid, unique and covered by
+// the context (a dot the context does not know would be dropped or resurrected by the next join).
+func dotKernelFromProto[V comparable](keys []*g01.Dot, values []V, ctx *DotContext) (*DotKernel[V], error) {
+	if len(keys) != len(values) {
+		return nil, fmt.Errorf("%w: %d keys and %d values", ErrMismatchedKernel, len(keys), len(values))
+	}
+	if len(keys) > MaxDots {
+		return nil, fmt.Errorf("%w: kernel with %d dots", ErrTooLarge, len(keys))
+	}
+
+	dk := NewDotKernel[V]()
+
+	for i, protoDot := range keys {
+		dot, err := DotFromProto(protoDot)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := dk.dotValues[dot]; ok {
+			return nil, fmt.Errorf("%w: %v", ErrDuplicateDot, dot)
+		}
+		if !ctx.Knows(dot) {
+			return nil, fmt.Errorf("%w: %v", ErrUncoveredDot, dot)
+		}
+		dk.dotValues[dot] = values[i]
 	}
 
 	dk.SetContext(ctx)
 
-	return (*EmptyDotKernel)(dk)
-}
\ No newline at end of file
+	return dk, nil
+}
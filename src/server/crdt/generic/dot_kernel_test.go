@@ -1,8 +1,11 @@
 package crdt
 
 import (
+	"errors"
 	"reflect"
 	"testing"
+
+	g01 "sdle-server/proto"
 )
 
 func TestDotKernel_NewDotKernel(t *testing.T) {
@@ -334,3 +337,45 @@ func TestDotKernel_Clone(t *testing.T) {
 		t.Errorf("Expected cloned kernel.dotValues to have 3 entries, got %d", len(clone.dotValues))
 	}
 }
+
+func TestDotKernel_FromProto_Malformed(t *testing.T) {
+	ctx := NewDotContext()
+	ctx.InsertDot(NewDot("node1", 1))
+
+	tests := []struct {
+		name   string
+		kernel *g01.IntDotKernel
+		err    error
+	}{
+		{"mismatched lengths", &g01.IntDotKernel{DotKeys: []*g01.Dot{{Id: "node1", Seq: 1}}}, ErrMismatchedKernel},
+		{"empty replica ID", &g01.IntDotKernel{DotKeys: []*g01.Dot{{Id: "", Seq: 1}}, DotValues: []int64{1}}, ErrInvalidDot},
+		{"zero sequence", &g01.IntDotKernel{DotKeys: []*g01.Dot{{Id: "node1", Seq: 0}}, DotValues: []int64{1}}, ErrInvalidDot},
+		{"uncovered dot", &g01.IntDotKernel{DotKeys: []*g01.Dot{{Id: "node2", Seq: 1}}, DotValues: []int64{1}}, ErrUncoveredDot},
+		{"duplicate dot", &g01.IntDotKernel{DotKeys: []*g01.Dot{{Id: "node1", Seq: 1}, {Id: "node1", Seq: 1}}, DotValues: []int64{1, 2}}, ErrDuplicateDot},
+		{"nil dot", &g01.IntDotKernel{DotKeys: []*g01.Dot{nil}, DotValues: []int64{1}}, ErrInvalidDot},
+	}
+
+	for _, test := range tests {
+		_, err := IntDotKernelFromProto(test.kernel, ctx)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+		}
+		if !errors.Is(err, ErrMalformed) {
+			t.Errorf("%s: expected the error to wrap ErrMalformed, got %v", test.name, err)
+		}
+	}
+}
+
+func TestDotKernel_FromProto_Valid(t *testing.T) {
+	kernel := NewDotKernel[int64]()
+	kernel.DotAdd("node1", 5)
+	kernel.DotAdd("node2", 3)
+
+	converted, err := IntDotKernelFromProto((*Int64DotKernel)(kernel).ToProto(), kernel.dotContext)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(kernel.dotValues, converted.dotValues) {
+		t.Errorf("Expected %v, got %v", kernel.dotValues, converted.dotValues)
+	}
+}
@@ -76,11 +76,14 @@ func (flag *DWFlag) ToProto() *g01.DWFlag {
 	}
 }
 
-func DWFlagFromProto(protoFlag *g01.DWFlag, replicaId string, ctx *DotContext) *DWFlag {
-	dotKernel := EmptyDotKernelFromProto(protoFlag.GetDotKernel(), ctx)
+func DWFlagFromProto(protoFlag *g01.DWFlag, replicaId string, ctx *DotContext) (*DWFlag, error) {
+	dotKernel, err := EmptyDotKernelFromProto(protoFlag.GetDotKernel(), ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	return &DWFlag{
 		replicaID: replicaId,
 		dotKernel: (*DotKernel[struct{}])(dotKernel),
-	}
+	}, nil
 }
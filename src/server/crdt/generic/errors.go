@@ -0,0 +1,23 @@
+package crdt
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Limits on decoded payloads, so that a peer or client cannot make a node allocate without bound
+const (
+	MaxReplicaIDLength = 256
+	MaxDots            = 100000 // per context or kernel
+)
+
+// Every error returned when decoding a CRDT wraps ErrMalformed, and one of the more specific errors below
+var ErrMalformed = errors.New("malformed CRDT payload")
+
+var (
+	ErrInvalidDot       = fmt.Errorf("%w: invalid dot", ErrMalformed)
+	ErrMismatchedKernel = fmt.Errorf("%w: mismatched dot keys and values", ErrMalformed)
+	ErrUncoveredDot     = fmt.Errorf("%w: dot not covered by the context", ErrMalformed)
+	ErrDuplicateDot     = fmt.Errorf("%w: duplicate dot", ErrMalformed)
+	ErrTooLarge         = fmt.Errorf("%w: payload exceeds size limits", ErrMalformed)
+)
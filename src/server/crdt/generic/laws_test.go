@@ -0,0 +1,183 @@
+package crdt
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"testing"
+
+	"sdle-server/crdt/crdttest"
+)
+
+// Lattice laws of every generic CRDT, checked on random histories. `go test` runs the seed histories; run e.g.
+// `go test -fuzz=FuzzORMap_Laws ./crdt/generic` to search for more.
+
+func sameContext(a *DotContext, b *DotContext) bool {
+	return a.Includes(b) && b.Includes(a)
+}
+
+func sameKernel[V comparable](a *DotKernel[V], b *DotKernel[V]) bool {
+	return maps.Equal(a.dotValues, b.dotValues) && sameContext(a.dotContext, b.dotContext)
+}
+
+// Dots of a kernel in a fixed order, so that random picks are reproducible
+func sortedDots[V comparable](kernel *DotKernel[V]) []Dot {
+	return slices.SortedFunc(maps.Keys(kernel.dotValues), func(a Dot, b Dot) int {
+		return cmp.Or(cmp.Compare(a.id, b.id), cmp.Compare(a.seq, b.seq))
+	})
+}
+
+func FuzzDotKernel_Laws(f *testing.F) {
+	crdttest.AddSeeds(f)
+
+	subject := crdttest.Subject[*DotKernel[int64]]{
+		New: func(replicaID string) *DotKernel[int64] {
+			return NewDotKernel[int64]()
+		},
+		Mutate: func(replicaID string, kernel *DotKernel[int64], src *crdttest.Source) *DotKernel[int64] {
+			switch src.Intn(4) {
+			case 0:
+				dots := sortedDots(kernel)
+				if len(dots) > 0 {
+					return kernel.RemoveDot(dots[src.Intn(len(dots))])
+				}
+				return kernel.Add(replicaID, src.Int64(5))
+			case 1:
+				return kernel.RemoveValue(src.Int64(2))
+			case 2:
+				return kernel.Reset()
+			default:
+				return kernel.Add(replicaID, src.Int64(2))
+			}
+		},
+		Equal: sameKernel[int64],
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		crdttest.CheckLaws(t, subject, data)
+	})
+}
+
+func FuzzCCounter_Laws(f *testing.F) {
+	crdttest.AddSeeds(f)
+
+	subject := crdttest.Subject[*CCounter]{
+		New: NewCCounter,
+		Mutate: func(replicaID string, counter *CCounter, src *crdttest.Source) *CCounter {
+			if src.Intn(4) == 0 {
+				return counter.Reset()
+			}
+			return counter.Inc(src.Int64(5))
+		},
+		Equal: func(a *CCounter, b *CCounter) bool {
+			return sameKernel(a.dotKernel, b.dotKernel)
+		},
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		crdttest.CheckLaws(t, subject, data)
+	})
+}
+
+func FuzzMVReg_Laws(f *testing.F) {
+	crdttest.AddSeeds(f)
+
+	subject := crdttest.Subject[*MVReg[string]]{
+		New: NewMVReg[string],
+		Mutate: func(replicaID string, reg *MVReg[string], src *crdttest.Source) *MVReg[string] {
+			if src.Intn(4) == 0 {
+				return reg.Reset()
+			}
+			return reg.Write(crdttest.Pick(src, "milk", "eggs", "bread"))
+		},
+		Equal: func(a *MVReg[string], b *MVReg[string]) bool {
+			return sameKernel(a.dotKernel, b.dotKernel)
+		},
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		crdttest.CheckLaws(t, subject, data)
+	})
+}
+
+func FuzzDWFlag_Laws(f *testing.F) {
+	crdttest.AddSeeds(f)
+
+	subject := crdttest.Subject[*DWFlag]{
+		New: NewDWFlag,
+		Mutate: func(replicaID string, flag *DWFlag, src *crdttest.Source) *DWFlag {
+			switch src.Intn(3) {
+			case 0:
+				return flag.Enable()
+			case 1:
+				return flag.Disable()
+			default:
+				return flag.Reset()
+			}
+		},
+		Equal: func(a *DWFlag, b *DWFlag) bool {
+			return sameKernel(a.dotKernel, b.dotKernel)
+		},
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		crdttest.CheckLaws(t, subject, data)
+	})
+}
+
+func FuzzORMap_Laws(f *testing.F) {
+	crdttest.AddSeeds(f)
+
+	subject := crdttest.Subject[*ORMap[string, *CCounter]]{
+		New: func(replicaID string) *ORMap[string, *CCounter] {
+			return NewORMap[string](replicaID, NewCCounter)
+		},
+		Mutate: func(replicaID string, ormap *ORMap[string, *CCounter], src *crdttest.Source) *ORMap[string, *CCounter] {
+			key := crdttest.Pick(src, "milk", "eggs", "bread")
+
+			switch src.Intn(5) {
+			case 0:
+				return ormap.Remove(key)
+			case 1:
+				return ormap.Reset()
+			default:
+				diff := src.Int64(5)
+				return ormap.Apply(key, func(counter *CCounter) *CCounter {
+					return counter.Inc(diff)
+				})
+			}
+		},
+		Equal: func(a *ORMap[string, *CCounter], b *ORMap[string, *CCounter]) bool {
+			if !sameContext(a.dotContext, b.dotContext) {
+				return false
+			}
+
+			// Values share the context of the map, so only their dots are compared. A missing key is a null value.
+			for key := range maps.Keys(a.valueMap) {
+				if !sameORMapValue(a, b, key) {
+					return false
+				}
+			}
+			for key := range maps.Keys(b.valueMap) {
+				if !sameORMapValue(a, b, key) {
+					return false
+				}
+			}
+			return true
+		},
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		crdttest.CheckLaws(t, subject, data)
+	})
+}
+
+func sameORMapValue(a *ORMap[string, *CCounter], b *ORMap[string, *CCounter], key string) bool {
+	values := func(ormap *ORMap[string, *CCounter]) map[Dot]int64 {
+		if counter, ok := ormap.valueMap[key]; ok {
+			return counter.dotKernel.dotValues
+		}
+		return nil
+	}
+	return maps.Equal(values(a), values(b))
+}
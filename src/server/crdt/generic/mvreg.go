@@ -102,11 +102,14 @@ func (reg *StringMVReg) ToProto() *g01.StringMVReg {
 	}
 }
 
-func StringMVRegFromProto(protoReg *g01.StringMVReg, replicaId string, ctx *DotContext) *StringMVReg {
-	dotKernel := StringDotKernelFromProto(protoReg.GetDotKernel(), ctx)
+func StringMVRegFromProto(protoReg *g01.StringMVReg, replicaId string, ctx *DotContext) (*StringMVReg, error) {
+	dotKernel, err := StringDotKernelFromProto(protoReg.GetDotKernel(), ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	return &StringMVReg{
 		id:        replicaId,
 		dotKernel: (*DotKernel[string])(dotKernel),
-	}
+	}, nil
 }
\ No newline at end of file
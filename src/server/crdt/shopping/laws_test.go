@@ -0,0 +1,98 @@
+package crdt
+
+import (
+	"fmt"
+	"maps"
+	"testing"
+
+	"sdle-server/crdt/crdttest"
+	pb "sdle-server/proto"
+)
+
+// Lattice laws of the shopping list, checked on random histories. `go test` runs the seed histories; run
+// `go test -fuzz=FuzzShoppingList_Laws ./crdt/shopping` to search for more.
+
+func dotValues[V comparable](keys []*pb.Dot, values []V) map[string]V {
+	result := make(map[string]V)
+	for i, dot := range keys {
+		result[fmt.Sprintf("%s.%d", dot.GetId(), dot.GetSeq())] = values[i]
+	}
+	return result
+}
+
+func dotSet(keys []*pb.Dot) map[string]bool {
+	result := make(map[string]bool)
+	for _, dot := range keys {
+		result[fmt.Sprintf("%s.%d", dot.GetId(), dot.GetSeq())] = true
+	}
+	return result
+}
+
+// Same dots and values in every field, and equivalent contexts. A missing item is a null item.
+func sameShoppingList(a *ShoppingList, b *ShoppingList) bool {
+	if !a.dotContext.Includes(b.dotContext) || !b.dotContext.Includes(a.dotContext) {
+		return false
+	}
+
+	protoA, protoB := a.ToProto(), b.ToProto()
+	nameA, nameB := protoA.GetName().GetDotKernel(), protoB.GetName().GetDotKernel()
+	if !maps.Equal(dotValues(nameA.GetDotKeys(), nameA.GetDotValues()), dotValues(nameB.GetDotKeys(), nameB.GetDotValues())) {
+		return false
+	}
+
+	itemIDs := maps.Clone(protoA.GetItems())
+	maps.Copy(itemIDs, protoB.GetItems())
+	for itemID := range itemIDs {
+		itemA, itemB := protoA.GetItems()[itemID], protoB.GetItems()[itemID]
+
+		name := func(item *pb.ShoppingItem) map[string]string {
+			kernel := item.GetName().GetDotKernel()
+			return dotValues(kernel.GetDotKeys(), kernel.GetDotValues())
+		}
+		quantity := func(item *pb.ShoppingItem) map[string]int64 {
+			kernel := item.GetQuantity().GetDotKernel()
+			return dotValues(kernel.GetDotKeys(), kernel.GetDotValues())
+		}
+		acquired := func(item *pb.ShoppingItem) map[string]int64 {
+			kernel := item.GetAcquired().GetDotKernel()
+			return dotValues(kernel.GetDotKeys(), kernel.GetDotValues())
+		}
+		deleted := func(item *pb.ShoppingItem) map[string]bool {
+			return dotSet(item.GetDeleted().GetDotKernel().GetDotKeys())
+		}
+
+		if !maps.Equal(name(itemA), name(itemB)) || !maps.Equal(quantity(itemA), quantity(itemB)) ||
+			!maps.Equal(acquired(itemA), acquired(itemB)) || !maps.Equal(deleted(itemA), deleted(itemB)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func FuzzShoppingList_Laws(f *testing.F) {
+	crdttest.AddSeeds(f)
+
+	subject := crdttest.Subject[*ShoppingList]{
+		New: func(replicaID string) *ShoppingList {
+			return NewShoppingList(replicaID, "list1")
+		},
+		Mutate: func(replicaID string, list *ShoppingList, src *crdttest.Source) *ShoppingList {
+			itemID := crdttest.Pick(src, "milk", "eggs", "bread")
+
+			switch src.Intn(5) {
+			case 0:
+				return list.SetName(crdttest.Pick(src, "Groceries", "Party"))
+			case 1:
+				return list.RemoveItem(itemID)
+			default:
+				return list.PutItem(itemID, itemID, src.Int64(5), src.Int64(3))
+			}
+		},
+		Equal: sameShoppingList,
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		crdttest.CheckLaws(t, subject, data)
+	})
+}
@@ -1,6 +1,7 @@
 package crdt
 
 import (
+	"fmt"
 	crdt "sdle-server/crdt/generic"
 	g01 "sdle-server/proto"
 )
@@ -229,19 +230,38 @@ func (si *ShoppingItem) ToProto() *g01.ShoppingItem {
 	}
 }
 
-func ShoppingItemFromProto(protoItem *g01.ShoppingItem, replicaID string, itemID string, ctx *crdt.DotContext) *ShoppingItem {
-	name := (*crdt.MVReg[string])(crdt.StringMVRegFromProto(protoItem.GetName(), replicaID, ctx))
-	quantity := crdt.CCounterFromProto(protoItem.GetQuantity(), replicaID, ctx)
-	acquired := crdt.CCounterFromProto(protoItem.GetAcquired(), replicaID, ctx)
-	deleted := crdt.DWFlagFromProto(protoItem.GetDeleted(), replicaID, ctx)
+func ShoppingItemFromProto(protoItem *g01.ShoppingItem, replicaID string, itemID string, ctx *crdt.DotContext) (*ShoppingItem, error) {
+	if len(itemID) > MaxIDLength {
+		return nil, fmt.Errorf("%w: item ID of %d bytes", crdt.ErrTooLarge, len(itemID))
+	}
+
+	name, err := crdt.StringMVRegFromProto(protoItem.GetName(), replicaID, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("name: %w", err)
+	}
+	if err := validateNames(protoItem.GetName().GetDotKernel().GetDotValues()); err != nil {
+		return nil, err
+	}
+	quantity, err := crdt.CCounterFromProto(protoItem.GetQuantity(), replicaID, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("quantity: %w", err)
+	}
+	acquired, err := crdt.CCounterFromProto(protoItem.GetAcquired(), replicaID, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquired: %w", err)
+	}
+	deleted, err := crdt.DWFlagFromProto(protoItem.GetDeleted(), replicaID, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("deleted: %w", err)
+	}
 
 	return &ShoppingItem{
 		replicaID:  replicaID,
 		dotContext: ctx,
 		itemID:     itemID,
-		name:       name,
+		name:       (*crdt.MVReg[string])(name),
 		quantity:   quantity,
 		acquired:   acquired,
 		deleted:    deleted,
-	}
+	}, nil
 }
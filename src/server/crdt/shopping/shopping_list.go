@@ -127,6 +127,12 @@ func (sl *ShoppingList) RemoveItem(itemID string) *ShoppingList {
 	return delta
 }
 
+// Checks if merging a delta would skip updates this replica has not seen yet (see DotContext.HasGap). In that case
+// the sender should ship its full state instead.
+func (sl *ShoppingList) HasCausalGap(delta *ShoppingList) bool {
+	return sl.dotContext.HasGap(delta.dotContext)
+}
+
 func (sl *ShoppingList) Join(other *ShoppingList) {
 	originalContext := sl.dotContext.Clone()
 
@@ -172,22 +178,65 @@ func (sl *ShoppingList) ToProto() *g01.ShoppingList {
 	}
 }
 
-func ShoppingListFromProto(protoList *g01.ShoppingList, replicaId string) *ShoppingList {
-	ctx := crdt.DotContextFromProto(protoList.GetDotContext())
+// Limits on decoded shopping lists, on top of those of the generic CRDTs
+const (
+	MaxIDLength   = 256  // list and item IDs
+	MaxNameLength = 1024 // list and item names
+	MaxItems      = 10000
+)
+
+// Decodes a shopping list received from a client or a peer, or read from storage. Malformed payloads are rejected
+// with an error wrapping crdt.ErrMalformed.
+func ShoppingListFromProto(protoList *g01.ShoppingList, replicaId string) (*ShoppingList, error) {
+	if protoList.GetId() == "" {
+		return nil, fmt.Errorf("%w: empty list ID", crdt.ErrMalformed)
+	}
+	if len(protoList.GetId()) > MaxIDLength {
+		return nil, fmt.Errorf("%w: list ID of %d bytes", crdt.ErrTooLarge, len(protoList.GetId()))
+	}
+	if len(protoList.GetItems()) > MaxItems {
+		return nil, fmt.Errorf("%w: list with %d items", crdt.ErrTooLarge, len(protoList.GetItems()))
+	}
+
+	ctx, err := crdt.DotContextFromProto(protoList.GetDotContext())
+	if err != nil {
+		return nil, err
+	}
 
 	itemMap := make(map[string]*ShoppingItem)
 	for id, protoItem := range protoList.GetItems() {
-		itemMap[id] = ShoppingItemFromProto(protoItem, replicaId, id, ctx)
+		item, err := ShoppingItemFromProto(protoItem, replicaId, id, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("item %q: %w", id, err)
+		}
+		itemMap[id] = item
 	}
 
 	createItem := func(id string) *ShoppingItem { return NewShoppingItem(id, "") }
 	items := crdt.NewORMapFrom(replicaId, createItem, ctx, itemMap)
 
+	name, err := crdt.StringMVRegFromProto(protoList.GetName(), replicaId, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("name: %w", err)
+	}
+	if err := validateNames(protoList.GetName().GetDotKernel().GetDotValues()); err != nil {
+		return nil, err
+	}
+
 	return &ShoppingList{
 		replicaID:  replicaId,
 		listID:     protoList.GetId(),
-		name:       (*crdt.MVReg[string])(crdt.StringMVRegFromProto(protoList.GetName(), replicaId, ctx)),
+		name:       (*crdt.MVReg[string])(name),
 		dotContext: ctx,
 		items:      items,
+	}, nil
+}
+
+func validateNames(names []string) error {
+	for _, name := range names {
+		if len(name) > MaxNameLength {
+			return fmt.Errorf("%w: name of %d bytes", crdt.ErrTooLarge, len(name))
+		}
 	}
+	return nil
 }
@@ -1,8 +1,11 @@
 package crdt
 
 import (
+	"errors"
 	"reflect"
 	crdt "sdle-server/crdt/generic"
+	pb "sdle-server/proto"
+	"strings"
 	"testing"
 )
 
@@ -431,7 +434,10 @@ func TestShoppingList_ToProtoAndFromProto_EmptyList(t *testing.T) {
     list := NewShoppingList("replica1", "list1")
 
     proto := list.ToProto()
-    converted := ShoppingListFromProto(proto, "replica1")
+    converted, err := ShoppingListFromProto(proto, "replica1")
+    if err != nil {
+        t.Fatalf("Expected no error converting from proto, got %v", err)
+    }
 
     if !listsEqual(list, converted) {
         t.Errorf("Expected converted list to be equal to the original,\n---\ngot %v\n---\nand %v\n---", list, converted)
@@ -443,7 +449,10 @@ func TestShoppingList_ToProtoAndFromProto_SingleItem(t *testing.T) {
     list.PutItem("item1", "Milk", 5, 2)
 
     proto := list.ToProto()
-    converted := ShoppingListFromProto(proto, "replica1")
+    converted, err := ShoppingListFromProto(proto, "replica1")
+    if err != nil {
+        t.Fatalf("Expected no error converting from proto, got %v", err)
+    }
 
     t.Logf("list: %v", list)
     t.Logf("conv: %v", converted)
@@ -459,7 +468,10 @@ func TestShoppingList_ToProtoAndFromProto_MultipleItems(t *testing.T) {
     list.PutItem("item2", "Bread", 3, 1)
 
     proto := list.ToProto()
-    converted := ShoppingListFromProto(proto, "replica1")
+    converted, err := ShoppingListFromProto(proto, "replica1")
+    if err != nil {
+        t.Fatalf("Expected no error converting from proto, got %v", err)
+    }
 
     if !listsEqual(list, converted) {
         t.Errorf("Expected converted list to be equal to the original,\n---\ngot %v\n---\nand %v\n---", list, converted)
@@ -471,7 +483,10 @@ func TestShoppingList_ToProtoAndFromProto_EmptyItem(t *testing.T) {
     list.PutItem("item1", "Milk", 0, 0) // Add an item with zero quantity and acquired
 
     proto := list.ToProto()
-    converted := ShoppingListFromProto(proto, "replica1")
+    converted, err := ShoppingListFromProto(proto, "replica1")
+    if err != nil {
+        t.Fatalf("Expected no error converting from proto, got %v", err)
+    }
 
     if !listsEqual(list, converted) {
         t.Errorf("Expected converted list to be equal to the original,\n---\ngot %v\n---\nand %v\n---", list, converted)
@@ -488,7 +503,10 @@ func TestShoppingList_ToProtoAndFromProto_ConflictingUpdates(t *testing.T) {
     list1.Join(list2)
 
     proto := list1.ToProto()
-    converted := ShoppingListFromProto(proto, "replica1")
+    converted, err := ShoppingListFromProto(proto, "replica1")
+    if err != nil {
+        t.Fatalf("Expected no error converting from proto, got %v", err)
+    }
 
     if !listsEqual(list1, converted) {
         t.Errorf("Expected converted list to be equal to the original after join,\n---\ngot %v\n---\nand %v\n---", list1, converted)
@@ -503,7 +521,10 @@ func TestShoppingList_ToProtoAndFromProto_RemoveItem(t *testing.T) {
     list.RemoveItem("item1")
 
     proto := list.ToProto()
-    converted := ShoppingListFromProto(proto, "replica1")
+    converted, err := ShoppingListFromProto(proto, "replica1")
+    if err != nil {
+        t.Fatalf("Expected no error converting from proto, got %v", err)
+    }
 
     if !listsEqual(list, converted) {
         t.Errorf("Expected converted list to be equal to the original after item removal,\n---\ngot %v\n---\nand %v\n---", list, converted)
@@ -520,9 +541,68 @@ func TestShoppingList_ToProtoAndFromProto_ComplexScenario(t *testing.T) {
     list.PutItem("item4", "Butter", 2, 0)
 
     proto := list.ToProto()
-    converted := ShoppingListFromProto(proto, "replica1")
+    converted, err := ShoppingListFromProto(proto, "replica1")
+    if err != nil {
+        t.Fatalf("Expected no error converting from proto, got %v", err)
+    }
 
     if !listsEqual(list, converted) {
         t.Errorf("Expected converted list to be equal to the original after complex scenario,\n---\ngot %v\n---\nand %v\n---", list, converted)
     }
-}
\ No newline at end of file
+}
+
+func TestShoppingList_HasCausalGap(t *testing.T) {
+    client := NewShoppingList("client", "list1")
+    replica := client.Clone()
+
+    delta1 := client.PutItem("item1", "Milk", 1, 0)
+    delta2 := client.PutItem("item2", "Eggs", 12, 0)
+
+    // The first delta carries several dots of the client, compacted into its version vector: the replica can not
+    // tell them from dots it missed
+    if !replica.HasCausalGap(delta1) {
+        t.Errorf("Expected a version vector past the next dot to be a gap")
+    }
+    if !replica.HasCausalGap(delta2) {
+        t.Errorf("Expected a gap when the replica missed the previous delta")
+    }
+
+    replica.Join(delta1)
+    if replica.HasCausalGap(delta2) {
+        t.Errorf("Expected no gap after the previous delta was merged")
+    }
+
+    replica.Join(delta2)
+    if !listsEqual(client, replica) {
+        t.Errorf("Expected the replica to converge after merging every delta")
+    }
+
+    // A full state never has gaps for a replica that is up to date
+    if replica.HasCausalGap(client) {
+        t.Errorf("Expected no gap for the full state of an up to date replica")
+    }
+}
+
+func TestShoppingList_FromProto_Malformed(t *testing.T) {
+    list := NewShoppingList("replica1", "list1")
+    list.PutItem("item1", "Milk", 5, 2)
+
+    emptyID := list.ToProto()
+    emptyID.Id = ""
+
+    longName := list.ToProto()
+    longName.GetItems()["item1"].GetName().GetDotKernel().DotValues[0] = strings.Repeat("a", MaxNameLength+1)
+
+    uncovered := list.ToProto()
+    uncovered.GetItems()["item1"].GetQuantity().GetDotKernel().GetDotKeys()[0].Id = "replica2"
+
+    for name, protoList := range map[string]*pb.ShoppingList{
+        "empty list ID":     emptyID,
+        "name too long":     longName,
+        "dot not in context": uncovered,
+    } {
+        if _, err := ShoppingListFromProto(protoList, "replica1"); !errors.Is(err, crdt.ErrMalformed) {
+            t.Errorf("%s: expected an error wrapping ErrMalformed, got %v", name, err)
+        }
+    }
+}
@@ -0,0 +1,68 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type NodeInterface interface {
+	// Reports why the node is down, nil while it runs
+	Live() error
+
+	// Reasons why the node cannot serve quorum operations, empty when it is ready to
+	NotReady() []string
+}
+
+const (
+	StatusOK       = "ok"
+	StatusNotReady = "not ready"
+	StatusDown     = "down"
+)
+
+// Body of the responses of the health endpoints
+type Status struct {
+	Status  string   `json:"status"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// Serves /healthz (liveness: 200 while the node runs) and /readyz (readiness: 200 while the node can serve quorum
+// operations, 503 with the reasons otherwise)
+func NewHandler(node NodeInterface) http.Handler {
+	h := &handler{node: node}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", h.healthz)
+	mux.HandleFunc("GET /readyz", h.readyz)
+	return mux
+}
+
+type handler struct {
+	node NodeInterface
+}
+
+func (h *handler) healthz(w http.ResponseWriter, r *http.Request) {
+	if err := h.node.Live(); err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, Status{Status: StatusDown, Reasons: []string{err.Error()}})
+		return
+	}
+	writeJSON(w, http.StatusOK, Status{Status: StatusOK})
+}
+
+func (h *handler) readyz(w http.ResponseWriter, r *http.Request) {
+	if err := h.node.Live(); err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, Status{Status: StatusDown, Reasons: []string{err.Error()}})
+		return
+	}
+	if reasons := h.node.NotReady(); len(reasons) > 0 {
+		writeJSON(w, http.StatusServiceUnavailable, Status{Status: StatusNotReady, Reasons: reasons})
+		return
+	}
+	writeJSON(w, http.StatusOK, Status{Status: StatusOK})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,61 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeNode struct {
+	live     error
+	notReady []string
+}
+
+func (f *fakeNode) Live() error        { return f.live }
+func (f *fakeNode) NotReady() []string { return f.notReady }
+
+func get(t *testing.T, h http.Handler, path string) (int, Status) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+
+	var status Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Expected a JSON status from %s, got %q", path, rec.Body.String())
+	}
+	return rec.Code, status
+}
+
+func TestHealth_Ready(t *testing.T) {
+	h := NewHandler(&fakeNode{})
+
+	for _, path := range []string{"/healthz", "/readyz"} {
+		if code, status := get(t, h, path); code != http.StatusOK || status.Status != StatusOK {
+			t.Errorf("Expected %s to be 200 ok, got %d %+v", path, code, status)
+		}
+	}
+}
+
+func TestHealth_NotReady(t *testing.T) {
+	h := NewHandler(&fakeNode{notReady: []string{"joining the ring"}})
+
+	if code, _ := get(t, h, "/healthz"); code != http.StatusOK {
+		t.Errorf("Expected a node that is not ready to be alive, got %d", code)
+	}
+	code, status := get(t, h, "/readyz")
+	if code != http.StatusServiceUnavailable || status.Status != StatusNotReady || len(status.Reasons) != 1 {
+		t.Errorf("Expected 503 not ready with the reason, got %d %+v", code, status)
+	}
+}
+
+func TestHealth_Down(t *testing.T) {
+	h := NewHandler(&fakeNode{live: errors.New("node stopping")})
+
+	for _, path := range []string{"/healthz", "/readyz"} {
+		if code, status := get(t, h, path); code != http.StatusServiceUnavailable || status.Status != StatusDown {
+			t.Errorf("Expected %s to be 503 down, got %d %+v", path, code, status)
+		}
+	}
+}
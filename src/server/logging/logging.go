@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+const (
+	FormatText = "text" // key=value pairs, one record per line
+	FormatJSON = "json" // one JSON object per line
+)
+
+// Names of the fields attached to log records, shared by all packages so that logs of several nodes can be filtered
+// and aggregated on them
+const (
+	KeyNode      = "node"       // ID of the node that logged the record
+	KeyRequest   = "request"    // type of the request being handled (e.g. "replica_put")
+	KeyKey       = "key"        // key of the store the record is about
+	KeyPeer      = "peer"       // ID of the other node involved
+	KeyMessageID = "message_id" // ID of the client message being handled
+	KeyTrace     = "trace_id"   // ID of the trace of the request being handled
+	KeyDuration  = "duration"   // time taken by the operation
+	KeyUser      = "user"       // authenticated user of the client session
+	KeyError     = "error"
+)
+
+// Creates a logger writing records of at least the given level ("debug", "info", "warn" or "error") to w, in the
+// given format (FormatText or FormatJSON)
+func New(w io.Writer, level string, format string) (*slog.Logger, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(format) {
+	case FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (expected %s or %s)", format, FormatText, FormatJSON)
+	}
+}
+
+func ParseLevel(level string) (slog.Level, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q (expected debug, info, warn or error)", level)
+	}
+	return lvl, nil
+}
+
+// Attribute holding an error, under KeyError
+func Err(err error) slog.Attr {
+	return slog.Any(KeyError, err)
+}
@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNew_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", FormatJSON)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	logger.Debug("hidden")
+	logger.With(KeyNode, "node1:5000").Warn("Replica write failed", KeyKey, "key1", KeyPeer, "node2:5001", Err(errors.New("timeout")))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected only the warning to be logged, got %q", buf.String())
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("Expected a JSON record, got %q", lines[0])
+	}
+	expected := map[string]any{
+		"level":  "WARN",
+		"msg":    "Replica write failed",
+		KeyNode:  "node1:5000",
+		KeyKey:   "key1",
+		KeyPeer:  "node2:5001",
+		KeyError: "timeout",
+	}
+	for field, value := range expected {
+		if record[field] != value {
+			t.Errorf("Expected %s=%v, got %v", field, value, record[field])
+		}
+	}
+}
+
+func TestNew_Text(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "DEBUG", "TEXT")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	logger.Debug("Received ping", KeyPeer, "node2:5001")
+	if !strings.Contains(buf.String(), "level=DEBUG") || !strings.Contains(buf.String(), "peer=node2:5001") {
+		t.Errorf("Expected a text record with the peer field, got %q", buf.String())
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "verbose", FormatText); err == nil {
+		t.Errorf("Expected an error for an unknown level")
+	}
+	if _, err := New(&bytes.Buffer{}, "info", "xml"); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
@@ -1,31 +1,113 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
+	"sdle-server/auth"
+	"sdle-server/config"
+	"sdle-server/logging"
 	"sdle-server/node"
+	"sdle-server/tracing"
+	"sdle-server/transport/zmqtransport"
+	"strings"
 	"syscall"
 	"time"
 )
 
 func main() {
-	if len(os.Args) != 3 {
-		fmt.Fprintln(os.Stderr, "usage: server <nodeID> <entryNodeID>")
+	defaults := config.DefaultConfig()
+	logLevel := flag.String("log-level", defaults.LogLevel, "minimum level of the logged records (debug, info, warn or error)")
+	logFormat := flag.String("log-format", defaults.LogFormat, "format of the logged records (text or json)")
+	curveSecretKeyFile := flag.String("curve-secret-key", defaults.CurveSecretKeyFile, "file holding the CurveZMQ secret key of the node (requires -curve-public-keys)")
+	curvePublicKeysFile := flag.String("curve-public-keys", defaults.CurvePublicKeysFile, "file holding the CurveZMQ public keys of the cluster, a \"<nodeID> <key>\" line per node")
+	authKeyFile := flag.String("auth-key", defaults.AuthKeyFile, "file holding the key WebSocket session tokens are signed with (sessions must authenticate if set)")
+	allowedOrigins := flag.String("allowed-origins", strings.Join(defaults.AllowedOrigins, ","), "comma-separated origins browsers may open WebSocket connections from (any if empty)")
+	traceFile := flag.String("trace-file", defaults.TraceFile, "file to export the spans of the node to, as OpenTelemetry JSON (tracing is off if empty)")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: server [flags] <nodeID> <entryNodeID>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	logger, err := logging.New(os.Stderr, *logLevel, *logFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error configuring logging - ", err.Error())
 		os.Exit(1)
 	}
+	slog.SetDefault(logger)
+
+	nodeID := flag.Arg(0)
+	entryID := flag.Arg(1)
 
-	nodeID := os.Args[1]
-	entryID := os.Args[2]
+	if *traceFile != "" {
+		f, err := os.Create(*traceFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error configuring tracing - ", err.Error())
+			os.Exit(1)
+		}
+		defer f.Close()
+
+		shutdownTracing, err := tracing.Setup(f, nodeID)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error configuring tracing - ", err.Error())
+			os.Exit(1)
+		}
+		// Flushes the pending spans once the node is stopped
+		defer shutdownTracing(context.Background())
+	}
+
+	var curve *zmqtransport.CurveKeys
+	if *curveSecretKeyFile != "" || *curvePublicKeysFile != "" {
+		if *curveSecretKeyFile == "" || *curvePublicKeysFile == "" {
+			fmt.Fprintln(os.Stderr, "Error configuring CurveZMQ - both -curve-secret-key and -curve-public-keys are required")
+			os.Exit(1)
+		}
+		curve, err = zmqtransport.LoadCurveKeys(*curveSecretKeyFile, *curvePublicKeysFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error configuring CurveZMQ - ", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	var authKey []byte
+	if *authKeyFile != "" {
+		authKey, err = auth.LoadKey(*authKeyFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error configuring authentication - ", err.Error())
+			os.Exit(1)
+		}
+	}
 
 	dataDir := "./data/" + nodeID
-	n, err := node.NewNode(nodeID, dataDir)
+	t, err := zmqtransport.New(nodeID, curve)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error creating transport - ", err.Error())
+		os.Exit(1)
+	}
+	n, err := node.NewNode(nodeID, dataDir, t)
 
 	if err != nil {
+		_ = t.Close()
 		fmt.Fprintln(os.Stderr, "Error creating node - ", err.Error())
 		os.Exit(1)
 	}
 
+	if authKey != nil {
+		n.WebSocketHandler().RequireAuthentication(authKey)
+	}
+	if *allowedOrigins != "" {
+		n.WebSocketHandler().AllowOrigins(strings.Split(*allowedOrigins, ","))
+	}
+
 	// Setup channels for errors and OS signals
 	errCh := make(chan error, 2)
 	sigCh := make(chan os.Signal, 1)
@@ -36,7 +118,7 @@ func main() {
 	// Try to join the ring via the entry node
 	time.Sleep(200 * time.Millisecond) // Give the listeners a brief moment to bind before attempting join.
 	joinTargetAddr := node.NodeIdToZMQAddr(entryID)
-	if err := n.JoinToRing(joinTargetAddr); err != nil {
+	if err := n.JoinToRing(context.Background(), joinTargetAddr); err != nil {
 		fmt.Fprintln(os.Stderr, "failed to join ring:", err)
 		os.Exit(1)
 	}
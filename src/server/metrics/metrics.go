@@ -0,0 +1,299 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Buckets (in seconds) for the latency of requests, from a local store access to a timed out hop
+var LatencyBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+
+// Set of metrics exposed by a node, written in the Prometheus text format
+type Registry struct {
+	mu       sync.Mutex
+	families []family
+}
+
+type family interface {
+	write(w io.Writer)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.families = append(r.families, f)
+}
+
+// Writes every metric, in the order they were registered
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	families := slices.Clone(r.families)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Serves the metrics to Prometheus scrapes
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.WriteText(w)
+	})
+}
+
+// Values of the labels of a series, keyed by their concatenation
+type seriesSet[T any] struct {
+	labels []string
+	mu     sync.Mutex
+	series map[string]*T
+	values map[string][]string
+	create func() *T
+}
+
+func newSeriesSet[T any](labels []string, create func() *T) seriesSet[T] {
+	return seriesSet[T]{labels: labels, series: map[string]*T{}, values: map[string][]string{}, create: create}
+}
+
+func (s *seriesSet[T]) get(labelValues []string) *T {
+	if len(labelValues) != len(s.labels) {
+		panic(fmt.Sprintf("metrics: expected %d label values, got %d", len(s.labels), len(labelValues)))
+	}
+	id := strings.Join(labelValues, "\xff")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.series[id]
+	if !ok {
+		t = s.create()
+		s.series[id] = t
+		s.values[id] = slices.Clone(labelValues)
+	}
+	return t
+}
+
+// Calls fn for every series, sorted by label values so that scrapes are stable
+func (s *seriesSet[T]) each(fn func(labels string, t *T)) {
+	s.mu.Lock()
+	ids := slices.Sorted(maps.Keys(s.series))
+	series := make([]*T, len(ids))
+	values := make([][]string, len(ids))
+	for i, id := range ids {
+		series[i], values[i] = s.series[id], s.values[id]
+	}
+	s.mu.Unlock()
+
+	for i := range ids {
+		fn(formatLabels(s.labels, values[i]), series[i])
+	}
+}
+
+// Monotonic count of events, partitioned by labels
+type CounterVec struct {
+	name, help string
+	set        seriesSet[counter]
+}
+
+type counter struct {
+	mu    sync.Mutex
+	value float64
+}
+
+func (r *Registry) NewCounterVec(name string, help string, labels ...string) *CounterVec {
+	c := &CounterVec{name: name, help: help, set: newSeriesSet(labels, func() *counter { return &counter{} })}
+	r.register(c)
+	return c
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	s := c.set.get(labelValues)
+	s.mu.Lock()
+	s.value += v
+	s.mu.Unlock()
+}
+
+// Current value of a series (0 if it was never incremented)
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	s := c.set.get(labelValues)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.value
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	writeHeader(w, c.name, c.help, "counter")
+	c.set.each(func(labels string, s *counter) {
+		s.mu.Lock()
+		v := s.value
+		s.mu.Unlock()
+		fmt.Fprintf(w, "%s%s %s\n", c.name, labels, formatValue(v))
+	})
+}
+
+// Value that goes up and down
+type Gauge struct {
+	name, help string
+	mu         sync.Mutex
+	value      float64
+}
+
+func (r *Registry) NewGauge(name string, help string) *Gauge {
+	g := &Gauge{name: name, help: help}
+	r.register(g)
+	return g
+}
+
+func (g *Gauge) Add(v float64) {
+	g.mu.Lock()
+	g.value += v
+	g.mu.Unlock()
+}
+
+func (g *Gauge) Inc() { g.Add(1) }
+func (g *Gauge) Dec() { g.Add(-1) }
+
+func (g *Gauge) Value() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.value
+}
+
+func (g *Gauge) write(w io.Writer) {
+	writeHeader(w, g.name, g.help, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, formatValue(g.Value()))
+}
+
+// Gauge whose value is read when the metrics are scraped
+type gaugeFunc struct {
+	name, help string
+	fn         func() float64
+}
+
+func (r *Registry) NewGaugeFunc(name string, help string, fn func() float64) {
+	r.register(&gaugeFunc{name: name, help: help, fn: fn})
+}
+
+func (g *gaugeFunc) write(w io.Writer) {
+	writeHeader(w, g.name, g.help, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, formatValue(g.fn()))
+}
+
+// Distribution of observed values in cumulative buckets, partitioned by labels
+type HistogramVec struct {
+	name, help string
+	buckets    []float64
+	set        seriesSet[histogram]
+}
+
+type histogram struct {
+	mu     sync.Mutex
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+func (r *Registry) NewHistogramVec(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{name: name, help: help, buckets: buckets}
+	h.set = newSeriesSet(labels, func() *histogram { return &histogram{counts: make([]uint64, len(buckets))} })
+	r.register(h)
+	return h
+}
+
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	s := h.set.get(labelValues)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i, _ := slices.BinarySearch(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.sum += v
+	s.count++
+}
+
+// Observes a duration, in seconds
+func (h *HistogramVec) ObserveDuration(d time.Duration, labelValues ...string) {
+	h.Observe(d.Seconds(), labelValues...)
+}
+
+// Number of observations of a series
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	s := h.set.get(labelValues)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	writeHeader(w, h.name, h.help, "histogram")
+	h.set.each(func(labels string, s *histogram) {
+		s.mu.Lock()
+		counts, sum, count := slices.Clone(s.counts), s.sum, s.count
+		s.mu.Unlock()
+
+		cumulative := uint64(0)
+		for i, bound := range h.buckets {
+			cumulative += counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, withLabel(labels, "le", formatValue(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, withLabel(labels, "le", "+Inf"), count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labels, formatValue(sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labels, count)
+	})
+}
+
+func writeHeader(w io.Writer, name string, help string, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+func formatLabels(names []string, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + escapeLabel(values[i]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Adds a label to formatted labels
+func withLabel(labels string, name string, value string) string {
+	pair := name + `="` + escapeLabel(value) + `"`
+	if labels == "" {
+		return "{" + pair + "}"
+	}
+	return labels[:len(labels)-1] + "," + pair + "}"
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatValue(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
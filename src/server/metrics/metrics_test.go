@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_WriteText(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounterVec("sdle_requests_total", "Requests handled.", "type", "outcome")
+	latency := r.NewHistogramVec("sdle_request_duration_seconds", "Time spent handling requests.", []float64{0.1, 1}, "type")
+	connections := r.NewGauge("sdle_websocket_connections", "Open connections.")
+	r.NewGaugeFunc("sdle_hints", "Stored hints.", func() float64 { return 7 })
+
+	requests.Inc("put", "ok")
+	requests.Inc("put", "ok")
+	requests.Inc("get", "error")
+	latency.Observe(0.05, "put")
+	latency.Observe(0.1, "put")
+	latency.Observe(3, "put")
+	connections.Inc()
+	connections.Inc()
+	connections.Dec()
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := `# HELP sdle_requests_total Requests handled.
+# TYPE sdle_requests_total counter
+sdle_requests_total{type="get",outcome="error"} 1
+sdle_requests_total{type="put",outcome="ok"} 2
+# HELP sdle_request_duration_seconds Time spent handling requests.
+# TYPE sdle_request_duration_seconds histogram
+sdle_request_duration_seconds_bucket{type="put",le="0.1"} 2
+sdle_request_duration_seconds_bucket{type="put",le="1"} 2
+sdle_request_duration_seconds_bucket{type="put",le="+Inf"} 3
+sdle_request_duration_seconds_sum{type="put"} 3.15
+sdle_request_duration_seconds_count{type="put"} 3
+# HELP sdle_websocket_connections Open connections.
+# TYPE sdle_websocket_connections gauge
+sdle_websocket_connections 1
+# HELP sdle_hints Stored hints.
+# TYPE sdle_hints gauge
+sdle_hints 7
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestRegistry_EscapesLabels(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("sdle_errors_total", "Errors.", "error").Inc("bad \"value\"\n")
+
+	var buf bytes.Buffer
+	_ = r.WriteText(&buf)
+	if !strings.Contains(buf.String(), `sdle_errors_total{error="bad \"value\"\n"} 1`) {
+		t.Errorf("Expected the label value to be escaped, got %s", buf.String())
+	}
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("sdle_requests_total", "Requests handled.", "type").Inc("ping")
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Expected the Prometheus text content type, got %s", ct)
+	}
+	if !strings.Contains(rec.Body.String(), `sdle_requests_total{type="ping"} 1`) {
+		t.Errorf("Expected the counter in the response, got %s", rec.Body.String())
+	}
+}
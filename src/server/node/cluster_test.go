@@ -0,0 +1,680 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"sdle-server/admin"
+	"sdle-server/auth"
+	generic "sdle-server/crdt/generic"
+	crdt "sdle-server/crdt/shopping"
+	"sdle-server/health"
+	pb "sdle-server/proto"
+	"sdle-server/replication"
+	"sdle-server/ringview"
+	"sdle-server/tracing"
+	"sdle-server/transport"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/protobuf/proto"
+)
+
+// Starts a cluster of nodes connected through an in-memory network
+func startMemoryCluster(t *testing.T, ids []string) []*Node {
+	t.Helper()
+
+	network := transport.NewMemoryNetwork()
+	baseDir := t.TempDir()
+	errCh := make(chan error, len(ids))
+
+	nodes := []*Node{}
+	for _, id := range ids {
+		memTransport, err := network.Listen(id)
+		if err != nil {
+			t.Fatalf("Expected no error listening on %s, got %v", id, err)
+		}
+
+		n, err := NewNodeWithTransport(id, baseDir, memTransport)
+		if err != nil {
+			t.Fatalf("Expected no error creating node %s, got %v", id, err)
+		}
+		n.Start(errCh)
+		t.Cleanup(func() { n.Stop() })
+
+		// The first node bootstraps the ring, the others join through it
+		if err := n.JoinToRing(t.Context(), NodeIdToZMQAddr(ids[0])); err != nil {
+			t.Fatalf("Expected node %s to join the ring, got %v", id, err)
+		}
+
+		nodes = append(nodes, n)
+	}
+
+	// Wait for the join gossip to reach every node
+	deadline := time.Now().Add(2 * time.Second)
+	for _, n := range nodes {
+		for len(n.GetRingView().GetKnownIds()) < len(ids) && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	return nodes
+}
+
+func TestCluster_InMemoryPutGet(t *testing.T) {
+	nodes := startMemoryCluster(t, []string{"node1:5000", "node2:5001", "node3:5002"})
+
+	for _, n := range nodes {
+		if got := len(n.GetRingView().GetKnownIds()); got != len(nodes) {
+			t.Fatalf("Expected node %s to know %d nodes, got %d", n.ID(), len(nodes), got)
+		}
+	}
+
+	if err := nodes[0].Put(t.Context(), "key1", []byte("value1")); err != nil {
+		t.Fatalf("Expected no error on Put, got %v", err)
+	}
+
+	for _, n := range nodes {
+		value, err := n.Get(t.Context(), "key1")
+		if err != nil {
+			t.Errorf("Expected no error on Get from %s, got %v", n.ID(), err)
+			continue
+		}
+		if string(value) != "value1" {
+			t.Errorf("Expected value1 from %s, got %s", n.ID(), value)
+		}
+	}
+}
+
+func TestCluster_RejectsMalformedShoppingList(t *testing.T) {
+	nodes := startMemoryCluster(t, []string{"node1:5000", "node2:5001", "node3:5002"})
+	key := ShoppingListKey("list1")
+	malformed := []byte{0xff, 0xff, 0xff}
+
+	// Whichever node coordinates, the write is rejected as malformed
+	for _, n := range nodes {
+		if err := n.PutDelta(t.Context(), key, malformed); !errors.Is(err, generic.ErrMalformed) {
+			t.Errorf("Expected PutDelta on %s to fail with ErrMalformed, got %v", n.ID(), err)
+		}
+		if err := n.Put(t.Context(), key, malformed); !errors.Is(err, generic.ErrMalformed) {
+			t.Errorf("Expected Put on %s to fail with ErrMalformed, got %v", n.ID(), err)
+		}
+	}
+
+	// A replica tells its peer that the payload was malformed
+	resp, err := nodes[0].sendReplicaDeltaRequest(t.Context(), NodeIdToZMQAddr(nodes[1].ID()), key, malformed)
+	if !errors.Is(err, generic.ErrMalformed) || resp.GetFailure() != pb.Failure_FAILURE_MALFORMED {
+		t.Errorf("Expected the replica to reject the delta as malformed, got %v", err)
+	}
+	resp, err = nodes[0].sendReplicaPutRequest(t.Context(), NodeIdToZMQAddr(nodes[1].ID()), key, malformed)
+	if !errors.Is(err, generic.ErrMalformed) || resp.GetFailure() != pb.Failure_FAILURE_MALFORMED {
+		t.Errorf("Expected the replica to reject the write as malformed, got %v", err)
+	}
+	resp, err = nodes[0].sendReplicaPutBatchRequest(t.Context(), NodeIdToZMQAddr(nodes[1].ID()),
+		[]*pb.RequestReplicaPut{{Key: "key1", Value: []byte("value")}, {Key: key, Value: malformed}})
+	if !errors.Is(err, generic.ErrMalformed) || resp.GetFailure() != pb.Failure_FAILURE_MALFORMED {
+		t.Errorf("Expected the replica to reject the batch as malformed, got %v", err)
+	}
+	if has, _ := nodes[1].store.Has([]byte("key1")); has {
+		t.Errorf("Expected no entry of a rejected batch to be stored")
+	}
+
+	for _, n := range nodes {
+		if count, err := n.hintStore.CountHints(); err != nil || count != 0 {
+			t.Errorf("Expected no hints on %s, got %d (error: %v)", n.ID(), count, err)
+		}
+		if _, err := n.Get(t.Context(), key); err == nil {
+			t.Errorf("Expected nothing to be stored on %s", n.ID())
+		}
+	}
+}
+
+func TestCluster_DeadlineTravelsWithRequest(t *testing.T) {
+	nodes := startMemoryCluster(t, []string{"node1:5000", "node2:5001"})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	req := &pb.Request{Origin: nodes[0].ID(), RequestType: &pb.Request_Ping{Ping: &pb.RequestPing{}}}
+	if _, err := nodes[0].sendRequest(ctx, NodeIdToZMQAddr(nodes[1].ID()), req); err != nil {
+		t.Fatalf("Expected the ping to succeed, got %v", err)
+	}
+	if req.TimeoutMs <= 0 || req.TimeoutMs > 50 {
+		t.Errorf("Expected the request to carry the time left to the caller (at most 50ms), got %dms", req.TimeoutMs)
+	}
+
+	// Each hop waits at most RequestTimeout, even for a caller without a deadline
+	req = &pb.Request{Origin: nodes[0].ID(), RequestType: &pb.Request_Ping{Ping: &pb.RequestPing{}}}
+	if _, err := nodes[0].sendRequest(t.Context(), NodeIdToZMQAddr(nodes[1].ID()), req); err != nil {
+		t.Fatalf("Expected the ping to succeed, got %v", err)
+	}
+	if limit := nodes[0].replConfig.RequestTimeout.Milliseconds(); req.TimeoutMs <= 0 || req.TimeoutMs > limit {
+		t.Errorf("Expected the request to carry at most %dms, got %dms", limit, req.TimeoutMs)
+	}
+
+	reqCtx, reqCancel := nodes[1].requestContext(&pb.Request{TimeoutMs: 50})
+	defer reqCancel()
+	if deadline, ok := reqCtx.Deadline(); !ok || time.Until(deadline) > 50*time.Millisecond {
+		t.Errorf("Expected the receiver to give up on the request within 50ms, got %v", deadline)
+	}
+}
+
+func TestCluster_AbandonedRequests(t *testing.T) {
+	nodes := startMemoryCluster(t, []string{"node1:5000", "node2:5001", "node3:5002"})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	for _, n := range nodes {
+		if err := n.Put(ctx, "key1", []byte("value1")); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected Put on %s to be abandoned, got %v", n.ID(), err)
+		}
+		if _, err := n.Get(ctx, "key1"); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected Get on %s to be abandoned, got %v", n.ID(), err)
+		}
+	}
+
+	for _, n := range nodes {
+		if has, _ := n.store.Has([]byte("key1")); has {
+			t.Errorf("Expected an abandoned write not to reach %s", n.ID())
+		}
+		if count, _ := n.hintStore.CountHints(); count != 0 {
+			t.Errorf("Expected an abandoned write not to leave hints on %s, got %d", n.ID(), count)
+		}
+		if suspected := n.failures.Suspected(); len(suspected) != 0 {
+			t.Errorf("Expected an abandoned request not to make %s suspect its peers, got %v", n.ID(), suspected)
+		}
+	}
+
+	// A replica drops a request its sender gave up on before it was handled
+	expired, expiredCancel := context.WithCancel(t.Context())
+	expiredCancel()
+	resp := nodes[1].dispatchRequest(expired, &pb.Request{RequestType: &pb.Request_ReplicaPut{
+		ReplicaPut: &pb.RequestReplicaPut{Key: "key1", Value: []byte("value1")},
+	}})
+	if resp.GetOk() {
+		t.Errorf("Expected the replica to drop the abandoned request")
+	}
+	if has, _ := nodes[1].store.Has([]byte("key1")); has {
+		t.Errorf("Expected the abandoned replica write not to be stored")
+	}
+}
+
+func TestCluster_Metrics(t *testing.T) {
+	nodes := startMemoryCluster(t, []string{"node1:5000", "node2:5001", "node3:5002"})
+
+	if err := nodes[0].Put(t.Context(), "key1", []byte("value1")); err != nil {
+		t.Fatalf("Expected Put to succeed, got %v", err)
+	}
+	if _, err := nodes[1].Get(t.Context(), "key1"); err != nil {
+		t.Fatalf("Expected Get to succeed, got %v", err)
+	}
+
+	puts, gets, replicaPuts := 0.0, 0.0, 0.0
+	for _, n := range nodes {
+		puts += n.metrics.quorums.Value("put", quorumAchieved)
+		gets += n.metrics.quorums.Value("get", quorumAchieved)
+		replicaPuts += n.metrics.requests.Value("replica_put", "ok")
+	}
+	if puts != 1 || gets != 1 {
+		t.Errorf("Expected one achieved PUT and GET quorum, got %v and %v", puts, gets)
+	}
+	if replicaPuts != 2 {
+		t.Errorf("Expected the coordinator to write 2 remote replicas, got %v", replicaPuts)
+	}
+
+	var buf bytes.Buffer
+	if err := nodes[0].Metrics().WriteText(&buf); err != nil {
+		t.Fatalf("Expected no error writing metrics, got %v", err)
+	}
+	for _, line := range []string{"sdle_hints 0", "sdle_ring_nodes 3", `sdle_gossip_messages_total{kind="join",direction="received"}`} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("Expected the metrics to contain %q, got:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestAdminAddr(t *testing.T) {
+	addr, err := AdminAddr("lists.example.com:5001")
+	if err != nil || addr != "127.0.0.1:9001" {
+		t.Errorf("Expected the admin API on the loopback interface, got %s %v", addr, err)
+	}
+}
+
+func TestCluster_AdminAPI(t *testing.T) {
+	nodes := startMemoryCluster(t, []string{"node1:5000", "node2:5001", "node3:5002"})
+	if err := nodes[0].Put(t.Context(), "key1", []byte("value1")); err != nil {
+		t.Fatalf("Expected Put to succeed, got %v", err)
+	}
+	h := admin.NewHandler(nodes[0])
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/status", nil))
+	var status admin.Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Expected a JSON status, got %q", rec.Body.String())
+	}
+	if status.ID != "node1:5000" || len(status.RingNodes) != 3 || len(status.Tokens) != nodes[0].replConfig.TokensPerNode {
+		t.Errorf("Expected the status of node1 in a 3-node ring, got %+v", status)
+	}
+
+	// With N=3, every node of the ring holds every key
+	hash := ringview.HashKey("key1")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", fmt.Sprintf("/admin/keys?start=%d&end=%d", hash, hash), nil))
+	var keys admin.Keys
+	if err := json.Unmarshal(rec.Body.Bytes(), &keys); err != nil {
+		t.Fatalf("Expected a JSON key list, got %q", rec.Body.String())
+	}
+	if len(keys.Keys) != 1 || keys.Keys[0].Key != "key1" {
+		t.Errorf("Expected key1 in its own hash, got %+v", keys.Keys)
+	}
+
+	if started := nodes[0].DeliverHints(""); len(started) != 0 {
+		t.Errorf("Expected no hint delivery without hints, got %v", started)
+	}
+}
+
+func TestCluster_TraceSpansReplicas(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(tracing.NewProvider("test", sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	nodes := startMemoryCluster(t, []string{"node1:5000", "node2:5001", "node3:5002"})
+
+	traceID := tracing.TraceIDFromMessageID("client1-1")
+	ctx, root := tracing.Start(tracing.WithTraceID(t.Context(), traceID), "client put")
+	if err := nodes[1].Put(ctx, "key1", []byte("value1")); err != nil {
+		t.Fatalf("Expected Put to succeed, got %v", err)
+	}
+	root.End()
+
+	spans := map[string]int{}
+	spanIDs := map[string]bool{}
+	var traced []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID() == traceID {
+			traced = append(traced, span)
+			spans[span.Name()]++
+			spanIDs[span.SpanContext().SpanID().String()] = true
+		}
+	}
+
+	// With N=3, the coordinator writes to the two other nodes, which handle the writes in the same trace
+	if spans["coordinate put"] != 1 || spans["send replica_put"] != 2 || spans["handle replica_put"] != 2 {
+		t.Errorf("Expected a coordinated put with 2 replica writes, got spans %v", spans)
+	}
+	for _, span := range traced {
+		if span.Name() != "client put" && !spanIDs[span.Parent().SpanID().String()] {
+			t.Errorf("Expected span %q to have a parent in the trace", span.Name())
+		}
+	}
+}
+
+func TestCluster_Readiness(t *testing.T) {
+	nodes := startMemoryCluster(t, []string{"node1:5000", "node2:5001"})
+	reasons := nodes[0].NotReady()
+	if len(reasons) != 1 || !strings.Contains(reasons[0], "fewer than N=3") {
+		t.Errorf("Expected a 2-node ring not to be ready for N=3, got %v", reasons)
+	}
+
+	nodes = startMemoryCluster(t, []string{"node1:5000", "node2:5001", "node3:5002"})
+	for _, n := range nodes {
+		if reasons := n.NotReady(); len(reasons) != 0 {
+			t.Errorf("Expected %s to be ready, got %v", n.id, reasons)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	health.NewHandler(nodes[0]).ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected /readyz to be 200, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	if err := nodes[2].store.Close(); err != nil {
+		t.Fatalf("Expected the store to close, got %v", err)
+	}
+	if reasons := nodes[2].NotReady(); len(reasons) != 1 || !strings.Contains(reasons[0], "store cannot accept writes") {
+		t.Errorf("Expected a closed store to make the node not ready, got %v", reasons)
+	}
+}
+
+// Returns the node outside the preference list of a key
+func misplacedNode(nodes []*Node, key string) (*Node, []string) {
+	owners := nodes[0].GetRingView().GetPreferenceList(key, nodes[0].replConfig.N).Nodes
+	for _, n := range nodes {
+		if !slices.Contains(owners, n.ID()) {
+			return n, owners
+		}
+	}
+	return nil, owners
+}
+
+func TestCluster_ScrubKeepsNewerMisplacedCopy(t *testing.T) {
+	nodes := startMemoryCluster(t, []string{"node1:5000", "node2:5001", "node3:5002", "node4:5003"})
+	key := ListAclKey("list1")
+	old, _ := proto.Marshal(&pb.ListAcl{ListId: "list1", Owner: "alice", Version: 1})
+	newer, _ := proto.Marshal(&pb.ListAcl{ListId: "list1", Owner: "alice", Version: 2})
+
+	if err := nodes[0].Put(t.Context(), key, old); err != nil {
+		t.Fatalf("Expected no error on Put, got %v", err)
+	}
+
+	// The node outside the preference list took a later write, e.g. as a fallback
+	misplaced, owners := misplacedNode(nodes, key)
+	if err := misplaced.storeMerged(key, newer); err != nil {
+		t.Fatal(err)
+	}
+
+	misplaced.scrub()
+
+	if has, _ := misplaced.store.Has([]byte(key)); has {
+		t.Errorf("Expected the misplaced copy to be deleted")
+	}
+	for _, n := range nodes {
+		if !slices.Contains(owners, n.ID()) {
+			continue
+		}
+		if value, err := n.store.Get([]byte(key)); err != nil || !bytes.Equal(value, newer) {
+			t.Errorf("Expected owner %s to hold the newer value, got %q %v", n.ID(), value, err)
+		}
+	}
+}
+
+func TestCluster_ScrubDoesNotOverwriteOpaqueKeys(t *testing.T) {
+	nodes := startMemoryCluster(t, []string{"node1:5000", "node2:5001", "node3:5002", "node4:5003"})
+
+	if err := nodes[0].Put(t.Context(), "key1", []byte("current")); err != nil {
+		t.Fatalf("Expected no error on Put, got %v", err)
+	}
+
+	// The misplaced copy is stale, and one owner lost the key
+	misplaced, owners := misplacedNode(nodes, "key1")
+	if err := misplaced.storeMerged("key1", []byte("stale")); err != nil {
+		t.Fatal(err)
+	}
+	var lost *Node
+	for _, n := range nodes {
+		if n.ID() == owners[len(owners)-1] {
+			lost = n
+		}
+	}
+	if err := lost.store.Delete([]byte("key1")); err != nil {
+		t.Fatal(err)
+	}
+
+	misplaced.scrub()
+
+	if has, _ := misplaced.store.Has([]byte("key1")); has {
+		t.Errorf("Expected the misplaced copy to be deleted")
+	}
+	for _, n := range nodes {
+		if !slices.Contains(owners, n.ID()) {
+			continue
+		}
+		expected := "current"
+		if n == lost {
+			expected = "stale"
+		}
+		if value, err := n.store.Get([]byte("key1")); err != nil || string(value) != expected {
+			t.Errorf("Expected owner %s to hold %q, got %q %v", n.ID(), expected, value, err)
+		}
+	}
+}
+
+func TestCluster_MoveTokenStreamsToEveryNewOwner(t *testing.T) {
+	nodes := startMemoryCluster(t, []string{"node1:5000", "node2:5001", "node3:5002", "node4:5003"})
+
+	// Only the primary of the key holds it, and moves its token right before the key
+	hash := ringview.HashKey("key1")
+	var token uint64
+	for rangeToken, r := range nodes[0].GetRingView().GetTokenRanges() {
+		if ringview.InHashSpace(hash, r.Start, r.End) {
+			token = rangeToken
+		}
+	}
+	primaryId, _ := nodes[0].GetRingView().GetTokenOwner(token)
+	var primary *Node
+	for _, n := range nodes {
+		if n.ID() == primaryId {
+			primary = n
+		}
+	}
+	if err := primary.storeMerged("key1", []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	successor, _ := primary.GetRingView().GetSuccessorToken(token)
+	receiver, _ := primary.GetRingView().GetTokenOwner(successor)
+
+	if err := primary.moveToken(token, hash-1, receiver); err != nil {
+		t.Fatalf("Expected the token to be moved, got %v", err)
+	}
+
+	owners := primary.GetRingView().GetPreferenceList("key1", primary.replConfig.N).Nodes
+	for _, n := range nodes {
+		if n == primary || !slices.Contains(owners, n.ID()) {
+			continue
+		}
+		if value, err := n.store.Get([]byte("key1")); err != nil || string(value) != "value" {
+			t.Errorf("Expected new owner %s to hold the moved key, got %q %v", n.ID(), value, err)
+		}
+	}
+}
+
+func TestCluster_HintedDeltaCarriesFullState(t *testing.T) {
+	nodes := startMemoryCluster(t, []string{"node1:5000", "node2:5001", "node3:5002", "node4:5003"})
+	key := ShoppingListKey("list1")
+	misplaced, owners := misplacedNode(nodes, key)
+	var coordinator *Node
+	for _, n := range nodes {
+		if n.ID() == owners[0] {
+			coordinator = n
+		}
+	}
+
+	list := crdt.NewShoppingList("client1", "list1")
+	first, _ := proto.Marshal(list.PutItem("milk", "Milk", 1, 0).ToProto())
+	if err := coordinator.coordinateReplicatedDelta(t.Context(), key, first); err != nil {
+		t.Fatal(err)
+	}
+
+	// The last owner misses the second delta, which the node outside the preference list keeps as a hint
+	coordinator.failures.ReportFailure(owners[2])
+	second, _ := proto.Marshal(list.PutItem("eggs", "Eggs", 12, 0).ToProto())
+	if err := coordinator.coordinateReplicatedDelta(t.Context(), key, second); err != nil {
+		t.Fatal(err)
+	}
+
+	hints, err := misplaced.hintStore.GetHintsFor(owners[2])
+	if err != nil || len(hints) != 1 {
+		t.Fatalf("Expected a hint for %s, got %v %v", owners[2], hints, err)
+	}
+	hinted, err := decodeShoppingList(hints[0].Value, misplaced.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hinted.GetItem("milk") == nil || hinted.GetItem("eggs") == nil {
+		t.Errorf("Expected the hint to carry the full state, got %v", hinted.Items())
+	}
+}
+
+func TestCluster_GetMissingKey(t *testing.T) {
+	nodes := startMemoryCluster(t, []string{"node1:5000", "node2:5001", "node3:5002"})
+
+	// Whichever node coordinates, the key is reported missing rather than the quorum failing
+	for _, n := range nodes {
+		if _, err := n.Get(t.Context(), "missing"); !errors.Is(err, replication.ErrNotFound) {
+			t.Errorf("Expected Get on %s to fail with ErrNotFound, got %v", n.ID(), err)
+		}
+	}
+}
+
+func TestCluster_MissingKeyNeedsPrimaries(t *testing.T) {
+	nodes := startMemoryCluster(t, []string{"node1:5000", "node2:5001", "node3:5002", "node4:5003"})
+	key := ListAclKey("list1")
+	acl, _ := proto.Marshal(&pb.ListAcl{ListId: "list1", Owner: "alice", Version: 1})
+	if err := nodes[0].Put(t.Context(), key, acl); err != nil {
+		t.Fatalf("Expected no error on Put, got %v", err)
+	}
+
+	// One primary lost the key and the two others are suspected: the fallback it reads then never stored the key
+	owners := nodes[0].GetRingView().GetPreferenceList(key, nodes[0].replConfig.N).Nodes
+	var coordinator *Node
+	for _, n := range nodes {
+		if n.ID() == owners[0] {
+			coordinator = n
+		}
+	}
+	if err := coordinator.store.Delete([]byte(key)); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range owners[1:] {
+		coordinator.failures.ReportFailure(id)
+	}
+
+	if _, err := coordinator.coordinateReplicatedGet(t.Context(), key); !errors.Is(err, replication.ErrQuorumNotMet) {
+		t.Errorf("Expected the read to fail rather than report the ACL missing, got %v", err)
+	}
+}
+
+func TestCluster_ListAcl(t *testing.T) {
+	nodes := startMemoryCluster(t, []string{"node1:5000", "node2:5001", "node3:5002"})
+	alice := auth.WithUser(t.Context(), "alice")
+	bob := auth.WithUser(t.Context(), "bob")
+	carol := auth.WithUser(t.Context(), "carol")
+
+	list := crdt.NewShoppingList("client1", "list1")
+	if err := nodes[0].HandleShoppingList(alice, list.SetName("Groceries")); err != nil {
+		t.Fatalf("Expected alice to create the list, got %v", err)
+	}
+
+	// The first writer owns the list, on every node
+	for _, n := range nodes {
+		acl, err := n.GetListAcl(alice, "list1")
+		if err != nil || acl.GetOwner() != "alice" {
+			t.Errorf("Expected alice to own the list on %s, got %v (error: %v)", n.ID(), acl, err)
+		}
+	}
+
+	if _, err := nodes[1].GetShoppingList(bob, "list1"); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("Expected bob not to read the list, got %v", err)
+	}
+	if _, err := nodes[1].GetShoppingList(t.Context(), "list1"); !errors.Is(err, auth.ErrUnauthenticated) {
+		t.Errorf("Expected anonymous reads to be denied, got %v", err)
+	}
+	if _, err := nodes[1].SetListAcl(bob, "list1", []string{"bob"}, nil); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("Expected bob not to change the ACL, got %v", err)
+	}
+
+	acl, err := nodes[2].SetListAcl(alice, "list1", []string{"bob"}, []string{"carol"})
+	if err != nil || acl.GetVersion() != 2 {
+		t.Fatalf("Expected alice to change the ACL to version 2, got %v (error: %v)", acl, err)
+	}
+
+	if err := nodes[1].HandleShoppingList(bob, list.PutItem("milk", "Milk", 2, 0)); err != nil {
+		t.Errorf("Expected bob to edit the list, got %v", err)
+	}
+	if _, err := nodes[1].GetShoppingList(carol, "list1"); err != nil {
+		t.Errorf("Expected carol to read the list, got %v", err)
+	}
+	if err := nodes[1].HandleShoppingList(carol, list.PutItem("eggs", "Eggs", 12, 0)); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("Expected carol not to edit the list, got %v", err)
+	}
+	if err := nodes[0].SubscribeShoppingList(t.Context(), "list1", "sub1", nil); !errors.Is(err, auth.ErrUnauthenticated) {
+		t.Errorf("Expected anonymous subscriptions to be denied, got %v", err)
+	}
+
+	// Lists without an ACL stay open to anonymous sessions
+	if err := nodes[0].HandleShoppingList(t.Context(), crdt.NewShoppingList("client2", "list2").SetName("Open")); err != nil {
+		t.Errorf("Expected an anonymous write to an open list, got %v", err)
+	}
+	if _, err := nodes[0].GetListAcl(t.Context(), "list2"); !errors.Is(err, replication.ErrNotFound) {
+		t.Errorf("Expected anonymous writes not to claim the list, got %v", err)
+	}
+}
+
+func TestCluster_ShareCapabilities(t *testing.T) {
+	nodes := startMemoryCluster(t, []string{"node1:5000", "node2:5001", "node3:5002"})
+	alice := auth.WithUser(t.Context(), "alice")
+	bob := auth.WithUser(t.Context(), "bob")
+
+	// Sharing an open list claims it
+	if err := nodes[0].ShareList(alice, "list1"); err != nil {
+		t.Fatalf("Expected alice to share the list, got %v", err)
+	}
+	if err := nodes[1].ShareList(bob, "list1"); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("Expected bob not to share alice's list, got %v", err)
+	}
+
+	reader := auth.NewCapability("list1", auth.AccessRead, "alice", time.Time{})
+	writer := auth.NewCapability("list1", auth.AccessWrite, "alice", time.Time{})
+	other := auth.NewCapability("list2", auth.AccessWrite, "alice", time.Time{})
+	readerCtx := auth.WithCapability(t.Context(), reader)
+
+	list := crdt.NewShoppingList("client1", "list1")
+	if err := nodes[1].HandleShoppingList(auth.WithCapability(t.Context(), writer), list.SetName("Groceries")); err != nil {
+		t.Errorf("Expected a write capability to edit the list, got %v", err)
+	}
+	if err := nodes[1].HandleShoppingList(readerCtx, list.PutItem("milk", "Milk", 1, 0)); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("Expected a read capability not to edit the list, got %v", err)
+	}
+	if _, err := nodes[2].GetShoppingList(auth.WithCapability(t.Context(), other), "list1"); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("Expected a capability of another list to be denied, got %v", err)
+	}
+	if _, err := nodes[2].GetShoppingList(readerCtx, "list1"); err != nil {
+		t.Errorf("Expected a read capability to read the list, got %v", err)
+	}
+
+	if err := nodes[0].RevokeCapability(bob, reader); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("Expected bob not to revoke alice's capability, got %v", err)
+	}
+	if err := nodes[0].RevokeCapability(alice, reader); err != nil {
+		t.Fatalf("Expected alice to revoke the capability, got %v", err)
+	}
+	for _, n := range nodes {
+		if _, err := n.GetShoppingList(readerCtx, "list1"); !errors.Is(err, auth.ErrForbidden) {
+			t.Errorf("Expected the revoked capability to be denied on %s, got %v", n.ID(), err)
+		}
+		if err := n.SubscribeShoppingList(auth.WithCapability(t.Context(), writer), "list1", "sub1", nil); err != nil {
+			t.Errorf("Expected the other capability to stay valid on %s, got %v", n.ID(), err)
+		}
+		n.UnsubscribeShoppingList("list1", "sub1", nil)
+	}
+}
+
+func TestMergeRevokedCapabilities(t *testing.T) {
+	now := time.Now()
+	encode := func(expires map[string]int64) []byte {
+		data, err := proto.Marshal(&pb.RevokedCapabilities{ListId: "list1", Expires: expires})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	merged, err := mergeRevokedCapabilities(
+		encode(map[string]int64{"c1": 0, "c2": now.Add(-time.Minute).Unix()}),
+		encode(map[string]int64{"c3": now.Add(time.Hour).Unix()}), now)
+	if err != nil {
+		t.Fatalf("Expected the sets to merge, got %v", err)
+	}
+
+	revoked, err := decodeRevokedCapabilities(merged)
+	if err != nil {
+		t.Fatalf("Expected the merged set to decode, got %v", err)
+	}
+	if _, ok := revoked.GetExpires()["c2"]; ok || len(revoked.GetExpires()) != 2 {
+		t.Errorf("Expected c1 and c3 (c2 expired), got %v", revoked.GetExpires())
+	}
+}
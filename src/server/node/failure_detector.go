@@ -0,0 +1,76 @@
+package node
+
+import (
+	"sync"
+	"time"
+)
+
+// Tracks which nodes are suspected to be down. A node becomes suspected when a request to it fails at the transport
+// level (timeout, connection error) and stops being suspected as soon as it answers any request.
+type FailureDetector struct {
+	mu             sync.RWMutex
+	suspectedSince map[string]time.Time
+	onRecover      []func(nodeId string)
+}
+
+func NewFailureDetector() *FailureDetector {
+	return &FailureDetector{
+		suspectedSince: make(map[string]time.Time),
+	}
+}
+
+func (fd *FailureDetector) ReportFailure(nodeId string) {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+
+	if _, ok := fd.suspectedSince[nodeId]; !ok {
+		fd.suspectedSince[nodeId] = time.Now()
+	}
+}
+
+// Registers a function called (synchronously) whenever a suspected node recovers
+func (fd *FailureDetector) OnRecover(fn func(nodeId string)) {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+
+	fd.onRecover = append(fd.onRecover, fn)
+}
+
+// Marks a node as alive. Returns true if the node was suspected before (i.e. it recovered).
+func (fd *FailureDetector) ReportSuccess(nodeId string) bool {
+	fd.mu.Lock()
+
+	if _, ok := fd.suspectedSince[nodeId]; !ok {
+		fd.mu.Unlock()
+		return false
+	}
+
+	delete(fd.suspectedSince, nodeId)
+	callbacks := fd.onRecover
+	fd.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn(nodeId)
+	}
+	return true
+}
+
+func (fd *FailureDetector) IsSuspected(nodeId string) bool {
+	fd.mu.RLock()
+	defer fd.mu.RUnlock()
+
+	_, ok := fd.suspectedSince[nodeId]
+	return ok
+}
+
+// Returns the suspected nodes and since when they are suspected
+func (fd *FailureDetector) Suspected() map[string]time.Time {
+	fd.mu.RLock()
+	defer fd.mu.RUnlock()
+
+	suspected := make(map[string]time.Time, len(fd.suspectedSince))
+	for nodeId, since := range fd.suspectedSince {
+		suspected[nodeId] = since
+	}
+	return suspected
+}
@@ -0,0 +1,30 @@
+package node
+
+import "testing"
+
+func TestFailureDetector_SuspectAndRecover(t *testing.T) {
+	fd := NewFailureDetector()
+
+	recovered := []string{}
+	fd.OnRecover(func(nodeId string) { recovered = append(recovered, nodeId) })
+
+	if fd.ReportSuccess("node-a") {
+		t.Errorf("Expected a node that was never suspected to not recover")
+	}
+
+	fd.ReportFailure("node-a")
+	fd.ReportFailure("node-a")
+	if !fd.IsSuspected("node-a") || len(fd.Suspected()) != 1 {
+		t.Fatalf("Expected node-a to be suspected")
+	}
+
+	if !fd.ReportSuccess("node-a") {
+		t.Errorf("Expected node-a to recover")
+	}
+	if fd.IsSuspected("node-a") {
+		t.Errorf("Expected node-a to no longer be suspected")
+	}
+	if len(recovered) != 1 || recovered[0] != "node-a" {
+		t.Errorf("Expected a single recovery callback for node-a, got %v", recovered)
+	}
+}
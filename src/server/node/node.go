@@ -3,22 +3,31 @@ package node
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"maps"
 	"net"
 	"net/http"
 	"path/filepath"
+	"sdle-server/admin"
+	"sdle-server/clock"
 	"sdle-server/communication"
 	"sdle-server/config"
+	"sdle-server/health"
+	"sdle-server/logging"
 	pb "sdle-server/proto"
 	"sdle-server/replication"
 	"sdle-server/ringview"
 	"sdle-server/storage"
+	"sdle-server/tracing"
+	"sdle-server/transport"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
-	"syscall"
+	"sync/atomic"
 	"time"
 
-	"github.com/pebbe/zmq4"
-	"google.golang.org/protobuf/proto"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Node struct {
@@ -27,215 +36,411 @@ type Node struct {
 	wsAddr        string
 	ringView      *ringview.RingView
 	store         storage.Store
-	repSock       *zmq4.Socket
+	transport     transport.Transport
 	httpServer    *http.Server
+	adminServer   *http.Server                    // admin API, reachable from the host of the node only
+	wsHandler     *communication.WebSocketHandler // nil if the node was created without a WebSocket server
 	stopCh        chan struct{}
+	ctx           context.Context // done once the node stops, ending the work it does on its own behalf
+	cancel        context.CancelFunc
 	wg            sync.WaitGroup
+	membershipMu  sync.Mutex // serializes changes to this node's own tokens
+	mergeMu       sync.Mutex // serializes read-merge-write cycles on the local store
 	replConfig    config.Config
 	hintStore     *replication.HintStore
 	subController *SubController
+	failures      *FailureDetector
+	hintDeliverer *hintDeliverer
+	clock         clock.Clock
+	scheduler     Scheduler
+	logger        *slog.Logger // records of the node carry its ID
+	metrics       *nodeMetrics
+	scrubbing     atomic.Bool // set while an ownership scrub runs
+	rebalancing   atomic.Bool // set while a rebalancing round runs
+	joining       atomic.Bool // set while the node joins the ring (and imports the hash spaces of its tokens)
 }
 
-func NewNode(id string, baseDir string) (*Node, error) {
-	addr := NodeIdToZMQAddr(id)
+// Runs background work of a node (gossip propagation, hint delivery, collision handling)
+type Scheduler func(task func())
 
-	// Prepare ring view, storage, and ZMQ socket
-	ringView := ringview.New()
+// Address of the HTTP server (WebSocket, metrics and health checks) of a node, derived from its ID
+func HTTPAddr(id string) (string, error) {
+	host, port, err := splitNodeID(id)
+	if err != nil {
+		return "", err
+	}
+	wsPort := port + 3000 // 5000 -> 8000, etc
+	return net.JoinHostPort(host, strconv.Itoa(wsPort)), nil
+}
 
-	dir := filepath.Join(baseDir, id)
-	store, err := storage.Open(dir)
+// Address of the admin API of a node, derived from its ID. It is bound to the loopback interface: the admin API is
+// not authenticated, so it is only served to the host of the node.
+func AdminAddr(id string) (string, error) {
+	_, port, err := splitNodeID(id)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
+	adminPort := port + 4000 // 5000 -> 9000, etc
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(adminPort)), nil
+}
+
+func splitNodeID(id string) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(id)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid node ID format: %w", err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port in node ID: %w", err)
+	}
+	return host, port, nil
+}
 
-	rep, err := zmq4.NewSocket(zmq4.REP)
+// Creates a node that talks to its peers through the given transport (in production, a zmqtransport.Transport) and
+// serves clients over WebSocket. The node owns the transport, and closes it when it stops.
+func NewNode(id string, baseDir string, t transport.Transport) (*Node, error) {
+	wsAddr, err := HTTPAddr(id)
+	if err != nil {
+		return nil, err
+	}
+	adminAddr, err := AdminAddr(id)
 	if err != nil {
-		_ = store.Close()
 		return nil, err
 	}
 
-	if err := rep.Bind(addr); err != nil {
-		_ = rep.Close()
-		_ = store.Close()
+	n, err := NewNodeWithTransport(id, baseDir, t)
+	if err != nil {
 		return nil, err
 	}
 
-	replConfig := config.DefaultConfig()
-	hintStore := replication.NewHintStore(store.GetDB())
+	// Setup WebSocket server
+	n.wsAddr = wsAddr
+	n.wsHandler = communication.NewWebSocketHandler(n, n.metrics.registry)
+	mux := http.NewServeMux()
+	mux.Handle("/ws", n.wsHandler)
+	mux.Handle("/metrics", n.metrics.registry.Handler())
+	healthHandler := health.NewHandler(n)
+	mux.Handle("/healthz", healthHandler)
+	mux.Handle("/readyz", healthHandler)
 
-	host, portStr, err := net.SplitHostPort(id)
-	if err != nil {
-		return nil, fmt.Errorf("invalid node ID format: %w", err)
+	n.httpServer = &http.Server{
+		Addr:    wsAddr,
+		Handler: mux,
 	}
-	port, err := strconv.Atoi(portStr)
+	n.adminServer = &http.Server{
+		Addr:    adminAddr,
+		Handler: admin.NewHandler(n),
+	}
+
+	return n, nil
+}
+
+// Handler of the WebSocket endpoint of the node, to configure before the node starts (nil if the node was created with
+// NewNodeWithTransport)
+func (n *Node) WebSocketHandler() *communication.WebSocketHandler {
+	return n.wsHandler
+}
+
+// Creates a node that talks to its peers through the given transport. Unlike NewNode, no WebSocket server is set
+// up, so the node is only reachable by other nodes (used to run whole clusters in a single process).
+func NewNodeWithTransport(id string, baseDir string, t transport.Transport) (*Node, error) {
+	addr := NodeIdToZMQAddr(id)
+
+	replConfig := config.DefaultConfig()
+	if err := replConfig.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	// Prepare ring view and storage
+	ringView := ringview.New()
+
+	dir := filepath.Join(baseDir, id)
+	store, err := storage.Open(dir)
 	if err != nil {
-		return nil, fmt.Errorf("invalid port in node ID: %w", err)
+		return nil, err
 	}
-	wsPort := port + 3000 // 5000 -> 8000, etc
-	wsAddr := net.JoinHostPort(host, strconv.Itoa(wsPort))
+
+	ctx, cancel := context.WithCancel(context.Background())
 
 	// Create node instance
 	n := &Node{
 		id:            id,
 		addr:          addr,
-		wsAddr:        wsAddr,
 		ringView:      ringView,
 		store:         *store,
-		repSock:       rep,
+		transport:     t,
 		stopCh:        make(chan struct{}),
+		ctx:           ctx,
+		cancel:        cancel,
 		replConfig:    replConfig,
-		hintStore:     hintStore,
 		subController: NewSubController(nil), // Will set node reference later
+		failures:      NewFailureDetector(),
+		hintDeliverer: newHintDeliverer(),
+		clock:         clock.Real(),
+		logger:        slog.Default().With(logging.KeyNode, id),
+	}
+
+	// Background work runs on its own goroutine, tracked so that Stop waits for it
+	n.scheduler = func(task func()) {
+		n.wg.Add(1)
+		go func() {
+			defer n.wg.Done()
+			task()
+		}()
 	}
 
 	// Set node reference in SubController
 	n.subController.SetNode(n)
 
-	// Setup WebSocket server
-	wsHandler := communication.NewWebSocketHandler(n)
-	mux := http.NewServeMux()
-	mux.Handle("/ws", wsHandler)
+	// Deliver hints as soon as their target is seen again
+	n.failures.OnRecover(func(nodeId string) {
+		n.logger.Info("Peer recovered, delivering its hints", logging.KeyPeer, nodeId)
+		n.scheduleHintDelivery(nodeId, true)
+	})
 
-	n.httpServer = &http.Server{
-		Addr:    wsAddr,
-		Handler: mux,
-	}
+	// Hints for the same key are merged like the replicas they stand for
+	n.hintStore = replication.NewHintStore(store.GetDB(), replication.HintStoreConfig{
+		TTL:          replConfig.HintTTL,
+		MaxPerTarget: replConfig.MaxHintsPerTarget,
+		MaxTotal:     replConfig.MaxHints,
+		Merge:        n.mergeValues,
+		Now:          n.now,
+	})
+
+	n.metrics = newNodeMetrics(n)
 
 	return n, nil
 }
 
+// Replaces the clock of the node (must be called before the node is started)
+func (n *Node) SetClock(c clock.Clock) {
+	n.clock = c
+}
+
+// Replaces how the node runs background work (must be called before the node is started). The simulator uses it to
+// run that work one task at a time, in an order of its choosing.
+func (n *Node) SetScheduler(s Scheduler) {
+	n.scheduler = s
+}
+
+func (n *Node) now() time.Time {
+	return n.clock.Now()
+}
+
+// Runs background work through the scheduler. A panic in it is logged and does not take down the node.
+func (n *Node) runAsync(name string, task func()) {
+	n.scheduler(func() {
+		_ = n.runGuarded(name, task)
+	})
+}
+
 func (n *Node) ID() string {
 	return n.id
 }
 
-// Starts completely the node (ZMQ receiver and WebSocket server)
+// Starts completely the node (request receiver, WebSocket server and periodic tasks)
 func (n *Node) Start(errCh chan<- error) {
-	n.wg.Add(3)
-	go n.startZMQLoop(errCh)
-	go n.startHTTPLoop(errCh)
+	n.wg.Add(2)
+	go n.startTransportLoop(errCh)
 	go n.StartPeriodicTasks(errCh)
+
+	if n.httpServer != nil {
+		n.wg.Add(2)
+		go n.startHTTPLoop(errCh)
+		go n.startAdminLoop(errCh)
+	}
 }
 
 func (n *Node) startHTTPLoop(errCh chan<- error) {
 	defer n.wg.Done()
-	n.logInfo("starting WebSocket server at " + n.wsAddr)
+	n.logger.Info("Starting WebSocket server", "addr", n.wsAddr)
 	if err := n.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		errCh <- fmt.Errorf("WebSocket server error: %w", err)
 	}
 }
 
+func (n *Node) startAdminLoop(errCh chan<- error) {
+	defer n.wg.Done()
+	n.logger.Info("Starting admin server", "addr", n.adminServer.Addr)
+	if err := n.adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		errCh <- fmt.Errorf("admin server error: %w", err)
+	}
+}
+
 func (n *Node) StartPeriodicTasks(errCh chan<- error) {
 	defer n.wg.Done()
-	n.logInfo("Periodic tasks started")
-	ticker := time.NewTicker(10 * time.Second)
+	n.superviseLoop("periodic tasks", n.runPeriodicTasks)
+}
+
+func (n *Node) runPeriodicTasks() {
+	n.logger.Debug("Periodic tasks started")
+	ticker := time.NewTicker(n.replConfig.HintDeliveryInterval)
 
 	defer ticker.Stop()
 
+	// A nil channel never fires, which disables the rebalancer
+	var rebalanceCh <-chan time.Time
+	if n.replConfig.RebalanceInterval > 0 {
+		rebalanceTicker := time.NewTicker(n.replConfig.RebalanceInterval)
+		defer rebalanceTicker.Stop()
+		rebalanceCh = rebalanceTicker.C
+	}
+
+	probeTicker := time.NewTicker(n.replConfig.FailureProbeInterval)
+	defer probeTicker.Stop()
+
+	var scrubCh <-chan time.Time
+	if n.replConfig.ScrubInterval > 0 {
+		scrubTicker := time.NewTicker(n.replConfig.ScrubInterval)
+		defer scrubTicker.Stop()
+		scrubCh = scrubTicker.C
+	}
+
 	for {
 		select {
 		case <-n.stopCh:
-			n.logInfo("Periodic tasks stopping.")
+			n.logger.Debug("Periodic tasks stopping")
 			return
 		case <-ticker.C:
-			n.sendAllHintedHandoffs()
+			n.expireHints(n.ctx)
+			n.scheduleAllHintDeliveries()
+		case <-probeTicker.C:
+			n.probeSuspectedNodes(n.ctx)
+		case <-rebalanceCh:
+			n.runAsync("rebalance", n.rebalance)
+		case <-scrubCh:
+			n.runAsync("anti-entropy", n.scrub)
 		}
 	}
 }
 
-// ZMQ message receiving loop
-func (n *Node) startZMQLoop(errCh chan<- error) {
+// Receives requests from other nodes until the transport is closed
+func (n *Node) startTransportLoop(errCh chan<- error) {
 	defer n.wg.Done()
-	n.logInfo("ZMQ socket started at " + n.addr)
+	n.logger.Info("Transport started", "addr", n.addr)
 
-	poller := zmq4.NewPoller()
-	poller.Add(n.repSock, zmq4.POLLIN)
-
-	for {
-		select {
-		case <-n.stopCh:
-			n.logInfo("ZMQ receiver stopping.")
-			return
-		default:
+	// Panics in handlers are recovered by HandleRequest; one in the transport itself restarts it, while an error
+	// serving stops the node
+	n.superviseLoop("transport loop", func() {
+		if err := n.transport.Serve(n.HandleRequest); err != nil {
+			errCh <- err
 		}
+	})
 
-		sockets, err := poller.Poll(100 * time.Millisecond)
-		if err != nil {
-			// ETERM is expected on shutdown, so we don't send it to the error channel.
-			if zmq4.AsErrno(err) != zmq4.ETERM {
-				errCh <- fmt.Errorf("ZMQ poll error: %w", err)
-			}
-			return
-		}
+	n.logger.Info("Transport receiver stopping")
+}
 
-		if len(sockets) == 0 {
-			continue // Poll timed out, loop again
-		}
+// Handles a request from another node. A panic in its handler is turned into an error response, so a bad request
+// cannot take down the node.
+func (n *Node) HandleRequest(req *pb.Request) (resp *pb.Response) {
+	start := time.Now()
+	defer func() {
+		elapsed := time.Since(start)
+		n.metrics.observeRequest(requestTypeName(req), resp, elapsed)
+		n.logger.Debug("Handled request", logging.KeyRequest, requestTypeName(req), logging.KeyPeer, req.GetOrigin(),
+			"ok", resp.GetOk(), logging.KeyDuration, elapsed)
+	}()
+
+	err := n.runGuarded("handler of "+requestTypeName(req)+" request from "+req.GetOrigin(), func() {
+		ctx, cancel := n.requestContext(req)
+		defer cancel()
+
+		ctx, span := n.startSpan(extractTrace(ctx, req), "handle "+requestTypeName(req), trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(tracing.AttrPeer.String(req.GetOrigin())))
+		defer func() { tracing.Finish(span, responseErr(resp)) }()
+
+		resp = n.dispatchRequest(ctx, req)
+	})
+	if err != nil {
+		return n.responseError("internal error handling " + requestTypeName(req) + " request")
+	}
+	return resp
+}
 
-		msgBytes, err := n.repSock.RecvBytes(zmq4.DONTWAIT)
-		if err != nil {
-			// EAGAIN is expected when there's nothing to receive, just continue
-			if zmq4.AsErrno(err) != zmq4.Errno(syscall.EAGAIN) {
-				errCh <- fmt.Errorf("ZMQ receive error: %w", err)
-			}
-			continue
-		}
+// Context of a request from another node: it is done once the sender gives up on the request, or the node stops
+func (n *Node) requestContext(req *pb.Request) (context.Context, context.CancelFunc) {
+	if req.GetTimeoutMs() > 0 {
+		return context.WithTimeout(n.ctx, time.Duration(req.GetTimeoutMs())*time.Millisecond)
+	}
+	return context.WithCancel(n.ctx)
+}
 
-		var req pb.Request
-		if err := proto.Unmarshal(msgBytes, &req); err != nil {
-			_ = n.sendResponseError("failed to unmarshal request: " + err.Error())
-			continue
-		}
+// Dispatches a request from another node to its handler
+func (n *Node) dispatchRequest(ctx context.Context, req *pb.Request) *pb.Response {
+	// A request from a node proves it is alive (origins are either node IDs or ZMQ addresses)
+	if originId := strings.TrimPrefix(req.Origin, "tcp://"); originId != "" && originId != n.id {
+		n.failures.ReportSuccess(originId)
+	}
 
-		switch req.GetRequestType().(type) {
-		case *pb.Request_Ping:
-			n.handlePing(&req)
-		case *pb.Request_FetchRing:
-			n.handleFetchRing(&req)
-		case *pb.Request_GossipJoin:
-			n.handleGossipJoin(&req)
-		case *pb.Request_Get:
-			n.handleGet(&req)
-		case *pb.Request_GetHashSpace:
-			n.handleGetHashSpace(&req)
-		case *pb.Request_Put:
-			n.handlePut(&req)
-		case *pb.Request_Delete:
-			n.handleDelete(&req)
-		case *pb.Request_Has:
-			n.handleHas(&req)
-		case *pb.Request_ReplicaPut:
-			n.handleReplicaPut(&req)
-		case *pb.Request_ReplicaGet:
-			n.handleReplicaGet(&req)
-		case *pb.Request_StoreHint:
-			n.handleStoreHint(&req)
-		default:
-			_ = n.sendResponseError("unknown request type")
-		}
+	// The sender gave up while the request was queued: its work would be wasted
+	if err := ctx.Err(); err != nil {
+		n.logger.Warn("Dropping abandoned request", logging.KeyRequest, requestTypeName(req), logging.KeyPeer, req.Origin, logging.Err(err))
+		return n.responseError("request abandoned: " + err.Error())
+	}
+
+	switch req.GetRequestType().(type) {
+	case *pb.Request_Ping:
+		return n.handlePing(req)
+	case *pb.Request_FetchRing:
+		return n.handleFetchRing(req)
+	case *pb.Request_GossipJoin:
+		return n.handleGossipJoin(ctx, req)
+	case *pb.Request_Get:
+		return n.handleGet(ctx, req)
+	case *pb.Request_GetHashSpace:
+		return n.handleGetHashSpace(req)
+	case *pb.Request_Put:
+		return n.handlePut(ctx, req)
+	case *pb.Request_Delete:
+		return n.handleDelete(req)
+	case *pb.Request_Has:
+		return n.handleHas(req)
+	case *pb.Request_ReplicaPut:
+		return n.handleReplicaPut(req)
+	case *pb.Request_ReplicaGet:
+		return n.handleReplicaGet(req)
+	case *pb.Request_StoreHint:
+		return n.handleStoreHint(req)
+	case *pb.Request_TokenLoads:
+		return n.handleTokenLoads(req)
+	case *pb.Request_GossipTokenMove:
+		return n.handleGossipTokenMove(ctx, req)
+	case *pb.Request_ReplicaPutBatch:
+		return n.handleReplicaPutBatch(req)
+	case *pb.Request_PutDelta:
+		return n.handlePutDelta(ctx, req)
+	case *pb.Request_ReplicaDelta:
+		return n.handleReplicaDelta(req)
+	default:
+		return n.responseError("unknown request type")
 	}
 }
 
 // Stops the node gracefully
 func (n *Node) Stop() error {
 	close(n.stopCh) // Signal all goroutines to stop
+	n.cancel()      // and abandon the requests they are waiting on
 
 	// Shutdown websockets server
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := n.httpServer.Shutdown(ctx); err != nil {
-		n.logError("WebSocket server shutdown error: " + err.Error())
+	if n.httpServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := n.httpServer.Shutdown(ctx); err != nil {
+			n.logger.Error("WebSocket server shutdown failed", logging.Err(err))
+		}
+		if err := n.adminServer.Shutdown(ctx); err != nil {
+			n.logger.Error("Admin server shutdown failed", logging.Err(err))
+		}
 	}
 
-	n.wg.Wait() // Wait for all goroutines to finish
-
-	// Close ZMQ socket and storage
+	// Close the transport (stops the receiver) and storage
 	var firstErr error
-	if n.repSock != nil {
-		if err := n.repSock.Close(); err != nil {
-			firstErr = err
-		}
+	if err := n.transport.Close(); err != nil {
+		firstErr = err
 	}
 
+	n.wg.Wait() // Wait for all goroutines to finish
+
 	if err := n.store.Close(); err != nil && firstErr == nil {
 		firstErr = err
 	}
@@ -243,8 +448,8 @@ func (n *Node) Stop() error {
 }
 
 // Get the current ring view from a target node and update the local ring view
-func (n *Node) updateRingView(targetAddr string) error {
-	resp, err := n.sendFetchRing(targetAddr)
+func (n *Node) updateRingView(ctx context.Context, targetAddr string) error {
+	resp, err := n.sendFetchRing(ctx, targetAddr)
 
 	if err != nil {
 		return err
@@ -252,20 +457,25 @@ func (n *Node) updateRingView(targetAddr string) error {
 
 	fetchRingResp := resp.GetFetchRing()
 	if fetchRingResp == nil {
-		n.logError("invalid FetchRing response from " + targetAddr)
+		n.logger.Error("Invalid FetchRing response", logging.KeyPeer, targetAddr)
 		return fmt.Errorf("invalid FetchRing response")
 	}
 
-	// Create new RingView from received tokenToNode map
-	newRingView := ringview.NewFromTokenMap(fetchRingResp.RingView.TokenToNode)
-	n.ringView = newRingView
+	// The ring view is shared with the request handlers and the readiness probe, so it is replaced in place
+	n.ringView.ReplaceMembership(fetchRingResp.RingView.TokenToNode, fetchRingResp.RingView.Generations)
 
 	return nil
 }
 
 // Adds the new node to the ring - first get the current ring view from a target node, then imports the data for its tokens and finally adds itself to the ring (using gossip to inform other nodes)
-func (n *Node) JoinToRing(targetAddr string) error {
-	err := n.updateRingView(targetAddr)
+func (n *Node) JoinToRing(ctx context.Context, targetAddr string) (err error) {
+	ctx, span := n.startSpan(ctx, "join ring", trace.WithAttributes(tracing.AttrPeer.String(ZMQAddrToNodeId(targetAddr))))
+	defer func() { tracing.Finish(span, err) }()
+
+	n.joining.Store(true)
+	defer n.joining.Store(false)
+
+	err = n.updateRingView(ctx, targetAddr)
 
 	if err != nil {
 		return err
@@ -274,85 +484,57 @@ func (n *Node) JoinToRing(targetAddr string) error {
 	tokens, transferredHashSpaces, added := n.ringView.JoinToRing(n.GetID())
 
 	if !added {
-		n.logInfo("already part of the ring, no action taken.")
+		n.logger.Info("Already part of the ring, no action taken")
 		return nil
 	}
 
-	n.logInfo("joined the ring with tokens: " + fmt.Sprint(tokens))
+	n.logger.Info("Joined the ring", "tokens", tokens)
 
 	for _, transferredHashSpace := range transferredHashSpaces {
-		n.logInfo("importing data for token range " + fmt.Sprintf("[%d - %d]", transferredHashSpace.Start, transferredHashSpace.End) + " from " + transferredHashSpace.PreviousOwnerId)
-
-		targetAddr := NodeIdToZMQAddr(transferredHashSpace.PreviousOwnerId)
-		hashSpaceResponse, error := n.sendGetHashSpace(targetAddr, transferredHashSpace.Start, transferredHashSpace.End)
-
-		if error != nil {
-			fmt.Println(error)
-			return error
-		}
-
-		valuesSpace := hashSpaceResponse.GetGetHashSpace().GetHashSpaceValues()
-
-		for key, value := range valuesSpace {
-			err := n.store.Put([]byte(key), value)
-			if err != nil {
-				n.logError("failed to import key '" + key + "': " + err.Error())
-				return fmt.Errorf("failed to import key '%s': %w", key, err)
-			}
+		if err := n.importHashSpace(ctx, transferredHashSpace); err != nil {
+			return err
 		}
-
-		n.logInfo("imported " + fmt.Sprint(len(valuesSpace)) + " key-value pairs for token range " + fmt.Sprintf("[%d - %d]", transferredHashSpace.Start, transferredHashSpace.End))
 	}
 
 	neighborsGossip := n.ringView.GetGossipNeighborsNodes(n.GetID())
-	n.logInfo("Starting gossip to inform other nodes about my joining. Neighbors: " + fmt.Sprint(neighborsGossip))
+	n.logger.Info("Gossiping the join to neighbors", "neighbors", neighborsGossip)
 
 	for _, nodeId := range neighborsGossip {
 		nodeAddr := NodeIdToZMQAddr(nodeId)
-		resp, err := n.sendJoinGossip(nodeAddr, n.GetID(), tokens)
+		resp, err := n.sendJoinGossip(ctx, nodeAddr, n.GetID(), tokens, n.ringView.GetNodeGeneration(n.GetID()))
 
-		n.logInfo("Gossip Response: Ok=" + fmt.Sprint(resp.Ok) + ", Error='" + fmt.Sprint(err) + "'")
+		n.logger.Debug("Join gossip sent", logging.KeyPeer, nodeId, "ok", resp.GetOk(), logging.Err(err))
 	}
 
 	return err
 }
 
-const (
-	colorReset  = "\033[0m"
-	colorRed    = "\033[31m"
-	colorGreen  = "\033[32m"
-	colorYellow = "\033[33m"
-	colorBlue   = "\033[34m"
-	colorCyan   = "\033[36m"
-	colorGray   = "\033[90m"
-)
+// Imports the data of a hash space from its previous owner into the local store
+func (n *Node) importHashSpace(ctx context.Context, transferredHashSpace ringview.TransferredHashSpace) error {
+	n.logger.Info("Importing token range", "start", transferredHashSpace.Start, "end", transferredHashSpace.End,
+		logging.KeyPeer, transferredHashSpace.PreviousOwnerId)
 
-func (n *Node) logInfo(msg string) {
-	ts := time.Now().Format("15:04:05.000")
-	fmt.Printf("%s[%s]%s %s[Node %s]%s %s[INFO]%s %s\n",
-		colorGray, ts, colorReset, colorCyan, n.id, colorReset, colorBlue, colorReset, msg)
-}
+	targetAddr := NodeIdToZMQAddr(transferredHashSpace.PreviousOwnerId)
+	hashSpaceResponse, err := n.sendGetHashSpace(ctx, targetAddr, transferredHashSpace.Start, transferredHashSpace.End)
 
-func (n *Node) logSuccess(msg string) {
-	ts := time.Now().Format("15:04:05.000")
-	fmt.Printf("%s[%s]%s %s[Node %s]%s %s[SUCCESS]%s %s\n",
-		colorGray, ts, colorReset, colorCyan, n.id, colorReset, colorGreen, colorReset, msg)
-}
+	if err != nil {
+		n.logger.Error("Failed to import token range", logging.KeyPeer, transferredHashSpace.PreviousOwnerId, logging.Err(err))
+		return err
+	}
 
-func (n *Node) logWarning(msg string) {
-	ts := time.Now().Format("15:04:05.000")
-	fmt.Printf("%s[%s]%s %s[Node %s]%s %s[WARNING]%s %s\n",
-		colorGray, ts, colorReset, colorCyan, n.id, colorReset, colorYellow, colorReset, msg)
-}
+	valuesSpace := hashSpaceResponse.GetGetHashSpace().GetHashSpaceValues()
 
-func (n *Node) logError(msg string) {
-	ts := time.Now().Format("15:04:05.000")
-	fmt.Printf("%s[%s]%s %s[Node %s]%s %s[ERROR]%s %s\n",
-		colorGray, ts, colorReset, colorCyan, n.id, colorReset, colorRed, colorReset, msg)
-}
+	for key, value := range valuesSpace {
+		err := n.storeMerged(key, value)
+		if err != nil {
+			n.logger.Error("Failed to import key", logging.KeyKey, key, logging.Err(err))
+			return fmt.Errorf("failed to import key '%s': %w", key, err)
+		}
+	}
 
-func (n *Node) log(msg string) {
-	n.logInfo(msg)
+	n.logger.Info("Imported token range", "start", transferredHashSpace.Start, "end", transferredHashSpace.End,
+		"keys", len(valuesSpace))
+	return nil
 }
 
 func NodeIdToZMQAddr(id string) string {
@@ -374,9 +556,35 @@ func (n *Node) GetID() string {
 func (n *Node) GetRingView() *ringview.RingView {
 	return n.ringView
 }
-func (n *Node) isNodeAlive(nodeId string) bool {
+
+func (n *Node) HintStats() replication.HintStats {
+	return n.hintStore.Stats()
+}
+
+// Runs one round of the hint and failure detection maintenance that StartPeriodicTasks runs on timers
+func (n *Node) RunMaintenance(ctx context.Context) {
+	n.probeSuspectedNodes(ctx)
+	n.expireHints(ctx)
+	n.scheduleAllHintDeliveries()
+}
+
+// Reads a key from the local store only, without going through the replicas
+func (n *Node) LocalGet(key string) ([]byte, error) {
+	return n.store.Get([]byte(key))
+}
+
+// Pings the nodes suspected to be down, so that the failure detector notices when they come back
+func (n *Node) probeSuspectedNodes(ctx context.Context) {
+	for _, nodeId := range slices.Sorted(maps.Keys(n.failures.Suspected())) {
+		if n.isNodeAlive(ctx, nodeId) {
+			n.logger.Info("Peer is reachable again", logging.KeyPeer, nodeId)
+		}
+	}
+}
+
+func (n *Node) isNodeAlive(ctx context.Context, nodeId string) bool {
 	nodeAddr := NodeIdToZMQAddr(nodeId)
-	resp, err := n.sendPing(nodeAddr)
+	resp, err := n.sendPing(ctx, nodeAddr)
 	if err != nil || resp == nil || !resp.Ok {
 		return false
 	}
@@ -0,0 +1,186 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sdle-server/auth"
+	generic "sdle-server/crdt/generic"
+	"sdle-server/logging"
+	pb "sdle-server/proto"
+	"sdle-server/replication"
+	"slices"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Prefix of the keys under which the access-control entries of shopping lists are stored
+const listAclKeyPrefix = "acl_"
+
+// Key under which the ACL of a shopping list is stored
+func ListAclKey(listID string) string {
+	return listAclKeyPrefix + listID
+}
+
+// Kind of access to a shopping list
+type listAccess int
+
+const (
+	accessRead  listAccess = iota // get or subscribe to the list
+	accessWrite                   // send deltas of the list
+	accessAdmin                   // change the ACL of the list
+)
+
+func decodeListAcl(data []byte) (*pb.ListAcl, error) {
+	var acl pb.ListAcl
+	if err := proto.Unmarshal(data, &acl); err != nil {
+		return nil, fmt.Errorf("%w: %v", generic.ErrMalformed, err)
+	}
+	if acl.GetOwner() == "" {
+		return nil, fmt.Errorf("%w: ACL of list '%s' has no owner", generic.ErrMalformed, acl.GetListId())
+	}
+	return &acl, nil
+}
+
+// Merges two ACLs of a list: the highest version wins. Concurrent changes of the same version are settled by
+// comparing their encodings, so every replica keeps the same one.
+func mergeListAcls(stored []byte, incoming []byte) ([]byte, error) {
+	storedAcl, err := decodeListAcl(stored)
+	if err != nil {
+		return nil, fmt.Errorf("stored ACL: %w", err)
+	}
+	incomingAcl, err := decodeListAcl(incoming)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case incomingAcl.GetVersion() > storedAcl.GetVersion():
+		return incoming, nil
+	case incomingAcl.GetVersion() < storedAcl.GetVersion():
+		return stored, nil
+	case bytes.Compare(incoming, stored) > 0:
+		return incoming, nil
+	}
+	return stored, nil
+}
+
+// Checks whether the user of a request may access a list with the given ACL. Lists without an ACL are open to
+// everyone; those with one are closed to unauthenticated sessions.
+func authorize(ctx context.Context, acl *pb.ListAcl, access listAccess) error {
+	if acl == nil {
+		return nil
+	}
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		return fmt.Errorf("%w: list '%s' has an owner", auth.ErrUnauthenticated, acl.GetListId())
+	}
+
+	allowed := user == acl.GetOwner()
+	switch access {
+	case accessRead:
+		allowed = allowed || slices.Contains(acl.GetEditors(), user) || slices.Contains(acl.GetViewers(), user)
+	case accessWrite:
+		allowed = allowed || slices.Contains(acl.GetEditors(), user)
+	}
+	if !allowed {
+		return fmt.Errorf("%w: user '%s' may not access list '%s'", auth.ErrForbidden, user, acl.GetListId())
+	}
+	return nil
+}
+
+// Reads the ACL of a list through a quorum read. Returns nil if the list has none, which takes R primaries answering
+// they miss it; any other failed read is an error, so a protected list is never mistaken for an open one (and claimed).
+func (n *Node) getListAcl(ctx context.Context, listID string) (*pb.ListAcl, error) {
+	data, err := n.Get(ctx, ListAclKey(listID))
+	if errors.Is(err, replication.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading the ACL of list '%s': %w", listID, err)
+	}
+	return decodeListAcl(data)
+}
+
+func (n *Node) putListAcl(ctx context.Context, acl *pb.ListAcl) error {
+	data, err := proto.Marshal(acl)
+	if err != nil {
+		return err
+	}
+	return n.Put(ctx, ListAclKey(acl.GetListId()), data)
+}
+
+// Checks whether the user of a request, or the capability presented with it, may access a list. Returns the ACL of
+// the list (nil if it has none).
+func (n *Node) authorizeList(ctx context.Context, listID string, access listAccess) (*pb.ListAcl, error) {
+	acl, err := n.getListAcl(ctx, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = authorize(ctx, acl, access)
+	if c, ok := auth.CapabilityFrom(ctx); ok && err != nil {
+		err = n.authorizeCapability(ctx, c, listID, access)
+	}
+	return acl, err
+}
+
+// Makes the user of a request the owner of a list without an ACL. Two users claiming the same list concurrently both
+// write version 1, and the replicas settle on one of them.
+func (n *Node) claimList(ctx context.Context, listID string) (*pb.ListAcl, error) {
+	user, ok := auth.User(ctx)
+	if !ok {
+		return nil, nil
+	}
+
+	acl := &pb.ListAcl{ListId: listID, Owner: user, Version: 1}
+	if err := n.putListAcl(ctx, acl); err != nil {
+		return nil, fmt.Errorf("claiming list '%s': %w", listID, err)
+	}
+	n.logger.Info("List claimed", "list_id", listID, logging.KeyUser, user)
+	return acl, nil
+}
+
+// Returns the ACL of a list, to any user that may read it. Returns replication.ErrNotFound if the list has none.
+func (n *Node) GetListAcl(ctx context.Context, listID string) (*pb.ListAcl, error) {
+	acl, err := n.authorizeList(ctx, listID, accessRead)
+	if err != nil {
+		return nil, err
+	}
+	if acl == nil {
+		return nil, fmt.Errorf("%w: list '%s' has no ACL", replication.ErrNotFound, listID)
+	}
+	return acl, nil
+}
+
+// Replaces the editors and viewers of a list. Only the owner of the list may, and a list without an ACL becomes owned
+// by the user.
+func (n *Node) SetListAcl(ctx context.Context, listID string, editors []string, viewers []string) (*pb.ListAcl, error) {
+	user, ok := auth.User(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%w: only the owner of a list may change its ACL", auth.ErrUnauthenticated)
+	}
+
+	acl, err := n.authorizeList(ctx, listID, accessAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if acl == nil {
+		acl = &pb.ListAcl{ListId: listID, Owner: user}
+	}
+
+	acl = &pb.ListAcl{
+		ListId:  listID,
+		Owner:   acl.GetOwner(),
+		Editors: editors,
+		Viewers: viewers,
+		Version: acl.GetVersion() + 1,
+	}
+	if err := n.putListAcl(ctx, acl); err != nil {
+		return nil, err
+	}
+	n.logger.Info("List ACL changed", "list_id", listID, logging.KeyUser, user, "version", acl.GetVersion())
+	return acl, nil
+}
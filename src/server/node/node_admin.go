@@ -0,0 +1,62 @@
+package node
+
+import (
+	"maps"
+	"slices"
+
+	"sdle-server/admin"
+	"sdle-server/ringview"
+)
+
+// Status of the node, served by the admin API
+func (n *Node) Status() admin.Status {
+	hints, _ := n.hintStore.CountHints()
+	lsm, vlog := n.store.Size()
+	return admin.Status{
+		ID:            n.id,
+		Tokens:        n.ringView.GetNodeTokens(n.id),
+		RingNodes:     slices.Sorted(slices.Values(n.ringView.GetKnownIds())),
+		Suspected:     slices.Sorted(maps.Keys(n.failures.Suspected())),
+		N:             n.replConfig.N,
+		W:             n.replConfig.W,
+		R:             n.replConfig.R,
+		Hints:         hints,
+		Subscriptions: n.subController.Count(),
+		StoreLSMBytes: lsm,
+		StoreLogBytes: vlog,
+	}
+}
+
+func (n *Node) PreferenceList(key string) ringview.PreferenceList {
+	return n.ringView.GetPreferenceList(key, n.replConfig.N)
+}
+
+func (n *Node) LocalKeySizes(start uint64, end uint64) (map[string]int64, error) {
+	return n.store.GetHashSpaceSizes(start, end)
+}
+
+// Starts delivering the hints for a node (for every node with hints if nodeId is empty), skipping the retry backoff
+func (n *Node) DeliverHints(nodeId string) []string {
+	targets := []string{nodeId}
+	if nodeId == "" {
+		targets = slices.Sorted(maps.Keys(n.hintStore.Stats().PerTarget))
+	}
+
+	started := []string{}
+	for _, target := range targets {
+		if n.scheduleHintDelivery(target, true) {
+			started = append(started, target)
+		}
+	}
+	return started
+}
+
+// Starts an ownership scrub in the background, unless one is already running
+func (n *Node) TriggerAntiEntropy() error {
+	if n.scrubbing.Load() {
+		return admin.ErrAlreadyRunning
+	}
+	n.logger.Info("Anti-entropy requested through the admin API")
+	n.runAsync("anti-entropy", n.scrub)
+	return nil
+}
@@ -1,37 +1,38 @@
 package node
 
 import (
+	"context"
 	"errors"
-	"fmt"
+	"sdle-server/logging"
+	"sdle-server/ringview"
+	"sdle-server/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func (n *Node) Get(key string) ([]byte, error) {
+func (n *Node) Get(ctx context.Context, key string) (_ []byte, err error) {
+	ctx, span := n.startSpan(ctx, "get", trace.WithAttributes(tracing.AttrKey.String(key)))
+	defer func() { tracing.Finish(span, err) }()
+
 	prefList := n.ringView.GetPreferenceList(key, n.replConfig.N)
 	if len(prefList.Nodes) == 0 {
-		n.logError("No node available for key '" + key + "'")
+		n.logger.Error("No node available for key", logging.KeyKey, key)
 		return nil, errors.New("no node available for key")
 	}
 
-	// Find the position on the preference list. If the node is not in the preference list, forward to coordinator. Otherwise, try to find the first alive node in the preference list. If the current node is the first alive node, it becomes the coordinator.
-	coordinatorId := prefList.Nodes[0] // First node is coordinator
-
-	// Find the earliest alive node in the preference list
-	for _, nodeId := range prefList.Nodes {
-		if n.isNodeAlive(nodeId) || nodeId == n.id {
-			coordinatorId = nodeId
-			break
-		}
-	}
+	coordinatorId := n.pickCoordinator(ctx, prefList)
+	span.SetAttributes(attrCoordinator.String(coordinatorId))
 
 	if coordinatorId == n.id {
-		n.logInfo("This node is coordinator. Orchestrating quorum read.")
-		return n.coordinateReplicatedGet(key)
+		n.logger.Debug("Coordinating GET locally", logging.KeyKey, key)
+		return n.coordinateReplicatedGet(ctx, key)
 	}
 
 	// Forward the request to the coordinator
-	n.logInfo("Forwarding GET request for key '" + key + "' to coordinator " + coordinatorId + ".")
+	n.logger.Debug("Forwarding GET to coordinator", logging.KeyKey, key, logging.KeyPeer, coordinatorId)
 	coordinatorAddr := NodeIdToZMQAddr(coordinatorId)
-	resp, err := n.sendGet(coordinatorAddr, key)
+	resp, err := n.sendGet(ctx, coordinatorAddr, key)
 
 	if err != nil {
 		return nil, err
@@ -40,78 +41,84 @@ func (n *Node) Get(key string) ([]byte, error) {
 	return resp.GetGet().Value, nil
 }
 
-func (n *Node) Put(key string, value []byte) error {
+func (n *Node) Put(ctx context.Context, key string, value []byte) (err error) {
+	ctx, span := n.startSpan(ctx, "put", trace.WithAttributes(tracing.AttrKey.String(key)))
+	defer func() { tracing.Finish(span, err) }()
+
 	// Get preference list to determine coordinator
 	prefList := n.ringView.GetPreferenceList(key, n.replConfig.N)
 	if len(prefList.Nodes) == 0 {
-		n.logError("No node available for key '" + key + "'")
+		n.logger.Error("No node available for key", logging.KeyKey, key)
 		return errors.New("no node available for key")
 	}
 
-	// Find the position on the preference list. If the node is not in the preference list, forward to coordinator. Otherwise, try to find the first alive node in the preference list. If the current node is the first alive node, it becomes the coordinator.
-	coordinatorId := prefList.Nodes[0] // First node is coordinator
-
-	// Find the earliest alive node in the preference list
-	for _, nodeId := range prefList.Nodes {
-		if n.isNodeAlive(nodeId) || nodeId == n.id {
-			coordinatorId = nodeId
-			break
-		}
-	}
+	coordinatorId := n.pickCoordinator(ctx, prefList)
+	span.SetAttributes(attrCoordinator.String(coordinatorId))
 
 	if coordinatorId == n.id {
 		// This node is the coordinator, so orchestrate replication
-		n.logInfo("This node is coordinator. Orchestrating replication.")
-		return n.coordinateReplicatedPut(key, value)
+		n.logger.Debug("Coordinating PUT locally", logging.KeyKey, key)
+		return n.coordinateReplicatedPut(ctx, key, value)
 	}
 
 	// Forward the request to the coordinator
-	n.logInfo("Forwarding PUT request for key '" + key + "' to coordinator " + coordinatorId + ".")
+	n.logger.Debug("Forwarding PUT to coordinator", logging.KeyKey, key, logging.KeyPeer, coordinatorId)
 	coordinatorAddr := NodeIdToZMQAddr(coordinatorId)
-	_, err := n.sendPut(coordinatorAddr, key, value)
+	_, err = n.sendPut(ctx, coordinatorAddr, key, value)
 	return err
 }
 
+// Attribute of the spans of client operations holding the node that coordinated them
+const attrCoordinator = attribute.Key("sdle.coordinator")
+
+// Finds the coordinator of a write: the earliest alive node in the preference list (this node, if it comes first).
+// If no node answers, the first node of the list is used.
+func (n *Node) pickCoordinator(ctx context.Context, prefList ringview.PreferenceList) string {
+	for _, nodeId := range prefList.Nodes {
+		if nodeId == n.id || (!n.failures.IsSuspected(nodeId) && n.isNodeAlive(ctx, nodeId)) {
+			return nodeId
+		}
+	}
+
+	return prefList.Nodes[0]
+}
+
 // TODO: delete this later
-func (n *Node) Delete(key string) error {
+func (n *Node) Delete(ctx context.Context, key string) error {
 	responsibleNodeId, ok := n.ringView.Lookup(key)
 	if !ok {
 		return errors.New("no node available for key")
 	}
 
-	n.log("Node " + responsibleNodeId + " is responsible for key '" + key + "'")
-
 	if responsibleNodeId == n.id {
 		// This node is responsible, delete from local store.
-		n.log("This node (" + n.id + ") is responsible. Deleting from local store.")
+		n.logger.Debug("Deleting key from local store", logging.KeyKey, key)
 		return n.store.Delete([]byte(key))
 	}
 
 	// Forward the request to the responsible node.
-	n.log("Forwarding DELETE request for key '" + key + "' to node " + responsibleNodeId + ".")
+	n.logger.Debug("Forwarding DELETE to responsible node", logging.KeyKey, key, logging.KeyPeer, responsibleNodeId)
 	responsibleNodeAddr := NodeIdToZMQAddr(responsibleNodeId)
-	_, err := n.sendDelete(responsibleNodeAddr, key)
+	_, err := n.sendDelete(ctx, responsibleNodeAddr, key)
 	return err
 }
 
-func (n *Node) Has(key string) (bool, error) {
+func (n *Node) Has(ctx context.Context, key string) (bool, error) {
 	responsibleNodeId, ok := n.ringView.Lookup(key)
 	if !ok {
 		return false, errors.New("no node available for key")
 	}
 
-	fmt.Printf("Node %s is responsible for key '%s'\n", responsibleNodeId, key)
-
 	if responsibleNodeId == n.id {
 		// This node is responsible, check local store.
-		n.log("This node (" + n.id + ") is responsible. Checking local store.")
+		n.logger.Debug("Checking local store for key", logging.KeyKey, key)
 		return n.store.Has([]byte(key))
 	}
 
 	// Forward the request to the responsible node.
-	n.log("Forwarding HAS request for key '" + key + "' to node " + responsibleNodeId + ".")
+	n.logger.Debug("Forwarding HAS to responsible node", logging.KeyKey, key, logging.KeyPeer, responsibleNodeId)
 	responsibleNodeAddr := NodeIdToZMQAddr(responsibleNodeId)
-	resp, err := n.sendHas(responsibleNodeAddr, key)
+	resp, err := n.sendHas(ctx, responsibleNodeAddr, key)
 	if err != nil {
 		return false, err
 	}
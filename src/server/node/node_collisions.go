@@ -0,0 +1,101 @@
+package node
+
+import (
+	"sdle-server/logging"
+	"sdle-server/ringview"
+)
+
+// Resolves token collisions found while merging membership information. Every node resolves them the same way
+// (see ringview.CollisionWinner): the loser picks a new token and re-gossips its membership, while any other node that
+// detects the collision makes sure the loser hears about the winner.
+func (n *Node) handleTokenCollisions(collisions []ringview.TokenCollision) {
+	for _, collision := range collisions {
+		n.logger.Warn("Token collision", "token", collision.Token, "winner", collision.Winner, "loser", collision.Loser)
+
+		if collision.Loser == n.id {
+			n.repickLostToken(collision)
+			continue
+		}
+
+		if collision.Winner == n.id {
+			continue
+		}
+
+		// Tell the loser about the winner, since it may have joined through a seed that never heard of it
+		loserAddr := NodeIdToZMQAddr(collision.Loser)
+		_, err := n.sendJoinGossip(n.ctx, loserAddr, collision.Winner, n.ringView.GetNodeTokens(collision.Winner),
+			n.ringView.GetNodeGeneration(collision.Winner))
+		n.logger.Info("Notified the loser of a token collision", logging.KeyPeer, collision.Loser, logging.Err(err))
+	}
+}
+
+// Replaces a token this node lost to a collision: picks a new token, imports its hash space, hands the data stored
+// under the lost token to the winner and gossips the new token list.
+func (n *Node) repickLostToken(collision ringview.TokenCollision) {
+	n.membershipMu.Lock()
+	defer n.membershipMu.Unlock()
+
+	// The lost token may have been replaced already (the same collision can be reported by several nodes)
+	if len(n.ringView.GetNodeTokens(n.id)) >= n.replConfig.TokensPerNode {
+		return
+	}
+
+	token, transferred, ok := n.ringView.ReplaceLostToken(n.id)
+	if !ok {
+		n.logger.Error("Failed to pick a new token to replace a lost one", "token", collision.Token)
+		return
+	}
+
+	n.logger.Info("Replaced lost token", "token", collision.Token, "new_token", token)
+
+	if transferred.PreviousOwnerId != n.id {
+		if err := n.importHashSpace(n.ctx, transferred); err != nil {
+			n.logger.Error("Failed to import data for new token", "token", token, logging.Err(err))
+		}
+	}
+
+	n.reconcileLostToken(collision)
+
+	tokens := n.ringView.GetNodeTokens(n.id)
+	generation := n.ringView.GetNodeGeneration(n.id)
+	for _, nodeId := range n.ringView.GetGossipNeighborsNodes(n.id) {
+		_, err := n.sendJoinGossip(n.ctx, NodeIdToZMQAddr(nodeId), n.id, tokens, generation)
+		n.logger.Debug("New tokens gossip sent", logging.KeyPeer, nodeId, logging.Err(err))
+	}
+}
+
+// Hands the keys stored while this node believed it owned the lost token to every replica of the range, now that the
+// winner is its primary. Values are merged on the replicas (see storeMerged), so updates written under the wrong
+// ownership are not lost.
+func (n *Node) reconcileLostToken(collision ringview.TokenCollision) {
+	tokenRange, ok := n.ringView.GetTokenRanges()[collision.Token]
+	if !ok {
+		return
+	}
+
+	values, err := n.store.GetHashSpace(tokenRange.Start, tokenRange.End)
+	if err != nil {
+		n.logger.Error("Failed to read data of lost token", "token", collision.Token, logging.Err(err))
+		return
+	}
+
+	reconciled := 0
+	for key, value := range values {
+		failed := false
+		for _, owner := range n.ringView.GetPreferenceList(key, n.replConfig.N).Nodes {
+			if owner == n.id {
+				continue
+			}
+			if err := n.sendReplicaPut(n.ctx, owner, key, value); err != nil {
+				n.logger.Error("Failed to reconcile key", logging.KeyKey, key, logging.KeyPeer, owner, logging.Err(err))
+				failed = true
+			}
+		}
+		if !failed {
+			reconciled++
+		}
+	}
+
+	n.logger.Info("Reconciled keys of lost token", "token", collision.Token, "reconciled", reconciled, "keys", len(values),
+		logging.KeyPeer, collision.Winner)
+}
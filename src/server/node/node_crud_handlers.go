@@ -1,25 +1,30 @@
 package node
 
 import (
+	"context"
+	"errors"
+
+	"sdle-server/logging"
 	pb "sdle-server/proto"
 	"sdle-server/replication"
 )
 
-func (n *Node) handleGet(req *pb.Request) error {
-	n.logInfo("Received GET from " + req.Origin)
+func (n *Node) handleGet(ctx context.Context, req *pb.Request) *pb.Response {
 	getReq := req.GetGet()
 	if getReq == nil {
-		n.logError("Invalid GET request from " + req.Origin)
-		return n.sendResponseError("invalid get request")
+		n.logger.Warn("Invalid request", logging.KeyRequest, "get", logging.KeyPeer, req.Origin)
+		return n.responseError("invalid get request")
 	}
 
 	// This node is coordinator orchestrate quorum read
-	value, err := n.coordinateReplicatedGet(getReq.Key)
+	value, err := n.coordinateReplicatedGet(ctx, getReq.Key)
 	if err != nil {
-		n.logError("Failed to coordinate replicated GET for key " + getReq.Key + ": " + err.Error())
-		return n.sendResponseError(err.Error())
+		if !errors.Is(err, replication.ErrNotFound) {
+			n.logger.Error("Replicated GET failed", logging.KeyKey, getReq.Key, logging.Err(err))
+		}
+		return n.responseFailure(err)
 	}
-	return n.sendResponseOK(&pb.Response{
+	return n.responseOK(&pb.Response{
 		Origin: n.id,
 		Ok:     true,
 		ResponseType: &pb.Response_Get{
@@ -28,22 +33,21 @@ func (n *Node) handleGet(req *pb.Request) error {
 	})
 }
 
-func (n *Node) handlePut(req *pb.Request) error {
-	n.logInfo("Received PUT from " + req.Origin)
+func (n *Node) handlePut(ctx context.Context, req *pb.Request) *pb.Response {
 	putReq := req.GetPut()
 	if putReq == nil {
-		n.logError("Invalid PUT request from " + req.Origin)
-		return n.sendResponseError("invalid put request")
+		n.logger.Warn("Invalid request", logging.KeyRequest, "put", logging.KeyPeer, req.Origin)
+		return n.responseError("invalid put request")
 	}
 
 	// This node is coordinator, orchestrate replication
-	err := n.coordinateReplicatedPut(putReq.Key, putReq.Value)
+	err := n.coordinateReplicatedPut(ctx, putReq.Key, putReq.Value)
 	if err != nil {
-		n.logError("Failed to coordinate replicated PUT for key " + putReq.Key + ": " + err.Error())
-		return n.sendResponseError(err.Error())
+		n.logger.Error("Replicated PUT failed", logging.KeyKey, putReq.Key, logging.Err(err))
+		return n.responseFailure(err)
 	}
 
-	return n.sendResponseOK(&pb.Response{
+	return n.responseOK(&pb.Response{
 		Origin: n.id,
 		Ok:     true,
 		ResponseType: &pb.Response_Put{
@@ -52,38 +56,36 @@ func (n *Node) handlePut(req *pb.Request) error {
 	})
 }
 
-func (n *Node) handleDelete(req *pb.Request) error {
-	n.log("Received DELETE from " + req.Origin)
+func (n *Node) handleDelete(req *pb.Request) *pb.Response {
 	delReq := req.GetDelete()
 	if delReq == nil {
-		return n.sendResponseError("invalid delete request")
+		return n.responseError("invalid delete request")
 	}
 
 	err := n.store.Delete([]byte(delReq.Key))
 	if err != nil {
-		return n.sendResponseError(err.Error())
+		return n.responseError(err.Error())
 	}
 
-	return n.sendResponseOK(&pb.Response{
+	return n.responseOK(&pb.Response{
 		Origin:       n.id,
 		Ok:           true,
 		ResponseType: &pb.Response_Delete{},
 	})
 }
 
-func (n *Node) handleHas(req *pb.Request) error {
-	n.log("Received HAS from " + req.Origin)
+func (n *Node) handleHas(req *pb.Request) *pb.Response {
 	hasReq := req.GetHas()
 	if hasReq == nil {
-		return n.sendResponseError("invalid has request")
+		return n.responseError("invalid has request")
 	}
 
 	value, err := n.store.Has([]byte(hasReq.Key))
 	if err != nil {
-		return n.sendResponseError(err.Error())
+		return n.responseError(err.Error())
 	}
 
-	return n.sendResponseOK(&pb.Response{
+	return n.responseOK(&pb.Response{
 		Origin: n.id,
 		Ok:     true,
 		ResponseType: &pb.Response_Has{
@@ -93,20 +95,25 @@ func (n *Node) handleHas(req *pb.Request) error {
 }
 
 // Handles a direct replica write (bypasses coordinator logic)
-func (n *Node) handleReplicaPut(req *pb.Request) error {
-	n.logInfo("Received REPLICA_PUT from " + req.Origin)
+func (n *Node) handleReplicaPut(req *pb.Request) *pb.Response {
 	replicaReq := req.GetReplicaPut()
 	if replicaReq == nil {
-		n.logError("Invalid REPLICA_PUT request from " + req.Origin)
-		return n.sendResponseError("invalid replica put request")
+		n.logger.Warn("Invalid request", logging.KeyRequest, "replica_put", logging.KeyPeer, req.Origin)
+		return n.responseError("invalid replica put request")
+	}
+
+	// Stored values are trusted from then on, so a malformed one is rejected before it is stored
+	if err := n.validateValue(replicaReq.Key, replicaReq.Value); err != nil {
+		n.logger.Warn("Rejecting replica write", logging.KeyKey, replicaReq.Key, logging.KeyPeer, req.Origin, logging.Err(err))
+		return n.responseFailure(err)
 	}
 
-	err := n.store.Put([]byte(replicaReq.Key), replicaReq.Value)
+	err := n.storeMerged(replicaReq.Key, replicaReq.Value)
 	if err != nil {
-		return n.sendResponseError(err.Error())
+		return n.responseFailure(err)
 	}
 
-	return n.sendResponseOK(&pb.Response{
+	return n.responseOK(&pb.Response{
 		Origin: n.id,
 		Ok:     true,
 		ResponseType: &pb.Response_ReplicaPut{
@@ -115,20 +122,66 @@ func (n *Node) handleReplicaPut(req *pb.Request) error {
 	})
 }
 
-func (n *Node) handleReplicaGet(req *pb.Request) error {
-	n.logInfo("Received REPLICA_GET from " + req.Origin)
+func (n *Node) handlePutDelta(ctx context.Context, req *pb.Request) *pb.Response {
+	deltaReq := req.GetPutDelta()
+	if deltaReq == nil {
+		n.logger.Warn("Invalid request", logging.KeyRequest, "put_delta", logging.KeyPeer, req.Origin)
+		return n.responseError("invalid put delta request")
+	}
+
+	// This node is coordinator, orchestrate replication
+	err := n.coordinateReplicatedDelta(ctx, deltaReq.Key, deltaReq.Delta)
+	if err != nil {
+		n.logger.Error("Replicated PUT_DELTA failed", logging.KeyKey, deltaReq.Key, logging.Err(err))
+		return n.responseFailure(err)
+	}
+
+	return n.responseOK(&pb.Response{
+		Origin: n.id,
+		Ok:     true,
+		ResponseType: &pb.Response_PutDelta{
+			PutDelta: &pb.ResponsePutDelta{},
+		},
+	})
+}
+
+func (n *Node) handleReplicaDelta(req *pb.Request) *pb.Response {
+	deltaReq := req.GetReplicaDelta()
+	if deltaReq == nil {
+		n.logger.Warn("Invalid request", logging.KeyRequest, "replica_delta", logging.KeyPeer, req.Origin)
+		return n.responseError("invalid replica delta request")
+	}
+
+	applied, err := n.applyDelta(deltaReq.Key, deltaReq.Delta)
+	if err != nil {
+		return n.responseFailure(err)
+	}
+	if !applied {
+		n.logger.Warn("Delta has a causal gap, asking for the full state", logging.KeyKey, deltaReq.Key, logging.KeyPeer, req.Origin)
+	}
+
+	return n.responseOK(&pb.Response{
+		Origin: n.id,
+		Ok:     true,
+		ResponseType: &pb.Response_ReplicaDelta{
+			ReplicaDelta: &pb.ResponseReplicaDelta{CausalGap: !applied},
+		},
+	})
+}
+
+func (n *Node) handleReplicaGet(req *pb.Request) *pb.Response {
 	replicaReq := req.GetReplicaGet()
 	if replicaReq == nil {
-		n.logError("Invalid REPLICA_GET request from " + req.Origin)
-		return n.sendResponseError("invalid replica get request")
+		n.logger.Warn("Invalid request", logging.KeyRequest, "replica_get", logging.KeyPeer, req.Origin)
+		return n.responseError("invalid replica get request")
 	}
 
 	value, err := n.store.Get([]byte(replicaReq.Key))
 	if err != nil {
-		return n.sendResponseError(err.Error())
+		return n.responseFailure(err)
 	}
 
-	return n.sendResponseOK(&pb.Response{
+	return n.responseOK(&pb.Response{
 		Origin: n.id,
 		Ok:     true,
 		ResponseType: &pb.Response_ReplicaGet{
@@ -137,13 +190,25 @@ func (n *Node) handleReplicaGet(req *pb.Request) error {
 	})
 }
 
+// Stores a write intended for another node: the value is kept as a local replica (so quorum reads can find it while
+// the intended node is down) together with a hint to deliver it once the intended node is back
+func (n *Node) storeHintedReplica(hint replication.Hint) error {
+	if err := n.validateValue(hint.Key, hint.Value); err != nil {
+		return err
+	}
+	// Store the hint first, so a full hint store rejects the write before the replica is kept
+	if err := n.hintStore.StoreHint(hint); err != nil {
+		return err
+	}
+	return n.storeMerged(hint.Key, hint.Value)
+}
+
 // Handles a request to store a hint for another node
-func (n *Node) handleStoreHint(req *pb.Request) error {
-	n.logInfo("Received STORE_HINT from " + req.Origin)
+func (n *Node) handleStoreHint(req *pb.Request) *pb.Response {
 	hintReq := req.GetStoreHint()
 	if hintReq == nil {
-		n.logError("Invalid STORE_HINT request from " + req.Origin)
-		return n.sendResponseError("invalid store hint request")
+		n.logger.Warn("Invalid request", logging.KeyRequest, "store_hint", logging.KeyPeer, req.Origin)
+		return n.responseError("invalid store hint request")
 	}
 
 	// Store the hint in this node's hint store
@@ -153,14 +218,14 @@ func (n *Node) handleStoreHint(req *pb.Request) error {
 		Value:        hintReq.Value,
 	}
 
-	err := n.hintStore.StoreHint(hint)
+	err := n.storeHintedReplica(hint)
 	if err != nil {
-		return n.sendResponseError(err.Error())
+		return n.responseFailure(err)
 	}
 
-	n.logSuccess("Stored hint for node " + hintReq.IntendedNode + " (key: " + hintReq.Key + ")")
+	n.logger.Debug("Stored hint", logging.KeyKey, hintReq.Key, "intended_node", hintReq.IntendedNode)
 
-	return n.sendResponseOK(&pb.Response{
+	return n.responseOK(&pb.Response{
 		Origin: n.id,
 		Ok:     true,
 		ResponseType: &pb.Response_StoreHint{
@@ -0,0 +1,240 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	crdt "sdle-server/crdt/shopping"
+	"sdle-server/logging"
+	"sdle-server/replication"
+	"sdle-server/tracing"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/proto"
+)
+
+// Writes a delta of a shopping list. Unlike Put, the delta is shipped to the replicas as is and joined with their
+// state, so a small edit never requires reading or sending the whole list.
+func (n *Node) PutDelta(ctx context.Context, key string, delta []byte) (err error) {
+	ctx, span := n.startSpan(ctx, "put_delta", trace.WithAttributes(tracing.AttrKey.String(key)))
+	defer func() { tracing.Finish(span, err) }()
+
+	prefList := n.ringView.GetPreferenceList(key, n.replConfig.N)
+	if len(prefList.Nodes) == 0 {
+		n.logger.Error("No node available for key", logging.KeyKey, key)
+		return errors.New("no node available for key")
+	}
+
+	coordinatorId := n.pickCoordinator(ctx, prefList)
+	span.SetAttributes(attrCoordinator.String(coordinatorId))
+
+	if coordinatorId == n.id {
+		n.logger.Debug("Coordinating PUT_DELTA locally", logging.KeyKey, key)
+		return n.coordinateReplicatedDelta(ctx, key, delta)
+	}
+
+	n.logger.Debug("Forwarding PUT_DELTA to coordinator", logging.KeyKey, key, logging.KeyPeer, coordinatorId)
+	coordinatorAddr := NodeIdToZMQAddr(coordinatorId)
+	_, err = n.sendPutDelta(ctx, coordinatorAddr, key, delta)
+	return err
+}
+
+// Sends a delta to the N nodes of the preference list. Replicas that are missing updates the delta depends on
+// receive the full state of the coordinator instead, and unreachable replicas get the delta as a hint.
+func (n *Node) coordinateReplicatedDelta(ctx context.Context, key string, delta []byte) (err error) {
+	defer func() { n.metrics.observeQuorum("put_delta", err) }()
+	ctx, span := n.startSpan(ctx, "coordinate put_delta", trace.WithAttributes(tracing.AttrKey.String(key)))
+	defer func() { tracing.Finish(span, err) }()
+	start := n.now()
+	logger := n.logger.With(logging.KeyKey, key)
+
+	// A malformed delta is rejected before any replica, or hint, gets it
+	if err := n.validateValue(key, delta); err != nil {
+		logger.Warn("Rejecting PUT_DELTA", logging.Err(err))
+		return err
+	}
+
+	prefList := n.ringView.GetPreferenceList(key, n.replConfig.N)
+
+	if len(prefList.Nodes) == 0 {
+		logger.Error("No nodes available for key")
+		return fmt.Errorf("no nodes available for key")
+	}
+
+	logger.Debug("Coordinating PUT_DELTA", "preference_list", prefList.Nodes, "n", n.replConfig.N, "w", n.replConfig.W)
+
+	successCount := 0
+	failedNodes := []string{}
+
+	for _, nodeId := range prefList.Nodes {
+		// The caller gave up: neither the remaining replicas nor hints are written
+		if err := ctx.Err(); err != nil {
+			logger.Warn("Abandoning PUT_DELTA", logging.Err(err))
+			return err
+		}
+
+		if nodeId != n.id && n.failures.IsSuspected(nodeId) {
+			logger.Debug("Skipping replica suspected to be down", logging.KeyPeer, nodeId)
+			failedNodes = append(failedNodes, nodeId)
+			continue
+		}
+
+		var err error
+		if nodeId == n.id {
+			err = n.applyLocalDelta(ctx, key, delta)
+		} else {
+			err = n.replicateDelta(ctx, nodeId, key, delta)
+		}
+
+		if err == nil {
+			successCount++
+		} else {
+			logger.Warn("Delta write failed", logging.KeyPeer, nodeId, logging.Err(err))
+			failedNodes = append(failedNodes, nodeId)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		logger.Warn("Abandoning PUT_DELTA before hinted handoff", logging.Err(err))
+		return err
+	}
+
+	// Hints are delivered without the causal gap check of deltas, so they carry the full state joined with the delta
+	if len(failedNodes) > 0 {
+		if state, err := n.hintedState(ctx, key, delta); err != nil {
+			logger.Warn("Failed to read the full state to hint", logging.Err(err))
+		} else {
+			hintsStored := n.attemptHintedHandoff(ctx, key, state, failedNodes, prefList)
+			successCount += hintsStored
+
+			logger.Info("Hinted handoff", "failed_nodes", failedNodes, "hints_stored", hintsStored)
+		}
+	}
+
+	span.SetAttributes(attrReplicas.Int(successCount))
+	if successCount >= n.replConfig.W {
+		logger.Debug("Write quorum achieved", "replicas", successCount, "w", n.replConfig.W,
+			logging.KeyDuration, n.now().Sub(start))
+		return nil
+	}
+
+	logger.Error("Write quorum not achieved", "replicas", successCount, "w", n.replConfig.W,
+		logging.KeyDuration, n.now().Sub(start))
+	return fmt.Errorf("%w: only %d/%d replicas achieved (W=%d required)",
+		replication.ErrInsufficientReplicas, successCount, n.replConfig.N, n.replConfig.W)
+}
+
+// Applies a delta to the local replica of the coordinator. If the local replica is missing updates the delta depends
+// on, it is first repaired with a quorum read.
+func (n *Node) applyLocalDelta(ctx context.Context, key string, delta []byte) error {
+	applied, err := n.applyDelta(key, delta)
+	if err != nil || applied {
+		return err
+	}
+
+	n.logger.Warn("Local replica is behind the delta, repairing it", logging.KeyKey, key)
+
+	if state, err := n.coordinateReplicatedGet(ctx, key); err == nil {
+		if err := n.storeMerged(key, state); err != nil {
+			return err
+		}
+	} else {
+		n.logger.Warn("Failed to repair local replica", logging.KeyKey, key, logging.Err(err))
+	}
+
+	return n.storeMerged(key, delta)
+}
+
+// Sends a delta to a replica, falling back to the full state when the replica reports a causal gap
+func (n *Node) replicateDelta(ctx context.Context, nodeId string, key string, delta []byte) error {
+	resp, err := n.sendReplicaDeltaRequest(ctx, NodeIdToZMQAddr(nodeId), key, delta)
+	if err != nil {
+		return err
+	}
+	if !resp.GetReplicaDelta().GetCausalGap() {
+		return nil
+	}
+
+	n.logger.Info("Replica is missing updates, sending the full state", logging.KeyKey, key, logging.KeyPeer, nodeId)
+
+	state, err := n.fullState(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	return n.sendReplicaPut(ctx, nodeId, key, state)
+}
+
+// The full state of a key: the local replica if this node has one, otherwise a quorum read
+func (n *Node) fullState(ctx context.Context, key string) ([]byte, error) {
+	has, err := n.store.Has([]byte(key))
+	if err != nil {
+		return nil, err
+	}
+	if has {
+		return n.store.Get([]byte(key))
+	}
+
+	return n.coordinateReplicatedGet(ctx, key)
+}
+
+// The full state of a key joined with a delta. If no replica knows the key yet, the delta is its whole state.
+func (n *Node) hintedState(ctx context.Context, key string, delta []byte) ([]byte, error) {
+	state, err := n.fullState(ctx, key)
+	if errors.Is(err, replication.ErrNotFound) {
+		return delta, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return n.mergeValues(key, state, delta)
+}
+
+// Joins a delta with the local replica, unless the replica is missing updates the delta depends on. Returns whether
+// the delta was applied. Values that are not shopping lists are always written.
+func (n *Node) applyDelta(key string, delta []byte) (bool, error) {
+	if !strings.HasPrefix(key, shoppingListKeyPrefix) {
+		return true, n.storeMerged(key, delta)
+	}
+
+	deltaList, err := decodeShoppingList(delta, n.id)
+	if err != nil {
+		return false, err
+	}
+
+	n.mergeMu.Lock()
+	defer n.mergeMu.Unlock()
+
+	has, err := n.store.Has([]byte(key))
+	if err != nil {
+		return false, err
+	}
+
+	list := crdt.NewShoppingList(n.id, deltaList.ListID())
+	if has {
+		storedData, err := n.store.Get([]byte(key))
+		if err != nil {
+			return false, err
+		}
+
+		list, err = decodeShoppingList(storedData, n.id)
+		if err != nil {
+			return false, fmt.Errorf("stored value of key '%s': %w", key, err)
+		}
+	}
+
+	if list.HasCausalGap(deltaList) {
+		return false, nil
+	}
+
+	list.Join(deltaList)
+
+	mergedData, err := proto.Marshal(list.ToProto())
+	if err != nil {
+		return false, err
+	}
+
+	return true, n.store.Put([]byte(key), mergedData)
+}
@@ -0,0 +1,38 @@
+package node
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Reports whether the node runs, for the liveness endpoint
+func (n *Node) Live() error {
+	select {
+	case <-n.stopCh:
+		return errors.New("node stopping")
+	default:
+		return nil
+	}
+}
+
+// Reasons why the node cannot serve quorum operations, for the readiness endpoint: it is still joining the ring, its
+// ring view has fewer than N nodes, or its store rejects writes
+func (n *Node) NotReady() []string {
+	reasons := []string{}
+
+	if n.joining.Load() {
+		reasons = append(reasons, "joining the ring")
+	} else if len(n.ringView.GetNodeTokens(n.id)) == 0 {
+		reasons = append(reasons, "not part of the ring")
+	}
+
+	if nodes := len(n.ringView.GetKnownIds()); nodes < n.replConfig.N {
+		reasons = append(reasons, fmt.Sprintf("ring view has %d nodes, fewer than N=%d", nodes, n.replConfig.N))
+	}
+
+	if err := n.store.CheckWritable(); err != nil {
+		reasons = append(reasons, "store cannot accept writes: "+err.Error())
+	}
+
+	return reasons
+}
@@ -0,0 +1,179 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sdle-server/logging"
+	pb "sdle-server/proto"
+	"sdle-server/replication"
+	"sdle-server/tracing"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Delivery state of the hints intended for a single node
+type hintTarget struct {
+	delivering  bool
+	failures    int       // consecutive failed deliveries
+	nextAttempt time.Time // deliveries are not retried before this time (backoff)
+}
+
+// Delivers hints to their intended nodes. Deliveries are triggered when a node is seen again (failure detector
+// recovery or membership gossip) and by a periodic sweep, are batched per target and back off per target on failure.
+type hintDeliverer struct {
+	mu      sync.Mutex
+	targets map[string]*hintTarget
+}
+
+func newHintDeliverer() *hintDeliverer {
+	return &hintDeliverer{
+		targets: make(map[string]*hintTarget),
+	}
+}
+
+// Starts delivering the hints for a node, unless a delivery is already running or the node is backing off.
+// force skips the backoff (used when the node was just seen alive). Returns whether a delivery was started.
+func (n *Node) scheduleHintDelivery(nodeId string, force bool) bool {
+	if nodeId == n.id || n.hintStore.Stats().PerTarget[nodeId] == 0 {
+		return false
+	}
+
+	d := n.hintDeliverer
+	d.mu.Lock()
+	target, ok := d.targets[nodeId]
+	if !ok {
+		target = &hintTarget{}
+		d.targets[nodeId] = target
+	}
+
+	if target.delivering || (!force && n.now().Before(target.nextAttempt)) {
+		d.mu.Unlock()
+		return false
+	}
+	target.delivering = true
+	d.mu.Unlock()
+
+	n.runAsync("hint delivery to "+nodeId, func() {
+		// A delivery that panics counts as a failed one, so the target is not left marked as delivering
+		var err error
+		if panicErr := n.runGuarded("hint delivery to "+nodeId, func() { err = n.deliverHintsTo(nodeId) }); panicErr != nil {
+			err = panicErr
+		}
+
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		target.delivering = false
+		if err == nil {
+			target.failures = 0
+			target.nextAttempt = time.Time{}
+			return
+		}
+
+		target.failures++
+		backoff := n.replConfig.HintRetryBackoff << min(target.failures-1, 16)
+		target.nextAttempt = n.now().Add(min(backoff, n.replConfig.HintRetryMaxBackoff))
+
+		n.logger.Warn("Hint delivery failed", logging.KeyPeer, nodeId, "failures", target.failures,
+			"retry_in", target.nextAttempt.Sub(n.now()).Round(time.Millisecond), logging.Err(err))
+	})
+	return true
+}
+
+// Periodic sweep: schedules the delivery of the hints of every node that is not suspected to be down. Suspected nodes
+// are left alone until the failure detector sees them again.
+func (n *Node) scheduleAllHintDeliveries() {
+	for _, nodeId := range slices.Sorted(maps.Keys(n.hintStore.Stats().PerTarget)) {
+		if !n.failures.IsSuspected(nodeId) {
+			n.scheduleHintDelivery(nodeId, false)
+		}
+	}
+}
+
+// Sends all hints for a node in throttled batches. Stops at the first failed batch.
+func (n *Node) deliverHintsTo(nodeId string) (err error) {
+	ctx, span := n.startSpan(n.ctx, "deliver hints", trace.WithAttributes(tracing.AttrPeer.String(nodeId)))
+	defer func() { tracing.Finish(span, err) }()
+
+	hints, err := n.hintStore.GetHintsFor(nodeId)
+	if err != nil {
+		return err
+	}
+	if len(hints) == 0 {
+		return nil
+	}
+
+	n.logger.Info("Delivering hints", logging.KeyPeer, nodeId, "hints", len(hints))
+
+	begin := n.now()
+	interval := time.Second / time.Duration(n.replConfig.HintBatchRate)
+
+	delivered := 0
+	for start := 0; start < len(hints); start += n.replConfig.HintBatchSize {
+		if start > 0 {
+			select {
+			case <-n.stopCh:
+				return fmt.Errorf("node stopping")
+			case <-n.clock.After(interval):
+			}
+		}
+
+		batch := hints[start:min(start+n.replConfig.HintBatchSize, len(hints))]
+		if err := n.sendHintBatch(ctx, nodeId, batch); err != nil {
+			return err
+		}
+
+		for _, hint := range batch {
+			_ = n.hintStore.DeleteDelivered(hint)
+		}
+		delivered += len(batch)
+	}
+
+	n.logger.Info("Delivered hints", logging.KeyPeer, nodeId, "hints", delivered, logging.KeyDuration, n.now().Sub(begin))
+	return nil
+}
+
+func (n *Node) sendHintBatch(ctx context.Context, nodeId string, hints []replication.Hint) error {
+	entries := make([]*pb.RequestReplicaPut, 0, len(hints))
+	for _, hint := range hints {
+		entries = append(entries, &pb.RequestReplicaPut{Key: hint.Key, Value: hint.Value})
+	}
+
+	_, err := n.sendReplicaPutBatchRequest(ctx, NodeIdToZMQAddr(nodeId), entries)
+	return err
+}
+
+// Handles a batch of replica writes (used to deliver hints)
+func (n *Node) handleReplicaPutBatch(req *pb.Request) *pb.Response {
+	batchReq := req.GetReplicaPutBatch()
+	if batchReq == nil {
+		n.logger.Warn("Invalid request", logging.KeyRequest, "replica_put_batch", logging.KeyPeer, req.Origin)
+		return n.responseError("invalid replica put batch request")
+	}
+
+	// The whole batch is rejected before any entry is stored if one of them is malformed
+	for _, entry := range batchReq.Entries {
+		if err := n.validateValue(entry.Key, entry.Value); err != nil {
+			n.logger.Warn("Rejecting replica write batch", logging.KeyKey, entry.Key, logging.KeyPeer, req.Origin, logging.Err(err))
+			return n.responseFailure(err)
+		}
+	}
+
+	for _, entry := range batchReq.Entries {
+		if err := n.storeMerged(entry.Key, entry.Value); err != nil {
+			return n.responseFailure(err)
+		}
+	}
+
+	return n.responseOK(&pb.Response{
+		Origin: n.id,
+		Ok:     true,
+		ResponseType: &pb.Response_ReplicaPutBatch{
+			ReplicaPutBatch: &pb.ResponseReplicaPutBatch{},
+		},
+	})
+}
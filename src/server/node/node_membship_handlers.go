@@ -1,13 +1,12 @@
 package node
 
 import (
-	"fmt"
+	"context"
+	"sdle-server/logging"
 	pb "sdle-server/proto"
 )
 
-func (n *Node) handlePing(req *pb.Request) error {
-	n.logInfo("Received Ping from " + req.Origin)
-
+func (n *Node) handlePing(req *pb.Request) *pb.Response {
 	response := &pb.Response{
 		ResponseType: &pb.Response_Ping{
 			Ping: &pb.ResponsePing{
@@ -16,62 +15,74 @@ func (n *Node) handlePing(req *pb.Request) error {
 		},
 	}
 
-	return n.sendResponseOK(response)
+	return n.responseOK(response)
 }
 
-func (n *Node) handleFetchRing(req *pb.Request) error {
-	n.logInfo("Received FetchRing from " + req.Origin)
-
+func (n *Node) handleFetchRing(req *pb.Request) *pb.Response {
 	response := &pb.Response{
 		ResponseType: &pb.Response_FetchRing{
 			FetchRing: &pb.ResponseFetchRing{
 				RingView: &pb.RingView{
 					TokenToNode: n.ringView.GetTokenToNode(),
+					Generations: n.ringView.GetGenerations(),
 				},
 			},
 		},
 	}
 
-	return n.sendResponseOK(response)
+	return n.responseOK(response)
 }
 
-func (n *Node) handleGossipJoin(req *pb.Request) error {
+func (n *Node) handleGossipJoin(ctx context.Context, req *pb.Request) *pb.Response {
 	gossipReq := req.GetGossipJoin()
-	n.logInfo("Received GossipJoin (start node: " + gossipReq.NewNodeId + "; received from: " + req.Origin + ")")
-	success := n.ringView.AddNode(gossipReq.NewNodeId, gossipReq.Tokens)
+	if gossipReq == nil {
+		n.logger.Warn("Invalid request", logging.KeyRequest, "gossip_join", logging.KeyPeer, req.Origin)
+		return n.responseError("invalid gossip join request")
+	}
+	n.metrics.gossip.Inc("join", "received")
 
-	if !success {
-		n.log("Node " + gossipReq.NewNodeId + " already exists in ring view. Finishing GossipJoin handling.")
-		return n.sendResponseError("Node already exists in ring view")
+	n.logger.Debug("Received join gossip", "new_node", gossipReq.NewNodeId, logging.KeyPeer, req.Origin)
+	success, collisions := n.ringView.AddNode(gossipReq.NewNodeId, gossipReq.Tokens, gossipReq.Generation)
+
+	// Membership gossip about a node means it is up: hand over the hints it missed
+	n.failures.ReportSuccess(gossipReq.NewNodeId)
+	n.scheduleHintDelivery(gossipReq.NewNodeId, false)
+
+	if len(collisions) > 0 {
+		n.runAsync("token collision handling", func() { n.handleTokenCollisions(collisions) })
 	}
 
-	n.logInfo("Node " + gossipReq.NewNodeId + " added to ring view successfully.")
+	if !success {
+		n.logger.Debug("Joined node already in ring view", "new_node", gossipReq.NewNodeId)
+		return n.responseError("Node already exists in ring view")
+	}
 
-	n.logInfo("New ring view: " + n.ringView.ToString())
+	n.logger.Info("Node added to ring view", "new_node", gossipReq.NewNodeId)
+	n.logger.Debug("New ring view", "ring", n.ringView.ToString())
 
 	gossipAddrs := n.ringView.GetGossipNeighborsNodes(n.GetID())
-	n.logInfo("Send gossip message from " + gossipReq.NewNodeId + " to neighbors " + fmt.Sprint(gossipAddrs))
+	n.logger.Debug("Propagating join gossip to neighbors", "new_node", gossipReq.NewNodeId, "neighbors", gossipAddrs)
 
 	// Propagate gossip asynchronously so we don't block the response
-	go func() {
+	gossipCtx := n.detachedContext(ctx)
+	n.runAsync("join gossip propagation", func() {
 		for _, nodeId := range gossipAddrs {
 			nodeAddr := NodeIdToZMQAddr(nodeId)
-			resp, err := n.sendJoinGossip(nodeAddr, gossipReq.NewNodeId, gossipReq.Tokens)
+			resp, err := n.sendJoinGossip(gossipCtx, nodeAddr, gossipReq.NewNodeId, gossipReq.Tokens, gossipReq.Generation)
 
-			n.logInfo("Gossip (start node: " + gossipReq.NewNodeId + "; response from:" + nodeAddr + ") Response: Ok=" + fmt.Sprint(resp.Ok) + ", Error='" + fmt.Sprint(err) + "'")
+			n.logger.Debug("Join gossip sent", "new_node", gossipReq.NewNodeId, logging.KeyPeer, nodeId, "ok", resp.GetOk(), logging.Err(err))
 		}
-	}()
+	})
 
-	return n.sendResponseOK(&pb.Response{})
+	return n.responseOK(&pb.Response{})
 }
 
-func (n *Node) handleGetHashSpace(req *pb.Request) error {
-	n.logInfo("Received GET HASHSPACE from " + req.Origin)
+func (n *Node) handleGetHashSpace(req *pb.Request) *pb.Response {
 	getReq := req.GetGetHashSpace()
 
 	if getReq == nil {
-		n.logError("Invalid GET HASHSPACE request from " + req.Origin)
-		return n.sendResponseError("invalid get hash space request")
+		n.logger.Warn("Invalid request", logging.KeyRequest, "get_hash_space", logging.KeyPeer, req.Origin)
+		return n.responseError("invalid get hash space request")
 	}
 
 	startHash := getReq.StartHashSpace
@@ -79,10 +90,10 @@ func (n *Node) handleGetHashSpace(req *pb.Request) error {
 
 	spaceValues, err := n.store.GetHashSpace(startHash, endHash)
 	if err != nil {
-		return n.sendResponseError(err.Error())
+		return n.responseError(err.Error())
 	}
 
-	return n.sendResponseOK(&pb.Response{
+	return n.responseOK(&pb.Response{
 		Origin: n.id,
 		Ok:     true,
 		ResponseType: &pb.Response_GetHashSpace{
@@ -0,0 +1,115 @@
+package node
+
+import (
+	"context"
+	"errors"
+	generic "sdle-server/crdt/generic"
+	"sdle-server/metrics"
+	pb "sdle-server/proto"
+	"sdle-server/replication"
+	"time"
+)
+
+// Metrics of a node, served on /metrics
+type nodeMetrics struct {
+	registry *metrics.Registry
+
+	requests        *metrics.CounterVec   // requests handled for peers, by type and outcome
+	requestDuration *metrics.HistogramVec // time spent handling requests for peers, by type
+	peerRequests    *metrics.CounterVec   // requests sent to peers, by type and outcome
+	peerDuration    *metrics.HistogramVec // time peers took to answer, by type
+	quorums         *metrics.CounterVec   // coordinated operations, by operation and outcome
+	gossip          *metrics.CounterVec   // membership gossip messages, by kind and direction
+}
+
+// Outcomes of coordinated operations
+const (
+	quorumAchieved  = "achieved"
+	quorumFailed    = "failed"
+	quorumAbandoned = "abandoned" // the caller gave up before the quorum was reached
+	quorumRejected  = "rejected"  // the value was malformed
+)
+
+func newNodeMetrics(n *Node) *nodeMetrics {
+	r := metrics.NewRegistry()
+	m := &nodeMetrics{
+		registry: r,
+		requests: r.NewCounterVec("sdle_requests_total",
+			"Requests handled for other nodes, by request type and outcome.", "type", "outcome"),
+		requestDuration: r.NewHistogramVec("sdle_request_duration_seconds",
+			"Time spent handling requests for other nodes.", metrics.LatencyBuckets, "type"),
+		peerRequests: r.NewCounterVec("sdle_peer_requests_total",
+			"Requests sent to other nodes, by request type and outcome.", "type", "outcome"),
+		peerDuration: r.NewHistogramVec("sdle_peer_request_duration_seconds",
+			"Time other nodes took to answer requests, including timed out ones.", metrics.LatencyBuckets, "type"),
+		quorums: r.NewCounterVec("sdle_quorum_operations_total",
+			"Operations coordinated by this node, by operation (put, put_delta, get) and outcome.", "operation", "outcome"),
+		gossip: r.NewCounterVec("sdle_gossip_messages_total",
+			"Membership gossip messages, by kind (join, token_move) and direction (sent, received).", "kind", "direction"),
+	}
+
+	r.NewGaugeFunc("sdle_hints", "Hints stored for other nodes.", func() float64 {
+		count, _ := n.hintStore.CountHints()
+		return float64(count)
+	})
+	r.NewGaugeFunc("sdle_subscriptions", "Shopping list subscriptions of WebSocket clients.", func() float64 {
+		return float64(n.subController.Count())
+	})
+	r.NewGaugeFunc("sdle_ring_nodes", "Nodes in the ring view.", func() float64 {
+		return float64(len(n.ringView.GetKnownIds()))
+	})
+	r.NewGaugeFunc("sdle_store_lsm_size_bytes", "Size of the LSM tree of the store.", func() float64 {
+		lsm, _ := n.store.Size()
+		return float64(lsm)
+	})
+	r.NewGaugeFunc("sdle_store_vlog_size_bytes", "Size of the value log of the store.", func() float64 {
+		_, vlog := n.store.Size()
+		return float64(vlog)
+	})
+
+	return m
+}
+
+func (m *nodeMetrics) observeRequest(requestType string, resp *pb.Response, elapsed time.Duration) {
+	outcome := "ok"
+	if !resp.GetOk() {
+		outcome = "error"
+	}
+	m.requests.Inc(requestType, outcome)
+	m.requestDuration.ObserveDuration(elapsed, requestType)
+}
+
+// Records a request sent to a peer. Requests the caller gave up on are not timed, since the peer is not to blame.
+func (m *nodeMetrics) observePeerRequest(ctx context.Context, requestType string, resp *pb.Response, err error, elapsed time.Duration) {
+	outcome := "ok"
+	switch {
+	case ctx.Err() != nil:
+		m.peerRequests.Inc(requestType, "abandoned")
+		return
+	case resp == nil && err != nil:
+		outcome = "unreachable"
+	case !resp.GetOk():
+		outcome = "error"
+	}
+	m.peerRequests.Inc(requestType, outcome)
+	m.peerDuration.ObserveDuration(elapsed, requestType)
+}
+
+// Records the outcome of a coordinated operation from the error it returned
+func (m *nodeMetrics) observeQuorum(operation string, err error) {
+	outcome := quorumFailed
+	switch {
+	case err == nil, errors.Is(err, replication.ErrNotFound):
+		outcome = quorumAchieved
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		outcome = quorumAbandoned
+	case errors.Is(err, generic.ErrMalformed):
+		outcome = quorumRejected
+	}
+	m.quorums.Inc(operation, outcome)
+}
+
+// Registry holding the metrics of the node
+func (n *Node) Metrics() *metrics.Registry {
+	return n.metrics.registry
+}
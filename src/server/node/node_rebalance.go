@@ -0,0 +1,219 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"sdle-server/logging"
+	pb "sdle-server/proto"
+	"sdle-server/rebalance"
+	"sdle-server/ringview"
+	"sdle-server/tracing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Runs a rebalancing round: collects the load of every token range in the ring and, if this node is the most loaded
+// one, moves one of its tokens backwards so that part of its hottest range is handed to the next node in the ring.
+func (n *Node) rebalance() {
+	if !n.rebalancing.CompareAndSwap(false, true) {
+		n.logger.Debug("Rebalance: a round is already running, skipped")
+		return
+	}
+	defer n.rebalancing.Store(false)
+
+	ranges := n.ringView.GetTokenRanges()
+	loads := n.collectTokenLoads(ranges)
+
+	successors := make(map[uint64]string, len(ranges))
+	for token := range ranges {
+		if next, ok := n.ringView.GetSuccessorToken(token); ok {
+			successors[token] = ranges[next].NodeId
+		}
+	}
+
+	nodeLoads := rebalance.NodeLoads(loads)
+	proposal, ok := rebalance.NewPlanner(n.replConfig).Propose(n.id, loads, successors)
+	if !ok {
+		n.logger.Debug("Rebalance: no token move needed", "imbalance", rebalance.Imbalance(nodeLoads), "node_loads", nodeLoads)
+		return
+	}
+
+	tokenRange := ranges[proposal.Token]
+	sizes, err := n.store.GetHashSpaceSizes(tokenRange.Start, tokenRange.End)
+	if err != nil {
+		n.logger.Error("Rebalance: failed to read keys of token", "token", proposal.Token, logging.Err(err))
+		return
+	}
+
+	newToken, moved, ok := rebalance.SplitPoint(tokenRange, sizes, proposal.Target)
+	if !ok {
+		n.logger.Info("Rebalance: token can not be split", "token", proposal.Token, "keys", len(sizes))
+		return
+	}
+
+	n.logger.Info("Rebalance: proposing a token move", "token", proposal.Token, "new_token", newToken, "moved_load", moved,
+		"load", nodeLoads[n.id], logging.KeyPeer, proposal.Receiver, "imbalance", rebalance.Imbalance(nodeLoads))
+
+	if n.replConfig.RebalanceDryRun {
+		n.logger.Info("Rebalance: dry-run mode, token move not executed")
+		return
+	}
+
+	if err := n.moveToken(proposal.Token, newToken, proposal.Receiver); err != nil {
+		n.logger.Error("Rebalance: failed to move token", "token", proposal.Token, logging.Err(err))
+		return
+	}
+
+	n.logger.Info("Rebalance: moved token", "token", proposal.Token, "new_token", newToken)
+}
+
+// Returns the load of every token range in the ring. Nodes that do not answer are left out of the computation.
+func (n *Node) collectTokenLoads(ranges map[uint64]ringview.TokenRange) []rebalance.TokenLoad {
+	loads := n.localTokenLoads(ranges)
+
+	for _, nodeId := range n.ringView.GetKnownIds() {
+		if nodeId == n.id {
+			continue
+		}
+
+		resp, err := n.sendTokenLoads(n.ctx, NodeIdToZMQAddr(nodeId))
+		if err != nil {
+			n.logger.Warn("Rebalance: failed to get token loads", logging.KeyPeer, nodeId, logging.Err(err))
+			continue
+		}
+
+		for _, l := range resp.GetTokenLoads().GetLoads() {
+			// Ignore tokens that do not match our ring view (e.g. moves that were not gossiped to us yet)
+			if r, ok := ranges[l.Token]; ok && r.NodeId == nodeId {
+				loads = append(loads, rebalance.TokenLoad{Token: l.Token, NodeId: nodeId, Keys: l.Keys, Bytes: l.Bytes})
+			}
+		}
+	}
+
+	return loads
+}
+
+// Returns the load of the ranges owned by this node
+func (n *Node) localTokenLoads(ranges map[uint64]ringview.TokenRange) []rebalance.TokenLoad {
+	loads := []rebalance.TokenLoad{}
+
+	for _, token := range n.ringView.GetNodeTokens(n.id) {
+		r := ranges[token]
+		load, err := n.store.GetHashSpaceLoad(r.Start, r.End)
+		if err != nil {
+			n.logger.Error("Failed to compute load of token", "token", token, logging.Err(err))
+			continue
+		}
+
+		loads = append(loads, rebalance.TokenLoad{Token: token, NodeId: n.id, Keys: load.Keys, Bytes: load.Bytes})
+	}
+
+	return loads
+}
+
+// Moves a token of this node backwards: streams the keys of (newToken, oldToken] to every node that owns them once the
+// token is moved (rate limited), then updates the local ring view and gossips the move.
+func (n *Node) moveToken(oldToken uint64, newToken uint64, receiver string) (err error) {
+	ctx, span := n.startSpan(n.ctx, "move token", trace.WithAttributes(tracing.AttrPeer.String(receiver)))
+	defer func() { tracing.Finish(span, err) }()
+
+	start := (newToken + 1) % n.replConfig.HashSpaceSize
+	values, err := n.store.GetHashSpace(start, oldToken)
+	if err != nil {
+		return err
+	}
+
+	n.logger.Info("Streaming token range", "start", start, "end", oldToken, "keys", len(values), logging.KeyPeer, receiver)
+
+	// The range changes primary, and so its whole preference list: compute the owners on the ring as it will be
+	moved := ringview.NewFromMembership(n.ringView.GetTokenToNode(), n.ringView.GetGenerations())
+	if !moved.MoveToken(n.id, oldToken, newToken, n.ringView.GetNodeGeneration(n.id)+1) {
+		return fmt.Errorf("token %d can not be moved to %d in the local ring view", oldToken, newToken)
+	}
+
+	limiter := time.NewTicker(time.Second / time.Duration(n.replConfig.RebalanceKeyRate))
+	defer limiter.Stop()
+
+	for key, value := range values {
+		select {
+		case <-n.stopCh:
+			return fmt.Errorf("node stopping, token move aborted")
+		case <-limiter.C:
+		}
+
+		for _, owner := range moved.GetPreferenceList(key, n.replConfig.N).Nodes {
+			if owner == n.id {
+				continue
+			}
+			if err := n.sendReplicaPut(ctx, owner, key, value); err != nil {
+				return fmt.Errorf("failed to stream key '%s' to %s: %w", key, owner, err)
+			}
+		}
+	}
+
+	n.membershipMu.Lock()
+	generation := n.ringView.GetNodeGeneration(n.id) + 1
+	if !n.ringView.MoveToken(n.id, oldToken, newToken, generation) {
+		n.membershipMu.Unlock()
+		return fmt.Errorf("token %d can not be moved to %d in the local ring view", oldToken, newToken)
+	}
+	n.membershipMu.Unlock()
+
+	n.gossipTokenMove(ctx, n.id, oldToken, newToken, generation)
+	return nil
+}
+
+func (n *Node) gossipTokenMove(ctx context.Context, nodeId string, oldToken uint64, newToken uint64, generation uint64) {
+	for _, neighborId := range n.ringView.GetGossipNeighborsNodes(n.GetID()) {
+		nodeAddr := NodeIdToZMQAddr(neighborId)
+		_, err := n.sendGossipTokenMove(ctx, nodeAddr, nodeId, oldToken, newToken, generation)
+
+		n.logger.Debug("Token move gossip sent", "moved_node", nodeId, logging.KeyPeer, neighborId, logging.Err(err))
+	}
+}
+
+func (n *Node) handleTokenLoads(req *pb.Request) *pb.Response {
+	loads := []*pb.TokenLoad{}
+	for _, l := range n.localTokenLoads(n.ringView.GetTokenRanges()) {
+		loads = append(loads, &pb.TokenLoad{Token: l.Token, Keys: l.Keys, Bytes: l.Bytes})
+	}
+
+	return n.responseOK(&pb.Response{
+		Origin: n.id,
+		Ok:     true,
+		ResponseType: &pb.Response_TokenLoads{
+			TokenLoads: &pb.ResponseTokenLoads{Loads: loads},
+		},
+	})
+}
+
+func (n *Node) handleGossipTokenMove(ctx context.Context, req *pb.Request) *pb.Response {
+	moveReq := req.GetGossipTokenMove()
+	if moveReq == nil {
+		n.logger.Warn("Invalid request", logging.KeyRequest, "gossip_token_move", logging.KeyPeer, req.Origin)
+		return n.responseError("invalid gossip token move request")
+	}
+	n.metrics.gossip.Inc("token_move", "received")
+
+	n.logger.Debug("Received token move gossip", "moved_node", moveReq.NodeId, "token", moveReq.OldToken,
+		"new_token", moveReq.NewToken, logging.KeyPeer, req.Origin)
+
+	if !n.ringView.MoveToken(moveReq.NodeId, moveReq.OldToken, moveReq.NewToken, moveReq.Generation) {
+		return n.responseError("Token move already known or not applicable")
+	}
+
+	// Propagate gossip asynchronously so we don't block the response
+	gossipCtx := n.detachedContext(ctx)
+	n.runAsync("token move gossip", func() {
+		n.gossipTokenMove(gossipCtx, moveReq.NodeId, moveReq.OldToken, moveReq.NewToken, moveReq.Generation)
+	})
+
+	return n.responseOK(&pb.Response{
+		Origin: n.id,
+		Ok:     true,
+		ResponseType: &pb.Response_GossipTokenMove{
+			GossipTokenMove: &pb.ResponseGossipTokenMove{},
+		},
+	})
+}
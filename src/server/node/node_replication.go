@@ -1,26 +1,53 @@
 package node
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sdle-server/config"
+	"sdle-server/logging"
 	"sdle-server/replication"
+	"sdle-server/ringview"
+	"sdle-server/storage"
+	"sdle-server/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attributes of the spans of coordinated operations
+const (
+	attrReplicas     = attribute.Key("sdle.replicas") // replicas written or read successfully (including hints)
+	attrIntendedNode = attribute.Key("sdle.intended_node")
 )
 
 // coordinateReplicatedPut orchestrates a replicated write operation.
 // This node acts as the coordinator and replicates the data to N nodes.
 // Strategy:
-// 1. Attempt to write to all N nodes in preference list
-// 2. For any failed nodes, use hinted handoff to ensure N total replicas
+// 1. Attempt to write to all N nodes in preference list (nodes suspected to be down are skipped)
+// 2. For any failed nodes, write to the next healthy nodes in the ring with a hint, to ensure N total replicas
 // 3. Return success if W writes succeed (sloppy quorum)
-func (n *Node) coordinateReplicatedPut(key string, value []byte) error {
+func (n *Node) coordinateReplicatedPut(ctx context.Context, key string, value []byte) (err error) {
+	defer func() { n.metrics.observeQuorum("put", err) }()
+	ctx, span := n.startSpan(ctx, "coordinate put", trace.WithAttributes(tracing.AttrKey.String(key)))
+	defer func() { tracing.Finish(span, err) }()
+	start := n.now()
+	logger := n.logger.With(logging.KeyKey, key)
+
+	// A malformed value is rejected before any replica, or hint, gets it
+	if err := n.validateValue(key, value); err != nil {
+		logger.Warn("Rejecting PUT", logging.Err(err))
+		return err
+	}
+
 	prefList := n.ringView.GetPreferenceList(key, n.replConfig.N)
 
 	if len(prefList.Nodes) == 0 {
-		n.logError("No nodes available for key '" + key + "'")
+		logger.Error("No nodes available for key")
 		return fmt.Errorf("no nodes available for key")
 	}
 
-	n.logInfo(fmt.Sprintf("Coordinating PUT for key '%s' to preference list: %v (N=%d, W=%d)",
-		key, prefList.Nodes, n.replConfig.N, n.replConfig.W))
+	logger.Debug("Coordinating PUT", "preference_list", prefList.Nodes, "n", n.replConfig.N, "w", n.replConfig.W)
 
 	successCount := 0
 	failedNodes := []string{}
@@ -28,23 +55,31 @@ func (n *Node) coordinateReplicatedPut(key string, value []byte) error {
 
 	// STEP 1: Attempt to write to ALL N nodes in preference list (don't stop early)
 	for _, nodeId := range prefList.Nodes {
+		// The caller gave up: neither the remaining replicas nor hints are written
+		if err := ctx.Err(); err != nil {
+			logger.Warn("Abandoning PUT", logging.Err(err))
+			return err
+		}
+
 		var err error
 
+		if nodeId != n.id && n.failures.IsSuspected(nodeId) {
+			logger.Debug("Skipping replica suspected to be down", logging.KeyPeer, nodeId)
+			failedNodes = append(failedNodes, nodeId)
+			continue
+		}
+
 		if nodeId == n.id {
 			// Write to local store
-			err = n.store.Put([]byte(key), value)
-			if err == nil {
-				n.logSuccess(fmt.Sprintf("Local write successful for key '%s'", key))
-			} else {
-				n.logError(fmt.Sprintf("Local write failed for key '%s': %v", key, err))
+			err = n.storeMerged(key, value)
+			if err != nil {
+				logger.Error("Local write failed", logging.Err(err))
 			}
 		} else {
 			// Write to remote replica
-			err = n.sendReplicaPut(nodeId, key, value)
-			if err == nil {
-				n.logSuccess(fmt.Sprintf("Replica write to %s successful for key '%s'", nodeId, key))
-			} else {
-				n.logError(fmt.Sprintf("Replica write to %s failed for key '%s': %v", nodeId, key, err))
+			err = n.sendReplicaPut(ctx, nodeId, key, value)
+			if err != nil {
+				logger.Warn("Replica write failed", logging.KeyPeer, nodeId, logging.Err(err))
 			}
 		}
 
@@ -56,114 +91,108 @@ func (n *Node) coordinateReplicatedPut(key string, value []byte) error {
 		}
 	}
 
-	n.logSuccess(fmt.Sprintf("Preference list writes: %d/%d successful", successCount, n.replConfig.N))
+	if err := ctx.Err(); err != nil {
+		logger.Warn("Abandoning PUT before hinted handoff", logging.Err(err))
+		return err
+	}
 
 	// STEP 2: Use hinted handoff for ALL failed nodes to ensure N total replicas
 	if len(failedNodes) > 0 {
-		n.logInfo(fmt.Sprintf("Attempting hinted handoff for %d failed nodes: %v",
-			len(failedNodes), failedNodes))
-
-		hintsStored := n.attemptHintedHandoff(key, value, failedNodes)
+		hintsStored := n.attemptHintedHandoff(ctx, key, value, failedNodes, prefList)
 		successCount += hintsStored
 
-		n.logInfo(fmt.Sprintf("Hinted handoff: stored %d/%d hints", hintsStored, len(failedNodes)))
+		logger.Info("Hinted handoff", "failed_nodes", failedNodes, "hints_stored", hintsStored)
 	}
 
 	// STEP 3: Final check - did we achieve W replicas?
+	span.SetAttributes(attrReplicas.Int(successCount))
 	if successCount >= n.replConfig.W {
-		n.logSuccess(fmt.Sprintf("SUCCESS: W=%d achieved with %d total replicas (N=%d)",
-			n.replConfig.W, successCount, n.replConfig.N))
+		logger.Debug("Write quorum achieved", "replicas", successCount, "w", n.replConfig.W,
+			logging.KeyDuration, n.now().Sub(start))
 		return nil
 	}
 
-	n.logError(fmt.Sprintf("FAILURE: W=%d not achieved - only %d/%d replicas stored",
-		n.replConfig.W, successCount, n.replConfig.N))
+	logger.Error("Write quorum not achieved", "replicas", successCount, "w", n.replConfig.W,
+		logging.KeyDuration, n.now().Sub(start))
 	return fmt.Errorf("%w: only %d/%d replicas achieved (W=%d required)",
 		replication.ErrInsufficientReplicas, successCount, n.replConfig.N, n.replConfig.W)
 }
 
-func (n *Node) sendAllHintedHandoffs() {
-	hints, _ := n.hintStore.GetAllHints()
-	if len(hints) == 0 {
+// Removes the hints whose TTL has passed and applies the configured expiry policy to them
+func (n *Node) expireHints(ctx context.Context) {
+	expired, err := n.hintStore.ExpireHints(n.now())
+	if err != nil {
+		n.logger.Error("Failed to expire hints", logging.Err(err))
+	}
+	if len(expired) == 0 {
 		return
 	}
-	n.logInfo(fmt.Sprintf("Starting hinted handoff delivery process - %d hints to deliver", len(hints)))
 
-	for _, hintList := range hints {
-		for _, hint := range hintList {
-			n.logInfo("Attempting to deliver hint for key " + hint.Key + " to node " + hint.IntendedNode)
-			err := n.sendReplicaPut(hint.IntendedNode, hint.Key, hint.Value)
+	n.logger.Warn("Hints expired", "count", len(expired), "policy", n.replConfig.HintExpiryPolicy)
 
-			if err != nil {
-				n.logError("Failed to deliver hint for key " + hint.Key + " to node " + hint.IntendedNode + ": " + err.Error())
+	if n.replConfig.HintExpiryPolicy != config.HintExpiryRedirect {
+		return // The hinted replicas stay in the local store until the scrubber relocates them
+	}
+
+	for _, hint := range expired {
+		for _, nodeId := range n.ringView.GetPreferenceList(hint.Key, n.replConfig.N).Nodes {
+			if nodeId == n.id || nodeId == hint.IntendedNode || n.failures.IsSuspected(nodeId) {
 				continue
 			}
 
-			n.logSuccess("Successfully delivered hint for key " + hint.Key + " to node " + hint.IntendedNode)
-			_ =
-				n.hintStore.DeleteHint(hint.IntendedNode, hint.Key)
+			if err := n.sendReplicaPut(ctx, nodeId, hint.Key, hint.Value); err != nil {
+				n.logger.Error("Failed to redirect expired hint", logging.KeyKey, hint.Key, logging.KeyPeer, nodeId, logging.Err(err))
+				continue
+			}
+			n.logger.Info("Redirected expired hint", logging.KeyKey, hint.Key, logging.KeyPeer, nodeId)
 		}
 	}
-	n.logInfo("Completed hinted handoff delivery process")
-
 }
 
-// Tries to store hints on additional nodes beyond the preference list
+// Writes the value, with a hint for the intended node, to the next healthy nodes in the ring after the preference list.
 // Returns the number of successful hint stores (counts toward W in sloppy quorum)
-func (n *Node) attemptHintedHandoff(key string, value []byte, failedNodes []string) int {
+func (n *Node) attemptHintedHandoff(ctx context.Context, key string, value []byte, failedNodes []string, prefList ringview.PreferenceList) (successCount int) {
 	if len(failedNodes) == 0 {
 		return 0
 	}
 
-	// Get all known nodes and find candidates for hinted handoff
-	allNodes := n.ringView.GetKnownIds()
-	prefList := n.ringView.GetPreferenceList(key, n.replConfig.N)
-
-	// Create set of nodes already in preference list
-	inPrefList := make(map[string]bool)
-	for _, nodeId := range prefList.Nodes {
-		inPrefList[nodeId] = true
-	}
+	ctx, span := n.startSpan(ctx, "hinted handoff", trace.WithAttributes(tracing.AttrKey.String(key),
+		attribute.StringSlice("sdle.failed_nodes", failedNodes)))
+	defer func() {
+		span.SetAttributes(attribute.Int("sdle.hints_stored", successCount))
+		span.End()
+	}()
 
-	// Find candidate nodes (not in preference list)
-	candidates := []string{}
-	for _, nodeId := range allNodes {
-		if !inPrefList[nodeId] && nodeId != n.id {
-			candidates = append(candidates, nodeId)
-		}
-	}
+	// Walk the ring past the preference list, skipping the nodes suspected to be down
+	candidates := prefList.HealthyFallbacks(n.failures.IsSuspected, len(prefList.Fallbacks))
 
 	if len(candidates) == 0 {
-		n.logError("No candidates available for hinted handoff")
+		n.logger.Error("No candidates available for hinted handoff", logging.KeyKey, key)
 		return 0
 	}
 
-	successCount := 0
 	candidateIdx := 0
 
-	// For each failed node, try to store a hint on a candidate node
+	// For each failed node, try to store a hint on the next candidate node (moving on if a candidate fails)
 	for _, failedNodeId := range failedNodes {
-		if candidateIdx >= len(candidates) {
-			break
-		}
-
-		candidateNodeId := candidates[candidateIdx]
-		candidateIdx++
-
 		hint := replication.Hint{
 			IntendedNode: failedNodeId,
 			Key:          key,
 			Value:        value,
 		}
 
-		// Try to send hint to candidate node
-		err := n.sendHintToNode(candidateNodeId, hint)
-		if err == nil {
-			n.logSuccess(fmt.Sprintf("Stored hint on %s for intended node %s (key: %s)",
-				candidateNodeId, failedNodeId, key))
-			successCount++
-		} else {
-			n.logError(fmt.Sprintf("✗ Failed to store hint on %s: %v", candidateNodeId, err))
+		for candidateIdx < len(candidates) {
+			candidateNodeId := candidates[candidateIdx]
+			candidateIdx++
+
+			err := n.sendHintToNode(ctx, candidateNodeId, hint)
+			if err == nil {
+				n.logger.Debug("Stored hint", logging.KeyKey, key, logging.KeyPeer, candidateNodeId, "intended_node", failedNodeId)
+				successCount++
+				break
+			}
+
+			n.logger.Warn("Failed to store hint", logging.KeyKey, key, logging.KeyPeer, candidateNodeId, logging.Err(err))
 		}
 	}
 
@@ -172,123 +201,193 @@ func (n *Node) attemptHintedHandoff(key string, value []byte, failedNodes []stri
 
 // sendReplicaPut sends a replica write request to a remote node
 // Uses ReplicaPut message type to bypass coordinator routing logic
-func (n *Node) sendReplicaPut(nodeId, key string, value []byte) error {
+func (n *Node) sendReplicaPut(ctx context.Context, nodeId, key string, value []byte) error {
 	nodeAddr := NodeIdToZMQAddr(nodeId)
-	_, err := n.sendReplicaPutRequest(nodeAddr, key, value)
+	_, err := n.sendReplicaPutRequest(ctx, nodeAddr, key, value)
 	return err
 }
 
 // sendHintToNode sends a hint to a remote node for storage
-func (n *Node) sendHintToNode(nodeId string, hint replication.Hint) error {
+func (n *Node) sendHintToNode(ctx context.Context, nodeId string, hint replication.Hint) (err error) {
+	ctx, span := n.startSpan(ctx, "store hint", trace.WithAttributes(tracing.AttrKey.String(hint.Key),
+		tracing.AttrPeer.String(nodeId), attrIntendedNode.String(hint.IntendedNode)))
+	defer func() { tracing.Finish(span, err) }()
+
 	// If it's this node, store locally
 	if nodeId == n.id {
-		return n.hintStore.StoreHint(hint)
+		return n.storeHintedReplica(hint)
 	}
 
 	// Send StoreHint request to the remote node
 	nodeAddr := NodeIdToZMQAddr(nodeId)
-	_, err := n.sendStoreHintRequest(nodeAddr, hint.IntendedNode, hint.Key, hint.Value)
+	_, err = n.sendStoreHintRequest(ctx, nodeAddr, hint.IntendedNode, hint.Key, hint.Value)
 	return err
 }
 
 // Orchestrates a replicated read operation.
 // This node acts as the coordinator and reads from R replicas.
+// While a node of the preference list is down, the next healthy nodes in the ring (which hold the hinted writes) are
+// read as well.
 // Returns the value after reading from R nodes (quorum read).
-func (n *Node) coordinateReplicatedGet(key string) ([]byte, error) {
+func (n *Node) coordinateReplicatedGet(ctx context.Context, key string) (_ []byte, err error) {
+	defer func() { n.metrics.observeQuorum("get", err) }()
+	ctx, span := n.startSpan(ctx, "coordinate get", trace.WithAttributes(tracing.AttrKey.String(key)))
+	defer func() { tracing.Finish(span, err) }()
+	start := n.now()
+	logger := n.logger.With(logging.KeyKey, key)
+
 	prefList := n.ringView.GetPreferenceList(key, n.replConfig.N)
 
 	if len(prefList.Nodes) == 0 {
-		n.logError("No nodes available for key '" + key + "'")
+		logger.Error("No nodes available for key")
 		return nil, fmt.Errorf("no nodes available for key")
 	}
 
-	n.logInfo(fmt.Sprintf("Coordinating GET for key '%s' from preference list: %v (R=%d)",
-		key, prefList.Nodes, n.replConfig.R))
+	logger.Debug("Coordinating GET", "preference_list", prefList.Nodes, "r", n.replConfig.R)
 
 	type readResult struct {
-		nodeId string
-		value  []byte
-		err    error
+		nodeId  string
+		primary bool // whether the node is in the preference list, rather than a fallback
+		value   []byte
+		err     error
 	}
 
 	results := []readResult{}
+	failedPrimaries := 0
+
+	// Reads a single replica and checks if we have R successful reads
+	read := func(nodeId string, primary bool) bool {
+		// The caller gave up: no more replicas are read
+		if ctx.Err() != nil {
+			return false
+		}
 
-	// Attempt to read from nodes in preference list until we get R successful reads
-	for _, nodeId := range prefList.Nodes {
 		var value []byte
 		var err error
 
 		if nodeId == n.id {
 			// Read from local store
 			value, err = n.store.Get([]byte(key))
-			if err == nil {
-				n.logSuccess(fmt.Sprintf("Local read successful for key '%s'", key))
-			} else {
-				n.logError(fmt.Sprintf("Local read failed for key '%s': %v", key, err))
+			if err != nil {
+				logger.Debug("Local read failed", logging.Err(err))
 			}
 		} else {
 			// Read from remote replica
-			value, err = n.sendReplicaGet(nodeId, key)
-			if err == nil {
-				n.logSuccess(fmt.Sprintf("Replica read from %s successful for key '%s'", nodeId, key))
-			} else {
-				n.logError(fmt.Sprintf("Replica read from %s failed for key '%s': %v", nodeId, key, err))
+			value, err = n.sendReplicaGet(ctx, nodeId, key)
+			if err != nil {
+				logger.Debug("Replica read failed", logging.KeyPeer, nodeId, logging.Err(err))
 			}
 		}
 
 		// Store result regardless of success/failure
 		results = append(results, readResult{
-			nodeId: nodeId,
-			value:  value,
-			err:    err,
+			nodeId:  nodeId,
+			primary: primary,
+			value:   value,
+			err:     err,
 		})
 
-		// Check if we have R successful reads
 		successCount := 0
 		for _, r := range results {
 			if r.err == nil {
 				successCount++
 			}
 		}
+		return successCount >= n.replConfig.R
+	}
 
-		if successCount >= n.replConfig.R {
-			n.logSuccess(fmt.Sprintf("Read quorum R=%d achieved", n.replConfig.R))
+	// Attempt to read from nodes in preference list until we get R successful reads
+	quorum := false
+	for _, nodeId := range prefList.Nodes {
+		if nodeId != n.id && n.failures.IsSuspected(nodeId) {
+			logger.Debug("Skipping replica suspected to be down", logging.KeyPeer, nodeId)
+			failedPrimaries++
+			continue
+		}
 
-			// Maybe we should do read repair here if values differ?
-			// For now, just return the first successful value
-			for _, r := range results {
-				if r.err == nil {
-					return r.value, nil
-				}
+		if quorum = read(nodeId, true); quorum {
+			break
+		}
+	}
+
+	// A primary is down: its writes went to the next healthy nodes in the ring, so read from them too
+	for _, r := range results {
+		if r.err != nil {
+			failedPrimaries++
+		}
+	}
+
+	if !quorum && failedPrimaries > 0 {
+		for _, nodeId := range prefList.HealthyFallbacks(n.failures.IsSuspected, failedPrimaries) {
+			logger.Debug("Reading from fallback node", logging.KeyPeer, nodeId)
+			if quorum = read(nodeId, false); quorum {
+				break
 			}
 		}
 	}
 
-	// Count successful reads
-	successCount := 0
-	var lastValue []byte
+	if err := ctx.Err(); err != nil {
+		logger.Warn("Abandoning GET", logging.Err(err))
+		return nil, err
+	}
+
+	// Count successful reads. Replicas may have received different deltas, so shopping lists are joined.
+	// Fallbacks only hold the writes their primaries missed, so only primaries that miss the key count as answers:
+	// otherwise a key whose primaries are down (e.g. the ACL of a list) could be reported missing, and overwritten.
+	successCount, notFoundCount := 0, 0
+	var value []byte
 	for _, r := range results {
-		if r.err == nil {
-			successCount++
-			lastValue = r.value
+		if r.primary && isNotFound(r.err) {
+			notFoundCount++
 		}
+		if r.err != nil {
+			continue
+		}
+
+		if successCount == 0 {
+			value = r.value
+		} else if merged, err := n.mergeValues(key, value, r.value); err == nil {
+			value = merged
+		} else {
+			logger.Warn("Failed to merge read", logging.KeyPeer, r.nodeId, logging.Err(err))
+		}
+		successCount++
 	}
 
+	span.SetAttributes(attrReplicas.Int(successCount))
 	if successCount >= n.replConfig.R {
-		return lastValue, nil
+		logger.Debug("Read quorum achieved", "reads", successCount, "r", n.replConfig.R,
+			logging.KeyDuration, n.now().Sub(start))
+		return value, nil
 	}
 
-	n.logError(fmt.Sprintf("Read quorum R=%d not achieved - only %d/%d reads succeeded",
-		n.replConfig.R, successCount, n.replConfig.R))
+	// Primaries that do not store the key answered too: the key is missing only if R of them lack it and no replica
+	// has it
+	if successCount+notFoundCount >= n.replConfig.R {
+		logger.Debug("Read quorum achieved", "reads", successCount, "not_found", notFoundCount, "r", n.replConfig.R,
+			logging.KeyDuration, n.now().Sub(start))
+		if successCount == 0 {
+			return nil, fmt.Errorf("%w: %s", replication.ErrNotFound, key)
+		}
+		return value, nil
+	}
+
+	logger.Error("Read quorum not achieved", "reads", successCount, "r", n.replConfig.R,
+		logging.KeyDuration, n.now().Sub(start))
 	return nil, fmt.Errorf("%w: only %d/%d reads succeeded",
 		replication.ErrQuorumNotMet, successCount, n.replConfig.R)
 }
 
+// Whether a read failed because the replica does not store the key, rather than because it could not answer
+func isNotFound(err error) bool {
+	return errors.Is(err, storage.ErrNotFound) || errors.Is(err, replication.ErrNotFound)
+}
+
 // sendReplicaGet sends a replica read request to a remote node
-func (n *Node) sendReplicaGet(nodeId, key string) ([]byte, error) {
+func (n *Node) sendReplicaGet(ctx context.Context, nodeId, key string) ([]byte, error) {
 	nodeAddr := NodeIdToZMQAddr(nodeId)
 
-	resp, err := n.sendReplicaGetRequest(nodeAddr, key)
+	resp, err := n.sendReplicaGetRequest(ctx, nodeAddr, key)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,141 @@
+package node
+
+import (
+	"bytes"
+	"sdle-server/logging"
+	"slices"
+	"time"
+)
+
+// Walks the local store and fixes the placement of every key according to the current ring view:
+//   - keys this node is responsible for are pushed to the other replicas of the preference list that miss them
+//     (e.g. a node that joined only imported the range of the previous primary owner);
+//   - keys this node is no longer responsible for are pushed to their rightful owners, and deleted locally once every
+//     owner holds them (and only if the key did not change since). Merged keys are pushed to every owner, which merges
+//     them with its own copy; opaque keys are only pushed to the owners that miss them, since they would overwrite it.
+func (n *Node) scrub() {
+	// Rounds can be triggered through the admin API as well as by the timer
+	if !n.scrubbing.CompareAndSwap(false, true) {
+		n.logger.Info("Scrub: a scrub is already running, skipped")
+		return
+	}
+	defer n.scrubbing.Store(false)
+
+	keys, err := n.store.GetKeys()
+	if err != nil {
+		n.logger.Error("Scrub: failed to list local keys", logging.Err(err))
+		return
+	}
+
+	begin := n.now()
+	n.logger.Info("Scrub: checking ownership of local keys", "keys", len(keys))
+
+	limiter := time.NewTicker(time.Second / time.Duration(n.replConfig.ScrubKeyRate))
+	defer limiter.Stop()
+
+	pushed, relocated := 0, 0
+
+	for _, key := range keys {
+		select {
+		case <-n.stopCh:
+			n.logger.Info("Scrub: node stopping, scrub aborted")
+			return
+		case <-limiter.C:
+		}
+
+		prefList := n.ringView.GetPreferenceList(key, n.replConfig.N)
+		if len(prefList.Nodes) == 0 {
+			continue
+		}
+
+		if slices.Contains(prefList.Nodes, n.id) {
+			_, missing := n.scrubKey(key, nil, prefList.Nodes, false)
+			pushed += missing
+			continue
+		}
+
+		// The local copy may be newer than the ones of the owners (e.g. it took writes as a fallback): a merged key is
+		// pushed to all of them, and the copy is only dropped once every one of them holds the key
+		value, err := n.store.Get([]byte(key))
+		if err != nil {
+			n.logger.Error("Scrub: failed to read key", logging.KeyKey, key, logging.Err(err))
+			continue
+		}
+		stored, sent := n.scrubKey(key, value, prefList.Nodes, isMergedKey(key))
+		pushed += sent
+		if stored < len(prefList.Nodes) {
+			n.logger.Warn("Scrub: keeping misplaced key, not every owner stored it", logging.KeyKey, key,
+				"stored", stored, "owners", len(prefList.Nodes))
+			continue
+		}
+
+		if n.deleteRelocated(key, value) {
+			relocated++
+		}
+	}
+
+	n.logger.Info("Scrub finished", "pushed", pushed, "relocated", relocated, logging.KeyDuration, n.now().Sub(begin))
+}
+
+// Deletes a relocated key, unless a write stored it since it was read to be pushed: the owners would miss that write
+func (n *Node) deleteRelocated(key string, pushed []byte) bool {
+	n.mergeMu.Lock()
+	defer n.mergeMu.Unlock()
+
+	current, err := n.store.Get([]byte(key))
+	if err != nil {
+		n.logger.Error("Scrub: failed to read misplaced key", logging.KeyKey, key, logging.Err(err))
+		return false
+	}
+	if !bytes.Equal(current, pushed) {
+		n.logger.Info("Scrub: keeping misplaced key, it changed while being pushed", logging.KeyKey, key)
+		return false
+	}
+
+	if err := n.store.Delete([]byte(key)); err != nil {
+		n.logger.Error("Scrub: failed to delete misplaced key", logging.KeyKey, key, logging.Err(err))
+		return false
+	}
+	return true
+}
+
+// Makes sure every owner of the key holds it. The local value (read from the store if nil) is pushed to every owner
+// if always is set, and otherwise only to the ones that miss it. Returns how many owners (other than this node) hold
+// the key (for pushes, once they stored it) and how many replicas were pushed.
+func (n *Node) scrubKey(key string, value []byte, owners []string, always bool) (confirmed int, pushed int) {
+	for _, ownerId := range owners {
+		if ownerId == n.id {
+			continue
+		}
+
+		if !always {
+			resp, err := n.sendHas(n.ctx, NodeIdToZMQAddr(ownerId), key)
+			if err != nil {
+				continue // Owner unreachable, try again on the next scrub
+			}
+			if resp.GetHas().GetHasKey() {
+				confirmed++
+				continue
+			}
+		}
+
+		if value == nil {
+			var err error
+			if value, err = n.store.Get([]byte(key)); err != nil {
+				n.logger.Error("Scrub: failed to read key", logging.KeyKey, key, logging.Err(err))
+				return confirmed, pushed
+			}
+		}
+
+		if err := n.sendReplicaPut(n.ctx, ownerId, key, value); err != nil {
+			n.logger.Warn("Scrub: failed to push key", logging.KeyKey, key, logging.KeyPeer, ownerId, logging.Err(err))
+			continue
+		}
+
+		n.logger.Info("Scrub: pushed replica", logging.KeyKey, key, logging.KeyPeer, ownerId)
+		confirmed++
+		pushed++
+	}
+
+	return confirmed, pushed
+}
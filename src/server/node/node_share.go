@@ -0,0 +1,135 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sdle-server/auth"
+	generic "sdle-server/crdt/generic"
+	"sdle-server/logging"
+	pb "sdle-server/proto"
+	"sdle-server/replication"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Prefix of the keys under which the revoked share capabilities of shopping lists are stored
+const revokedKeyPrefix = "revoked_"
+
+// Key under which the revoked share capabilities of a shopping list are stored
+func RevokedCapabilitiesKey(listID string) string {
+	return revokedKeyPrefix + listID
+}
+
+func decodeRevokedCapabilities(data []byte) (*pb.RevokedCapabilities, error) {
+	var revoked pb.RevokedCapabilities
+	if err := proto.Unmarshal(data, &revoked); err != nil {
+		return nil, fmt.Errorf("%w: %v", generic.ErrMalformed, err)
+	}
+	return &revoked, nil
+}
+
+// Merges two sets of revoked capabilities by union. Capabilities that expired are dropped, as they can no longer be
+// presented anyway.
+func mergeRevokedCapabilities(stored []byte, incoming []byte, now time.Time) ([]byte, error) {
+	merged, err := decodeRevokedCapabilities(stored)
+	if err != nil {
+		return nil, fmt.Errorf("stored revoked capabilities: %w", err)
+	}
+	incomingSet, err := decodeRevokedCapabilities(incoming)
+	if err != nil {
+		return nil, err
+	}
+
+	if merged.Expires == nil {
+		merged.Expires = make(map[string]int64)
+	}
+	for id, expires := range incomingSet.GetExpires() {
+		merged.Expires[id] = expires
+	}
+	for id, expires := range merged.Expires {
+		if expires != 0 && expires <= now.Unix() {
+			delete(merged.Expires, id)
+		}
+	}
+
+	return proto.MarshalOptions{Deterministic: true}.Marshal(merged)
+}
+
+// Checks whether a capability was revoked, through a quorum read. A failed read is an error, so a revoked capability
+// is never accepted because its revocation could not be read.
+func (n *Node) isRevoked(ctx context.Context, c auth.Capability) (bool, error) {
+	data, err := n.Get(ctx, RevokedCapabilitiesKey(c.ListID))
+	if errors.Is(err, replication.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reading the revoked capabilities of list '%s': %w", c.ListID, err)
+	}
+
+	revoked, err := decodeRevokedCapabilities(data)
+	if err != nil {
+		return false, err
+	}
+	_, ok := revoked.GetExpires()[c.ID]
+	return ok, nil
+}
+
+// Checks whether a verified capability grants an access to a list
+func (n *Node) authorizeCapability(ctx context.Context, c auth.Capability, listID string, access listAccess) error {
+	switch {
+	case c.ListID != listID:
+		return fmt.Errorf("%w: capability is for list '%s', not '%s'", auth.ErrForbidden, c.ListID, listID)
+	case access == accessAdmin, access == accessWrite && !c.CanWrite():
+		return fmt.Errorf("%w: capability grants %s access to list '%s'", auth.ErrForbidden, c.Access, listID)
+	}
+
+	revoked, err := n.isRevoked(ctx, c)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return fmt.Errorf("%w: capability of list '%s' was revoked", auth.ErrForbidden, listID)
+	}
+	return nil
+}
+
+// Checks that the user of a request may share a list, that is, that they own it. A list without an ACL becomes owned
+// by the user, so that the capabilities they share are the only way for others to access it.
+func (n *Node) ShareList(ctx context.Context, listID string) error {
+	if _, ok := auth.User(ctx); !ok {
+		return fmt.Errorf("%w: only the owner of a list may share it", auth.ErrUnauthenticated)
+	}
+
+	acl, err := n.authorizeList(ctx, listID, accessAdmin)
+	if err != nil {
+		return err
+	}
+	if acl == nil {
+		_, err = n.claimList(ctx, listID)
+	}
+	return err
+}
+
+// Revokes a share capability. Only the owner of its list may.
+func (n *Node) RevokeCapability(ctx context.Context, c auth.Capability) error {
+	user, ok := auth.User(ctx)
+	if !ok {
+		return fmt.Errorf("%w: only the owner of a list may revoke its capabilities", auth.ErrUnauthenticated)
+	}
+	if _, err := n.authorizeList(ctx, c.ListID, accessAdmin); err != nil {
+		return err
+	}
+
+	// Replicas merge the set by union, so writing the revoked capability alone adds it to the set
+	data, err := proto.Marshal(&pb.RevokedCapabilities{ListId: c.ListID, Expires: map[string]int64{c.ID: c.Expires}})
+	if err != nil {
+		return err
+	}
+	if err := n.Put(ctx, RevokedCapabilitiesKey(c.ListID), data); err != nil {
+		return err
+	}
+	n.logger.Info("Capability revoked", "list_id", c.ListID, logging.KeyUser, user, "capability", c.ID)
+	return nil
+}
@@ -1,53 +1,160 @@
 package node
 
 import (
+	"context"
 	"fmt"
+	"sdle-server/communication"
+	generic "sdle-server/crdt/generic"
 	crdt "sdle-server/crdt/shopping"
+	"sdle-server/logging"
 	pb "sdle-server/proto"
+	"sdle-server/tracing"
+	"strings"
 
-	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/proto"
 )
 
-func (n *Node) HandleShoppingList(delta *crdt.ShoppingList) error {
-	n.logInfo(fmt.Sprintf("Received shopping list %s", delta.ListID()))
+// Prefix of the keys under which shopping lists are stored
+const shoppingListKeyPrefix = "shoppinglist_"
 
-	var oldList *crdt.ShoppingList
+// Key under which a shopping list is stored
+func ShoppingListKey(listID string) string {
+	return shoppingListKeyPrefix + listID
+}
 
-	// Use distributed GET instead of direct store access
-	if oldListData, err := n.Get("shoppinglist_" + delta.ListID()); err == nil {
-		var oldListProto pb.ShoppingList
+// Decodes a stored or received shopping list. Malformed payloads are rejected with an error wrapping
+// generic.ErrMalformed.
+func decodeShoppingList(data []byte, replicaID string) (*crdt.ShoppingList, error) {
+	var listProto pb.ShoppingList
+	if err := proto.Unmarshal(data, &listProto); err != nil {
+		return nil, fmt.Errorf("%w: %v", generic.ErrMalformed, err)
+	}
+	return crdt.ShoppingListFromProto(&listProto, replicaID)
+}
+
+// Checks a value before it is written: shopping lists, their ACLs and their revoked capabilities must decode. Other
+// values are opaque.
+func (n *Node) validateValue(key string, value []byte) error {
+	switch {
+	case strings.HasPrefix(key, shoppingListKeyPrefix):
+		_, err := decodeShoppingList(value, n.id)
+		return err
+	case strings.HasPrefix(key, listAclKeyPrefix):
+		_, err := decodeListAcl(value)
+		return err
+	case strings.HasPrefix(key, revokedKeyPrefix):
+		_, err := decodeRevokedCapabilities(value)
+		return err
+	}
+	return nil
+}
+
+// Whether the values of a key are merged with the stored one instead of overwriting it
+func isMergedKey(key string) bool {
+	return strings.HasPrefix(key, shoppingListKeyPrefix) || strings.HasPrefix(key, listAclKeyPrefix) ||
+		strings.HasPrefix(key, revokedKeyPrefix)
+}
+
+// Writes a value to the local store. Shopping lists (and their ACLs and revoked capabilities) are merged with the
+// stored state instead of overwriting it, so replica writes, imports and reconciliations never lose concurrent updates.
+func (n *Node) storeMerged(key string, value []byte) error {
+	// Held for every key, so the scrubber can delete a key it relocated without losing a write stored in the meantime
+	n.mergeMu.Lock()
+	defer n.mergeMu.Unlock()
+
+	if !isMergedKey(key) {
+		return n.store.Put([]byte(key), value)
+	}
+
+	has, err := n.store.Has([]byte(key))
+	if err != nil {
+		return err
+	}
+	if !has {
+		return n.store.Put([]byte(key), value)
+	}
+
+	storedData, err := n.store.Get([]byte(key))
+	if err != nil {
+		return err
+	}
 
-		proto.Unmarshal(oldListData, &oldListProto)
-		oldList = crdt.ShoppingListFromProto(&oldListProto, n.id)
-	} else {
-		oldList = crdt.NewShoppingList(n.id, delta.ListID())
+	mergedData, err := n.mergeValues(key, storedData, value)
+	if err != nil {
+		return err
+	}
+
+	return n.store.Put([]byte(key), mergedData)
+}
+
+// Merges two values written for the same key. Shopping lists are joined, the latest version of an ACL wins, sets of
+// revoked capabilities are united, and any other value is overwritten.
+func (n *Node) mergeValues(key string, stored []byte, incoming []byte) ([]byte, error) {
+	if strings.HasPrefix(key, listAclKeyPrefix) {
+		return mergeListAcls(stored, incoming)
+	}
+	if strings.HasPrefix(key, revokedKeyPrefix) {
+		return mergeRevokedCapabilities(stored, incoming, n.now())
+	}
+	if !strings.HasPrefix(key, shoppingListKeyPrefix) {
+		return incoming, nil
+	}
+
+	merged, err := decodeShoppingList(stored, n.id)
+	if err != nil {
+		return nil, fmt.Errorf("stored value of key '%s': %w", key, err)
+	}
+	incomingList, err := decodeShoppingList(incoming, n.id)
+	if err != nil {
+		return nil, err
 	}
+	merged.Join(incomingList)
+
+	return proto.Marshal(merged.ToProto())
+}
 
-	oldList.Join(delta)
+// Writes the delta of a shopping list, if the user of the request may write the list. The first authenticated user to
+// write a list without an ACL becomes its owner.
+func (n *Node) HandleShoppingList(ctx context.Context, delta *crdt.ShoppingList) error {
+	n.logger.Debug("Received shopping list", "list_id", delta.ListID())
 
-	newListProto := oldList.ToProto()
-	newListData, err := proto.Marshal(newListProto)
+	acl, err := n.authorizeList(ctx, delta.ListID(), accessWrite)
+	if err != nil {
+		return err
+	}
+	if acl == nil {
+		if _, err := n.claimList(ctx, delta.ListID()); err != nil {
+			return err
+		}
+	}
 
+	deltaData, err := proto.Marshal(delta.ToProto())
 	if err != nil {
 		return err
 	}
 
-	// Use distributed PUT instead of direct store access
-	if err := n.Put("shoppinglist_"+delta.ListID(), newListData); err != nil {
+	// Only the delta is replicated: replicas join it with their state
+	if err := n.PutDelta(ctx, ShoppingListKey(delta.ListID()), deltaData); err != nil {
 		return err
 	}
 
+	_, span := n.startSpan(ctx, "notify subscribers", trace.WithAttributes(tracing.AttrListID.String(delta.ListID())))
 	n.subController.NotifySubscribers(delta.ListID(), *delta)
+	span.End()
 
 	return nil
 }
 
-func (n *Node) GetShoppingList(listID string) (*pb.ShoppingList, error) {
-	n.logInfo(fmt.Sprintf("Getting shopping list %s", listID))
+func (n *Node) GetShoppingList(ctx context.Context, listID string) (*pb.ShoppingList, error) {
+	n.logger.Debug("Getting shopping list", "list_id", listID)
+
+	if _, err := n.authorizeList(ctx, listID, accessRead); err != nil {
+		return nil, err
+	}
 
 	// Use distributed GET instead of direct store access
-	listData, err := n.Get("shoppinglist_" + listID)
+	listData, err := n.Get(ctx, ShoppingListKey(listID))
 	if err != nil {
 		return nil, err
 	}
@@ -60,14 +167,22 @@ func (n *Node) GetShoppingList(listID string) (*pb.ShoppingList, error) {
 	return &listProto, nil
 }
 
-func (n *Node) SubscribeShoppingList(listID string, messageID string, conn *websocket.Conn) error {
-	n.logInfo(fmt.Sprintf("Handling subscribe shopping list %s", listID))
+// Subscribes a connection to the deltas of a list, if the user of the request may read it. Access is only checked
+// here: a subscriber removed from the ACL keeps receiving deltas until it unsubscribes.
+func (n *Node) SubscribeShoppingList(ctx context.Context, listID string, messageID string, conn *communication.Connection) error {
+	n.logger.Debug("Subscribing to shopping list", "list_id", listID, logging.KeyMessageID, messageID)
+
+	if _, err := n.authorizeList(ctx, listID, accessRead); err != nil {
+		return err
+	}
 	n.subController.AddSubscriber(listID, messageID, conn)
 	return nil
 }
 
-func (n *Node) UnsubscribeShoppingList(listID string, messageID string) error {
-	n.logInfo(fmt.Sprintf("Handling unsubscribe shopping list %s", listID))
-	n.subController.RemoveSubscriber(listID, messageID)
+// Unsubscribes a connection from a list. Message IDs are chosen by clients, so only the subscription of that
+// connection is dropped.
+func (n *Node) UnsubscribeShoppingList(listID string, messageID string, conn *communication.Connection) error {
+	n.logger.Debug("Unsubscribing from shopping list", "list_id", listID, logging.KeyMessageID, messageID)
+	n.subController.RemoveSubscriber(listID, messageID, conn)
 	return nil
 }
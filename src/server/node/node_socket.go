@@ -1,73 +1,86 @@
 package node
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"time"
 
-	"sdle-server/config"
+	generic "sdle-server/crdt/generic"
 	pb "sdle-server/proto"
+	"sdle-server/replication"
+	"sdle-server/storage"
+	"sdle-server/tracing"
 
-	"github.com/pebbe/zmq4"
-	"google.golang.org/protobuf/proto"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func (n *Node) sendRequest(peerAddr string, request proto.Message, timeout time.Duration) (*pb.Response, error) {
-	reqSock, err := zmq4.NewSocket(zmq4.REQ)
-	if err != nil {
+// Sends a request to a peer and waits for its response, for at most RequestTimeout and never past the deadline of ctx.
+// The time left travels with the request, so the peer gives up on it when this node does. Transport failures are
+// reported to the failure detector.
+func (n *Node) sendRequest(ctx context.Context, peerAddr string, request *pb.Request) (resp *pb.Response, err error) {
+	// The caller already gave up: nothing is sent, and the peer is not to blame
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	defer reqSock.Close()
 
-	if err := reqSock.Connect(peerAddr); err != nil {
-		return nil, err
-	}
+	peerId := ZMQAddrToNodeId(peerAddr)
+	ctx, span := n.startSpan(ctx, "send "+requestTypeName(request), trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(tracing.AttrPeer.String(peerId)))
+	defer func() { tracing.Finish(span, err) }()
+	injectTrace(ctx, request)
 
-	if timeout > 0 {
-		_ = reqSock.SetRcvtimeo(timeout)
-		_ = reqSock.SetSndtimeo(timeout)
+	hopCtx, cancel := context.WithTimeout(ctx, n.replConfig.RequestTimeout)
+	defer cancel()
+	if deadline, ok := hopCtx.Deadline(); ok {
+		request.TimeoutMs = max(time.Until(deadline).Milliseconds(), 1)
 	}
 
-	buffer, _ := proto.Marshal(request)
-	if _, err := reqSock.SendBytes(buffer, 0); err != nil {
-		return nil, err
-	}
-	responseBytes, err := reqSock.RecvBytes(0)
-	if err != nil {
-		return nil, err
-	}
+	start := time.Now()
+	resp, err = n.transport.Send(hopCtx, peerId, request)
+	n.metrics.observePeerRequest(ctx, requestTypeName(request), resp, err, time.Since(start))
 
-	var resp pb.Response
-	if err := proto.Unmarshal(responseBytes, &resp); err != nil {
+	if resp == nil && err != nil {
+		if ctx.Err() == nil {
+			n.failures.ReportFailure(peerId)
+		}
 		return nil, err
 	}
+	n.failures.ReportSuccess(peerId)
 
 	if !resp.Ok {
-		return &resp, errors.New(resp.Error)
+		switch resp.GetFailure() {
+		case pb.Failure_FAILURE_MALFORMED:
+			return resp, fmt.Errorf("%w: %s", generic.ErrMalformed, resp.Error)
+		case pb.Failure_FAILURE_NOT_FOUND:
+			return resp, fmt.Errorf("%w: %s", replication.ErrNotFound, resp.Error)
+		}
+		return resp, errors.New(resp.Error)
 	}
-	return &resp, nil
+	return resp, nil
 }
 
-func (n *Node) sendPing(peerAddr string) (*pb.Response, error) {
+func (n *Node) sendPing(ctx context.Context, peerAddr string) (*pb.Response, error) {
 	pingReq := &pb.Request{
 		Origin: n.addr,
 		RequestType: &pb.Request_Ping{
 			Ping: &pb.RequestPing{},
 		},
 	}
-	return n.sendRequest(peerAddr, pingReq, config.DefaultConfig().RequestTimeout)
+	return n.sendRequest(ctx, peerAddr, pingReq)
 }
 
-func (n *Node) sendFetchRing(peerAddr string) (*pb.Response, error) {
+func (n *Node) sendFetchRing(ctx context.Context, peerAddr string) (*pb.Response, error) {
 	req := &pb.Request{
 		Origin: n.addr,
 		RequestType: &pb.Request_FetchRing{
 			FetchRing: &pb.RequestFetchRing{},
 		},
 	}
-	return n.sendRequest(peerAddr, req, config.DefaultConfig().RequestTimeout)
+	return n.sendRequest(ctx, peerAddr, req)
 }
 
-func (n *Node) sendGetHashSpace(peerAddr string, startHashSpace uint64, endHashSpace uint64) (*pb.Response, error) {
+func (n *Node) sendGetHashSpace(ctx context.Context, peerAddr string, startHashSpace uint64, endHashSpace uint64) (*pb.Response, error) {
 	req := &pb.Request{
 		Origin: n.addr,
 		RequestType: &pb.Request_GetHashSpace{
@@ -77,83 +90,141 @@ func (n *Node) sendGetHashSpace(peerAddr string, startHashSpace uint64, endHashS
 			},
 		},
 	}
-	return n.sendRequest(peerAddr, req, config.DefaultConfig().RequestTimeout)
+	return n.sendRequest(ctx, peerAddr, req)
 }
 
-func (n *Node) sendJoinGossip(peerAddr string, newNodeID string, tokens []uint64) (*pb.Response, error) {
+func (n *Node) sendJoinGossip(ctx context.Context, peerAddr string, newNodeID string, tokens []uint64, generation uint64) (*pb.Response, error) {
 	req := &pb.Request{
 		Origin: n.addr,
 		RequestType: &pb.Request_GossipJoin{
 			GossipJoin: &pb.RequestGossipJoin{
-				NewNodeId: newNodeID,
-				Tokens:    tokens,
+				NewNodeId:  newNodeID,
+				Tokens:     tokens,
+				Generation: generation,
+			},
+		},
+	}
+	n.metrics.gossip.Inc("join", "sent")
+	return n.sendRequest(ctx, peerAddr, req)
+}
+
+func (n *Node) sendGossipTokenMove(ctx context.Context, peerAddr string, nodeID string, oldToken uint64, newToken uint64, generation uint64) (*pb.Response, error) {
+	req := &pb.Request{
+		Origin: n.addr,
+		RequestType: &pb.Request_GossipTokenMove{
+			GossipTokenMove: &pb.RequestGossipTokenMove{
+				NodeId:     nodeID,
+				OldToken:   oldToken,
+				NewToken:   newToken,
+				Generation: generation,
 			},
 		},
 	}
-	return n.sendRequest(peerAddr, req, config.DefaultConfig().RequestTimeout)
+	n.metrics.gossip.Inc("token_move", "sent")
+	return n.sendRequest(ctx, peerAddr, req)
+}
+
+func (n *Node) sendTokenLoads(ctx context.Context, peerAddr string) (*pb.Response, error) {
+	req := &pb.Request{
+		Origin: n.id,
+		RequestType: &pb.Request_TokenLoads{
+			TokenLoads: &pb.RequestTokenLoads{},
+		},
+	}
+	return n.sendRequest(ctx, peerAddr, req)
 }
 
-func (n *Node) sendGet(peerAddr string, key string) (*pb.Response, error) {
+func (n *Node) sendGet(ctx context.Context, peerAddr string, key string) (*pb.Response, error) {
 	req := &pb.Request{
 		Origin: n.id,
 		RequestType: &pb.Request_Get{
 			Get: &pb.RequestGet{Key: key},
 		},
 	}
-	return n.sendRequest(peerAddr, req, config.DefaultConfig().RequestTimeout)
+	return n.sendRequest(ctx, peerAddr, req)
 }
 
-func (n *Node) sendPut(peerAddr string, key string, value []byte) (*pb.Response, error) {
+func (n *Node) sendPut(ctx context.Context, peerAddr string, key string, value []byte) (*pb.Response, error) {
 	req := &pb.Request{
 		Origin: n.id,
 		RequestType: &pb.Request_Put{
 			Put: &pb.RequestPut{Key: key, Value: value},
 		},
 	}
-	return n.sendRequest(peerAddr, req, config.DefaultConfig().RequestTimeout)
+	return n.sendRequest(ctx, peerAddr, req)
 }
 
-func (n *Node) sendDelete(peerAddr string, key string) (*pb.Response, error) {
+func (n *Node) sendDelete(ctx context.Context, peerAddr string, key string) (*pb.Response, error) {
 	req := &pb.Request{
 		Origin: n.id,
 		RequestType: &pb.Request_Delete{
 			Delete: &pb.RequestDelete{Key: key},
 		},
 	}
-	return n.sendRequest(peerAddr, req, config.DefaultConfig().RequestTimeout)
+	return n.sendRequest(ctx, peerAddr, req)
 }
 
-func (n *Node) sendHas(peerAddr string, key string) (*pb.Response, error) {
+func (n *Node) sendHas(ctx context.Context, peerAddr string, key string) (*pb.Response, error) {
 	req := &pb.Request{
 		Origin: n.id,
 		RequestType: &pb.Request_Has{
 			Has: &pb.RequestHas{Key: key},
 		},
 	}
-	return n.sendRequest(peerAddr, req, config.DefaultConfig().RequestTimeout)
+	return n.sendRequest(ctx, peerAddr, req)
 }
 
-func (n *Node) sendReplicaPutRequest(peerAddr string, key string, value []byte) (*pb.Response, error) {
+func (n *Node) sendReplicaPutRequest(ctx context.Context, peerAddr string, key string, value []byte) (*pb.Response, error) {
 	req := &pb.Request{
 		Origin: n.id,
 		RequestType: &pb.Request_ReplicaPut{
 			ReplicaPut: &pb.RequestReplicaPut{Key: key, Value: value},
 		},
 	}
-	return n.sendRequest(peerAddr, req, config.DefaultConfig().RequestTimeout)
+	return n.sendRequest(ctx, peerAddr, req)
+}
+
+func (n *Node) sendReplicaPutBatchRequest(ctx context.Context, peerAddr string, entries []*pb.RequestReplicaPut) (*pb.Response, error) {
+	req := &pb.Request{
+		Origin: n.id,
+		RequestType: &pb.Request_ReplicaPutBatch{
+			ReplicaPutBatch: &pb.RequestReplicaPutBatch{Entries: entries},
+		},
+	}
+	return n.sendRequest(ctx, peerAddr, req)
+}
+
+func (n *Node) sendPutDelta(ctx context.Context, peerAddr string, key string, delta []byte) (*pb.Response, error) {
+	req := &pb.Request{
+		Origin: n.id,
+		RequestType: &pb.Request_PutDelta{
+			PutDelta: &pb.RequestPutDelta{Key: key, Delta: delta},
+		},
+	}
+	return n.sendRequest(ctx, peerAddr, req)
+}
+
+func (n *Node) sendReplicaDeltaRequest(ctx context.Context, peerAddr string, key string, delta []byte) (*pb.Response, error) {
+	req := &pb.Request{
+		Origin: n.id,
+		RequestType: &pb.Request_ReplicaDelta{
+			ReplicaDelta: &pb.RequestReplicaDelta{Key: key, Delta: delta},
+		},
+	}
+	return n.sendRequest(ctx, peerAddr, req)
 }
 
-func (n *Node) sendReplicaGetRequest(peerAddr string, key string) (*pb.Response, error) {
+func (n *Node) sendReplicaGetRequest(ctx context.Context, peerAddr string, key string) (*pb.Response, error) {
 	req := &pb.Request{
 		Origin: n.id,
 		RequestType: &pb.Request_ReplicaGet{
 			ReplicaGet: &pb.RequestReplicaGet{Key: key},
 		},
 	}
-	return n.sendRequest(peerAddr, req, config.DefaultConfig().RequestTimeout)
+	return n.sendRequest(ctx, peerAddr, req)
 }
 
-func (n *Node) sendStoreHintRequest(peerAddr string, intendedNode string, key string, value []byte) (*pb.Response, error) {
+func (n *Node) sendStoreHintRequest(ctx context.Context, peerAddr string, intendedNode string, key string, value []byte) (*pb.Response, error) {
 	req := &pb.Request{
 		Origin: n.id,
 		RequestType: &pb.Request_StoreHint{
@@ -164,21 +235,26 @@ func (n *Node) sendStoreHintRequest(peerAddr string, intendedNode string, key st
 			},
 		},
 	}
-	return n.sendRequest(peerAddr, req, config.DefaultConfig().RequestTimeout)
+	return n.sendRequest(ctx, peerAddr, req)
 }
 
-func (n *Node) sendResponseOK(response *pb.Response) error {
-
+func (n *Node) responseOK(response *pb.Response) *pb.Response {
 	response.Ok = true
+	return response
+}
 
-	buffer, _ := proto.Marshal(response)
-	_, err := n.repSock.SendBytes(buffer, 0)
-	return err
+func (n *Node) responseError(errStr string) *pb.Response {
+	return &pb.Response{Ok: false, Error: errStr}
 }
 
-func (n *Node) sendResponseError(errStr string) error {
-	resp := &pb.Response{Ok: false, Error: errStr}
-	buffer, _ := proto.Marshal(resp)
-	_, err := n.repSock.SendBytes(buffer, 0)
-	return err
+// Rejects a request because of an error, telling the sender whether its payload was malformed or the key is missing
+func (n *Node) responseFailure(err error) *pb.Response {
+	resp := n.responseError(err.Error())
+	switch {
+	case errors.Is(err, generic.ErrMalformed):
+		resp.Failure = pb.Failure_FAILURE_MALFORMED
+	case errors.Is(err, storage.ErrNotFound), errors.Is(err, replication.ErrNotFound):
+		resp.Failure = pb.Failure_FAILURE_NOT_FOUND
+	}
+	return resp
 }
@@ -0,0 +1,51 @@
+package node
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	pb "sdle-server/proto"
+)
+
+// Time to wait before restarting a long-running loop that panicked, so a loop that keeps panicking does not spin
+const loopRestartDelay = time.Second
+
+// Runs a function, recovering from a panic in it. The panic is logged with its stack trace, under the name of what
+// was running, and reported as an error.
+func (n *Node) runGuarded(name string, fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			n.logger.Error("Panic in "+name, "panic", r, "stack", string(debug.Stack()))
+			err = fmt.Errorf("panic in %s: %v", name, r)
+		}
+	}()
+
+	fn()
+	return nil
+}
+
+// Runs a long-running loop until it returns, restarting it whenever it panics (unless the node is stopping)
+func (n *Node) superviseLoop(name string, loop func()) {
+	for {
+		if err := n.runGuarded(name, loop); err == nil {
+			return
+		}
+
+		select {
+		case <-n.stopCh:
+			return
+		case <-time.After(loopRestartDelay):
+		}
+		n.logger.Warn("Restarting " + name)
+	}
+}
+
+// Name of the type of a request, as declared in node.proto (e.g. "replica_put")
+func requestTypeName(req *pb.Request) string {
+	msg := req.ProtoReflect()
+	if field := msg.WhichOneof(msg.Descriptor().Oneofs().ByName("request_type")); field != nil {
+		return string(field.Name())
+	}
+	return "unknown"
+}
@@ -0,0 +1,116 @@
+package node
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "sdle-server/proto"
+	"sdle-server/transport"
+)
+
+func TestHandleRequest_RecoversFromPanic(t *testing.T) {
+	// A node without a ring view or store panics on any request that touches them
+	n := &Node{id: "node1:5000", logger: slog.Default()}
+	n.metrics = newNodeMetrics(n)
+
+	resp := n.HandleRequest(&pb.Request{RequestType: &pb.Request_Get{Get: &pb.RequestGet{Key: "key1"}}})
+	if resp.GetOk() {
+		t.Fatalf("Expected an error response, got %v", resp)
+	}
+	if !strings.Contains(resp.GetError(), "get") {
+		t.Errorf("Expected the error to name the request type, got %s", resp.GetError())
+	}
+}
+
+func TestRunAsync_RecoversFromPanic(t *testing.T) {
+	n := &Node{id: "node1:5000", logger: slog.Default()}
+	n.scheduler = func(task func()) { task() }
+
+	ran := false
+	n.runAsync("test task", func() { panic("boom") })
+	n.runAsync("test task", func() { ran = true })
+
+	if !ran {
+		t.Errorf("Expected background work to keep running after a panic")
+	}
+}
+
+func TestSuperviseLoop_RestartsAfterPanic(t *testing.T) {
+	n := &Node{id: "node1:5000", stopCh: make(chan struct{}), logger: slog.Default()}
+
+	runs := 0
+	n.superviseLoop("test loop", func() {
+		runs++
+		if runs == 1 {
+			panic("boom")
+		}
+	})
+
+	if runs != 2 {
+		t.Errorf("Expected the loop to be restarted once, got %d runs", runs)
+	}
+}
+
+// Transport that panics the first time it serves
+type panickingTransport struct {
+	*transport.MemoryTransport
+	panicked atomic.Bool
+}
+
+func (t *panickingTransport) Serve(handler transport.Handler) error {
+	if t.panicked.CompareAndSwap(false, true) {
+		panic("transport failure")
+	}
+	return t.MemoryTransport.Serve(handler)
+}
+
+func TestTransportLoop_RestartsAfterPanic(t *testing.T) {
+	network := transport.NewMemoryNetwork()
+	memTransport, err := network.Listen("node1:5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := NewNodeWithTransport("node1:5000", t.TempDir(), &panickingTransport{MemoryTransport: memTransport})
+	if err != nil {
+		t.Fatal(err)
+	}
+	errCh := make(chan error, 1)
+	n.Start(errCh)
+	t.Cleanup(func() { n.Stop() })
+
+	peer, err := network.Listen("node2:5001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	resp, err := peer.Send(ctx, n.ID(), &pb.Request{RequestType: &pb.Request_Ping{Ping: &pb.RequestPing{}}})
+	if err != nil || !resp.GetOk() {
+		t.Fatalf("Expected the node to keep serving after its transport panicked, got %v %v", resp, err)
+	}
+
+	select {
+	case err := <-errCh:
+		t.Errorf("Expected the panic not to stop the node, got %v", err)
+	default:
+	}
+}
+
+func TestSuperviseLoop_StopsWithNode(t *testing.T) {
+	n := &Node{id: "node1:5000", stopCh: make(chan struct{}), logger: slog.Default()}
+	close(n.stopCh)
+
+	runs := 0
+	n.superviseLoop("test loop", func() {
+		runs++
+		panic("boom")
+	})
+
+	if runs != 1 {
+		t.Errorf("Expected a stopped node not to restart the loop, got %d runs", runs)
+	}
+}
@@ -0,0 +1,67 @@
+package node
+
+import (
+	"context"
+	"errors"
+
+	pb "sdle-server/proto"
+	"sdle-server/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attaches the span of ctx to a request to another node, so the span of the peer handling it joins the same trace
+func injectTrace(ctx context.Context, req *pb.Request) {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if !spanCtx.IsValid() || !spanCtx.IsSampled() {
+		return
+	}
+
+	traceID, spanID := spanCtx.TraceID(), spanCtx.SpanID()
+	req.TraceId = traceID[:]
+	req.SpanId = spanID[:]
+}
+
+// Context carrying the span of the sender of a request, as the remote parent of the spans handling it
+func extractTrace(ctx context.Context, req *pb.Request) context.Context {
+	var traceID trace.TraceID
+	var spanID trace.SpanID
+	if len(req.GetTraceId()) != len(traceID) || len(req.GetSpanId()) != len(spanID) {
+		return ctx
+	}
+	copy(traceID[:], req.GetTraceId())
+	copy(spanID[:], req.GetSpanId())
+
+	spanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled, // only sampled spans are propagated
+		Remote:     true,
+	})
+	if !spanCtx.IsValid() {
+		return ctx
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, spanCtx)
+}
+
+// Starts a span of this node
+func (n *Node) startSpan(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return tracing.Start(ctx, name, append(opts, trace.WithAttributes(tracing.AttrNode.String(n.id)))...)
+}
+
+// Context of background work started while handling a request: it lives as long as the node, but the spans of the
+// work stay in the trace of the request
+func (n *Node) detachedContext(ctx context.Context) context.Context {
+	return trace.ContextWithSpanContext(n.ctx, trace.SpanContextFromContext(ctx))
+}
+
+// Error reported by a response, nil if it is successful
+func responseErr(resp *pb.Response) error {
+	if resp.GetOk() {
+		return nil
+	}
+	if resp.GetError() == "" {
+		return errors.New("request failed")
+	}
+	return errors.New(resp.GetError())
+}
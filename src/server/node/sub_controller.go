@@ -1,21 +1,23 @@
 package node
 
 import (
-	"log"
 	"sdle-server/communication"
 	crdt "sdle-server/crdt/shopping"
+	"sdle-server/logging"
+	"slices"
+	"sync"
 
-	"github.com/gorilla/websocket"
 	"google.golang.org/protobuf/proto"
 )
 
 type SubInfo struct {
 	MessageID string
-	conn      *websocket.Conn
+	conn      *communication.Connection
 }
 
 type SubController struct {
 	node *Node
+	mu          sync.Mutex // guards subscribers, read by metric scrapes as well as WebSocket handlers
 	subscribers map[string]([]*SubInfo)
 }
 
@@ -34,13 +36,17 @@ func (sc *SubController) SetNode(node *Node) {
 	sc.node = node
 }
 
-func (sc *SubController) AddSubscriber(listID string, messageID string, conn *websocket.Conn) {
+func (sc *SubController) AddSubscriber(listID string, messageID string, conn *communication.Connection) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
 	if sc.subscribers[listID] == nil {
 		sc.subscribers[listID] = []*SubInfo{}
 	}
 
+	// Message IDs are chosen by clients, so two connections may use the same one
 	for _, existingConn := range sc.subscribers[listID] {
-		if existingConn.MessageID == messageID {
+		if existingConn.MessageID == messageID && existingConn.conn == conn {
 			return // Already subscribed
 		}
 	}
@@ -51,22 +57,39 @@ func (sc *SubController) AddSubscriber(listID string, messageID string, conn *we
 	})
 }
 
-func (sc *SubController) RemoveSubscriber(listID string, messageID string) {
+func (sc *SubController) RemoveSubscriber(listID string, messageID string, conn *communication.Connection) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
 	subscribers := sc.subscribers[listID]
 	if subscribers == nil {
 		return
 	}
 
 	for i, existingConn := range subscribers {
-		if existingConn.MessageID == messageID {
+		if existingConn.MessageID == messageID && existingConn.conn == conn {
 			sc.subscribers[listID] = append(subscribers[:i], subscribers[i+1:]...)
 			break
 		}
 	}
 }
 
+// Number of subscriptions, over all lists
+func (sc *SubController) Count() int {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	count := 0
+	for _, subscribers := range sc.subscribers {
+		count += len(subscribers)
+	}
+	return count
+}
+
 func (sc *SubController) NotifySubscribers(listID string, list crdt.ShoppingList) {
-	subscribers := sc.subscribers[listID]
+	sc.mu.Lock()
+	subscribers := slices.Clone(sc.subscribers[listID])
+	sc.mu.Unlock()
 	if subscribers == nil {
 		return
 	}
@@ -74,19 +97,19 @@ func (sc *SubController) NotifySubscribers(listID string, list crdt.ShoppingList
 	for _, subInfo := range subscribers {
 		message, err := communication.NewShoppingListResponse(&list, subInfo.MessageID)
 		if err != nil {
-			log.Println("Error creating shopping list response:", err)
+			sc.node.logger.Error("Failed to create shopping list notification", "list_id", listID, logging.Err(err))
 			return
 		}
 
 		data, err := proto.Marshal(message)
 		if err != nil {
-			log.Println("Error marshaling shopping list response:", err)
+			sc.node.logger.Error("Failed to marshal shopping list notification", "list_id", listID, logging.Err(err))
 			return
 		}
 
-		if err := subInfo.conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
-			// Handle error, possibly remove subscriber
-			log.Println("Error notifying subscriber:", err)
+		if err := subInfo.conn.WriteMessage(data); err != nil {
+			// The connection is closed, which drops its subscriptions
+			sc.node.logger.Warn("Failed to notify subscriber", "list_id", listID, logging.KeyMessageID, subInfo.MessageID, logging.Err(err))
 			continue
 		}
 	}
@@ -0,0 +1,25 @@
+package node
+
+import (
+	"testing"
+
+	"sdle-server/communication"
+)
+
+func TestSubController_SameMessageIDOnTwoConnections(t *testing.T) {
+	sc := NewSubController(nil)
+	conn1, conn2 := &communication.Connection{}, &communication.Connection{}
+
+	// Message IDs are chosen by clients, so they may collide across connections
+	sc.AddSubscriber("list1", "sub1", conn1)
+	sc.AddSubscriber("list1", "sub1", conn2)
+	sc.AddSubscriber("list1", "sub1", conn2)
+	if got := sc.Count(); got != 2 {
+		t.Fatalf("Expected a subscription per connection, got %d", got)
+	}
+
+	sc.RemoveSubscriber("list1", "sub1", conn1)
+	if got := sc.subscribers["list1"]; len(got) != 1 || got[0].conn != conn2 {
+		t.Errorf("Expected only the subscription of the other connection to remain, got %v", got)
+	}
+}
@@ -21,19 +21,86 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type ShareAccess int32
+
+const (
+	ShareAccess_SHARE_READ  ShareAccess = 0
+	ShareAccess_SHARE_WRITE ShareAccess = 1
+)
+
+// Enum value maps for ShareAccess.
+var (
+	ShareAccess_name = map[int32]string{
+		0: "SHARE_READ",
+		1: "SHARE_WRITE",
+	}
+	ShareAccess_value = map[string]int32{
+		"SHARE_READ":  0,
+		"SHARE_WRITE": 1,
+	}
+)
+
+func (x ShareAccess) Enum() *ShareAccess {
+	p := new(ShareAccess)
+	*p = x
+	return p
+}
+
+func (x ShareAccess) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ShareAccess) Descriptor() protoreflect.EnumDescriptor {
+	return file_client_proto_enumTypes[0].Descriptor()
+}
+
+func (ShareAccess) Type() protoreflect.EnumType {
+	return &file_client_proto_enumTypes[0]
+}
+
+func (x ShareAccess) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ShareAccess.Descriptor instead.
+func (ShareAccess) EnumDescriptor() ([]byte, []int) {
+	return file_client_proto_rawDescGZIP(), []int{0}
+}
+
 type ErrorCode int32
 
 const (
-	ErrorCode_NOT_FOUND ErrorCode = 0
+	ErrorCode_NOT_FOUND              ErrorCode = 0
+	ErrorCode_INVALID_REQUEST        ErrorCode = 1
+	ErrorCode_INTERNAL_ERROR         ErrorCode = 2
+	ErrorCode_UNAUTHENTICATED        ErrorCode = 3 // the session must authenticate first (or its token expired)
+	ErrorCode_FORBIDDEN              ErrorCode = 4 // the user of the session may not access the list
+	ErrorCode_MESSAGE_TOO_LARGE      ErrorCode = 5 // the message exceeds the maximum size (its message ID is unknown, so the response has none)
+	ErrorCode_RATE_LIMITED           ErrorCode = 6 // the connection, or the list, sent too many requests: retry later
+	ErrorCode_TOO_MANY_SUBSCRIPTIONS ErrorCode = 7 // the connection holds the maximum number of subscriptions
 )
 
 // Enum value maps for ErrorCode.
 var (
 	ErrorCode_name = map[int32]string{
 		0: "NOT_FOUND",
+		1: "INVALID_REQUEST",
+		2: "INTERNAL_ERROR",
+		3: "UNAUTHENTICATED",
+		4: "FORBIDDEN",
+		5: "MESSAGE_TOO_LARGE",
+		6: "RATE_LIMITED",
+		7: "TOO_MANY_SUBSCRIPTIONS",
 	}
 	ErrorCode_value = map[string]int32{
-		"NOT_FOUND": 0,
+		"NOT_FOUND":              0,
+		"INVALID_REQUEST":        1,
+		"INTERNAL_ERROR":         2,
+		"UNAUTHENTICATED":        3,
+		"FORBIDDEN":              4,
+		"MESSAGE_TOO_LARGE":      5,
+		"RATE_LIMITED":           6,
+		"TOO_MANY_SUBSCRIPTIONS": 7,
 	}
 )
 
@@ -48,11 +115,11 @@ func (x ErrorCode) String() string {
 }
 
 func (ErrorCode) Descriptor() protoreflect.EnumDescriptor {
-	return file_client_proto_enumTypes[0].Descriptor()
+	return file_client_proto_enumTypes[1].Descriptor()
 }
 
 func (ErrorCode) Type() protoreflect.EnumType {
-	return &file_client_proto_enumTypes[0]
+	return &file_client_proto_enumTypes[1]
 }
 
 func (x ErrorCode) Number() protoreflect.EnumNumber {
@@ -61,7 +128,7 @@ func (x ErrorCode) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use ErrorCode.Descriptor instead.
 func (ErrorCode) EnumDescriptor() ([]byte, []int) {
-	return file_client_proto_rawDescGZIP(), []int{0}
+	return file_client_proto_rawDescGZIP(), []int{1}
 }
 
 type GetShoppingListRequest struct {
@@ -188,6 +255,382 @@ func (*RequestRingView) Descriptor() ([]byte, []int) {
 	return file_client_proto_rawDescGZIP(), []int{2}
 }
 
+// Identifies the user of the session with a signed token (see the auth package)
+type AuthenticateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Token         string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuthenticateRequest) Reset() {
+	*x = AuthenticateRequest{}
+	mi := &file_client_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthenticateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthenticateRequest) ProtoMessage() {}
+
+func (x *AuthenticateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_client_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthenticateRequest.ProtoReflect.Descriptor instead.
+func (*AuthenticateRequest) Descriptor() ([]byte, []int) {
+	return file_client_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *AuthenticateRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+type GetListAclRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetListAclRequest) Reset() {
+	*x = GetListAclRequest{}
+	mi := &file_client_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetListAclRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetListAclRequest) ProtoMessage() {}
+
+func (x *GetListAclRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_client_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetListAclRequest.ProtoReflect.Descriptor instead.
+func (*GetListAclRequest) Descriptor() ([]byte, []int) {
+	return file_client_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetListAclRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+// Replaces the editors and viewers of a list (only its owner may)
+type SetListAclRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Editors       []string               `protobuf:"bytes,2,rep,name=editors,proto3" json:"editors,omitempty"`
+	Viewers       []string               `protobuf:"bytes,3,rep,name=viewers,proto3" json:"viewers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetListAclRequest) Reset() {
+	*x = SetListAclRequest{}
+	mi := &file_client_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetListAclRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetListAclRequest) ProtoMessage() {}
+
+func (x *SetListAclRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_client_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetListAclRequest.ProtoReflect.Descriptor instead.
+func (*SetListAclRequest) Descriptor() ([]byte, []int) {
+	return file_client_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SetListAclRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *SetListAclRequest) GetEditors() []string {
+	if x != nil {
+		return x.Editors
+	}
+	return nil
+}
+
+func (x *SetListAclRequest) GetViewers() []string {
+	if x != nil {
+		return x.Viewers
+	}
+	return nil
+}
+
+type Authenticated struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Authenticated) Reset() {
+	*x = Authenticated{}
+	mi := &file_client_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Authenticated) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Authenticated) ProtoMessage() {}
+
+func (x *Authenticated) ProtoReflect() protoreflect.Message {
+	mi := &file_client_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Authenticated.ProtoReflect.Descriptor instead.
+func (*Authenticated) Descriptor() ([]byte, []int) {
+	return file_client_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Authenticated) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+// Mints a share capability of a list (only its owner may)
+type CreateShareLinkRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Access        ShareAccess            `protobuf:"varint,2,opt,name=access,proto3,enum=ShareAccess" json:"access,omitempty"`
+	TtlSeconds    int64                  `protobuf:"varint,3,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"` // 0 if the capability never expires
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateShareLinkRequest) Reset() {
+	*x = CreateShareLinkRequest{}
+	mi := &file_client_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateShareLinkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateShareLinkRequest) ProtoMessage() {}
+
+func (x *CreateShareLinkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_client_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateShareLinkRequest.ProtoReflect.Descriptor instead.
+func (*CreateShareLinkRequest) Descriptor() ([]byte, []int) {
+	return file_client_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *CreateShareLinkRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *CreateShareLinkRequest) GetAccess() ShareAccess {
+	if x != nil {
+		return x.Access
+	}
+	return ShareAccess_SHARE_READ
+}
+
+func (x *CreateShareLinkRequest) GetTtlSeconds() int64 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+// Revokes a share capability of a list (only its owner may)
+type RevokeShareLinkRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Capability    string                 `protobuf:"bytes,1,opt,name=capability,proto3" json:"capability,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeShareLinkRequest) Reset() {
+	*x = RevokeShareLinkRequest{}
+	mi := &file_client_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokeShareLinkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokeShareLinkRequest) ProtoMessage() {}
+
+func (x *RevokeShareLinkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_client_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevokeShareLinkRequest.ProtoReflect.Descriptor instead.
+func (*RevokeShareLinkRequest) Descriptor() ([]byte, []int) {
+	return file_client_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *RevokeShareLinkRequest) GetCapability() string {
+	if x != nil {
+		return x.Capability
+	}
+	return ""
+}
+
+type ShareLink struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Capability    string                 `protobuf:"bytes,1,opt,name=capability,proto3" json:"capability,omitempty"` // token to present in the capability field of requests
+	CapabilityId  string                 `protobuf:"bytes,2,opt,name=capability_id,json=capabilityId,proto3" json:"capability_id,omitempty"`
+	ListId        string                 `protobuf:"bytes,3,opt,name=list_id,json=listId,proto3" json:"list_id,omitempty"`
+	Access        ShareAccess            `protobuf:"varint,4,opt,name=access,proto3,enum=ShareAccess" json:"access,omitempty"`
+	Expires       int64                  `protobuf:"varint,5,opt,name=expires,proto3" json:"expires,omitempty"` // Unix seconds, 0 if never
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShareLink) Reset() {
+	*x = ShareLink{}
+	mi := &file_client_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShareLink) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShareLink) ProtoMessage() {}
+
+func (x *ShareLink) ProtoReflect() protoreflect.Message {
+	mi := &file_client_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShareLink.ProtoReflect.Descriptor instead.
+func (*ShareLink) Descriptor() ([]byte, []int) {
+	return file_client_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ShareLink) GetCapability() string {
+	if x != nil {
+		return x.Capability
+	}
+	return ""
+}
+
+func (x *ShareLink) GetCapabilityId() string {
+	if x != nil {
+		return x.CapabilityId
+	}
+	return ""
+}
+
+func (x *ShareLink) GetListId() string {
+	if x != nil {
+		return x.ListId
+	}
+	return ""
+}
+
+func (x *ShareLink) GetAccess() ShareAccess {
+	if x != nil {
+		return x.Access
+	}
+	return ShareAccess_SHARE_READ
+}
+
+func (x *ShareLink) GetExpires() int64 {
+	if x != nil {
+		return x.Expires
+	}
+	return 0
+}
+
 type Ok struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -196,7 +639,7 @@ type Ok struct {
 
 func (x *Ok) Reset() {
 	*x = Ok{}
-	mi := &file_client_proto_msgTypes[3]
+	mi := &file_client_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -208,7 +651,7 @@ func (x *Ok) String() string {
 func (*Ok) ProtoMessage() {}
 
 func (x *Ok) ProtoReflect() protoreflect.Message {
-	mi := &file_client_proto_msgTypes[3]
+	mi := &file_client_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -221,18 +664,24 @@ func (x *Ok) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Ok.ProtoReflect.Descriptor instead.
 func (*Ok) Descriptor() ([]byte, []int) {
-	return file_client_proto_rawDescGZIP(), []int{3}
+	return file_client_proto_rawDescGZIP(), []int{10}
 }
 
 type ClientRequest struct {
-	state     protoimpl.MessageState `protogen:"open.v1"`
-	MessageId string                 `protobuf:"bytes,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	MessageId  string                 `protobuf:"bytes,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	Capability string                 `protobuf:"bytes,11,opt,name=capability,proto3" json:"capability,omitempty"` // share capability of the list of the request, in place of a session identity
 	// Types that are valid to be assigned to RequestType:
 	//
 	//	*ClientRequest_ShoppingList
 	//	*ClientRequest_GetShoppingList_
 	//	*ClientRequest_SubscribeShoppingList
 	//	*ClientRequest_RingView
+	//	*ClientRequest_Authenticate
+	//	*ClientRequest_GetListAcl
+	//	*ClientRequest_SetListAcl
+	//	*ClientRequest_CreateShareLink
+	//	*ClientRequest_RevokeShareLink
 	RequestType   isClientRequest_RequestType `protobuf_oneof:"request_type"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -240,7 +689,7 @@ type ClientRequest struct {
 
 func (x *ClientRequest) Reset() {
 	*x = ClientRequest{}
-	mi := &file_client_proto_msgTypes[4]
+	mi := &file_client_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -252,7 +701,7 @@ func (x *ClientRequest) String() string {
 func (*ClientRequest) ProtoMessage() {}
 
 func (x *ClientRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_client_proto_msgTypes[4]
+	mi := &file_client_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -265,7 +714,7 @@ func (x *ClientRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClientRequest.ProtoReflect.Descriptor instead.
 func (*ClientRequest) Descriptor() ([]byte, []int) {
-	return file_client_proto_rawDescGZIP(), []int{4}
+	return file_client_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *ClientRequest) GetMessageId() string {
@@ -275,6 +724,13 @@ func (x *ClientRequest) GetMessageId() string {
 	return ""
 }
 
+func (x *ClientRequest) GetCapability() string {
+	if x != nil {
+		return x.Capability
+	}
+	return ""
+}
+
 func (x *ClientRequest) GetRequestType() isClientRequest_RequestType {
 	if x != nil {
 		return x.RequestType
@@ -318,6 +774,51 @@ func (x *ClientRequest) GetRingView() *RequestRingView {
 	return nil
 }
 
+func (x *ClientRequest) GetAuthenticate() *AuthenticateRequest {
+	if x != nil {
+		if x, ok := x.RequestType.(*ClientRequest_Authenticate); ok {
+			return x.Authenticate
+		}
+	}
+	return nil
+}
+
+func (x *ClientRequest) GetGetListAcl() *GetListAclRequest {
+	if x != nil {
+		if x, ok := x.RequestType.(*ClientRequest_GetListAcl); ok {
+			return x.GetListAcl
+		}
+	}
+	return nil
+}
+
+func (x *ClientRequest) GetSetListAcl() *SetListAclRequest {
+	if x != nil {
+		if x, ok := x.RequestType.(*ClientRequest_SetListAcl); ok {
+			return x.SetListAcl
+		}
+	}
+	return nil
+}
+
+func (x *ClientRequest) GetCreateShareLink() *CreateShareLinkRequest {
+	if x != nil {
+		if x, ok := x.RequestType.(*ClientRequest_CreateShareLink); ok {
+			return x.CreateShareLink
+		}
+	}
+	return nil
+}
+
+func (x *ClientRequest) GetRevokeShareLink() *RevokeShareLinkRequest {
+	if x != nil {
+		if x, ok := x.RequestType.(*ClientRequest_RevokeShareLink); ok {
+			return x.RevokeShareLink
+		}
+	}
+	return nil
+}
+
 type isClientRequest_RequestType interface {
 	isClientRequest_RequestType()
 }
@@ -338,6 +839,26 @@ type ClientRequest_RingView struct {
 	RingView *RequestRingView `protobuf:"bytes,5,opt,name=ring_view,json=ringView,proto3,oneof"`
 }
 
+type ClientRequest_Authenticate struct {
+	Authenticate *AuthenticateRequest `protobuf:"bytes,6,opt,name=authenticate,proto3,oneof"`
+}
+
+type ClientRequest_GetListAcl struct {
+	GetListAcl *GetListAclRequest `protobuf:"bytes,7,opt,name=get_list_acl,json=getListAcl,proto3,oneof"`
+}
+
+type ClientRequest_SetListAcl struct {
+	SetListAcl *SetListAclRequest `protobuf:"bytes,8,opt,name=set_list_acl,json=setListAcl,proto3,oneof"`
+}
+
+type ClientRequest_CreateShareLink struct {
+	CreateShareLink *CreateShareLinkRequest `protobuf:"bytes,9,opt,name=create_share_link,json=createShareLink,proto3,oneof"`
+}
+
+type ClientRequest_RevokeShareLink struct {
+	RevokeShareLink *RevokeShareLinkRequest `protobuf:"bytes,10,opt,name=revoke_share_link,json=revokeShareLink,proto3,oneof"`
+}
+
 func (*ClientRequest_ShoppingList) isClientRequest_RequestType() {}
 
 func (*ClientRequest_GetShoppingList_) isClientRequest_RequestType() {}
@@ -346,6 +867,16 @@ func (*ClientRequest_SubscribeShoppingList) isClientRequest_RequestType() {}
 
 func (*ClientRequest_RingView) isClientRequest_RequestType() {}
 
+func (*ClientRequest_Authenticate) isClientRequest_RequestType() {}
+
+func (*ClientRequest_GetListAcl) isClientRequest_RequestType() {}
+
+func (*ClientRequest_SetListAcl) isClientRequest_RequestType() {}
+
+func (*ClientRequest_CreateShareLink) isClientRequest_RequestType() {}
+
+func (*ClientRequest_RevokeShareLink) isClientRequest_RequestType() {}
+
 type ServerResponse struct {
 	state     protoimpl.MessageState `protogen:"open.v1"`
 	MessageId string                 `protobuf:"bytes,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
@@ -354,6 +885,10 @@ type ServerResponse struct {
 	//	*ServerResponse_ShoppingList
 	//	*ServerResponse_Error
 	//	*ServerResponse_RingView
+	//	*ServerResponse_Authenticated
+	//	*ServerResponse_ListAcl
+	//	*ServerResponse_ShareLink
+	//	*ServerResponse_Ok
 	ResponseType  isServerResponse_ResponseType `protobuf_oneof:"response_type"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -361,7 +896,7 @@ type ServerResponse struct {
 
 func (x *ServerResponse) Reset() {
 	*x = ServerResponse{}
-	mi := &file_client_proto_msgTypes[5]
+	mi := &file_client_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -373,7 +908,7 @@ func (x *ServerResponse) String() string {
 func (*ServerResponse) ProtoMessage() {}
 
 func (x *ServerResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_client_proto_msgTypes[5]
+	mi := &file_client_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -386,7 +921,7 @@ func (x *ServerResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ServerResponse.ProtoReflect.Descriptor instead.
 func (*ServerResponse) Descriptor() ([]byte, []int) {
-	return file_client_proto_rawDescGZIP(), []int{5}
+	return file_client_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *ServerResponse) GetMessageId() string {
@@ -430,6 +965,42 @@ func (x *ServerResponse) GetRingView() *RingView {
 	return nil
 }
 
+func (x *ServerResponse) GetAuthenticated() *Authenticated {
+	if x != nil {
+		if x, ok := x.ResponseType.(*ServerResponse_Authenticated); ok {
+			return x.Authenticated
+		}
+	}
+	return nil
+}
+
+func (x *ServerResponse) GetListAcl() *ListAcl {
+	if x != nil {
+		if x, ok := x.ResponseType.(*ServerResponse_ListAcl); ok {
+			return x.ListAcl
+		}
+	}
+	return nil
+}
+
+func (x *ServerResponse) GetShareLink() *ShareLink {
+	if x != nil {
+		if x, ok := x.ResponseType.(*ServerResponse_ShareLink); ok {
+			return x.ShareLink
+		}
+	}
+	return nil
+}
+
+func (x *ServerResponse) GetOk() *Ok {
+	if x != nil {
+		if x, ok := x.ResponseType.(*ServerResponse_Ok); ok {
+			return x.Ok
+		}
+	}
+	return nil
+}
+
 type isServerResponse_ResponseType interface {
 	isServerResponse_ResponseType()
 }
@@ -446,12 +1017,36 @@ type ServerResponse_RingView struct {
 	RingView *RingView `protobuf:"bytes,4,opt,name=ring_view,json=ringView,proto3,oneof"`
 }
 
+type ServerResponse_Authenticated struct {
+	Authenticated *Authenticated `protobuf:"bytes,5,opt,name=authenticated,proto3,oneof"`
+}
+
+type ServerResponse_ListAcl struct {
+	ListAcl *ListAcl `protobuf:"bytes,6,opt,name=list_acl,json=listAcl,proto3,oneof"`
+}
+
+type ServerResponse_ShareLink struct {
+	ShareLink *ShareLink `protobuf:"bytes,7,opt,name=share_link,json=shareLink,proto3,oneof"`
+}
+
+type ServerResponse_Ok struct {
+	Ok *Ok `protobuf:"bytes,8,opt,name=ok,proto3,oneof"`
+}
+
 func (*ServerResponse_ShoppingList) isServerResponse_ResponseType() {}
 
 func (*ServerResponse_Error) isServerResponse_ResponseType() {}
 
 func (*ServerResponse_RingView) isServerResponse_ResponseType() {}
 
+func (*ServerResponse_Authenticated) isServerResponse_ResponseType() {}
+
+func (*ServerResponse_ListAcl) isServerResponse_ResponseType() {}
+
+func (*ServerResponse_ShareLink) isServerResponse_ResponseType() {}
+
+func (*ServerResponse_Ok) isServerResponse_ResponseType() {}
+
 var File_client_proto protoreflect.FileDescriptor
 
 const file_client_proto_rawDesc = "" +
@@ -462,26 +1057,81 @@ const file_client_proto_rawDesc = "" +
 	"\x02id\x18\x01 \x01(\tR\x02id\".\n" +
 	"\x1cSubscribeShoppingListRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\"\x11\n" +
-	"\x0fRequestRingView\"\x04\n" +
-	"\x02Ok\"\xc5\x02\n" +
+	"\x0fRequestRingView\"+\n" +
+	"\x13AuthenticateRequest\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\"#\n" +
+	"\x11GetListAclRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"W\n" +
+	"\x11SetListAclRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\aeditors\x18\x02 \x03(\tR\aeditors\x12\x18\n" +
+	"\aviewers\x18\x03 \x03(\tR\aviewers\"(\n" +
+	"\rAuthenticated\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\"o\n" +
+	"\x16CreateShareLinkRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12$\n" +
+	"\x06access\x18\x02 \x01(\x0e2\f.ShareAccessR\x06access\x12\x1f\n" +
+	"\vttl_seconds\x18\x03 \x01(\x03R\n" +
+	"ttlSeconds\"8\n" +
+	"\x16RevokeShareLinkRequest\x12\x1e\n" +
+	"\n" +
+	"capability\x18\x01 \x01(\tR\n" +
+	"capability\"\xa9\x01\n" +
+	"\tShareLink\x12\x1e\n" +
+	"\n" +
+	"capability\x18\x01 \x01(\tR\n" +
+	"capability\x12#\n" +
+	"\rcapability_id\x18\x02 \x01(\tR\fcapabilityId\x12\x17\n" +
+	"\alist_id\x18\x03 \x01(\tR\x06listId\x12$\n" +
+	"\x06access\x18\x04 \x01(\x0e2\f.ShareAccessR\x06access\x12\x18\n" +
+	"\aexpires\x18\x05 \x01(\x03R\aexpires\"\x04\n" +
+	"\x02Ok\"\x9f\x05\n" +
 	"\rClientRequest\x12\x1d\n" +
 	"\n" +
-	"message_id\x18\x01 \x01(\tR\tmessageId\x124\n" +
+	"message_id\x18\x01 \x01(\tR\tmessageId\x12\x1e\n" +
+	"\n" +
+	"capability\x18\v \x01(\tR\n" +
+	"capability\x124\n" +
 	"\rshopping_list\x18\x02 \x01(\v2\r.ShoppingListH\x00R\fshoppingList\x12E\n" +
 	"\x11get_shopping_list\x18\x03 \x01(\v2\x17.GetShoppingListRequestH\x00R\x0fgetShoppingList\x12W\n" +
 	"\x17subscribe_shopping_list\x18\x04 \x01(\v2\x1d.SubscribeShoppingListRequestH\x00R\x15subscribeShoppingList\x12/\n" +
-	"\tring_view\x18\x05 \x01(\v2\x10.RequestRingViewH\x00R\bringViewB\x0e\n" +
-	"\frequest_type\"\xc4\x01\n" +
+	"\tring_view\x18\x05 \x01(\v2\x10.RequestRingViewH\x00R\bringView\x12:\n" +
+	"\fauthenticate\x18\x06 \x01(\v2\x14.AuthenticateRequestH\x00R\fauthenticate\x126\n" +
+	"\fget_list_acl\x18\a \x01(\v2\x12.GetListAclRequestH\x00R\n" +
+	"getListAcl\x126\n" +
+	"\fset_list_acl\x18\b \x01(\v2\x12.SetListAclRequestH\x00R\n" +
+	"setListAcl\x12E\n" +
+	"\x11create_share_link\x18\t \x01(\v2\x17.CreateShareLinkRequestH\x00R\x0fcreateShareLink\x12E\n" +
+	"\x11revoke_share_link\x18\n" +
+	" \x01(\v2\x17.RevokeShareLinkRequestH\x00R\x0frevokeShareLinkB\x0e\n" +
+	"\frequest_type\"\xe7\x02\n" +
 	"\x0eServerResponse\x12\x1d\n" +
 	"\n" +
 	"message_id\x18\x01 \x01(\tR\tmessageId\x124\n" +
 	"\rshopping_list\x18\x02 \x01(\v2\r.ShoppingListH\x00R\fshoppingList\x12\"\n" +
 	"\x05error\x18\x03 \x01(\x0e2\n" +
 	".ErrorCodeH\x00R\x05error\x12(\n" +
-	"\tring_view\x18\x04 \x01(\v2\t.RingViewH\x00R\bringViewB\x0f\n" +
-	"\rresponse_type*\x1a\n" +
+	"\tring_view\x18\x04 \x01(\v2\t.RingViewH\x00R\bringView\x126\n" +
+	"\rauthenticated\x18\x05 \x01(\v2\x0e.AuthenticatedH\x00R\rauthenticated\x12%\n" +
+	"\blist_acl\x18\x06 \x01(\v2\b.ListAclH\x00R\alistAcl\x12+\n" +
+	"\n" +
+	"share_link\x18\a \x01(\v2\n" +
+	".ShareLinkH\x00R\tshareLink\x12\x15\n" +
+	"\x02ok\x18\b \x01(\v2\x03.OkH\x00R\x02okB\x0f\n" +
+	"\rresponse_type*.\n" +
+	"\vShareAccess\x12\x0e\n" +
+	"\n" +
+	"SHARE_READ\x10\x00\x12\x0f\n" +
+	"\vSHARE_WRITE\x10\x01*\xac\x01\n" +
 	"\tErrorCode\x12\r\n" +
-	"\tNOT_FOUND\x10\x00B'Z%gitlab.up.pt/classes/sdle/2025/t2/g01b\x06proto3"
+	"\tNOT_FOUND\x10\x00\x12\x13\n" +
+	"\x0fINVALID_REQUEST\x10\x01\x12\x12\n" +
+	"\x0eINTERNAL_ERROR\x10\x02\x12\x13\n" +
+	"\x0fUNAUTHENTICATED\x10\x03\x12\r\n" +
+	"\tFORBIDDEN\x10\x04\x12\x15\n" +
+	"\x11MESSAGE_TOO_LARGE\x10\x05\x12\x10\n" +
+	"\fRATE_LIMITED\x10\x06\x12\x1a\n" +
+	"\x16TOO_MANY_SUBSCRIPTIONS\x10\aB'Z%gitlab.up.pt/classes/sdle/2025/t2/g01b\x06proto3"
 
 var (
 	file_client_proto_rawDescOnce sync.Once
@@ -495,32 +1145,52 @@ func file_client_proto_rawDescGZIP() []byte {
 	return file_client_proto_rawDescData
 }
 
-var file_client_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_client_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_client_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_client_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
 var file_client_proto_goTypes = []any{
-	(ErrorCode)(0),                       // 0: ErrorCode
-	(*GetShoppingListRequest)(nil),       // 1: GetShoppingListRequest
-	(*SubscribeShoppingListRequest)(nil), // 2: SubscribeShoppingListRequest
-	(*RequestRingView)(nil),              // 3: RequestRingView
-	(*Ok)(nil),                           // 4: Ok
-	(*ClientRequest)(nil),                // 5: ClientRequest
-	(*ServerResponse)(nil),               // 6: ServerResponse
-	(*ShoppingList)(nil),                 // 7: ShoppingList
-	(*RingView)(nil),                     // 8: RingView
+	(ShareAccess)(0),                     // 0: ShareAccess
+	(ErrorCode)(0),                       // 1: ErrorCode
+	(*GetShoppingListRequest)(nil),       // 2: GetShoppingListRequest
+	(*SubscribeShoppingListRequest)(nil), // 3: SubscribeShoppingListRequest
+	(*RequestRingView)(nil),              // 4: RequestRingView
+	(*AuthenticateRequest)(nil),          // 5: AuthenticateRequest
+	(*GetListAclRequest)(nil),            // 6: GetListAclRequest
+	(*SetListAclRequest)(nil),            // 7: SetListAclRequest
+	(*Authenticated)(nil),                // 8: Authenticated
+	(*CreateShareLinkRequest)(nil),       // 9: CreateShareLinkRequest
+	(*RevokeShareLinkRequest)(nil),       // 10: RevokeShareLinkRequest
+	(*ShareLink)(nil),                    // 11: ShareLink
+	(*Ok)(nil),                           // 12: Ok
+	(*ClientRequest)(nil),                // 13: ClientRequest
+	(*ServerResponse)(nil),               // 14: ServerResponse
+	(*ShoppingList)(nil),                 // 15: ShoppingList
+	(*RingView)(nil),                     // 16: RingView
+	(*ListAcl)(nil),                      // 17: ListAcl
 }
 var file_client_proto_depIdxs = []int32{
-	7, // 0: ClientRequest.shopping_list:type_name -> ShoppingList
-	1, // 1: ClientRequest.get_shopping_list:type_name -> GetShoppingListRequest
-	2, // 2: ClientRequest.subscribe_shopping_list:type_name -> SubscribeShoppingListRequest
-	3, // 3: ClientRequest.ring_view:type_name -> RequestRingView
-	7, // 4: ServerResponse.shopping_list:type_name -> ShoppingList
-	0, // 5: ServerResponse.error:type_name -> ErrorCode
-	8, // 6: ServerResponse.ring_view:type_name -> RingView
-	7, // [7:7] is the sub-list for method output_type
-	7, // [7:7] is the sub-list for method input_type
-	7, // [7:7] is the sub-list for extension type_name
-	7, // [7:7] is the sub-list for extension extendee
-	0, // [0:7] is the sub-list for field type_name
+	0,  // 0: CreateShareLinkRequest.access:type_name -> ShareAccess
+	0,  // 1: ShareLink.access:type_name -> ShareAccess
+	15, // 2: ClientRequest.shopping_list:type_name -> ShoppingList
+	2,  // 3: ClientRequest.get_shopping_list:type_name -> GetShoppingListRequest
+	3,  // 4: ClientRequest.subscribe_shopping_list:type_name -> SubscribeShoppingListRequest
+	4,  // 5: ClientRequest.ring_view:type_name -> RequestRingView
+	5,  // 6: ClientRequest.authenticate:type_name -> AuthenticateRequest
+	6,  // 7: ClientRequest.get_list_acl:type_name -> GetListAclRequest
+	7,  // 8: ClientRequest.set_list_acl:type_name -> SetListAclRequest
+	9,  // 9: ClientRequest.create_share_link:type_name -> CreateShareLinkRequest
+	10, // 10: ClientRequest.revoke_share_link:type_name -> RevokeShareLinkRequest
+	15, // 11: ServerResponse.shopping_list:type_name -> ShoppingList
+	1,  // 12: ServerResponse.error:type_name -> ErrorCode
+	16, // 13: ServerResponse.ring_view:type_name -> RingView
+	8,  // 14: ServerResponse.authenticated:type_name -> Authenticated
+	17, // 15: ServerResponse.list_acl:type_name -> ListAcl
+	11, // 16: ServerResponse.share_link:type_name -> ShareLink
+	12, // 17: ServerResponse.ok:type_name -> Ok
+	18, // [18:18] is the sub-list for method output_type
+	18, // [18:18] is the sub-list for method input_type
+	18, // [18:18] is the sub-list for extension type_name
+	18, // [18:18] is the sub-list for extension extendee
+	0,  // [0:18] is the sub-list for field type_name
 }
 
 func init() { file_client_proto_init() }
@@ -530,24 +1200,33 @@ func file_client_proto_init() {
 	}
 	file_shopping_proto_init()
 	file_node_proto_init()
-	file_client_proto_msgTypes[4].OneofWrappers = []any{
+	file_client_proto_msgTypes[11].OneofWrappers = []any{
 		(*ClientRequest_ShoppingList)(nil),
 		(*ClientRequest_GetShoppingList_)(nil),
 		(*ClientRequest_SubscribeShoppingList)(nil),
 		(*ClientRequest_RingView)(nil),
+		(*ClientRequest_Authenticate)(nil),
+		(*ClientRequest_GetListAcl)(nil),
+		(*ClientRequest_SetListAcl)(nil),
+		(*ClientRequest_CreateShareLink)(nil),
+		(*ClientRequest_RevokeShareLink)(nil),
 	}
-	file_client_proto_msgTypes[5].OneofWrappers = []any{
+	file_client_proto_msgTypes[12].OneofWrappers = []any{
 		(*ServerResponse_ShoppingList)(nil),
 		(*ServerResponse_Error)(nil),
 		(*ServerResponse_RingView)(nil),
+		(*ServerResponse_Authenticated)(nil),
+		(*ServerResponse_ListAcl)(nil),
+		(*ServerResponse_ShareLink)(nil),
+		(*ServerResponse_Ok)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_client_proto_rawDesc), len(file_client_proto_rawDesc)),
-			NumEnums:      1,
-			NumMessages:   6,
+			NumEnums:      2,
+			NumMessages:   13,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -21,9 +21,60 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Why a request was rejected, for errors the sender handles differently from a plain failure
+type Failure int32
+
+const (
+	Failure_FAILURE_UNSPECIFIED Failure = 0
+	Failure_FAILURE_MALFORMED   Failure = 1 // the payload is not a valid value (e.g. a malformed shopping list); retrying cannot help
+	Failure_FAILURE_NOT_FOUND   Failure = 2 // the key is not stored
+)
+
+// Enum value maps for Failure.
+var (
+	Failure_name = map[int32]string{
+		0: "FAILURE_UNSPECIFIED",
+		1: "FAILURE_MALFORMED",
+		2: "FAILURE_NOT_FOUND",
+	}
+	Failure_value = map[string]int32{
+		"FAILURE_UNSPECIFIED": 0,
+		"FAILURE_MALFORMED":   1,
+		"FAILURE_NOT_FOUND":   2,
+	}
+)
+
+func (x Failure) Enum() *Failure {
+	p := new(Failure)
+	*p = x
+	return p
+}
+
+func (x Failure) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Failure) Descriptor() protoreflect.EnumDescriptor {
+	return file_node_proto_enumTypes[0].Descriptor()
+}
+
+func (Failure) Type() protoreflect.EnumType {
+	return &file_node_proto_enumTypes[0]
+}
+
+func (x Failure) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Failure.Descriptor instead.
+func (Failure) EnumDescriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{0}
+}
+
 type RingView struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	TokenToNode   map[uint64]string      `protobuf:"bytes,1,rep,name=token_to_node,json=tokenToNode,proto3" json:"token_to_node,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Generations   map[string]uint64      `protobuf:"bytes,2,rep,name=generations,proto3" json:"generations,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // membership generation of each node
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -65,9 +116,19 @@ func (x *RingView) GetTokenToNode() map[uint64]string {
 	return nil
 }
 
+func (x *RingView) GetGenerations() map[string]uint64 {
+	if x != nil {
+		return x.Generations
+	}
+	return nil
+}
+
 type Request struct {
-	state  protoimpl.MessageState `protogen:"open.v1"`
-	Origin string                 `protobuf:"bytes,1,opt,name=origin,proto3" json:"origin,omitempty"` // id of the node that sent the request
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Origin    string                 `protobuf:"bytes,1,opt,name=origin,proto3" json:"origin,omitempty"`                         // id of the node that sent the request
+	TimeoutMs int64                  `protobuf:"varint,2,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"` // time left before the sender gives up on the request, 0 if it waits forever
+	TraceId   []byte                 `protobuf:"bytes,3,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`        // trace of the request (16 bytes), empty if it is not traced
+	SpanId    []byte                 `protobuf:"bytes,4,opt,name=span_id,json=spanId,proto3" json:"span_id,omitempty"`           // span of the sender the request belongs to (8 bytes)
 	// Types that are valid to be assigned to RequestType:
 	//
 	//	*Request_Ping
@@ -81,6 +142,11 @@ type Request struct {
 	//	*Request_ReplicaPut
 	//	*Request_ReplicaGet
 	//	*Request_StoreHint
+	//	*Request_TokenLoads
+	//	*Request_GossipTokenMove
+	//	*Request_ReplicaPutBatch
+	//	*Request_PutDelta
+	//	*Request_ReplicaDelta
 	RequestType   isRequest_RequestType `protobuf_oneof:"request_type"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -123,6 +189,27 @@ func (x *Request) GetOrigin() string {
 	return ""
 }
 
+func (x *Request) GetTimeoutMs() int64 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+func (x *Request) GetTraceId() []byte {
+	if x != nil {
+		return x.TraceId
+	}
+	return nil
+}
+
+func (x *Request) GetSpanId() []byte {
+	if x != nil {
+		return x.SpanId
+	}
+	return nil
+}
+
 func (x *Request) GetRequestType() isRequest_RequestType {
 	if x != nil {
 		return x.RequestType
@@ -229,6 +316,51 @@ func (x *Request) GetStoreHint() *RequestStoreHint {
 	return nil
 }
 
+func (x *Request) GetTokenLoads() *RequestTokenLoads {
+	if x != nil {
+		if x, ok := x.RequestType.(*Request_TokenLoads); ok {
+			return x.TokenLoads
+		}
+	}
+	return nil
+}
+
+func (x *Request) GetGossipTokenMove() *RequestGossipTokenMove {
+	if x != nil {
+		if x, ok := x.RequestType.(*Request_GossipTokenMove); ok {
+			return x.GossipTokenMove
+		}
+	}
+	return nil
+}
+
+func (x *Request) GetReplicaPutBatch() *RequestReplicaPutBatch {
+	if x != nil {
+		if x, ok := x.RequestType.(*Request_ReplicaPutBatch); ok {
+			return x.ReplicaPutBatch
+		}
+	}
+	return nil
+}
+
+func (x *Request) GetPutDelta() *RequestPutDelta {
+	if x != nil {
+		if x, ok := x.RequestType.(*Request_PutDelta); ok {
+			return x.PutDelta
+		}
+	}
+	return nil
+}
+
+func (x *Request) GetReplicaDelta() *RequestReplicaDelta {
+	if x != nil {
+		if x, ok := x.RequestType.(*Request_ReplicaDelta); ok {
+			return x.ReplicaDelta
+		}
+	}
+	return nil
+}
+
 type isRequest_RequestType interface {
 	isRequest_RequestType()
 }
@@ -277,6 +409,26 @@ type Request_StoreHint struct {
 	StoreHint *RequestStoreHint `protobuf:"bytes,21,opt,name=store_hint,json=storeHint,proto3,oneof"`
 }
 
+type Request_TokenLoads struct {
+	TokenLoads *RequestTokenLoads `protobuf:"bytes,22,opt,name=token_loads,json=tokenLoads,proto3,oneof"`
+}
+
+type Request_GossipTokenMove struct {
+	GossipTokenMove *RequestGossipTokenMove `protobuf:"bytes,23,opt,name=gossip_token_move,json=gossipTokenMove,proto3,oneof"`
+}
+
+type Request_ReplicaPutBatch struct {
+	ReplicaPutBatch *RequestReplicaPutBatch `protobuf:"bytes,24,opt,name=replica_put_batch,json=replicaPutBatch,proto3,oneof"`
+}
+
+type Request_PutDelta struct {
+	PutDelta *RequestPutDelta `protobuf:"bytes,25,opt,name=put_delta,json=putDelta,proto3,oneof"`
+}
+
+type Request_ReplicaDelta struct {
+	ReplicaDelta *RequestReplicaDelta `protobuf:"bytes,26,opt,name=replica_delta,json=replicaDelta,proto3,oneof"`
+}
+
 func (*Request_Ping) isRequest_RequestType() {}
 
 func (*Request_FetchRing) isRequest_RequestType() {}
@@ -299,6 +451,16 @@ func (*Request_ReplicaGet) isRequest_RequestType() {}
 
 func (*Request_StoreHint) isRequest_RequestType() {}
 
+func (*Request_TokenLoads) isRequest_RequestType() {}
+
+func (*Request_GossipTokenMove) isRequest_RequestType() {}
+
+func (*Request_ReplicaPutBatch) isRequest_RequestType() {}
+
+func (*Request_PutDelta) isRequest_RequestType() {}
+
+func (*Request_ReplicaDelta) isRequest_RequestType() {}
+
 type RequestPing struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -375,6 +537,7 @@ type RequestGossipJoin struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	NewNodeId     string                 `protobuf:"bytes,1,opt,name=new_node_id,json=newNodeId,proto3" json:"new_node_id,omitempty"`
 	Tokens        []uint64               `protobuf:"varint,2,rep,packed,name=tokens,proto3" json:"tokens,omitempty"`
+	Generation    uint64                 `protobuf:"varint,3,opt,name=generation,proto3" json:"generation,omitempty"` // membership generation of the tokens: a newer one replaces the tokens of the node as a whole
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -423,6 +586,13 @@ func (x *RequestGossipJoin) GetTokens() []uint64 {
 	return nil
 }
 
+func (x *RequestGossipJoin) GetGeneration() uint64 {
+	if x != nil {
+		return x.Generation
+	}
+	return 0
+}
+
 type RequestGetHashSpace struct {
 	state          protoimpl.MessageState `protogen:"open.v1"`
 	StartHashSpace uint64                 `protobuf:"varint,1,opt,name=start_hash_space,json=startHashSpace,proto3" json:"start_hash_space,omitempty"`
@@ -475,6 +645,110 @@ func (x *RequestGetHashSpace) GetEndHashSpace() uint64 {
 	return 0
 }
 
+type RequestGossipTokenMove struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	OldToken      uint64                 `protobuf:"varint,2,opt,name=old_token,json=oldToken,proto3" json:"old_token,omitempty"`
+	NewToken      uint64                 `protobuf:"varint,3,opt,name=new_token,json=newToken,proto3" json:"new_token,omitempty"`
+	Generation    uint64                 `protobuf:"varint,4,opt,name=generation,proto3" json:"generation,omitempty"` // membership generation of the node once the token is moved
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RequestGossipTokenMove) Reset() {
+	*x = RequestGossipTokenMove{}
+	mi := &file_node_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RequestGossipTokenMove) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RequestGossipTokenMove) ProtoMessage() {}
+
+func (x *RequestGossipTokenMove) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RequestGossipTokenMove.ProtoReflect.Descriptor instead.
+func (*RequestGossipTokenMove) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *RequestGossipTokenMove) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *RequestGossipTokenMove) GetOldToken() uint64 {
+	if x != nil {
+		return x.OldToken
+	}
+	return 0
+}
+
+func (x *RequestGossipTokenMove) GetNewToken() uint64 {
+	if x != nil {
+		return x.NewToken
+	}
+	return 0
+}
+
+func (x *RequestGossipTokenMove) GetGeneration() uint64 {
+	if x != nil {
+		return x.Generation
+	}
+	return 0
+}
+
+type RequestTokenLoads struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RequestTokenLoads) Reset() {
+	*x = RequestTokenLoads{}
+	mi := &file_node_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RequestTokenLoads) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RequestTokenLoads) ProtoMessage() {}
+
+func (x *RequestTokenLoads) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RequestTokenLoads.ProtoReflect.Descriptor instead.
+func (*RequestTokenLoads) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{7}
+}
+
 type RequestGet struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
@@ -484,7 +758,7 @@ type RequestGet struct {
 
 func (x *RequestGet) Reset() {
 	*x = RequestGet{}
-	mi := &file_node_proto_msgTypes[6]
+	mi := &file_node_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -496,7 +770,7 @@ func (x *RequestGet) String() string {
 func (*RequestGet) ProtoMessage() {}
 
 func (x *RequestGet) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[6]
+	mi := &file_node_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -509,7 +783,7 @@ func (x *RequestGet) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RequestGet.ProtoReflect.Descriptor instead.
 func (*RequestGet) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{6}
+	return file_node_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *RequestGet) GetKey() string {
@@ -529,7 +803,7 @@ type RequestPut struct {
 
 func (x *RequestPut) Reset() {
 	*x = RequestPut{}
-	mi := &file_node_proto_msgTypes[7]
+	mi := &file_node_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -541,7 +815,7 @@ func (x *RequestPut) String() string {
 func (*RequestPut) ProtoMessage() {}
 
 func (x *RequestPut) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[7]
+	mi := &file_node_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -554,7 +828,7 @@ func (x *RequestPut) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RequestPut.ProtoReflect.Descriptor instead.
 func (*RequestPut) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{7}
+	return file_node_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *RequestPut) GetKey() string {
@@ -580,7 +854,7 @@ type RequestDelete struct {
 
 func (x *RequestDelete) Reset() {
 	*x = RequestDelete{}
-	mi := &file_node_proto_msgTypes[8]
+	mi := &file_node_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -592,7 +866,7 @@ func (x *RequestDelete) String() string {
 func (*RequestDelete) ProtoMessage() {}
 
 func (x *RequestDelete) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[8]
+	mi := &file_node_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -605,7 +879,7 @@ func (x *RequestDelete) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RequestDelete.ProtoReflect.Descriptor instead.
 func (*RequestDelete) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{8}
+	return file_node_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *RequestDelete) GetKey() string {
@@ -624,7 +898,7 @@ type RequestHas struct {
 
 func (x *RequestHas) Reset() {
 	*x = RequestHas{}
-	mi := &file_node_proto_msgTypes[9]
+	mi := &file_node_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -636,7 +910,7 @@ func (x *RequestHas) String() string {
 func (*RequestHas) ProtoMessage() {}
 
 func (x *RequestHas) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[9]
+	mi := &file_node_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -649,7 +923,7 @@ func (x *RequestHas) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RequestHas.ProtoReflect.Descriptor instead.
 func (*RequestHas) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{9}
+	return file_node_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *RequestHas) GetKey() string {
@@ -669,7 +943,7 @@ type RequestReplicaPut struct {
 
 func (x *RequestReplicaPut) Reset() {
 	*x = RequestReplicaPut{}
-	mi := &file_node_proto_msgTypes[10]
+	mi := &file_node_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -681,7 +955,7 @@ func (x *RequestReplicaPut) String() string {
 func (*RequestReplicaPut) ProtoMessage() {}
 
 func (x *RequestReplicaPut) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[10]
+	mi := &file_node_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -694,7 +968,7 @@ func (x *RequestReplicaPut) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RequestReplicaPut.ProtoReflect.Descriptor instead.
 func (*RequestReplicaPut) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{10}
+	return file_node_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *RequestReplicaPut) GetKey() string {
@@ -720,7 +994,7 @@ type RequestReplicaGet struct {
 
 func (x *RequestReplicaGet) Reset() {
 	*x = RequestReplicaGet{}
-	mi := &file_node_proto_msgTypes[11]
+	mi := &file_node_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -732,7 +1006,7 @@ func (x *RequestReplicaGet) String() string {
 func (*RequestReplicaGet) ProtoMessage() {}
 
 func (x *RequestReplicaGet) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[11]
+	mi := &file_node_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -745,7 +1019,7 @@ func (x *RequestReplicaGet) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RequestReplicaGet.ProtoReflect.Descriptor instead.
 func (*RequestReplicaGet) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{11}
+	return file_node_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *RequestReplicaGet) GetKey() string {
@@ -755,30 +1029,28 @@ func (x *RequestReplicaGet) GetKey() string {
 	return ""
 }
 
-type RequestStoreHint struct {
+type RequestReplicaPutBatch struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	IntendedNode  string                 `protobuf:"bytes,1,opt,name=intended_node,json=intendedNode,proto3" json:"intended_node,omitempty"`
-	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
-	Value         []byte                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	Entries       []*RequestReplicaPut   `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *RequestStoreHint) Reset() {
-	*x = RequestStoreHint{}
-	mi := &file_node_proto_msgTypes[12]
+func (x *RequestReplicaPutBatch) Reset() {
+	*x = RequestReplicaPutBatch{}
+	mi := &file_node_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RequestStoreHint) String() string {
+func (x *RequestReplicaPutBatch) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RequestStoreHint) ProtoMessage() {}
+func (*RequestReplicaPutBatch) ProtoMessage() {}
 
-func (x *RequestStoreHint) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[12]
+func (x *RequestReplicaPutBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -789,26 +1061,176 @@ func (x *RequestStoreHint) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RequestStoreHint.ProtoReflect.Descriptor instead.
-func (*RequestStoreHint) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{12}
+// Deprecated: Use RequestReplicaPutBatch.ProtoReflect.Descriptor instead.
+func (*RequestReplicaPutBatch) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{14}
 }
 
-func (x *RequestStoreHint) GetIntendedNode() string {
+func (x *RequestReplicaPutBatch) GetEntries() []*RequestReplicaPut {
 	if x != nil {
-		return x.IntendedNode
+		return x.Entries
 	}
-	return ""
+	return nil
 }
 
-func (x *RequestStoreHint) GetKey() string {
+type RequestPutDelta struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Delta         []byte                 `protobuf:"bytes,2,opt,name=delta,proto3" json:"delta,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RequestPutDelta) Reset() {
+	*x = RequestPutDelta{}
+	mi := &file_node_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RequestPutDelta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RequestPutDelta) ProtoMessage() {}
+
+func (x *RequestPutDelta) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RequestPutDelta.ProtoReflect.Descriptor instead.
+func (*RequestPutDelta) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *RequestPutDelta) GetKey() string {
 	if x != nil {
 		return x.Key
 	}
 	return ""
 }
 
-func (x *RequestStoreHint) GetValue() []byte {
+func (x *RequestPutDelta) GetDelta() []byte {
+	if x != nil {
+		return x.Delta
+	}
+	return nil
+}
+
+type RequestReplicaDelta struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Delta         []byte                 `protobuf:"bytes,2,opt,name=delta,proto3" json:"delta,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RequestReplicaDelta) Reset() {
+	*x = RequestReplicaDelta{}
+	mi := &file_node_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RequestReplicaDelta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RequestReplicaDelta) ProtoMessage() {}
+
+func (x *RequestReplicaDelta) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RequestReplicaDelta.ProtoReflect.Descriptor instead.
+func (*RequestReplicaDelta) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *RequestReplicaDelta) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *RequestReplicaDelta) GetDelta() []byte {
+	if x != nil {
+		return x.Delta
+	}
+	return nil
+}
+
+type RequestStoreHint struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	IntendedNode  string                 `protobuf:"bytes,1,opt,name=intended_node,json=intendedNode,proto3" json:"intended_node,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Value         []byte                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RequestStoreHint) Reset() {
+	*x = RequestStoreHint{}
+	mi := &file_node_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RequestStoreHint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RequestStoreHint) ProtoMessage() {}
+
+func (x *RequestStoreHint) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RequestStoreHint.ProtoReflect.Descriptor instead.
+func (*RequestStoreHint) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *RequestStoreHint) GetIntendedNode() string {
+	if x != nil {
+		return x.IntendedNode
+	}
+	return ""
+}
+
+func (x *RequestStoreHint) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *RequestStoreHint) GetValue() []byte {
 	if x != nil {
 		return x.Value
 	}
@@ -816,10 +1238,11 @@ func (x *RequestStoreHint) GetValue() []byte {
 }
 
 type Response struct {
-	state  protoimpl.MessageState `protogen:"open.v1"`
-	Origin string                 `protobuf:"bytes,1,opt,name=origin,proto3" json:"origin,omitempty"` // id of the node that sent the response
-	Ok     bool                   `protobuf:"varint,2,opt,name=ok,proto3" json:"ok,omitempty"`
-	Error  string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Origin  string                 `protobuf:"bytes,1,opt,name=origin,proto3" json:"origin,omitempty"` // id of the node that sent the response
+	Ok      bool                   `protobuf:"varint,2,opt,name=ok,proto3" json:"ok,omitempty"`
+	Error   string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	Failure Failure                `protobuf:"varint,4,opt,name=failure,proto3,enum=Failure" json:"failure,omitempty"`
 	// Types that are valid to be assigned to ResponseType:
 	//
 	//	*Response_Ping
@@ -833,6 +1256,11 @@ type Response struct {
 	//	*Response_ReplicaPut
 	//	*Response_ReplicaGet
 	//	*Response_StoreHint
+	//	*Response_TokenLoads
+	//	*Response_GossipTokenMove
+	//	*Response_ReplicaPutBatch
+	//	*Response_PutDelta
+	//	*Response_ReplicaDelta
 	ResponseType  isResponse_ResponseType `protobuf_oneof:"response_type"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -840,7 +1268,7 @@ type Response struct {
 
 func (x *Response) Reset() {
 	*x = Response{}
-	mi := &file_node_proto_msgTypes[13]
+	mi := &file_node_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -852,7 +1280,7 @@ func (x *Response) String() string {
 func (*Response) ProtoMessage() {}
 
 func (x *Response) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[13]
+	mi := &file_node_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -865,7 +1293,7 @@ func (x *Response) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Response.ProtoReflect.Descriptor instead.
 func (*Response) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{13}
+	return file_node_proto_rawDescGZIP(), []int{18}
 }
 
 func (x *Response) GetOrigin() string {
@@ -889,6 +1317,13 @@ func (x *Response) GetError() string {
 	return ""
 }
 
+func (x *Response) GetFailure() Failure {
+	if x != nil {
+		return x.Failure
+	}
+	return Failure_FAILURE_UNSPECIFIED
+}
+
 func (x *Response) GetResponseType() isResponse_ResponseType {
 	if x != nil {
 		return x.ResponseType
@@ -995,6 +1430,51 @@ func (x *Response) GetStoreHint() *ResponseStoreHint {
 	return nil
 }
 
+func (x *Response) GetTokenLoads() *ResponseTokenLoads {
+	if x != nil {
+		if x, ok := x.ResponseType.(*Response_TokenLoads); ok {
+			return x.TokenLoads
+		}
+	}
+	return nil
+}
+
+func (x *Response) GetGossipTokenMove() *ResponseGossipTokenMove {
+	if x != nil {
+		if x, ok := x.ResponseType.(*Response_GossipTokenMove); ok {
+			return x.GossipTokenMove
+		}
+	}
+	return nil
+}
+
+func (x *Response) GetReplicaPutBatch() *ResponseReplicaPutBatch {
+	if x != nil {
+		if x, ok := x.ResponseType.(*Response_ReplicaPutBatch); ok {
+			return x.ReplicaPutBatch
+		}
+	}
+	return nil
+}
+
+func (x *Response) GetPutDelta() *ResponsePutDelta {
+	if x != nil {
+		if x, ok := x.ResponseType.(*Response_PutDelta); ok {
+			return x.PutDelta
+		}
+	}
+	return nil
+}
+
+func (x *Response) GetReplicaDelta() *ResponseReplicaDelta {
+	if x != nil {
+		if x, ok := x.ResponseType.(*Response_ReplicaDelta); ok {
+			return x.ReplicaDelta
+		}
+	}
+	return nil
+}
+
 type isResponse_ResponseType interface {
 	isResponse_ResponseType()
 }
@@ -1043,6 +1523,26 @@ type Response_StoreHint struct {
 	StoreHint *ResponseStoreHint `protobuf:"bytes,21,opt,name=store_hint,json=storeHint,proto3,oneof"`
 }
 
+type Response_TokenLoads struct {
+	TokenLoads *ResponseTokenLoads `protobuf:"bytes,22,opt,name=token_loads,json=tokenLoads,proto3,oneof"`
+}
+
+type Response_GossipTokenMove struct {
+	GossipTokenMove *ResponseGossipTokenMove `protobuf:"bytes,23,opt,name=gossip_token_move,json=gossipTokenMove,proto3,oneof"`
+}
+
+type Response_ReplicaPutBatch struct {
+	ReplicaPutBatch *ResponseReplicaPutBatch `protobuf:"bytes,24,opt,name=replica_put_batch,json=replicaPutBatch,proto3,oneof"`
+}
+
+type Response_PutDelta struct {
+	PutDelta *ResponsePutDelta `protobuf:"bytes,25,opt,name=put_delta,json=putDelta,proto3,oneof"`
+}
+
+type Response_ReplicaDelta struct {
+	ReplicaDelta *ResponseReplicaDelta `protobuf:"bytes,26,opt,name=replica_delta,json=replicaDelta,proto3,oneof"`
+}
+
 func (*Response_Ping) isResponse_ResponseType() {}
 
 func (*Response_FetchRing) isResponse_ResponseType() {}
@@ -1065,6 +1565,16 @@ func (*Response_ReplicaGet) isResponse_ResponseType() {}
 
 func (*Response_StoreHint) isResponse_ResponseType() {}
 
+func (*Response_TokenLoads) isResponse_ResponseType() {}
+
+func (*Response_GossipTokenMove) isResponse_ResponseType() {}
+
+func (*Response_ReplicaPutBatch) isResponse_ResponseType() {}
+
+func (*Response_PutDelta) isResponse_ResponseType() {}
+
+func (*Response_ReplicaDelta) isResponse_ResponseType() {}
+
 type ResponsePing struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	PongMessage   string                 `protobuf:"bytes,1,opt,name=pong_message,json=pongMessage,proto3" json:"pong_message,omitempty"`
@@ -1074,7 +1584,7 @@ type ResponsePing struct {
 
 func (x *ResponsePing) Reset() {
 	*x = ResponsePing{}
-	mi := &file_node_proto_msgTypes[14]
+	mi := &file_node_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1086,7 +1596,7 @@ func (x *ResponsePing) String() string {
 func (*ResponsePing) ProtoMessage() {}
 
 func (x *ResponsePing) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[14]
+	mi := &file_node_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1099,7 +1609,7 @@ func (x *ResponsePing) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResponsePing.ProtoReflect.Descriptor instead.
 func (*ResponsePing) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{14}
+	return file_node_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *ResponsePing) GetPongMessage() string {
@@ -1118,7 +1628,7 @@ type ResponseFetchRing struct {
 
 func (x *ResponseFetchRing) Reset() {
 	*x = ResponseFetchRing{}
-	mi := &file_node_proto_msgTypes[15]
+	mi := &file_node_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1130,7 +1640,7 @@ func (x *ResponseFetchRing) String() string {
 func (*ResponseFetchRing) ProtoMessage() {}
 
 func (x *ResponseFetchRing) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[15]
+	mi := &file_node_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1143,7 +1653,7 @@ func (x *ResponseFetchRing) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResponseFetchRing.ProtoReflect.Descriptor instead.
 func (*ResponseFetchRing) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{15}
+	return file_node_proto_rawDescGZIP(), []int{20}
 }
 
 func (x *ResponseFetchRing) GetRingView() *RingView {
@@ -1161,7 +1671,7 @@ type ResponseGossipJoin struct {
 
 func (x *ResponseGossipJoin) Reset() {
 	*x = ResponseGossipJoin{}
-	mi := &file_node_proto_msgTypes[16]
+	mi := &file_node_proto_msgTypes[21]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1173,7 +1683,7 @@ func (x *ResponseGossipJoin) String() string {
 func (*ResponseGossipJoin) ProtoMessage() {}
 
 func (x *ResponseGossipJoin) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[16]
+	mi := &file_node_proto_msgTypes[21]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1186,7 +1696,43 @@ func (x *ResponseGossipJoin) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResponseGossipJoin.ProtoReflect.Descriptor instead.
 func (*ResponseGossipJoin) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{16}
+	return file_node_proto_rawDescGZIP(), []int{21}
+}
+
+type ResponseGossipTokenMove struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResponseGossipTokenMove) Reset() {
+	*x = ResponseGossipTokenMove{}
+	mi := &file_node_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResponseGossipTokenMove) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResponseGossipTokenMove) ProtoMessage() {}
+
+func (x *ResponseGossipTokenMove) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResponseGossipTokenMove.ProtoReflect.Descriptor instead.
+func (*ResponseGossipTokenMove) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{22}
 }
 
 type ResponseGetHashSpace struct {
@@ -1198,7 +1744,7 @@ type ResponseGetHashSpace struct {
 
 func (x *ResponseGetHashSpace) Reset() {
 	*x = ResponseGetHashSpace{}
-	mi := &file_node_proto_msgTypes[17]
+	mi := &file_node_proto_msgTypes[23]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1210,7 +1756,7 @@ func (x *ResponseGetHashSpace) String() string {
 func (*ResponseGetHashSpace) ProtoMessage() {}
 
 func (x *ResponseGetHashSpace) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[17]
+	mi := &file_node_proto_msgTypes[23]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1223,7 +1769,7 @@ func (x *ResponseGetHashSpace) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResponseGetHashSpace.ProtoReflect.Descriptor instead.
 func (*ResponseGetHashSpace) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{17}
+	return file_node_proto_rawDescGZIP(), []int{23}
 }
 
 func (x *ResponseGetHashSpace) GetHashSpaceValues() map[string][]byte {
@@ -1242,7 +1788,7 @@ type ResponseGet struct {
 
 func (x *ResponseGet) Reset() {
 	*x = ResponseGet{}
-	mi := &file_node_proto_msgTypes[18]
+	mi := &file_node_proto_msgTypes[24]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1254,7 +1800,7 @@ func (x *ResponseGet) String() string {
 func (*ResponseGet) ProtoMessage() {}
 
 func (x *ResponseGet) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[18]
+	mi := &file_node_proto_msgTypes[24]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1267,7 +1813,7 @@ func (x *ResponseGet) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResponseGet.ProtoReflect.Descriptor instead.
 func (*ResponseGet) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{18}
+	return file_node_proto_rawDescGZIP(), []int{24}
 }
 
 func (x *ResponseGet) GetValue() []byte {
@@ -1285,7 +1831,7 @@ type ResponsePut struct {
 
 func (x *ResponsePut) Reset() {
 	*x = ResponsePut{}
-	mi := &file_node_proto_msgTypes[19]
+	mi := &file_node_proto_msgTypes[25]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1297,7 +1843,7 @@ func (x *ResponsePut) String() string {
 func (*ResponsePut) ProtoMessage() {}
 
 func (x *ResponsePut) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[19]
+	mi := &file_node_proto_msgTypes[25]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1310,7 +1856,7 @@ func (x *ResponsePut) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResponsePut.ProtoReflect.Descriptor instead.
 func (*ResponsePut) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{19}
+	return file_node_proto_rawDescGZIP(), []int{25}
 }
 
 type ResponseDelete struct {
@@ -1321,7 +1867,7 @@ type ResponseDelete struct {
 
 func (x *ResponseDelete) Reset() {
 	*x = ResponseDelete{}
-	mi := &file_node_proto_msgTypes[20]
+	mi := &file_node_proto_msgTypes[26]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1333,7 +1879,7 @@ func (x *ResponseDelete) String() string {
 func (*ResponseDelete) ProtoMessage() {}
 
 func (x *ResponseDelete) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[20]
+	mi := &file_node_proto_msgTypes[26]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1346,7 +1892,7 @@ func (x *ResponseDelete) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResponseDelete.ProtoReflect.Descriptor instead.
 func (*ResponseDelete) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{20}
+	return file_node_proto_rawDescGZIP(), []int{26}
 }
 
 type ResponseHas struct {
@@ -1358,7 +1904,7 @@ type ResponseHas struct {
 
 func (x *ResponseHas) Reset() {
 	*x = ResponseHas{}
-	mi := &file_node_proto_msgTypes[21]
+	mi := &file_node_proto_msgTypes[27]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1370,7 +1916,7 @@ func (x *ResponseHas) String() string {
 func (*ResponseHas) ProtoMessage() {}
 
 func (x *ResponseHas) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[21]
+	mi := &file_node_proto_msgTypes[27]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1383,7 +1929,7 @@ func (x *ResponseHas) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResponseHas.ProtoReflect.Descriptor instead.
 func (*ResponseHas) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{21}
+	return file_node_proto_rawDescGZIP(), []int{27}
 }
 
 func (x *ResponseHas) GetHasKey() bool {
@@ -1401,7 +1947,7 @@ type ResponseReplicaPut struct {
 
 func (x *ResponseReplicaPut) Reset() {
 	*x = ResponseReplicaPut{}
-	mi := &file_node_proto_msgTypes[22]
+	mi := &file_node_proto_msgTypes[28]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1413,7 +1959,7 @@ func (x *ResponseReplicaPut) String() string {
 func (*ResponseReplicaPut) ProtoMessage() {}
 
 func (x *ResponseReplicaPut) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[22]
+	mi := &file_node_proto_msgTypes[28]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1426,7 +1972,7 @@ func (x *ResponseReplicaPut) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResponseReplicaPut.ProtoReflect.Descriptor instead.
 func (*ResponseReplicaPut) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{22}
+	return file_node_proto_rawDescGZIP(), []int{28}
 }
 
 type ResponseReplicaGet struct {
@@ -1438,7 +1984,7 @@ type ResponseReplicaGet struct {
 
 func (x *ResponseReplicaGet) Reset() {
 	*x = ResponseReplicaGet{}
-	mi := &file_node_proto_msgTypes[23]
+	mi := &file_node_proto_msgTypes[29]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1450,7 +1996,7 @@ func (x *ResponseReplicaGet) String() string {
 func (*ResponseReplicaGet) ProtoMessage() {}
 
 func (x *ResponseReplicaGet) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[23]
+	mi := &file_node_proto_msgTypes[29]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1463,7 +2009,7 @@ func (x *ResponseReplicaGet) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResponseReplicaGet.ProtoReflect.Descriptor instead.
 func (*ResponseReplicaGet) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{23}
+	return file_node_proto_rawDescGZIP(), []int{29}
 }
 
 func (x *ResponseReplicaGet) GetValue() []byte {
@@ -1473,6 +2019,124 @@ func (x *ResponseReplicaGet) GetValue() []byte {
 	return nil
 }
 
+type ResponseReplicaPutBatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResponseReplicaPutBatch) Reset() {
+	*x = ResponseReplicaPutBatch{}
+	mi := &file_node_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResponseReplicaPutBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResponseReplicaPutBatch) ProtoMessage() {}
+
+func (x *ResponseReplicaPutBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResponseReplicaPutBatch.ProtoReflect.Descriptor instead.
+func (*ResponseReplicaPutBatch) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{30}
+}
+
+type ResponsePutDelta struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResponsePutDelta) Reset() {
+	*x = ResponsePutDelta{}
+	mi := &file_node_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResponsePutDelta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResponsePutDelta) ProtoMessage() {}
+
+func (x *ResponsePutDelta) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResponsePutDelta.ProtoReflect.Descriptor instead.
+func (*ResponsePutDelta) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{31}
+}
+
+// causal_gap is set when the replica is missing updates the delta depends on; the delta was not applied and the
+// sender should ship its full state instead
+type ResponseReplicaDelta struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CausalGap     bool                   `protobuf:"varint,1,opt,name=causal_gap,json=causalGap,proto3" json:"causal_gap,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResponseReplicaDelta) Reset() {
+	*x = ResponseReplicaDelta{}
+	mi := &file_node_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResponseReplicaDelta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResponseReplicaDelta) ProtoMessage() {}
+
+func (x *ResponseReplicaDelta) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResponseReplicaDelta.ProtoReflect.Descriptor instead.
+func (*ResponseReplicaDelta) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *ResponseReplicaDelta) GetCausalGap() bool {
+	if x != nil {
+		return x.CausalGap
+	}
+	return false
+}
+
 type ResponseStoreHint struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -1481,7 +2145,7 @@ type ResponseStoreHint struct {
 
 func (x *ResponseStoreHint) Reset() {
 	*x = ResponseStoreHint{}
-	mi := &file_node_proto_msgTypes[24]
+	mi := &file_node_proto_msgTypes[33]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1493,7 +2157,7 @@ func (x *ResponseStoreHint) String() string {
 func (*ResponseStoreHint) ProtoMessage() {}
 
 func (x *ResponseStoreHint) ProtoReflect() protoreflect.Message {
-	mi := &file_node_proto_msgTypes[24]
+	mi := &file_node_proto_msgTypes[33]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1506,7 +2170,111 @@ func (x *ResponseStoreHint) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResponseStoreHint.ProtoReflect.Descriptor instead.
 func (*ResponseStoreHint) Descriptor() ([]byte, []int) {
-	return file_node_proto_rawDescGZIP(), []int{24}
+	return file_node_proto_rawDescGZIP(), []int{33}
+}
+
+type TokenLoad struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Token         uint64                 `protobuf:"varint,1,opt,name=token,proto3" json:"token,omitempty"`
+	Keys          int64                  `protobuf:"varint,2,opt,name=keys,proto3" json:"keys,omitempty"`
+	Bytes         int64                  `protobuf:"varint,3,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TokenLoad) Reset() {
+	*x = TokenLoad{}
+	mi := &file_node_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TokenLoad) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TokenLoad) ProtoMessage() {}
+
+func (x *TokenLoad) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TokenLoad.ProtoReflect.Descriptor instead.
+func (*TokenLoad) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *TokenLoad) GetToken() uint64 {
+	if x != nil {
+		return x.Token
+	}
+	return 0
+}
+
+func (x *TokenLoad) GetKeys() int64 {
+	if x != nil {
+		return x.Keys
+	}
+	return 0
+}
+
+func (x *TokenLoad) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+type ResponseTokenLoads struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Loads         []*TokenLoad           `protobuf:"bytes,1,rep,name=loads,proto3" json:"loads,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResponseTokenLoads) Reset() {
+	*x = ResponseTokenLoads{}
+	mi := &file_node_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResponseTokenLoads) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResponseTokenLoads) ProtoMessage() {}
+
+func (x *ResponseTokenLoads) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResponseTokenLoads.ProtoReflect.Descriptor instead.
+func (*ResponseTokenLoads) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *ResponseTokenLoads) GetLoads() []*TokenLoad {
+	if x != nil {
+		return x.Loads
+	}
+	return nil
 }
 
 var File_node_proto protoreflect.FileDescriptor
@@ -1514,14 +2282,22 @@ var File_node_proto protoreflect.FileDescriptor
 const file_node_proto_rawDesc = "" +
 	"\n" +
 	"\n" +
-	"node.proto\"\x8a\x01\n" +
+	"node.proto\"\x88\x02\n" +
 	"\bRingView\x12>\n" +
-	"\rtoken_to_node\x18\x01 \x03(\v2\x1a.RingView.TokenToNodeEntryR\vtokenToNode\x1a>\n" +
+	"\rtoken_to_node\x18\x01 \x03(\v2\x1a.RingView.TokenToNodeEntryR\vtokenToNode\x12<\n" +
+	"\vgenerations\x18\x02 \x03(\v2\x1a.RingView.GenerationsEntryR\vgenerations\x1a>\n" +
 	"\x10TokenToNodeEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\x04R\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xad\x04\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a>\n" +
+	"\x10GenerationsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x04R\x05value:\x028\x01\"\xb3\a\n" +
 	"\aRequest\x12\x16\n" +
-	"\x06origin\x18\x01 \x01(\tR\x06origin\x12\"\n" +
+	"\x06origin\x18\x01 \x01(\tR\x06origin\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18\x02 \x01(\x03R\ttimeoutMs\x12\x19\n" +
+	"\btrace_id\x18\x03 \x01(\fR\atraceId\x12\x17\n" +
+	"\aspan_id\x18\x04 \x01(\fR\x06spanId\x12\"\n" +
 	"\x04ping\x18\v \x01(\v2\f.RequestPingH\x00R\x04ping\x122\n" +
 	"\n" +
 	"fetch_ring\x18\f \x01(\v2\x11.RequestFetchRingH\x00R\tfetchRing\x125\n" +
@@ -1537,16 +2313,33 @@ const file_node_proto_rawDesc = "" +
 	"\vreplica_get\x18\x14 \x01(\v2\x12.RequestReplicaGetH\x00R\n" +
 	"replicaGet\x122\n" +
 	"\n" +
-	"store_hint\x18\x15 \x01(\v2\x11.RequestStoreHintH\x00R\tstoreHintB\x0e\n" +
+	"store_hint\x18\x15 \x01(\v2\x11.RequestStoreHintH\x00R\tstoreHint\x125\n" +
+	"\vtoken_loads\x18\x16 \x01(\v2\x12.RequestTokenLoadsH\x00R\n" +
+	"tokenLoads\x12E\n" +
+	"\x11gossip_token_move\x18\x17 \x01(\v2\x17.RequestGossipTokenMoveH\x00R\x0fgossipTokenMove\x12E\n" +
+	"\x11replica_put_batch\x18\x18 \x01(\v2\x17.RequestReplicaPutBatchH\x00R\x0freplicaPutBatch\x12/\n" +
+	"\tput_delta\x18\x19 \x01(\v2\x10.RequestPutDeltaH\x00R\bputDelta\x12;\n" +
+	"\rreplica_delta\x18\x1a \x01(\v2\x14.RequestReplicaDeltaH\x00R\freplicaDeltaB\x0e\n" +
 	"\frequest_type\"\r\n" +
 	"\vRequestPing\"\x12\n" +
-	"\x10RequestFetchRing\"K\n" +
+	"\x10RequestFetchRing\"k\n" +
 	"\x11RequestGossipJoin\x12\x1e\n" +
 	"\vnew_node_id\x18\x01 \x01(\tR\tnewNodeId\x12\x16\n" +
-	"\x06tokens\x18\x02 \x03(\x04R\x06tokens\"e\n" +
+	"\x06tokens\x18\x02 \x03(\x04R\x06tokens\x12\x1e\n" +
+	"\n" +
+	"generation\x18\x03 \x01(\x04R\n" +
+	"generation\"e\n" +
 	"\x13RequestGetHashSpace\x12(\n" +
 	"\x10start_hash_space\x18\x01 \x01(\x04R\x0estartHashSpace\x12$\n" +
-	"\x0eend_hash_space\x18\x02 \x01(\x04R\fendHashSpace\"\x1e\n" +
+	"\x0eend_hash_space\x18\x02 \x01(\x04R\fendHashSpace\"\x8b\x01\n" +
+	"\x16RequestGossipTokenMove\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x1b\n" +
+	"\told_token\x18\x02 \x01(\x04R\boldToken\x12\x1b\n" +
+	"\tnew_token\x18\x03 \x01(\x04R\bnewToken\x12\x1e\n" +
+	"\n" +
+	"generation\x18\x04 \x01(\x04R\n" +
+	"generation\"\x13\n" +
+	"\x11RequestTokenLoads\"\x1e\n" +
 	"\n" +
 	"RequestGet\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\"4\n" +
@@ -1563,15 +2356,24 @@ const file_node_proto_rawDesc = "" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
 	"\x05value\x18\x02 \x01(\fR\x05value\"%\n" +
 	"\x11RequestReplicaGet\x12\x10\n" +
-	"\x03key\x18\x01 \x01(\tR\x03key\"_\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\"F\n" +
+	"\x16RequestReplicaPutBatch\x12,\n" +
+	"\aentries\x18\x01 \x03(\v2\x12.RequestReplicaPutR\aentries\"9\n" +
+	"\x0fRequestPutDelta\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05delta\x18\x02 \x01(\fR\x05delta\"=\n" +
+	"\x13RequestReplicaDelta\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05delta\x18\x02 \x01(\fR\x05delta\"_\n" +
 	"\x10RequestStoreHint\x12#\n" +
 	"\rintended_node\x18\x01 \x01(\tR\fintendedNode\x12\x10\n" +
 	"\x03key\x18\x02 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x03 \x01(\fR\x05value\"\xe0\x04\n" +
+	"\x05value\x18\x03 \x01(\fR\x05value\"\xbc\a\n" +
 	"\bResponse\x12\x16\n" +
 	"\x06origin\x18\x01 \x01(\tR\x06origin\x12\x0e\n" +
 	"\x02ok\x18\x02 \x01(\bR\x02ok\x12\x14\n" +
-	"\x05error\x18\x03 \x01(\tR\x05error\x12#\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12\"\n" +
+	"\afailure\x18\x04 \x01(\x0e2\b.FailureR\afailure\x12#\n" +
 	"\x04ping\x18\v \x01(\v2\r.ResponsePingH\x00R\x04ping\x123\n" +
 	"\n" +
 	"fetch_ring\x18\f \x01(\v2\x12.ResponseFetchRingH\x00R\tfetchRing\x126\n" +
@@ -1587,13 +2389,20 @@ const file_node_proto_rawDesc = "" +
 	"\vreplica_get\x18\x14 \x01(\v2\x13.ResponseReplicaGetH\x00R\n" +
 	"replicaGet\x123\n" +
 	"\n" +
-	"store_hint\x18\x15 \x01(\v2\x12.ResponseStoreHintH\x00R\tstoreHintB\x0f\n" +
+	"store_hint\x18\x15 \x01(\v2\x12.ResponseStoreHintH\x00R\tstoreHint\x126\n" +
+	"\vtoken_loads\x18\x16 \x01(\v2\x13.ResponseTokenLoadsH\x00R\n" +
+	"tokenLoads\x12F\n" +
+	"\x11gossip_token_move\x18\x17 \x01(\v2\x18.ResponseGossipTokenMoveH\x00R\x0fgossipTokenMove\x12F\n" +
+	"\x11replica_put_batch\x18\x18 \x01(\v2\x18.ResponseReplicaPutBatchH\x00R\x0freplicaPutBatch\x120\n" +
+	"\tput_delta\x18\x19 \x01(\v2\x11.ResponsePutDeltaH\x00R\bputDelta\x12<\n" +
+	"\rreplica_delta\x18\x1a \x01(\v2\x15.ResponseReplicaDeltaH\x00R\freplicaDeltaB\x0f\n" +
 	"\rresponse_type\"1\n" +
 	"\fResponsePing\x12!\n" +
 	"\fpong_message\x18\x01 \x01(\tR\vpongMessage\";\n" +
 	"\x11ResponseFetchRing\x12&\n" +
 	"\tring_view\x18\x01 \x01(\v2\t.RingViewR\bringView\"\x14\n" +
-	"\x12ResponseGossipJoin\"\xb0\x01\n" +
+	"\x12ResponseGossipJoin\"\x19\n" +
+	"\x17ResponseGossipTokenMove\"\xb0\x01\n" +
 	"\x14ResponseGetHashSpace\x12T\n" +
 	"\x0fhashSpaceValues\x18\x01 \x03(\v2*.ResponseGetHashSpace.HashSpaceValuesEntryR\x0fhashSpaceValues\x1aB\n" +
 	"\x14HashSpaceValuesEntry\x12\x10\n" +
@@ -1607,8 +2416,24 @@ const file_node_proto_rawDesc = "" +
 	"\ahas_key\x18\x01 \x01(\bR\x06hasKey\"\x14\n" +
 	"\x12ResponseReplicaPut\"*\n" +
 	"\x12ResponseReplicaGet\x12\x14\n" +
-	"\x05value\x18\x01 \x01(\fR\x05value\"\x13\n" +
-	"\x11ResponseStoreHintB'Z%gitlab.up.pt/classes/sdle/2025/t2/g01b\x06proto3"
+	"\x05value\x18\x01 \x01(\fR\x05value\"\x19\n" +
+	"\x17ResponseReplicaPutBatch\"\x12\n" +
+	"\x10ResponsePutDelta\"5\n" +
+	"\x14ResponseReplicaDelta\x12\x1d\n" +
+	"\n" +
+	"causal_gap\x18\x01 \x01(\bR\tcausalGap\"\x13\n" +
+	"\x11ResponseStoreHint\"K\n" +
+	"\tTokenLoad\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\x04R\x05token\x12\x12\n" +
+	"\x04keys\x18\x02 \x01(\x03R\x04keys\x12\x14\n" +
+	"\x05bytes\x18\x03 \x01(\x03R\x05bytes\"6\n" +
+	"\x12ResponseTokenLoads\x12 \n" +
+	"\x05loads\x18\x01 \x03(\v2\n" +
+	".TokenLoadR\x05loads*P\n" +
+	"\aFailure\x12\x17\n" +
+	"\x13FAILURE_UNSPECIFIED\x10\x00\x12\x15\n" +
+	"\x11FAILURE_MALFORMED\x10\x01\x12\x15\n" +
+	"\x11FAILURE_NOT_FOUND\x10\x02B'Z%gitlab.up.pt/classes/sdle/2025/t2/g01b\x06proto3"
 
 var (
 	file_node_proto_rawDescOnce sync.Once
@@ -1622,67 +2447,95 @@ func file_node_proto_rawDescGZIP() []byte {
 	return file_node_proto_rawDescData
 }
 
-var file_node_proto_msgTypes = make([]protoimpl.MessageInfo, 27)
+var file_node_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_node_proto_msgTypes = make([]protoimpl.MessageInfo, 39)
 var file_node_proto_goTypes = []any{
-	(*RingView)(nil),             // 0: RingView
-	(*Request)(nil),              // 1: Request
-	(*RequestPing)(nil),          // 2: RequestPing
-	(*RequestFetchRing)(nil),     // 3: RequestFetchRing
-	(*RequestGossipJoin)(nil),    // 4: RequestGossipJoin
-	(*RequestGetHashSpace)(nil),  // 5: RequestGetHashSpace
-	(*RequestGet)(nil),           // 6: RequestGet
-	(*RequestPut)(nil),           // 7: RequestPut
-	(*RequestDelete)(nil),        // 8: RequestDelete
-	(*RequestHas)(nil),           // 9: RequestHas
-	(*RequestReplicaPut)(nil),    // 10: RequestReplicaPut
-	(*RequestReplicaGet)(nil),    // 11: RequestReplicaGet
-	(*RequestStoreHint)(nil),     // 12: RequestStoreHint
-	(*Response)(nil),             // 13: Response
-	(*ResponsePing)(nil),         // 14: ResponsePing
-	(*ResponseFetchRing)(nil),    // 15: ResponseFetchRing
-	(*ResponseGossipJoin)(nil),   // 16: ResponseGossipJoin
-	(*ResponseGetHashSpace)(nil), // 17: ResponseGetHashSpace
-	(*ResponseGet)(nil),          // 18: ResponseGet
-	(*ResponsePut)(nil),          // 19: ResponsePut
-	(*ResponseDelete)(nil),       // 20: ResponseDelete
-	(*ResponseHas)(nil),          // 21: ResponseHas
-	(*ResponseReplicaPut)(nil),   // 22: ResponseReplicaPut
-	(*ResponseReplicaGet)(nil),   // 23: ResponseReplicaGet
-	(*ResponseStoreHint)(nil),    // 24: ResponseStoreHint
-	nil,                          // 25: RingView.TokenToNodeEntry
-	nil,                          // 26: ResponseGetHashSpace.HashSpaceValuesEntry
+	(Failure)(0),                    // 0: Failure
+	(*RingView)(nil),                // 1: RingView
+	(*Request)(nil),                 // 2: Request
+	(*RequestPing)(nil),             // 3: RequestPing
+	(*RequestFetchRing)(nil),        // 4: RequestFetchRing
+	(*RequestGossipJoin)(nil),       // 5: RequestGossipJoin
+	(*RequestGetHashSpace)(nil),     // 6: RequestGetHashSpace
+	(*RequestGossipTokenMove)(nil),  // 7: RequestGossipTokenMove
+	(*RequestTokenLoads)(nil),       // 8: RequestTokenLoads
+	(*RequestGet)(nil),              // 9: RequestGet
+	(*RequestPut)(nil),              // 10: RequestPut
+	(*RequestDelete)(nil),           // 11: RequestDelete
+	(*RequestHas)(nil),              // 12: RequestHas
+	(*RequestReplicaPut)(nil),       // 13: RequestReplicaPut
+	(*RequestReplicaGet)(nil),       // 14: RequestReplicaGet
+	(*RequestReplicaPutBatch)(nil),  // 15: RequestReplicaPutBatch
+	(*RequestPutDelta)(nil),         // 16: RequestPutDelta
+	(*RequestReplicaDelta)(nil),     // 17: RequestReplicaDelta
+	(*RequestStoreHint)(nil),        // 18: RequestStoreHint
+	(*Response)(nil),                // 19: Response
+	(*ResponsePing)(nil),            // 20: ResponsePing
+	(*ResponseFetchRing)(nil),       // 21: ResponseFetchRing
+	(*ResponseGossipJoin)(nil),      // 22: ResponseGossipJoin
+	(*ResponseGossipTokenMove)(nil), // 23: ResponseGossipTokenMove
+	(*ResponseGetHashSpace)(nil),    // 24: ResponseGetHashSpace
+	(*ResponseGet)(nil),             // 25: ResponseGet
+	(*ResponsePut)(nil),             // 26: ResponsePut
+	(*ResponseDelete)(nil),          // 27: ResponseDelete
+	(*ResponseHas)(nil),             // 28: ResponseHas
+	(*ResponseReplicaPut)(nil),      // 29: ResponseReplicaPut
+	(*ResponseReplicaGet)(nil),      // 30: ResponseReplicaGet
+	(*ResponseReplicaPutBatch)(nil), // 31: ResponseReplicaPutBatch
+	(*ResponsePutDelta)(nil),        // 32: ResponsePutDelta
+	(*ResponseReplicaDelta)(nil),    // 33: ResponseReplicaDelta
+	(*ResponseStoreHint)(nil),       // 34: ResponseStoreHint
+	(*TokenLoad)(nil),               // 35: TokenLoad
+	(*ResponseTokenLoads)(nil),      // 36: ResponseTokenLoads
+	nil,                             // 37: RingView.TokenToNodeEntry
+	nil,                             // 38: RingView.GenerationsEntry
+	nil,                             // 39: ResponseGetHashSpace.HashSpaceValuesEntry
 }
 var file_node_proto_depIdxs = []int32{
-	25, // 0: RingView.token_to_node:type_name -> RingView.TokenToNodeEntry
-	2,  // 1: Request.ping:type_name -> RequestPing
-	3,  // 2: Request.fetch_ring:type_name -> RequestFetchRing
-	4,  // 3: Request.gossip_join:type_name -> RequestGossipJoin
-	5,  // 4: Request.get_hash_space:type_name -> RequestGetHashSpace
-	6,  // 5: Request.get:type_name -> RequestGet
-	7,  // 6: Request.put:type_name -> RequestPut
-	8,  // 7: Request.delete:type_name -> RequestDelete
-	9,  // 8: Request.has:type_name -> RequestHas
-	10, // 9: Request.replica_put:type_name -> RequestReplicaPut
-	11, // 10: Request.replica_get:type_name -> RequestReplicaGet
-	12, // 11: Request.store_hint:type_name -> RequestStoreHint
-	14, // 12: Response.ping:type_name -> ResponsePing
-	15, // 13: Response.fetch_ring:type_name -> ResponseFetchRing
-	16, // 14: Response.gossip_join:type_name -> ResponseGossipJoin
-	17, // 15: Response.get_hash_space:type_name -> ResponseGetHashSpace
-	18, // 16: Response.get:type_name -> ResponseGet
-	19, // 17: Response.put:type_name -> ResponsePut
-	20, // 18: Response.delete:type_name -> ResponseDelete
-	21, // 19: Response.has:type_name -> ResponseHas
-	22, // 20: Response.replica_put:type_name -> ResponseReplicaPut
-	23, // 21: Response.replica_get:type_name -> ResponseReplicaGet
-	24, // 22: Response.store_hint:type_name -> ResponseStoreHint
-	0,  // 23: ResponseFetchRing.ring_view:type_name -> RingView
-	26, // 24: ResponseGetHashSpace.hashSpaceValues:type_name -> ResponseGetHashSpace.HashSpaceValuesEntry
-	25, // [25:25] is the sub-list for method output_type
-	25, // [25:25] is the sub-list for method input_type
-	25, // [25:25] is the sub-list for extension type_name
-	25, // [25:25] is the sub-list for extension extendee
-	0,  // [0:25] is the sub-list for field type_name
+	37, // 0: RingView.token_to_node:type_name -> RingView.TokenToNodeEntry
+	38, // 1: RingView.generations:type_name -> RingView.GenerationsEntry
+	3,  // 2: Request.ping:type_name -> RequestPing
+	4,  // 3: Request.fetch_ring:type_name -> RequestFetchRing
+	5,  // 4: Request.gossip_join:type_name -> RequestGossipJoin
+	6,  // 5: Request.get_hash_space:type_name -> RequestGetHashSpace
+	9,  // 6: Request.get:type_name -> RequestGet
+	10, // 7: Request.put:type_name -> RequestPut
+	11, // 8: Request.delete:type_name -> RequestDelete
+	12, // 9: Request.has:type_name -> RequestHas
+	13, // 10: Request.replica_put:type_name -> RequestReplicaPut
+	14, // 11: Request.replica_get:type_name -> RequestReplicaGet
+	18, // 12: Request.store_hint:type_name -> RequestStoreHint
+	8,  // 13: Request.token_loads:type_name -> RequestTokenLoads
+	7,  // 14: Request.gossip_token_move:type_name -> RequestGossipTokenMove
+	15, // 15: Request.replica_put_batch:type_name -> RequestReplicaPutBatch
+	16, // 16: Request.put_delta:type_name -> RequestPutDelta
+	17, // 17: Request.replica_delta:type_name -> RequestReplicaDelta
+	13, // 18: RequestReplicaPutBatch.entries:type_name -> RequestReplicaPut
+	0,  // 19: Response.failure:type_name -> Failure
+	20, // 20: Response.ping:type_name -> ResponsePing
+	21, // 21: Response.fetch_ring:type_name -> ResponseFetchRing
+	22, // 22: Response.gossip_join:type_name -> ResponseGossipJoin
+	24, // 23: Response.get_hash_space:type_name -> ResponseGetHashSpace
+	25, // 24: Response.get:type_name -> ResponseGet
+	26, // 25: Response.put:type_name -> ResponsePut
+	27, // 26: Response.delete:type_name -> ResponseDelete
+	28, // 27: Response.has:type_name -> ResponseHas
+	29, // 28: Response.replica_put:type_name -> ResponseReplicaPut
+	30, // 29: Response.replica_get:type_name -> ResponseReplicaGet
+	34, // 30: Response.store_hint:type_name -> ResponseStoreHint
+	36, // 31: Response.token_loads:type_name -> ResponseTokenLoads
+	23, // 32: Response.gossip_token_move:type_name -> ResponseGossipTokenMove
+	31, // 33: Response.replica_put_batch:type_name -> ResponseReplicaPutBatch
+	32, // 34: Response.put_delta:type_name -> ResponsePutDelta
+	33, // 35: Response.replica_delta:type_name -> ResponseReplicaDelta
+	1,  // 36: ResponseFetchRing.ring_view:type_name -> RingView
+	39, // 37: ResponseGetHashSpace.hashSpaceValues:type_name -> ResponseGetHashSpace.HashSpaceValuesEntry
+	35, // 38: ResponseTokenLoads.loads:type_name -> TokenLoad
+	39, // [39:39] is the sub-list for method output_type
+	39, // [39:39] is the sub-list for method input_type
+	39, // [39:39] is the sub-list for extension type_name
+	39, // [39:39] is the sub-list for extension extendee
+	0,  // [0:39] is the sub-list for field type_name
 }
 
 func init() { file_node_proto_init() }
@@ -1702,8 +2555,13 @@ func file_node_proto_init() {
 		(*Request_ReplicaPut)(nil),
 		(*Request_ReplicaGet)(nil),
 		(*Request_StoreHint)(nil),
+		(*Request_TokenLoads)(nil),
+		(*Request_GossipTokenMove)(nil),
+		(*Request_ReplicaPutBatch)(nil),
+		(*Request_PutDelta)(nil),
+		(*Request_ReplicaDelta)(nil),
 	}
-	file_node_proto_msgTypes[13].OneofWrappers = []any{
+	file_node_proto_msgTypes[18].OneofWrappers = []any{
 		(*Response_Ping)(nil),
 		(*Response_FetchRing)(nil),
 		(*Response_GossipJoin)(nil),
@@ -1715,19 +2573,25 @@ func file_node_proto_init() {
 		(*Response_ReplicaPut)(nil),
 		(*Response_ReplicaGet)(nil),
 		(*Response_StoreHint)(nil),
+		(*Response_TokenLoads)(nil),
+		(*Response_GossipTokenMove)(nil),
+		(*Response_ReplicaPutBatch)(nil),
+		(*Response_PutDelta)(nil),
+		(*Response_ReplicaDelta)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_node_proto_rawDesc), len(file_node_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   27,
+			NumEnums:      1,
+			NumMessages:   39,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
 		GoTypes:           file_node_proto_goTypes,
 		DependencyIndexes: file_node_proto_depIdxs,
+		EnumInfos:         file_node_proto_enumTypes,
 		MessageInfos:      file_node_proto_msgTypes,
 	}.Build()
 	File_node_proto = out.File
@@ -157,6 +157,137 @@ func (x *ShoppingList) GetDotContext() *DotContext {
 	return nil
 }
 
+// Who may access a shopping list. The owner may also change the entry; editors may read and write the list, viewers
+// may only read it.
+type ListAcl struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ListId        string                 `protobuf:"bytes,1,opt,name=list_id,json=listId,proto3" json:"list_id,omitempty"`
+	Owner         string                 `protobuf:"bytes,2,opt,name=owner,proto3" json:"owner,omitempty"`
+	Editors       []string               `protobuf:"bytes,3,rep,name=editors,proto3" json:"editors,omitempty"`
+	Viewers       []string               `protobuf:"bytes,4,rep,name=viewers,proto3" json:"viewers,omitempty"`
+	Version       uint64                 `protobuf:"varint,5,opt,name=version,proto3" json:"version,omitempty"` // incremented on every change: replicas keep the entry with the highest version
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAcl) Reset() {
+	*x = ListAcl{}
+	mi := &file_shopping_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAcl) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAcl) ProtoMessage() {}
+
+func (x *ListAcl) ProtoReflect() protoreflect.Message {
+	mi := &file_shopping_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAcl.ProtoReflect.Descriptor instead.
+func (*ListAcl) Descriptor() ([]byte, []int) {
+	return file_shopping_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListAcl) GetListId() string {
+	if x != nil {
+		return x.ListId
+	}
+	return ""
+}
+
+func (x *ListAcl) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *ListAcl) GetEditors() []string {
+	if x != nil {
+		return x.Editors
+	}
+	return nil
+}
+
+func (x *ListAcl) GetViewers() []string {
+	if x != nil {
+		return x.Viewers
+	}
+	return nil
+}
+
+func (x *ListAcl) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+// Share capabilities of a list that were revoked. Replicas merge their sets by union.
+type RevokedCapabilities struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ListId        string                 `protobuf:"bytes,1,opt,name=list_id,json=listId,proto3" json:"list_id,omitempty"`
+	Expires       map[string]int64       `protobuf:"bytes,2,rep,name=expires,proto3" json:"expires,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // capability ID -> when it expires (Unix seconds, 0 if never); dropped once expired
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokedCapabilities) Reset() {
+	*x = RevokedCapabilities{}
+	mi := &file_shopping_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokedCapabilities) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokedCapabilities) ProtoMessage() {}
+
+func (x *RevokedCapabilities) ProtoReflect() protoreflect.Message {
+	mi := &file_shopping_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevokedCapabilities.ProtoReflect.Descriptor instead.
+func (*RevokedCapabilities) Descriptor() ([]byte, []int) {
+	return file_shopping_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RevokedCapabilities) GetListId() string {
+	if x != nil {
+		return x.ListId
+	}
+	return ""
+}
+
+func (x *RevokedCapabilities) GetExpires() map[string]int64 {
+	if x != nil {
+		return x.Expires
+	}
+	return nil
+}
+
 var File_shopping_proto protoreflect.FileDescriptor
 
 const file_shopping_proto_rawDesc = "" +
@@ -177,7 +308,19 @@ const file_shopping_proto_rawDesc = "" +
 	"\n" +
 	"ItemsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12#\n" +
-	"\x05value\x18\x02 \x01(\v2\r.ShoppingItemR\x05value:\x028\x01B'Z%gitlab.up.pt/classes/sdle/2025/t2/g01b\x06proto3"
+	"\x05value\x18\x02 \x01(\v2\r.ShoppingItemR\x05value:\x028\x01\"\x86\x01\n" +
+	"\aListAcl\x12\x17\n" +
+	"\alist_id\x18\x01 \x01(\tR\x06listId\x12\x14\n" +
+	"\x05owner\x18\x02 \x01(\tR\x05owner\x12\x18\n" +
+	"\aeditors\x18\x03 \x03(\tR\aeditors\x12\x18\n" +
+	"\aviewers\x18\x04 \x03(\tR\aviewers\x12\x18\n" +
+	"\aversion\x18\x05 \x01(\x04R\aversion\"\xa7\x01\n" +
+	"\x13RevokedCapabilities\x12\x17\n" +
+	"\alist_id\x18\x01 \x01(\tR\x06listId\x12;\n" +
+	"\aexpires\x18\x02 \x03(\v2!.RevokedCapabilities.ExpiresEntryR\aexpires\x1a:\n" +
+	"\fExpiresEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01B'Z%gitlab.up.pt/classes/sdle/2025/t2/g01b\x06proto3"
 
 var (
 	file_shopping_proto_rawDescOnce sync.Once
@@ -191,30 +334,34 @@ func file_shopping_proto_rawDescGZIP() []byte {
 	return file_shopping_proto_rawDescData
 }
 
-var file_shopping_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_shopping_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
 var file_shopping_proto_goTypes = []any{
-	(*ShoppingItem)(nil), // 0: ShoppingItem
-	(*ShoppingList)(nil), // 1: ShoppingList
-	nil,                  // 2: ShoppingList.ItemsEntry
-	(*StringMVReg)(nil),  // 3: StringMVReg
-	(*CCounter)(nil),     // 4: CCounter
-	(*DWFlag)(nil),       // 5: DWFlag
-	(*DotContext)(nil),   // 6: DotContext
+	(*ShoppingItem)(nil),        // 0: ShoppingItem
+	(*ShoppingList)(nil),        // 1: ShoppingList
+	(*ListAcl)(nil),             // 2: ListAcl
+	(*RevokedCapabilities)(nil), // 3: RevokedCapabilities
+	nil,                         // 4: ShoppingList.ItemsEntry
+	nil,                         // 5: RevokedCapabilities.ExpiresEntry
+	(*StringMVReg)(nil),         // 6: StringMVReg
+	(*CCounter)(nil),            // 7: CCounter
+	(*DWFlag)(nil),              // 8: DWFlag
+	(*DotContext)(nil),          // 9: DotContext
 }
 var file_shopping_proto_depIdxs = []int32{
-	3, // 0: ShoppingItem.name:type_name -> StringMVReg
-	4, // 1: ShoppingItem.quantity:type_name -> CCounter
-	4, // 2: ShoppingItem.acquired:type_name -> CCounter
-	5, // 3: ShoppingItem.deleted:type_name -> DWFlag
-	3, // 4: ShoppingList.name:type_name -> StringMVReg
-	2, // 5: ShoppingList.items:type_name -> ShoppingList.ItemsEntry
-	6, // 6: ShoppingList.dot_context:type_name -> DotContext
-	0, // 7: ShoppingList.ItemsEntry.value:type_name -> ShoppingItem
-	8, // [8:8] is the sub-list for method output_type
-	8, // [8:8] is the sub-list for method input_type
-	8, // [8:8] is the sub-list for extension type_name
-	8, // [8:8] is the sub-list for extension extendee
-	0, // [0:8] is the sub-list for field type_name
+	6, // 0: ShoppingItem.name:type_name -> StringMVReg
+	7, // 1: ShoppingItem.quantity:type_name -> CCounter
+	7, // 2: ShoppingItem.acquired:type_name -> CCounter
+	8, // 3: ShoppingItem.deleted:type_name -> DWFlag
+	6, // 4: ShoppingList.name:type_name -> StringMVReg
+	4, // 5: ShoppingList.items:type_name -> ShoppingList.ItemsEntry
+	9, // 6: ShoppingList.dot_context:type_name -> DotContext
+	5, // 7: RevokedCapabilities.expires:type_name -> RevokedCapabilities.ExpiresEntry
+	0, // 8: ShoppingList.ItemsEntry.value:type_name -> ShoppingItem
+	9, // [9:9] is the sub-list for method output_type
+	9, // [9:9] is the sub-list for method input_type
+	9, // [9:9] is the sub-list for extension type_name
+	9, // [9:9] is the sub-list for extension extendee
+	0, // [0:9] is the sub-list for field type_name
 }
 
 func init() { file_shopping_proto_init() }
@@ -229,7 +376,7 @@ func file_shopping_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_shopping_proto_rawDesc), len(file_shopping_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   3,
+			NumMessages:   6,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -0,0 +1,163 @@
+package rebalance
+
+import (
+	"sdle-server/config"
+	rv "sdle-server/ringview"
+	"slices"
+)
+
+// Estimated storage overhead of a key, so that ranges with many small values still count as loaded
+const KeyOverhead = 64
+
+// Load of the hash space owned by a single token
+type TokenLoad struct {
+	Token  uint64
+	NodeId string
+	Keys   int64
+	Bytes  int64
+}
+
+func (l TokenLoad) Weight() int64 {
+	return l.Bytes + l.Keys*KeyOverhead
+}
+
+// A proposal to shrink the range of Token, handing part of its load (Target) to Receiver (the owner of the next token)
+type Proposal struct {
+	Token    uint64
+	Receiver string
+	Target   int64
+}
+
+type Planner struct {
+	Threshold float64 // load factor (relative to the mean) above which a node gives load away
+	MinLoad   int64   // nodes below this load are never rebalanced
+}
+
+func NewPlanner(cfg config.Config) Planner {
+	return Planner{
+		Threshold: cfg.RebalanceThreshold,
+		MinLoad:   cfg.RebalanceMinLoad,
+	}
+}
+
+// Sums the load of every node
+func NodeLoads(loads []TokenLoad) map[string]int64 {
+	result := make(map[string]int64)
+	for _, l := range loads {
+		result[l.NodeId] += l.Weight()
+	}
+	return result
+}
+
+// Returns the ratio between the most loaded node and the mean load (1 means perfectly balanced)
+func Imbalance(nodeLoads map[string]int64) float64 {
+	if len(nodeLoads) == 0 {
+		return 1
+	}
+
+	total, highest := int64(0), int64(0)
+	for _, load := range nodeLoads {
+		total += load
+		highest = max(highest, load)
+	}
+
+	if total == 0 {
+		return 1
+	}
+
+	mean := float64(total) / float64(len(nodeLoads))
+	return float64(highest) / mean
+}
+
+// Decides if nodeId should give away part of one of its ranges. Only the most loaded node proposes moves, so that
+// concurrent rebalancers on different nodes do not fight over the same ranges.
+// successors maps each token to the owner of the next token in the ring.
+func (p Planner) Propose(nodeId string, loads []TokenLoad, successors map[uint64]string) (Proposal, bool) {
+	nodeLoads := NodeLoads(loads)
+	if len(nodeLoads) < 2 {
+		return Proposal{}, false
+	}
+
+	// Ties are broken by node ID so that exactly one node considers itself the most loaded
+	mostLoaded := ""
+	for id, load := range nodeLoads {
+		if mostLoaded == "" || load > nodeLoads[mostLoaded] || (load == nodeLoads[mostLoaded] && id < mostLoaded) {
+			mostLoaded = id
+		}
+	}
+
+	if mostLoaded != nodeId || nodeLoads[nodeId] < p.MinLoad || Imbalance(nodeLoads) < p.Threshold {
+		return Proposal{}, false
+	}
+
+	total := int64(0)
+	for _, load := range nodeLoads {
+		total += load
+	}
+	excess := nodeLoads[nodeId] - total/int64(len(nodeLoads))
+
+	// Try the heaviest ranges first
+	own := []TokenLoad{}
+	for _, l := range loads {
+		if l.NodeId == nodeId {
+			own = append(own, l)
+		}
+	}
+	slices.SortFunc(own, func(a, b TokenLoad) int {
+		if a.Weight() != b.Weight() {
+			return int(b.Weight() - a.Weight())
+		}
+		return int(a.Token) - int(b.Token)
+	})
+
+	for _, l := range own {
+		receiver, ok := successors[l.Token]
+		if !ok || receiver == nodeId || l.Weight() == 0 {
+			continue
+		}
+
+		return Proposal{
+			Token:    l.Token,
+			Receiver: receiver,
+			Target:   min(excess, l.Weight()/2),
+		}, true
+	}
+
+	return Proposal{}, false
+}
+
+// Finds the new position of the token r.End so that the keys in (newToken, r.End] weigh about target.
+// sizes maps each key in the range to its size in bytes. Returns the new token and the weight that changes owner.
+func SplitPoint(r rv.TokenRange, sizes map[string]int64, target int64) (newToken uint64, moved int64, ok bool) {
+	hashSpaceSize := config.DefaultConfig().HashSpaceSize
+	distance := func(hash uint64) uint64 { // distance from the token, going backwards
+		return (r.End + hashSpaceSize - hash) % hashSpaceSize
+	}
+
+	weights := make(map[uint64]int64)
+	for key, size := range sizes {
+		weights[rv.HashKey(key)] += size + KeyOverhead
+	}
+
+	hashes := make([]uint64, 0, len(weights))
+	for hash := range weights {
+		hashes = append(hashes, hash)
+	}
+	slices.SortFunc(hashes, func(a, b uint64) int {
+		return int(distance(a)) - int(distance(b))
+	})
+
+	// Keys hashed exactly at the token lie in (newToken, r.End] whatever the new token, so they always change owner.
+	// The last hash always stays, so the shrunk range is never empty.
+	for i := 0; i < len(hashes)-1; i++ {
+		moved += weights[hashes[i]]
+		if moved >= target {
+			return hashes[i+1], moved, true
+		}
+	}
+
+	if moved == 0 {
+		return 0, 0, false
+	}
+	return hashes[len(hashes)-1], moved, true
+}
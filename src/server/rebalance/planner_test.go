@@ -0,0 +1,145 @@
+package rebalance
+
+import (
+	rv "sdle-server/ringview"
+	"testing"
+)
+
+func TestPlanner_ProposeMostLoadedNode(t *testing.T) {
+	loads := []TokenLoad{
+		{Token: 100, NodeId: "a", Keys: 100, Bytes: 100000},
+		{Token: 200, NodeId: "b", Keys: 1, Bytes: 100},
+		{Token: 300, NodeId: "c", Keys: 1, Bytes: 100},
+	}
+	successors := map[uint64]string{100: "b", 200: "c", 300: "a"}
+	planner := Planner{Threshold: 1.25, MinLoad: 0}
+
+	proposal, ok := planner.Propose("a", loads, successors)
+	if !ok {
+		t.Fatalf("Expected a proposal for the most loaded node")
+	}
+	if proposal.Token != 100 || proposal.Receiver != "b" {
+		t.Errorf("Expected token 100 to be handed to b, got %+v", proposal)
+	}
+	if proposal.Target <= 0 || proposal.Target > loads[0].Weight()/2 {
+		t.Errorf("Expected target between 0 and half the range weight, got %d", proposal.Target)
+	}
+
+	if _, ok := planner.Propose("b", loads, successors); ok {
+		t.Errorf("Expected no proposal for a node that is not the most loaded")
+	}
+}
+
+func TestPlanner_ProposeBalanced(t *testing.T) {
+	loads := []TokenLoad{
+		{Token: 100, NodeId: "a", Keys: 10, Bytes: 1000},
+		{Token: 200, NodeId: "b", Keys: 10, Bytes: 1000},
+	}
+	successors := map[uint64]string{100: "b", 200: "a"}
+
+	if _, ok := (Planner{Threshold: 1.25}).Propose("a", loads, successors); ok {
+		t.Errorf("Expected no proposal for a balanced ring")
+	}
+}
+
+func TestPlanner_ProposeMinLoad(t *testing.T) {
+	loads := []TokenLoad{
+		{Token: 100, NodeId: "a", Keys: 10, Bytes: 1000},
+		{Token: 200, NodeId: "b", Keys: 0, Bytes: 0},
+	}
+	successors := map[uint64]string{100: "b", 200: "a"}
+
+	if _, ok := (Planner{Threshold: 1.25, MinLoad: 1 << 20}).Propose("a", loads, successors); ok {
+		t.Errorf("Expected no proposal below the minimum load")
+	}
+}
+
+func TestPlanner_ProposeSkipsOwnSuccessor(t *testing.T) {
+	loads := []TokenLoad{
+		{Token: 100, NodeId: "a", Keys: 100, Bytes: 100000},
+		{Token: 150, NodeId: "a", Keys: 10, Bytes: 1000},
+		{Token: 200, NodeId: "b", Keys: 0, Bytes: 0},
+	}
+	successors := map[uint64]string{100: "a", 150: "b", 200: "a"}
+
+	proposal, ok := (Planner{Threshold: 1.25}).Propose("a", loads, successors)
+	if !ok {
+		t.Fatalf("Expected a proposal")
+	}
+	if proposal.Token != 150 {
+		t.Errorf("Expected token 150 (the only one followed by another node), got %d", proposal.Token)
+	}
+}
+
+func TestImbalance(t *testing.T) {
+	if got := Imbalance(map[string]int64{"a": 10, "b": 10}); got != 1 {
+		t.Errorf("Expected imbalance 1, got %f", got)
+	}
+	if got := Imbalance(map[string]int64{"a": 30, "b": 10}); got != 1.5 {
+		t.Errorf("Expected imbalance 1.5, got %f", got)
+	}
+	if got := Imbalance(map[string]int64{}); got != 1 {
+		t.Errorf("Expected imbalance 1 for an empty ring, got %f", got)
+	}
+}
+
+func TestSplitPoint(t *testing.T) {
+	sizes := map[string]int64{}
+	for i := range 100 {
+		sizes["key"+string(rune('a'+i%26))+string(rune('a'+i/26))] = 100
+	}
+
+	// The whole hash space belongs to a single token
+	r := rv.TokenRange{Start: 1, End: 0}
+	total := int64(len(sizes)) * (100 + KeyOverhead)
+
+	newToken, moved, ok := SplitPoint(r, sizes, total/2)
+	if !ok {
+		t.Fatalf("Expected a split point")
+	}
+	if moved < total/2 || moved >= total {
+		t.Errorf("Expected to move at least half of the load without moving everything, moved %d of %d", moved, total)
+	}
+
+	// Check that exactly the moved weight lies in (newToken, End]
+	inMovedRange := int64(0)
+	for key, size := range sizes {
+		if rv.InHashSpace(rv.HashKey(key), newToken+1, r.End) {
+			inMovedRange += size + KeyOverhead
+		}
+	}
+	if inMovedRange != moved {
+		t.Errorf("Expected %d load units in the moved range, got %d", moved, inMovedRange)
+	}
+}
+
+func TestSplitPoint_SingleKey(t *testing.T) {
+	r := rv.TokenRange{Start: 1, End: 0}
+
+	if _, _, ok := SplitPoint(r, map[string]int64{"only-key": 10}, 5); ok {
+		t.Errorf("Expected a range with a single key to not be split")
+	}
+}
+
+func TestSplitPoint_KeyAtToken(t *testing.T) {
+	sizes := map[string]int64{"key-a": 100, "key-b": 100, "key-c": 100}
+	// The whole hash space belongs to a single token, on which key-a is hashed
+	end := rv.HashKey("key-a")
+	r := rv.TokenRange{Start: end + 1, End: end}
+
+	newToken, moved, ok := SplitPoint(r, sizes, 1)
+	if !ok {
+		t.Fatalf("Expected a split point")
+	}
+
+	// The key at the token changes owner with the rest of (newToken, End], so its weight is moved
+	inMovedRange := int64(0)
+	for key, size := range sizes {
+		if rv.InHashSpace(rv.HashKey(key), newToken+1, r.End) {
+			inMovedRange += size + KeyOverhead
+		}
+	}
+	if inMovedRange != moved || moved < 100+KeyOverhead {
+		t.Errorf("Expected the key at the token to be counted, moved %d with %d in the moved range", moved, inMovedRange)
+	}
+}
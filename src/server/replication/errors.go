@@ -8,4 +8,6 @@ var (
 	ErrInvalidR             = errors.New("R must be between 1 and N")
 	ErrInsufficientReplicas = errors.New("insufficient replicas written")
 	ErrQuorumNotMet         = errors.New("quorum not met")
+	ErrHintLimitReached     = errors.New("hint limit reached")
+	ErrNotFound             = errors.New("key not found")
 )
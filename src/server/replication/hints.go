@@ -2,12 +2,16 @@ package replication
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sdle-server/storage"
+	"sync"
+	"time"
 
 	"github.com/dgraph-io/badger/v4"
 )
 
-const hintPrefix = "hint:"
+const hintPrefix = storage.HintKeyPrefix
 
 // Hint represents a write that couldn't reach its intended node.
 // Each node stores hints in its own database for data that should have been written to other nodes and were temporarily unavailable.
@@ -16,30 +20,142 @@ type Hint struct {
 	IntendedNode string
 	Key          string
 	Value        []byte
+	StoredAt     time.Time // last time the hint was stored or merged (the TTL counts from here)
+}
+
+// Merges two values written for the same key (e.g. joining two CRDT states)
+type MergeFunc func(key string, stored []byte, incoming []byte) ([]byte, error)
+
+type HintStoreConfig struct {
+	TTL          time.Duration    // hints older than this are expired (0 means hints never expire)
+	MaxPerTarget int              // maximum number of hints for a single intended node (0 means no limit)
+	MaxTotal     int              // maximum number of hints in the store (0 means no limit)
+	Merge        MergeFunc        // merges hints for the same key (nil means the newest value wins)
+	Now          func() time.Time // source of the StoredAt timestamps (nil means time.Now)
+}
+
+// Accounting of the hints held by a node
+type HintStats struct {
+	Total     int
+	PerTarget map[string]int
+	Bytes     int64
+	Merged    uint64 // hints merged into an existing hint for the same key
+	Rejected  uint64 // hints refused because a limit was reached
+	Expired   uint64 // hints removed after their TTL
+	Delivered uint64 // hints removed after being delivered
 }
 
 // HintStore manages hints in a node's local database.
 // Uses the same DB instance as the regular data store.
 type HintStore struct {
-	db *badger.DB
+	db     *badger.DB
+	config HintStoreConfig
+
+	mu    sync.Mutex // protects the counters below and serializes read-merge-write cycles
+	stats HintStats
 }
 
-func NewHintStore(db *badger.DB) *HintStore {
-	return &HintStore{db: db}
+func NewHintStore(db *badger.DB, config HintStoreConfig) *HintStore {
+	h := &HintStore{
+		db:     db,
+		config: config,
+		stats:  HintStats{PerTarget: make(map[string]int)},
+	}
+
+	// Rebuild the counters from the hints that survived a restart
+	if hints, err := h.GetAllHints(); err == nil {
+		for nodeId, nodeHints := range hints {
+			h.stats.PerTarget[nodeId] = len(nodeHints)
+			h.stats.Total += len(nodeHints)
+			for _, hint := range nodeHints {
+				h.stats.Bytes += int64(len(hint.Value))
+			}
+		}
+	}
+
+	return h
 }
 
-func (h *HintStore) StoreHint(hint Hint) error {
+func hintKey(nodeId string, key string) []byte {
 	// format: hint:{intended_node}:{original_key}
-	hintKey := fmt.Sprintf("%s%s:%s", hintPrefix, hint.IntendedNode, hint.Key)
+	return []byte(fmt.Sprintf("%s%s:%s", hintPrefix, nodeId, key))
+}
+
+// Stores a hint. A hint for a key that already has one for the same node is merged into it, so no update is lost.
+// Returns ErrHintLimitReached when the store is full.
+func (h *HintStore) StoreHint(hint Hint) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stored, err := h.getHint(hint.IntendedNode, hint.Key)
+	exists := err == nil
+	if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+		return err
+	}
+
+	if !exists {
+		if h.config.MaxTotal > 0 && h.stats.Total >= h.config.MaxTotal {
+			h.stats.Rejected++
+			return fmt.Errorf("%w: %d hints stored", ErrHintLimitReached, h.stats.Total)
+		}
+		if h.config.MaxPerTarget > 0 && h.stats.PerTarget[hint.IntendedNode] >= h.config.MaxPerTarget {
+			h.stats.Rejected++
+			return fmt.Errorf("%w: %d hints stored for %s", ErrHintLimitReached, h.stats.PerTarget[hint.IntendedNode], hint.IntendedNode)
+		}
+	}
+
+	if exists && h.config.Merge != nil {
+		merged, err := h.config.Merge(hint.Key, stored.Value, hint.Value)
+		if err != nil {
+			return fmt.Errorf("failed to merge hint: %w", err)
+		}
+		hint.Value = merged
+	}
+	hint.StoredAt = h.now()
 
 	data, err := json.Marshal(hint)
 	if err != nil {
 		return fmt.Errorf("failed to marshal hint: %w", err)
 	}
 
-	return h.db.Update(func(txn *badger.Txn) error {
-		return txn.Set([]byte(hintKey), data)
+	err = h.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(hintKey(hint.IntendedNode, hint.Key), data)
+	})
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		h.stats.Merged++
+		h.stats.Bytes += int64(len(hint.Value)) - int64(len(stored.Value))
+	} else {
+		h.stats.Total++
+		h.stats.PerTarget[hint.IntendedNode]++
+		h.stats.Bytes += int64(len(hint.Value))
+	}
+
+	return nil
+}
+
+func (h *HintStore) now() time.Time {
+	if h.config.Now != nil {
+		return h.config.Now()
+	}
+	return time.Now()
+}
+
+func (h *HintStore) getHint(nodeId string, key string) (Hint, error) {
+	var hint Hint
+	err := h.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(hintKey(nodeId, key))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &hint)
+		})
 	})
+	return hint, err
 }
 
 // Retrieves all hints stored in this node's DB that are intended for a specific node
@@ -75,13 +191,92 @@ func (h *HintStore) GetHintsFor(nodeId string) ([]Hint, error) {
 	return hints, err
 }
 
-// Removes a hint from this node's DB after successful delivery
+// Removes a hint from this node's DB
 func (h *HintStore) DeleteHint(nodeId, key string) error {
-	hintKey := fmt.Sprintf("%s%s:%s", hintPrefix, nodeId, key)
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
-	return h.db.Update(func(txn *badger.Txn) error {
-		return txn.Delete([]byte(hintKey))
+	_, err := h.deleteHint(nodeId, key, time.Time{})
+	return err
+}
+
+// Removes a hint after successful delivery. If the hint was merged with a newer write in the meantime it is kept,
+// since the delivered value does not include that write.
+func (h *HintStore) DeleteDelivered(hint Hint) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	deleted, err := h.deleteHint(hint.IntendedNode, hint.Key, hint.StoredAt)
+	if deleted {
+		h.stats.Delivered++
+	}
+	return err
+}
+
+// Deletes a hint (only if it was stored at storedAt, unless storedAt is zero). Must be called with the lock held.
+func (h *HintStore) deleteHint(nodeId string, key string, storedAt time.Time) (bool, error) {
+	stored, err := h.getHint(nodeId, key)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if !storedAt.IsZero() && !stored.StoredAt.Equal(storedAt) {
+		return false, nil
+	}
+
+	err = h.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(hintKey(nodeId, key))
 	})
+	if err != nil {
+		return false, err
+	}
+
+	h.stats.Total--
+	h.stats.PerTarget[nodeId]--
+	if h.stats.PerTarget[nodeId] <= 0 {
+		delete(h.stats.PerTarget, nodeId)
+	}
+	h.stats.Bytes -= int64(len(stored.Value))
+
+	return true, nil
+}
+
+// Removes the hints whose TTL has passed and returns them, so the caller can apply the expiry policy
+func (h *HintStore) ExpireHints(now time.Time) ([]Hint, error) {
+	if h.config.TTL <= 0 {
+		return nil, nil
+	}
+
+	all, err := h.GetAllHints()
+	if err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	expired := []Hint{}
+	for _, hints := range all {
+		for _, hint := range hints {
+			if now.Sub(hint.StoredAt) < h.config.TTL {
+				continue
+			}
+
+			deleted, err := h.deleteHint(hint.IntendedNode, hint.Key, hint.StoredAt)
+			if err != nil {
+				return expired, err
+			}
+			if deleted {
+				h.stats.Expired++
+				expired = append(expired, hint)
+			}
+		}
+	}
+
+	return expired, nil
 }
 
 // Returns all hints in this node's DB, grouped by intended node
@@ -135,3 +330,16 @@ func (h *HintStore) CountHints() (int, error) {
 
 	return count, err
 }
+
+// Returns a snapshot of the hint accounting
+func (h *HintStore) Stats() HintStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stats := h.stats
+	stats.PerTarget = make(map[string]int, len(h.stats.PerTarget))
+	for nodeId, count := range h.stats.PerTarget {
+		stats.PerTarget[nodeId] = count
+	}
+	return stats
+}
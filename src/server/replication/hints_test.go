@@ -0,0 +1,157 @@
+package replication
+
+import (
+	"errors"
+	"sdle-server/storage"
+	"testing"
+	"time"
+)
+
+func openHintStore(t *testing.T, config HintStoreConfig) *HintStore {
+	s, err := storage.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return NewHintStore(s.GetDB(), config)
+}
+
+func concatMerge(key string, stored []byte, incoming []byte) ([]byte, error) {
+	return append(append([]byte{}, stored...), incoming...), nil
+}
+
+func TestHintStore_MergeSameKey(t *testing.T) {
+	h := openHintStore(t, HintStoreConfig{Merge: concatMerge})
+
+	if err := h.StoreHint(Hint{IntendedNode: "node-a", Key: "k", Value: []byte("1")}); err != nil {
+		t.Fatalf("StoreHint: %v", err)
+	}
+	if err := h.StoreHint(Hint{IntendedNode: "node-a", Key: "k", Value: []byte("2")}); err != nil {
+		t.Fatalf("StoreHint: %v", err)
+	}
+
+	hints, err := h.GetHintsFor("node-a")
+	if err != nil {
+		t.Fatalf("GetHintsFor: %v", err)
+	}
+	if len(hints) != 1 || string(hints[0].Value) != "12" {
+		t.Fatalf("Expected a single merged hint with value 12, got %v", hints)
+	}
+
+	stats := h.Stats()
+	if stats.Total != 1 || stats.Merged != 1 || stats.Bytes != 2 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestHintStore_Limits(t *testing.T) {
+	h := openHintStore(t, HintStoreConfig{MaxPerTarget: 2, MaxTotal: 3})
+
+	for _, key := range []string{"a", "b"} {
+		if err := h.StoreHint(Hint{IntendedNode: "node-a", Key: key}); err != nil {
+			t.Fatalf("StoreHint: %v", err)
+		}
+	}
+
+	if err := h.StoreHint(Hint{IntendedNode: "node-a", Key: "c"}); !errors.Is(err, ErrHintLimitReached) {
+		t.Errorf("Expected the per-target limit to be reached, got %v", err)
+	}
+
+	// Merging into an existing hint does not count toward the limits
+	if err := h.StoreHint(Hint{IntendedNode: "node-a", Key: "a"}); err != nil {
+		t.Errorf("Expected a hint for an existing key to be accepted, got %v", err)
+	}
+
+	if err := h.StoreHint(Hint{IntendedNode: "node-b", Key: "a"}); err != nil {
+		t.Fatalf("StoreHint: %v", err)
+	}
+	if err := h.StoreHint(Hint{IntendedNode: "node-c", Key: "a"}); !errors.Is(err, ErrHintLimitReached) {
+		t.Errorf("Expected the global limit to be reached, got %v", err)
+	}
+
+	if stats := h.Stats(); stats.Rejected != 2 || stats.Total != 3 || stats.PerTarget["node-a"] != 2 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestHintStore_Expire(t *testing.T) {
+	h := openHintStore(t, HintStoreConfig{TTL: time.Hour})
+
+	if err := h.StoreHint(Hint{IntendedNode: "node-a", Key: "k"}); err != nil {
+		t.Fatalf("StoreHint: %v", err)
+	}
+
+	expired, err := h.ExpireHints(time.Now())
+	if err != nil || len(expired) != 0 {
+		t.Fatalf("Expected no expired hints yet, got %v (%v)", expired, err)
+	}
+
+	expired, err = h.ExpireHints(time.Now().Add(2 * time.Hour))
+	if err != nil || len(expired) != 1 || expired[0].Key != "k" {
+		t.Fatalf("Expected the hint to expire, got %v (%v)", expired, err)
+	}
+
+	if count, _ := h.CountHints(); count != 0 {
+		t.Errorf("Expected the expired hint to be removed, %d left", count)
+	}
+	if stats := h.Stats(); stats.Total != 0 || stats.Expired != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestHintStore_DeleteDeliveredKeepsNewerWrites(t *testing.T) {
+	h := openHintStore(t, HintStoreConfig{Merge: concatMerge})
+
+	if err := h.StoreHint(Hint{IntendedNode: "node-a", Key: "k", Value: []byte("1")}); err != nil {
+		t.Fatalf("StoreHint: %v", err)
+	}
+	delivered, _ := h.GetHintsFor("node-a")
+
+	// A new write is merged while the old value is being delivered
+	if err := h.StoreHint(Hint{IntendedNode: "node-a", Key: "k", Value: []byte("2")}); err != nil {
+		t.Fatalf("StoreHint: %v", err)
+	}
+
+	if err := h.DeleteDelivered(delivered[0]); err != nil {
+		t.Fatalf("DeleteDelivered: %v", err)
+	}
+	if count, _ := h.CountHints(); count != 1 {
+		t.Fatalf("Expected the merged hint to be kept")
+	}
+
+	current, _ := h.GetHintsFor("node-a")
+	if err := h.DeleteDelivered(current[0]); err != nil {
+		t.Fatalf("DeleteDelivered: %v", err)
+	}
+	if count, _ := h.CountHints(); count != 0 {
+		t.Errorf("Expected the delivered hint to be removed")
+	}
+	if stats := h.Stats(); stats.Delivered != 1 || stats.Total != 0 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestHintStore_CountersSurviveReopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := storage.Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	h := NewHintStore(s.GetDB(), HintStoreConfig{})
+	_ = h.StoreHint(Hint{IntendedNode: "node-a", Key: "a", Value: []byte("xy")})
+	_ = h.StoreHint(Hint{IntendedNode: "node-b", Key: "a", Value: []byte("z")})
+	s.Close()
+
+	s, err = storage.Open(dir)
+	if err != nil {
+		t.Fatalf("Reopen: %v", err)
+	}
+	defer s.Close()
+
+	stats := NewHintStore(s.GetDB(), HintStoreConfig{}).Stats()
+	if stats.Total != 2 || stats.PerTarget["node-a"] != 1 || stats.Bytes != 3 {
+		t.Errorf("Unexpected stats after reopen %+v", stats)
+	}
+}
@@ -11,10 +11,14 @@ import (
 	"sync"
 )
 
+// The membership of a node is its token list, versioned by a generation that the node bumps whenever it changes its
+// tokens (after a collision or a move). A newer generation replaces the tokens of the node as a whole, so that gossip
+// delivered late can not bring back a token the node has since given up.
 type RingView struct {
 	tokens      []uint64          // sorted list of tokens
 	tokenToNode map[uint64]string // maps each token to its node
 	nodes       []string          // list of node IDs
+	generations map[string]uint64 // membership generation of each node (0 if unknown)
 	mu          sync.RWMutex      // mutex for concurrent access
 }
 
@@ -24,9 +28,31 @@ type TransferredHashSpace struct {
 	PreviousOwnerId string
 }
 
+// The hash space [Start, End] owned by a token (End is the token itself). Start > End means the range wraps around the ring
+type TokenRange struct {
+	Start  uint64
+	End    uint64
+	NodeId string
+}
+
 type PreferenceList struct {
-	Nodes []string // coordinator comes first
-	N     int      // replication factor
+	Nodes     []string // coordinator comes first
+	N         int      // replication factor
+	Fallbacks []string // remaining nodes, in ring order after the first N (used for sloppy quorum)
+}
+
+// Returns up to count fallback nodes, in ring order, skipping the ones that are suspected to be down
+func (p PreferenceList) HealthyFallbacks(isSuspected func(nodeId string) bool, count int) []string {
+	healthy := make([]string, 0, count)
+	for _, nodeId := range p.Fallbacks {
+		if len(healthy) == count {
+			break
+		}
+		if !isSuspected(nodeId) {
+			healthy = append(healthy, nodeId)
+		}
+	}
+	return healthy
 }
 
 func New() *RingView {
@@ -35,6 +61,7 @@ func New() *RingView {
 		tokens:      make([]uint64, 0),
 		nodes:       make([]string, 0),
 		tokenToNode: make(map[uint64]string),
+		generations: make(map[string]uint64),
 	}
 }
 
@@ -63,6 +90,27 @@ func NewFromTokenMap(tokenToNode map[uint64]string) *RingView {
 	return rv
 }
 
+// Creates a new RingView from a tokenToNode map and the membership generation of its nodes
+func NewFromMembership(tokenToNode map[uint64]string, generations map[string]uint64) *RingView {
+	rv := NewFromTokenMap(tokenToNode)
+	maps.Copy(rv.generations, generations)
+	return rv
+}
+
+// Replaces the whole content of the ring view with the given membership, in place, so that the readers holding the
+// ring view see either the old or the new membership
+func (r *RingView) ReplaceMembership(tokenToNode map[uint64]string, generations map[string]uint64) {
+	replacement := NewFromMembership(tokenToNode, generations)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens = replacement.tokens
+	r.tokenToNode = replacement.tokenToNode
+	r.nodes = replacement.nodes
+	r.generations = replacement.generations
+}
+
 // Adds a node to the Ring, generating new tokens for it. If the hashSpace needs to be transferred from other nodes, it is returned as a list of transferredHashSpace structs.
 func (r *RingView) JoinToRing(nodeId string) (tokens []uint64, transferredHashSpaces []TransferredHashSpace, added bool) {
 	r.mu.Lock()
@@ -122,31 +170,149 @@ func (r *RingView) JoinToRing(nodeId string) (tokens []uint64, transferredHashSp
 
 	r.nodes = append(r.nodes, nodeId)
 	slices.Sort(r.nodes)
+	r.generations[nodeId] = 1
 
 	return generated_tokens, transferredHashSpaces, true
 }
 
-// Adds a node with pre-defined tokens to the ring (used when node info is received from other nodes). Returns false if the node already exists.
-func (r *RingView) AddNode(nodeId string, tokens []uint64) (added bool) {
+// Two nodes that picked the same token. The winner keeps the token and the loser must pick a new one
+type TokenCollision struct {
+	Token  uint64
+	Winner string
+	Loser  string
+}
+
+// Decides deterministically which of two nodes keeps a token both of them picked (the smallest ID wins), so that
+// every node resolves a collision the same way regardless of the order in which it learns about the two nodes
+func CollisionWinner(a string, b string) string {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Merges a node with pre-defined tokens into the ring (used when node info is received from other nodes).
+// Membership older than the known generation of the node is ignored, and newer membership replaces its tokens as a
+// whole. Tokens already owned by another node are resolved with CollisionWinner and reported as collisions.
+// Returns false if the ring view did not change.
+func (r *RingView) AddNode(nodeId string, tokens []uint64, generation uint64) (added bool, collisions []TokenCollision) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	// Check if node already exists
-	if slices.Contains(r.nodes, nodeId) {
-		return false
+	known := r.generations[nodeId]
+	if generation < known {
+		return false, nil
+	}
+
+	if generation > known {
+		r.generations[nodeId] = generation
+		for token, owner := range r.tokenToNode {
+			if owner == nodeId && !slices.Contains(tokens, token) {
+				delete(r.tokenToNode, token)
+				r.tokens = slices.DeleteFunc(r.tokens, func(t uint64) bool { return t == token })
+				added = true
+			}
+		}
 	}
 
-	// Add tokens to the ring
 	for _, h := range tokens {
-		r.tokenToNode[h] = nodeId
-		r.tokens = append(r.tokens, h)
+		owner, exists := r.tokenToNode[h]
+
+		if !exists {
+			r.tokenToNode[h] = nodeId
+			r.tokens = append(r.tokens, h)
+			added = true
+			continue
+		}
+
+		if owner == nodeId {
+			continue
+		}
+
+		winner := CollisionWinner(owner, nodeId)
+		loser := owner
+		if winner == owner {
+			loser = nodeId
+		}
+
+		collisions = append(collisions, TokenCollision{Token: h, Winner: winner, Loser: loser})
+		if winner != owner {
+			r.tokenToNode[h] = winner
+			added = true
+		}
 	}
 
-	r.nodes = append(r.nodes, nodeId)
+	if !slices.Contains(r.nodes, nodeId) {
+		r.nodes = append(r.nodes, nodeId)
+		added = true
+	}
 
 	slices.Sort(r.nodes)
 	slices.Sort(r.tokens)
 
+	return added, collisions
+}
+
+// Picks a new token for a node that lost one to a collision. Returns the new token and the hash space that must be
+// imported from its previous owner.
+func (r *RingView) ReplaceLostToken(nodeId string) (token uint64, transferred TransferredHashSpace, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.tokens) == 0 {
+		return 0, TransferredHashSpace{}, false
+	}
+
+	token = r.generateNewToken(nodeId, nil)
+
+	nextDefinedTokenIdx, _ := r.getNextDefinedTokenIdx(token)
+	previousDefinedTokenIdx, _ := r.getPreviousDefinedTokenIdx(token)
+
+	transferred = TransferredHashSpace{
+		Start:           (r.tokens[previousDefinedTokenIdx] + 1) % config.DefaultConfig().HashSpaceSize,
+		End:             token,
+		PreviousOwnerId: r.tokenToNode[r.tokens[nextDefinedTokenIdx]],
+	}
+
+	r.tokenToNode[token] = nodeId
+	r.tokens = append(r.tokens, token)
+	slices.Sort(r.tokens)
+	r.generations[nodeId]++
+
+	if !slices.Contains(r.nodes, nodeId) {
+		r.nodes = append(r.nodes, nodeId)
+		slices.Sort(r.nodes)
+	}
+
+	return token, transferred, true
+}
+
+// Moves a token of a node to a new position in the ring, as its membership of the given generation. Returns false if
+// the generation is not newer than the known one, the old token does not belong to the node or the new token is already in use.
+func (r *RingView) MoveToken(nodeId string, oldToken uint64, newToken uint64, generation uint64) (moved bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if generation <= r.generations[nodeId] {
+		return false
+	}
+
+	if owner, ok := r.tokenToNode[oldToken]; !ok || owner != nodeId {
+		return false
+	}
+
+	if _, used := r.tokenToNode[newToken]; used {
+		return false
+	}
+
+	delete(r.tokenToNode, oldToken)
+	r.tokenToNode[newToken] = nodeId
+
+	r.tokens = slices.DeleteFunc(r.tokens, func(t uint64) bool { return t == oldToken })
+	r.tokens = append(r.tokens, newToken)
+	slices.Sort(r.tokens)
+	r.generations[nodeId] = generation
+
 	return true
 }
 
@@ -236,6 +402,76 @@ func (r *RingView) GetTokenToNode() map[uint64]string {
 	return tokenToNodeCopy
 }
 
+// Returns a copy of the membership generation of each node
+func (r *RingView) GetGenerations() map[string]uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return maps.Clone(r.generations)
+}
+
+// Returns the membership generation of a node (0 if unknown)
+func (r *RingView) GetNodeGeneration(nodeId string) uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.generations[nodeId]
+}
+
+// Returns the tokens owned by a node, sorted
+func (r *RingView) GetNodeTokens(nodeId string) []uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tokens := make([]uint64, 0, config.DefaultConfig().TokensPerNode)
+	for _, t := range r.tokens { // tokens are kept sorted
+		if r.tokenToNode[t] == nodeId {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
+
+// Returns the hash space range owned by each token in the ring, indexed by token
+func (r *RingView) GetTokenRanges() map[uint64]TokenRange {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ranges := make(map[uint64]TokenRange, len(r.tokens))
+	for i, t := range r.tokens {
+		previous := r.tokens[(i-1+len(r.tokens))%len(r.tokens)]
+
+		ranges[t] = TokenRange{
+			Start:  (previous + 1) % config.DefaultConfig().HashSpaceSize,
+			End:    t,
+			NodeId: r.tokenToNode[t],
+		}
+	}
+	return ranges
+}
+
+// Returns the token that follows the given one in the ring (wraps around). Returns false if the token is not in the ring.
+func (r *RingView) GetSuccessorToken(token uint64) (uint64, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	idx, found := slices.BinarySearch(r.tokens, token)
+	if !found {
+		return 0, false
+	}
+
+	return r.tokens[(idx+1)%len(r.tokens)], true
+}
+
+// Returns the node that owns a token, if any
+func (r *RingView) GetTokenOwner(token uint64) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	nodeId, ok := r.tokenToNode[token]
+	return nodeId, ok
+}
+
 // Returns a copy of the known node IDs
 func (r *RingView) GetKnownIds() []string {
 	r.mu.RLock()
@@ -270,6 +506,14 @@ func HashKey(s string) uint64 {
 	return binary.BigEndian.Uint64(sum[:8]) % config.DefaultConfig().HashSpaceSize
 }
 
+// Checks if a hash belongs to the hash space [start, end], which wraps around the ring when start > end
+func InHashSpace(hash uint64, start uint64, end uint64) bool {
+	if start <= end {
+		return hash >= start && hash <= end
+	}
+	return hash >= start || hash <= end
+}
+
 // Generates a new unique token for a node based on the node ID and a counter (counter should be unique per node)
 func (r *RingView) generateNewToken(nodeId string, additionalUsedIds []uint64) uint64 {
 	var newToken uint64
@@ -303,20 +547,27 @@ func (r *RingView) GetPreferenceList(key string, N int) PreferenceList {
 	startIdx, _ := r.getNextDefinedTokenIdx(keyHash)
 
 	nodes := make([]string, 0, N)
+	fallbacks := make([]string, 0)
 	seenNodes := make(map[string]bool)
 
-	// Go around the ring to find N distinct nodes
-	for i := 0; len(nodes) < N && i < len(r.tokens); i++ {
+	// Go around the ring to find N distinct nodes, then keep walking to collect the fallback nodes
+	for i := 0; i < len(r.tokens); i++ {
 		idx := (startIdx + i) % len(r.tokens)
 		token := r.tokens[idx]
 		nodeId := r.tokenToNode[token]
 
 		// Only add if we haven't seen this node yet (since a node can have multiple tokens)
-		if !seenNodes[nodeId] {
+		if seenNodes[nodeId] {
+			continue
+		}
+		seenNodes[nodeId] = true
+
+		if len(nodes) < N {
 			nodes = append(nodes, nodeId)
-			seenNodes[nodeId] = true
+		} else {
+			fallbacks = append(fallbacks, nodeId)
 		}
 	}
 
-	return PreferenceList{Nodes: nodes, N: N}
+	return PreferenceList{Nodes: nodes, N: N, Fallbacks: fallbacks}
 }
@@ -0,0 +1,206 @@
+package ringview
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestRingView_MoveToken(t *testing.T) {
+	r := NewFromTokenMap(map[uint64]string{100: "a", 200: "b", 300: "c"})
+
+	if !r.MoveToken("a", 100, 50, 1) {
+		t.Fatalf("Expected token 100 of a to be moved")
+	}
+	if owner, ok := r.GetTokenOwner(50); !ok || owner != "a" {
+		t.Errorf("Expected token 50 to belong to a, got %q", owner)
+	}
+	if _, ok := r.GetTokenOwner(100); ok {
+		t.Errorf("Expected token 100 to be removed")
+	}
+	if !slices.IsSorted(r.tokens) {
+		t.Errorf("Expected tokens to remain sorted, got %v", r.tokens)
+	}
+
+	if r.MoveToken("a", 100, 60, 2) {
+		t.Errorf("Expected moving an unknown token to fail")
+	}
+	if r.MoveToken("b", 50, 60, 1) {
+		t.Errorf("Expected moving a token of another node to fail")
+	}
+	if r.MoveToken("a", 50, 200, 2) {
+		t.Errorf("Expected moving a token onto a used token to fail")
+	}
+	if r.MoveToken("a", 50, 60, 1) {
+		t.Errorf("Expected a move of an old generation to fail")
+	}
+}
+
+func TestRingView_GetTokenRanges(t *testing.T) {
+	r := NewFromTokenMap(map[uint64]string{100: "a", 200: "b"})
+	ranges := r.GetTokenRanges()
+
+	if got := ranges[200]; got.Start != 101 || got.End != 200 || got.NodeId != "b" {
+		t.Errorf("Unexpected range for token 200: %+v", got)
+	}
+
+	// The first token wraps around the ring
+	if got := ranges[100]; got.Start != 201 || got.End != 100 || got.NodeId != "a" {
+		t.Errorf("Unexpected range for token 100: %+v", got)
+	}
+	if !InHashSpace(0, ranges[100].Start, ranges[100].End) || !InHashSpace(300, ranges[100].Start, ranges[100].End) {
+		t.Errorf("Expected the wrapping range to contain hashes on both sides of zero")
+	}
+	if InHashSpace(150, ranges[100].Start, ranges[100].End) {
+		t.Errorf("Expected hash 150 to belong to token 200")
+	}
+}
+
+func TestRingView_GetSuccessorToken(t *testing.T) {
+	r := NewFromTokenMap(map[uint64]string{100: "a", 200: "b"})
+
+	if next, ok := r.GetSuccessorToken(100); !ok || next != 200 {
+		t.Errorf("Expected 200 after 100, got %d", next)
+	}
+	if next, ok := r.GetSuccessorToken(200); !ok || next != 100 {
+		t.Errorf("Expected the ring to wrap around to 100, got %d", next)
+	}
+	if _, ok := r.GetSuccessorToken(150); ok {
+		t.Errorf("Expected no successor for an unknown token")
+	}
+}
+
+func TestRingView_AddNodeCollision(t *testing.T) {
+	// Two views that learn about the same colliding nodes in different orders
+	r1 := NewFromTokenMap(map[uint64]string{100: "seed"})
+	r2 := NewFromTokenMap(map[uint64]string{100: "seed"})
+
+	r1.AddNode("node-b", []uint64{200, 300}, 1)
+	_, collisions1 := r1.AddNode("node-a", []uint64{200, 400}, 1)
+
+	r2.AddNode("node-a", []uint64{200, 400}, 1)
+	_, collisions2 := r2.AddNode("node-b", []uint64{200, 300}, 1)
+
+	if len(collisions1) != 1 || len(collisions2) != 1 {
+		t.Fatalf("Expected one collision in each view, got %v and %v", collisions1, collisions2)
+	}
+
+	want := TokenCollision{Token: 200, Winner: "node-a", Loser: "node-b"}
+	if collisions1[0] != want || collisions2[0] != want {
+		t.Errorf("Expected collision %+v in both views, got %+v and %+v", want, collisions1[0], collisions2[0])
+	}
+
+	for _, r := range []*RingView{r1, r2} {
+		if owner, _ := r.GetTokenOwner(200); owner != "node-a" {
+			t.Errorf("Expected token 200 to be owned by the winner, got %s", owner)
+		}
+	}
+}
+
+func TestRingView_AddNodeMerge(t *testing.T) {
+	r := NewFromTokenMap(map[uint64]string{100: "a"})
+
+	if added, _ := r.AddNode("b", []uint64{200}, 1); !added {
+		t.Errorf("Expected a new node to be added")
+	}
+	if added, _ := r.AddNode("b", []uint64{200}, 1); added {
+		t.Errorf("Expected re-adding the same membership to change nothing")
+	}
+	if added, _ := r.AddNode("b", []uint64{200, 300}, 1); !added {
+		t.Errorf("Expected a new token of a known node to be merged")
+	}
+	if tokens := r.GetNodeTokens("b"); !slices.Equal(tokens, []uint64{200, 300}) {
+		t.Errorf("Expected tokens [200 300], got %v", tokens)
+	}
+}
+
+func TestRingView_AddNodeGeneration(t *testing.T) {
+	r := NewFromTokenMap(map[uint64]string{100: "a"})
+	r.AddNode("b", []uint64{200, 300}, 1)
+	r.MoveToken("b", 200, 150, 2)
+
+	// The join gossip of b arrives late, after the move
+	if added, _ := r.AddNode("b", []uint64{200, 300}, 1); added {
+		t.Errorf("Expected membership of an old generation to be ignored")
+	}
+	if tokens := r.GetNodeTokens("b"); !slices.Equal(tokens, []uint64{150, 300}) {
+		t.Errorf("Expected tokens [150 300], got %v", tokens)
+	}
+
+	if added, _ := r.AddNode("b", []uint64{120, 300}, 3); !added {
+		t.Errorf("Expected membership of a newer generation to be applied")
+	}
+	if tokens := r.GetNodeTokens("b"); !slices.Equal(tokens, []uint64{120, 300}) {
+		t.Errorf("Expected the newer membership to replace the tokens as a whole, got %v", tokens)
+	}
+	if _, ok := r.GetTokenOwner(150); ok || !slices.IsSorted(r.tokens) || len(r.tokens) != 3 {
+		t.Errorf("Expected token 150 to be dropped, got %v", r.tokens)
+	}
+}
+
+func TestRingView_ReplaceMembership(t *testing.T) {
+	r := NewFromTokenMap(map[uint64]string{100: "a"})
+	r.AddNode("b", []uint64{200}, 1)
+
+	r.ReplaceMembership(map[uint64]string{300: "c", 150: "d"}, map[string]uint64{"c": 2, "d": 1})
+
+	if ids := r.GetKnownIds(); !slices.Equal(ids, []string{"c", "d"}) {
+		t.Errorf("Expected nodes [c d], got %v", ids)
+	}
+	if !slices.Equal(r.tokens, []uint64{150, 300}) {
+		t.Errorf("Expected tokens [150 300], got %v", r.tokens)
+	}
+	if r.GetNodeGeneration("c") != 2 || r.GetNodeGeneration("b") != 0 {
+		t.Errorf("Expected the generations to be replaced, got %v", r.GetGenerations())
+	}
+}
+
+func TestRingView_ReplaceLostToken(t *testing.T) {
+	r := NewFromTokenMap(map[uint64]string{100: "a", 200: "b"})
+
+	token, transferred, ok := r.ReplaceLostToken("b")
+	if !ok {
+		t.Fatalf("Expected a new token")
+	}
+	if owner, _ := r.GetTokenOwner(token); owner != "b" {
+		t.Errorf("Expected the new token to belong to b, got %s", owner)
+	}
+	if transferred.End != token || transferred.PreviousOwnerId == "" {
+		t.Errorf("Unexpected transferred hash space %+v", transferred)
+	}
+	if len(r.GetNodeTokens("b")) != 2 {
+		t.Errorf("Expected b to own 2 tokens, got %v", r.GetNodeTokens("b"))
+	}
+	if r.GetNodeGeneration("b") != 1 {
+		t.Errorf("Expected the new token to bump the generation of b, got %d", r.GetNodeGeneration("b"))
+	}
+}
+
+func TestRingView_GetPreferenceListFallbacks(t *testing.T) {
+	r := NewFromTokenMap(map[uint64]string{10: "a", 20: "b", 30: "a", 40: "c", 50: "d", 60: "e"})
+
+	// Find a key that hashes before the first token, so the walk starts at token 10
+	key := ""
+	for i := 0; ; i++ {
+		key = "key" + strconv.Itoa(i)
+		if HashKey(key) <= 10 || HashKey(key) > 60 {
+			break
+		}
+	}
+
+	prefList := r.GetPreferenceList(key, 2)
+	if !slices.Equal(prefList.Nodes, []string{"a", "b"}) {
+		t.Errorf("Expected preference list [a b], got %v", prefList.Nodes)
+	}
+	if !slices.Equal(prefList.Fallbacks, []string{"c", "d", "e"}) {
+		t.Errorf("Expected fallbacks [c d e] (without repeating a), got %v", prefList.Fallbacks)
+	}
+
+	isSuspected := func(nodeId string) bool { return nodeId == "c" }
+	if got := prefList.HealthyFallbacks(isSuspected, 1); !slices.Equal(got, []string{"d"}) {
+		t.Errorf("Expected healthy fallbacks [d], got %v", got)
+	}
+	if got := prefList.HealthyFallbacks(isSuspected, 5); !slices.Equal(got, []string{"d", "e"}) {
+		t.Errorf("Expected healthy fallbacks [d e], got %v", got)
+	}
+}
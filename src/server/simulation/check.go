@@ -0,0 +1,124 @@
+package simulation
+
+import (
+	"fmt"
+	"strings"
+
+	"sdle-server/checker"
+	"sdle-server/config"
+	crdt "sdle-server/crdt/shopping"
+	"sdle-server/node"
+	pb "sdle-server/proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Checks that every replica of every shopping list holds the same state, equal to the join of the replicas of the
+// clients. Meant to be called after Settle.
+func (s *Simulator) CheckConvergence() error {
+	live := s.liveNodes()
+	if len(live) == 0 {
+		return fmt.Errorf("no live nodes")
+	}
+	ringView := s.nodes[live[0]].GetRingView()
+
+	problems := []string{}
+	for _, listID := range s.listIDs() {
+		expected := s.expectedList(listID)
+		key := node.ShoppingListKey(listID)
+
+		for _, nodeId := range ringView.GetPreferenceList(key, config.DefaultConfig().N).Nodes {
+			if s.crashed[nodeId] {
+				problems = append(problems, fmt.Sprintf("%s: replica %s is down", listID, nodeId))
+				continue
+			}
+
+			data, err := s.nodes[nodeId].LocalGet(key)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: replica %s has no state: %v", listID, nodeId, err))
+				continue
+			}
+
+			replica, err := canonicalState(data)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: replica %s is unreadable: %v", listID, nodeId, err))
+				continue
+			}
+
+			if replica != expected {
+				problems = append(problems, fmt.Sprintf("%s: replica %s diverged\n  expected: %s\n  got:      %s",
+					listID, nodeId, expected, replica))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("replicas did not converge:\n%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// Records the state of every live replica of every list as a final observation of the history. Meant to be called
+// after Settle, before checker.Check.
+func (s *Simulator) ObserveReplicas() {
+	ringView := s.nodes[s.liveNodes()[0]].GetRingView()
+
+	for _, listID := range s.listIDs() {
+		key := node.ShoppingListKey(listID)
+		for _, nodeId := range ringView.GetPreferenceList(key, config.DefaultConfig().N).Nodes {
+			if s.crashed[nodeId] {
+				continue
+			}
+			data, err := s.nodes[nodeId].LocalGet(key)
+			if err != nil {
+				continue
+			}
+			if list, err := decodeList(data); err == nil {
+				s.history.ObserveFinal(nodeId, list)
+			}
+		}
+	}
+}
+
+func (s *Simulator) listIDs() []string {
+	ids := []string{}
+	for _, list := range s.clients[0].lists {
+		ids = append(ids, list.ListID())
+	}
+	return ids
+}
+
+// Canonical state of the join of the client replicas of a list
+func (s *Simulator) expectedList(listID string) string {
+	joined := crdt.NewShoppingList("simulation", listID)
+	for _, c := range s.clients {
+		for _, list := range c.lists {
+			if list.ListID() == listID {
+				joined.Join(list)
+			}
+		}
+	}
+
+	data, _ := proto.Marshal(joined.ToProto())
+	state, _ := canonicalState(data)
+	return state
+}
+
+// Renders a stored shopping list in a form that does not depend on the order of its maps and repeated fields
+func canonicalState(data []byte) (string, error) {
+	list, err := decodeList(data)
+	if err != nil {
+		return "", err
+	}
+	return checker.CanonicalState(list), nil
+}
+
+// Decodes a stored shopping list. Round-tripping through the CRDT normalizes it (e.g. compacts its context) the same
+// way on every side of a comparison.
+func decodeList(data []byte) (*crdt.ShoppingList, error) {
+	var listProto pb.ShoppingList
+	if err := proto.Unmarshal(data, &listProto); err != nil {
+		return nil, err
+	}
+	return crdt.ShoppingListFromProto(&listProto, "simulation")
+}
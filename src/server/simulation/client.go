@@ -0,0 +1,49 @@
+package simulation
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"sdle-server/checker"
+	crdt "sdle-server/crdt/shopping"
+)
+
+// Number of distinct items per list the clients pick from, kept small so that clients often edit the same items
+const itemsPerList = 4
+
+// A simulated client: it keeps its own replica of every list and sends a delta to a node for each edit
+type client struct {
+	id    string
+	lists []*crdt.ShoppingList
+}
+
+func newClient(id string, lists int) *client {
+	c := &client{id: id}
+	for i := range lists {
+		c.lists = append(c.lists, crdt.NewShoppingList(id, fmt.Sprintf("list%d", i+1)))
+	}
+	return c
+}
+
+// Edits a random list, records the edit in the history and returns its delta, its index in the history and a
+// description for the trace
+func (c *client) randomOperation(rng *rand.Rand, history *checker.History) (*crdt.ShoppingList, int, string) {
+	list := c.lists[rng.IntN(len(c.lists))]
+	itemID := fmt.Sprintf("item%d", rng.IntN(itemsPerList)+1)
+
+	switch rng.IntN(4) {
+	case 0:
+		delta := list.RemoveItem(itemID)
+		index := history.RecordRemove(c.id, list, delta, itemID)
+		return delta, index, fmt.Sprintf("remove %s from %s", itemID, list.ListID())
+	case 1:
+		delta := list.PutItem(itemID, itemID, 0, 1)
+		index := history.RecordPut(c.id, list, delta, itemID, 0, 1)
+		return delta, index, fmt.Sprintf("acquire %s in %s", itemID, list.ListID())
+	default:
+		quantity := int64(rng.IntN(5) + 1)
+		delta := list.PutItem(itemID, itemID, quantity, 0)
+		index := history.RecordPut(c.id, list, delta, itemID, quantity, 0)
+		return delta, index, fmt.Sprintf("add %d %s to %s", quantity, itemID, list.ListID())
+	}
+}
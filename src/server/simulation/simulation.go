@@ -0,0 +1,397 @@
+package simulation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"sdle-server/checker"
+	"sdle-server/clock"
+	"sdle-server/config"
+	crdt "sdle-server/crdt/shopping"
+	"sdle-server/node"
+)
+
+// Maximum number of background tasks run when draining the queue, so that a task that keeps rescheduling itself
+// cannot hang the simulation
+const maxDrainedTasks = 100000
+
+// Probability that a client reads a list back after editing it
+const readRate = 0.2
+
+// Message faults injected by the simulated network. Each probability is drawn independently for every message.
+type Faults struct {
+	DropRate      float64       // probability that a request, or its response, is lost
+	DuplicateRate float64       // probability that a request is handled twice
+	DelayRate     float64       // probability that a message is delayed
+	MaxDelay      time.Duration // delays are uniform in (0, MaxDelay]; a request delayed past its timeout times out
+}
+
+type Config struct {
+	Seed    uint64
+	Nodes   int    // number of nodes in the cluster
+	Clients int    // number of simulated clients
+	Lists   int    // number of shopping lists shared by the clients
+	Dir     string // directory for the stores of the nodes
+}
+
+// A background task of a node (gossip, hint delivery, ...), tagged with the incarnation of the node that scheduled it
+type task struct {
+	nodeId      string
+	incarnation int
+	run         func()
+}
+
+// Runs a cluster of nodes inside one process. Every message is delivered on the simulator's goroutine and the
+// background work of the nodes is queued and run one task at a time, in an order drawn from the seed, against a
+// virtual clock. A run is therefore reproducible from its seed.
+//
+// A Simulator is not safe for concurrent use.
+type Simulator struct {
+	rng   *rand.Rand
+	clock *clock.Virtual
+	dir   string
+
+	ids          []string
+	nodes        map[string]*node.Node
+	transports   map[string]*simTransport
+	crashed      map[string]bool
+	incarnations map[string]int
+	groups       map[string]int // partition group of each node (nodes in different groups cannot talk)
+
+	faults  Faults
+	tasks   []task
+	clients []*client
+	history *checker.History
+	trace   []string
+}
+
+func New(cfg Config) (*Simulator, error) {
+	if cfg.Nodes < 1 || cfg.Clients < 1 || cfg.Lists < 1 {
+		return nil, errors.New("a simulation needs at least one node, client and list")
+	}
+
+	s := &Simulator{
+		rng:          rand.New(rand.NewPCG(cfg.Seed, cfg.Seed)),
+		clock:        clock.NewVirtual(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)),
+		dir:          cfg.Dir,
+		nodes:        make(map[string]*node.Node),
+		transports:   make(map[string]*simTransport),
+		crashed:      make(map[string]bool),
+		incarnations: make(map[string]int),
+		groups:       make(map[string]int),
+		history:      checker.NewHistory(),
+	}
+
+	for i := range cfg.Nodes {
+		s.ids = append(s.ids, fmt.Sprintf("node%d:%d", i+1, 5000+i))
+	}
+
+	for i := range cfg.Clients {
+		s.clients = append(s.clients, newClient(fmt.Sprintf("client%d", i+1), cfg.Lists))
+	}
+
+	for _, id := range s.ids {
+		if err := s.boot(id); err != nil {
+			s.Close()
+			return nil, err
+		}
+
+		// The first node bootstraps the ring, the others join through it
+		if err := s.nodes[id].JoinToRing(context.Background(), node.NodeIdToZMQAddr(s.ids[0])); err != nil {
+			s.Close()
+			return nil, fmt.Errorf("node %s failed to join: %w", id, err)
+		}
+		s.drain()
+	}
+
+	for _, id := range s.ids {
+		if known := len(s.nodes[id].GetRingView().GetKnownIds()); known != len(s.ids) {
+			s.Close()
+			return nil, fmt.Errorf("node %s only knows %d of %d nodes after joining", id, known, len(s.ids))
+		}
+	}
+
+	return s, nil
+}
+
+// Creates a node (or recreates it after a crash, reopening its store)
+func (s *Simulator) boot(id string) error {
+	t := newSimTransport(s, id)
+
+	n, err := node.NewNodeWithTransport(id, s.dir, t)
+	if err != nil {
+		return err
+	}
+
+	incarnation := s.incarnations[id]
+	n.SetClock(s.clock)
+	n.SetScheduler(func(run func()) {
+		s.tasks = append(s.tasks, task{nodeId: id, incarnation: incarnation, run: run})
+	})
+	t.handler = n.HandleRequest
+
+	s.nodes[id] = n
+	s.transports[id] = t
+	return nil
+}
+
+func (s *Simulator) NodeIds() []string {
+	return slices.Clone(s.ids)
+}
+
+// Events of the run (faults, crashes, client operations), in order. Two runs with the same seed have the same trace.
+func (s *Simulator) Trace() []string {
+	return slices.Clone(s.trace)
+}
+
+// Operations of the clients and the states they read, for checker.Check
+func (s *Simulator) History() *checker.History {
+	return s.history
+}
+
+func (s *Simulator) record(event string) {
+	s.trace = append(s.trace, fmt.Sprintf("[%s] %s", s.clock.Now().Format("15:04:05.000"), event))
+}
+
+func (s *Simulator) chance(p float64) bool {
+	return p > 0 && s.rng.Float64() < p
+}
+
+func (s *Simulator) delay() time.Duration {
+	if s.faults.MaxDelay <= 0 || !s.chance(s.faults.DelayRate) {
+		return 0
+	}
+	return time.Duration(s.rng.Int64N(int64(s.faults.MaxDelay))) + 1
+}
+
+func (s *Simulator) connected(a string, b string) bool {
+	return s.groups[a] == s.groups[b]
+}
+
+func (s *Simulator) SetFaults(faults Faults) {
+	s.record(fmt.Sprintf("faults set to %+v", faults))
+	s.faults = faults
+}
+
+// Splits the nodes into groups that cannot talk to each other. Nodes left out of every group form one more group.
+func (s *Simulator) Partition(groups ...[]string) {
+	s.record(fmt.Sprintf("partition %v", groups))
+
+	s.groups = make(map[string]int)
+	for _, id := range s.ids {
+		s.groups[id] = len(groups)
+	}
+	for i, group := range groups {
+		for _, id := range group {
+			s.groups[id] = i
+		}
+	}
+}
+
+// Removes every partition and message fault
+func (s *Simulator) Heal() {
+	s.record("heal")
+	s.groups = make(map[string]int)
+	s.faults = Faults{}
+}
+
+// Stops a node abruptly: its pending background work is lost, only what reached its store survives
+func (s *Simulator) Crash(id string) error {
+	if s.crashed[id] {
+		return fmt.Errorf("node %s already crashed", id)
+	}
+
+	s.record("crash " + id)
+	s.crashed[id] = true
+	s.incarnations[id]++
+	return s.nodes[id].Stop()
+}
+
+// Brings a crashed node back with the data of its store. It fetches the ring from the first node it can reach.
+func (s *Simulator) Restart(id string) error {
+	if !s.crashed[id] {
+		return fmt.Errorf("node %s is not crashed", id)
+	}
+
+	s.record("restart " + id)
+	if err := s.boot(id); err != nil {
+		return err
+	}
+	s.crashed[id] = false
+
+	return s.rejoin(id)
+}
+
+func (s *Simulator) rejoin(id string) error {
+	for _, peerId := range s.ids {
+		if peerId == id || s.crashed[peerId] {
+			continue
+		}
+		if err := s.nodes[id].JoinToRing(context.Background(), node.NodeIdToZMQAddr(peerId)); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("node %s could not reach any node to rejoin", id)
+}
+
+func (s *Simulator) liveNodes() []string {
+	live := []string{}
+	for _, id := range s.ids {
+		if !s.crashed[id] {
+			live = append(live, id)
+		}
+	}
+	return live
+}
+
+// Runs up to max queued background tasks, picked in random order. Tasks of crashed nodes are discarded.
+func (s *Simulator) runTasks(max int) {
+	for range max {
+		if len(s.tasks) == 0 {
+			return
+		}
+
+		i := s.rng.IntN(len(s.tasks))
+		t := s.tasks[i]
+		s.tasks = slices.Delete(s.tasks, i, i+1)
+
+		if s.crashed[t.nodeId] || s.incarnations[t.nodeId] != t.incarnation {
+			continue
+		}
+		t.run()
+	}
+}
+
+func (s *Simulator) drain() {
+	s.runTasks(maxDrainedTasks)
+}
+
+// Runs a random workload: each step is a client operation, some background work or a maintenance round of a node
+func (s *Simulator) Run(steps int) {
+	for range steps {
+		switch r := s.rng.Float64(); {
+		case r < 0.6:
+			s.clientOperation()
+		case r < 0.9:
+			s.runTasks(1 + s.rng.IntN(4))
+		default:
+			live := s.liveNodes()
+			if len(live) == 0 {
+				continue
+			}
+			id := live[s.rng.IntN(len(live))]
+			s.clock.Advance(time.Second)
+			s.nodes[id].RunMaintenance(context.Background())
+		}
+	}
+}
+
+func (s *Simulator) clientOperation() {
+	live := s.liveNodes()
+	if len(live) == 0 {
+		return
+	}
+
+	c := s.clients[s.rng.IntN(len(s.clients))]
+	nodeId := live[s.rng.IntN(len(live))]
+
+	delta, index, description := c.randomOperation(s.rng, s.history)
+	err := s.nodes[nodeId].HandleShoppingList(context.Background(), delta)
+	s.record(fmt.Sprintf("%s -> %s: %s (error: %v)", c.id, nodeId, description, err))
+	if err == nil {
+		s.history.Ack(index)
+	}
+
+	// Sometimes the client reads the list back, possibly from another node
+	if s.chance(readRate) {
+		s.clientRead(c, delta.ListID(), live[s.rng.IntN(len(live))])
+	}
+}
+
+func (s *Simulator) clientRead(c *client, listID string, nodeId string) {
+	listProto, err := s.nodes[nodeId].GetShoppingList(context.Background(), listID)
+	s.record(fmt.Sprintf("%s -> %s: read %s (error: %v)", c.id, nodeId, listID, err))
+	if err != nil {
+		return
+	}
+	list, err := crdt.ShoppingListFromProto(listProto, c.id)
+	if err != nil {
+		s.record(fmt.Sprintf("%s: could not decode %s from %s: %v", c.id, listID, nodeId, err))
+		return
+	}
+	s.history.Observe(c.id+"@"+nodeId, list)
+}
+
+// Lets the cluster recover after the faults were healed and the crashed nodes restarted: runs maintenance rounds
+// (failure detection, hint delivery) until no hints are left, and has every client resend its full lists, like
+// clients do when they reconnect.
+func (s *Simulator) Settle() error {
+	s.record("settle")
+
+	for _, id := range s.liveNodes() {
+		if err := s.rejoin(id); err != nil {
+			return err
+		}
+	}
+
+	s.recover()
+
+	for _, c := range s.clients {
+		for _, list := range c.lists {
+			live := s.liveNodes()
+			nodeId := live[s.rng.IntN(len(live))]
+			if err := s.nodes[nodeId].HandleShoppingList(context.Background(), list.Clone()); err != nil {
+				return fmt.Errorf("%s failed to resend list %s: %w", c.id, list.ListID(), err)
+			}
+		}
+	}
+
+	// Replicas still suspected by a coordinator got the resent lists as hints
+	s.recover()
+
+	return nil
+}
+
+// Runs maintenance rounds on every live node until all hints are delivered
+func (s *Simulator) recover() {
+	for range 20 {
+		// Past any hint delivery backoff
+		s.clock.Advance(config.DefaultConfig().HintRetryMaxBackoff)
+		for _, id := range s.liveNodes() {
+			s.nodes[id].RunMaintenance(context.Background())
+		}
+		s.drain()
+
+		if s.pendingHints() == 0 {
+			return
+		}
+	}
+}
+
+func (s *Simulator) pendingHints() int {
+	total := 0
+	for _, id := range s.liveNodes() {
+		total += s.nodes[id].HintStats().Total
+	}
+	return total
+}
+
+// Stops every live node
+func (s *Simulator) Close() error {
+	var firstErr error
+	for _, id := range s.ids {
+		n, ok := s.nodes[id]
+		if !ok || s.crashed[id] {
+			continue
+		}
+		if err := n.Stop(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		s.crashed[id] = true
+	}
+	return firstErr
+}
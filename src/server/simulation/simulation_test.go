@@ -0,0 +1,85 @@
+package simulation
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"sdle-server/checker"
+)
+
+// Runs a workload through drops, duplicates, delays, a partition and a crash-restart, then heals everything
+func runFaultySimulation(t *testing.T, seed uint64) *Simulator {
+	t.Helper()
+
+	s, err := New(Config{Seed: seed, Nodes: 5, Clients: 3, Lists: 2, Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Expected the cluster to boot, got %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	ids := s.NodeIds()
+
+	s.Run(30)
+
+	s.SetFaults(Faults{DropRate: 0.1, DuplicateRate: 0.1, DelayRate: 0.2, MaxDelay: 150 * time.Millisecond})
+	s.Run(60)
+
+	s.Partition(ids[:2])
+	s.Run(40)
+
+	if err := s.Crash(ids[2]); err != nil {
+		t.Fatalf("Expected no error crashing %s, got %v", ids[2], err)
+	}
+	s.Run(40)
+
+	s.Heal()
+	if err := s.Restart(ids[2]); err != nil {
+		t.Fatalf("Expected no error restarting %s, got %v", ids[2], err)
+	}
+	s.Run(20)
+
+	if err := s.Settle(); err != nil {
+		t.Fatalf("Expected the cluster to settle, got %v", err)
+	}
+
+	return s
+}
+
+func TestSimulation_ConvergesAfterFaults(t *testing.T) {
+	for _, seed := range []uint64{1, 2, 3} {
+		s := runFaultySimulation(t, seed)
+
+		if err := s.CheckConvergence(); err != nil {
+			t.Errorf("Seed %d: %v", seed, err)
+		}
+	}
+}
+
+func TestSimulation_HistoryIsConsistent(t *testing.T) {
+	for _, seed := range []uint64{1, 2, 3} {
+		s := runFaultySimulation(t, seed)
+		s.ObserveReplicas()
+
+		for _, violation := range checker.Check(s.History()) {
+			t.Errorf("Seed %d: %s", seed, violation)
+		}
+	}
+}
+
+func TestSimulation_ReproducibleFromSeed(t *testing.T) {
+	first := runFaultySimulation(t, 42).Trace()
+	second := runFaultySimulation(t, 42).Trace()
+
+	if len(first) == 0 {
+		t.Fatalf("Expected the run to record events")
+	}
+	if !slices.Equal(first, second) {
+		for i := range min(len(first), len(second)) {
+			if first[i] != second[i] {
+				t.Fatalf("Expected identical traces for the same seed, first difference at event %d:\n%s\n%s", i, first[i], second[i])
+			}
+		}
+		t.Fatalf("Expected identical traces for the same seed, got %d and %d events", len(first), len(second))
+	}
+}
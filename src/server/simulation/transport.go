@@ -0,0 +1,131 @@
+package simulation
+
+import (
+	"context"
+	"fmt"
+
+	"sdle-server/config"
+	pb "sdle-server/proto"
+	"sdle-server/transport"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Transport of a simulated node. Requests are handled synchronously on the sender's goroutine, so a whole cluster
+// runs on the simulator's goroutine and every fault decision is taken in a reproducible order.
+//
+// Time is simulated too: a lost message costs the sender RequestTimeout on the simulated clock. Deadlines of contexts
+// are measured on the real clock, so they are ignored, and not passed on to the receiver, to keep runs reproducible.
+type simTransport struct {
+	sim     *Simulator
+	id      string
+	handler transport.Handler
+	stopCh  chan struct{}
+	closed  bool
+}
+
+func newSimTransport(sim *Simulator, id string) *simTransport {
+	return &simTransport{sim: sim, id: id, stopCh: make(chan struct{})}
+}
+
+func (t *simTransport) Send(ctx context.Context, peerId string, req *pb.Request) (*pb.Response, error) {
+	s := t.sim
+	timeout := config.DefaultConfig().RequestTimeout
+
+	peer, ok := s.transports[peerId]
+	if !ok || peer.closed || peer.handler == nil {
+		s.record(fmt.Sprintf("%s -> %s: unreachable", t.id, peerId))
+		return nil, transport.ErrPeerUnreachable
+	}
+
+	// Messages a node sends to itself never cross the network
+	if peerId == t.id {
+		return peer.deliver(req)
+	}
+
+	if !s.connected(t.id, peerId) {
+		s.record(fmt.Sprintf("%s -> %s: partitioned", t.id, peerId))
+		s.clock.Advance(timeout)
+		return nil, transport.ErrTimeout
+	}
+
+	if s.chance(s.faults.DropRate) {
+		s.record(fmt.Sprintf("%s -> %s: request dropped", t.id, peerId))
+		s.clock.Advance(timeout)
+		return nil, transport.ErrTimeout
+	}
+
+	delay := s.delay()
+	resp, err := peer.deliver(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.chance(s.faults.DuplicateRate) {
+		s.record(fmt.Sprintf("%s -> %s: request duplicated", t.id, peerId))
+		_, _ = peer.deliver(req)
+	}
+
+	if s.chance(s.faults.DropRate) {
+		s.record(fmt.Sprintf("%s -> %s: response dropped", t.id, peerId))
+		s.clock.Advance(timeout)
+		return nil, transport.ErrTimeout
+	}
+
+	delay += s.delay()
+	if delay > timeout {
+		s.record(fmt.Sprintf("%s -> %s: delayed by %v, timed out", t.id, peerId, delay))
+		s.clock.Advance(timeout)
+		return nil, transport.ErrTimeout
+	}
+
+	s.clock.Advance(delay)
+	return resp, nil
+}
+
+// Runs the handler of the node on a copy of the request, serialized like on the wire
+func (t *simTransport) deliver(req *pb.Request) (*pb.Response, error) {
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var copied pb.Request
+	if err := proto.Unmarshal(data, &copied); err != nil {
+		return nil, err
+	}
+	copied.TimeoutMs = 0
+
+	resp := t.handler(&copied)
+
+	data, err = proto.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var respCopy pb.Response
+	if err := proto.Unmarshal(data, &respCopy); err != nil {
+		return nil, err
+	}
+
+	return &respCopy, nil
+}
+
+// The simulator installs the handler itself when it boots a node; Serve only exists to satisfy the interface
+func (t *simTransport) Serve(handler transport.Handler) error {
+	if t.closed {
+		return transport.ErrClosed
+	}
+
+	t.handler = handler
+	<-t.stopCh
+	return nil
+}
+
+func (t *simTransport) Close() error {
+	if !t.closed {
+		t.closed = true
+		close(t.stopCh)
+	}
+	return nil
+}
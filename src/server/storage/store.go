@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"bytes"
 	"errors"
 	"os"
 	"path/filepath"
@@ -9,10 +10,30 @@ import (
 	"github.com/dgraph-io/badger/v4"
 )
 
+// Prefix of the keys used to store hints for other nodes (see the replication package)
+const HintKeyPrefix = "hint:"
+
+// Returned by Get when the key is not stored
+var ErrNotFound = badger.ErrKeyNotFound
+
+// Key written by CheckWritable
+const healthKey = "health:probe"
+
 type Store struct {
 	db *badger.DB
 }
 
+// Number of keys and bytes (keys + values) stored in a hash space
+type HashSpaceLoad struct {
+	Keys  int64
+	Bytes int64
+}
+
+// Internal keys share the DB with the data but are not part of the hash space
+func IsInternalKey(key []byte) bool {
+	return bytes.HasPrefix(key, []byte(HintKeyPrefix)) || bytes.Equal(key, []byte(healthKey))
+}
+
 func Open(dirPath string) (*Store, error) {
 	if err := os.MkdirAll(filepath.Clean(dirPath), 0o700); err != nil {
 		return nil, err
@@ -37,6 +58,16 @@ func (s *Store) GetDB() *badger.DB {
 	return s.db
 }
 
+// Size on disk, in bytes, of the LSM tree and of the value log
+func (s *Store) Size() (lsm int64, vlog int64) {
+	return s.db.Size()
+}
+
+// Checks that the store accepts writes, by writing an internal key
+func (s *Store) CheckWritable() error {
+	return s.Put([]byte(healthKey), []byte{1})
+}
+
 func (s *Store) Put(key, value []byte) error {
 	return s.db.Update(func(txn *badger.Txn) error {
 		return txn.Set(key, value)
@@ -92,8 +123,12 @@ func (s *Store) GetHashSpace(start uint64, end uint64) (map[string][]byte, error
 		for it.Rewind(); it.Valid(); it.Next() {
 			item := it.Item()
 			k := item.Key()
+			if IsInternalKey(k) {
+				continue
+			}
+
 			hash := rv.HashKey(string(k))
-			if hash >= start && hash <= end {
+			if rv.InHashSpace(hash, start, end) {
 				v, err := item.ValueCopy(nil)
 				if err != nil {
 					return err
@@ -105,3 +140,62 @@ func (s *Store) GetHashSpace(start uint64, end uint64) (map[string][]byte, error
 	})
 	return result, err
 }
+
+// Returns every data key in the store (internal keys, such as hints, are left out)
+func (s *Store) GetKeys() ([]string, error) {
+	keys := []string{}
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			k := it.Item().Key()
+			if !IsInternalKey(k) {
+				keys = append(keys, string(k))
+			}
+		}
+		return nil
+	})
+	return keys, err
+}
+
+// Returns the size (key + value bytes) of every key in the hash space, without reading the values
+func (s *Store) GetHashSpaceSizes(start uint64, end uint64) (map[string]int64, error) {
+	result := make(map[string]int64)
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			k := item.Key()
+			if IsInternalKey(k) {
+				continue
+			}
+
+			if rv.InHashSpace(rv.HashKey(string(k)), start, end) {
+				result[string(k)] = int64(len(k)) + item.ValueSize()
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+// Returns the number of keys and bytes stored in the hash space
+func (s *Store) GetHashSpaceLoad(start uint64, end uint64) (HashSpaceLoad, error) {
+	sizes, err := s.GetHashSpaceSizes(start, end)
+	if err != nil {
+		return HashSpaceLoad{}, err
+	}
+
+	load := HashSpaceLoad{Keys: int64(len(sizes))}
+	for _, size := range sizes {
+		load.Bytes += size
+	}
+	return load, nil
+}
@@ -87,3 +87,78 @@ func TestStore_PersistenceAcrossReopen(t *testing.T) {
 		t.Fatalf("value mismatch after reopen: got %q want %q", got, val)
 	}
 }
+
+func TestStore_GetHashSpaceLoad(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+
+	keys := []string{"a", "b", "c", "d", "e"}
+	for _, k := range keys {
+		if err := s.Put([]byte(k), []byte("value")); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+	if err := s.Put([]byte(HintKeyPrefix+"node:a"), []byte("hint")); err != nil {
+		t.Fatalf("Put hint: %v", err)
+	}
+
+	// A wrapping range that covers the whole hash space
+	load, err := s.GetHashSpaceLoad(1, 0)
+	if err != nil {
+		t.Fatalf("GetHashSpaceLoad: %v", err)
+	}
+	if load.Keys != int64(len(keys)) {
+		t.Errorf("got %d keys want %d (hints must not be counted)", load.Keys, len(keys))
+	}
+	if load.Bytes != int64(len(keys)*(1+len("value"))) {
+		t.Errorf("got %d bytes want %d", load.Bytes, len(keys)*(1+len("value")))
+	}
+}
+
+func TestStore_GetKeys(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+
+	for _, k := range []string{"b", "a", HintKeyPrefix + "node:a"} {
+		if err := s.Put([]byte(k), []byte("value")); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+
+	keys, err := s.GetKeys()
+	if err != nil {
+		t.Fatalf("GetKeys: %v", err)
+	}
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Errorf("got %v want [a b]", keys)
+	}
+}
+
+func TestStore_CheckWritable(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	if err := s.CheckWritable(); err != nil {
+		t.Fatalf("CheckWritable: %v", err)
+	}
+	keys, err := s.GetKeys()
+	if err != nil {
+		t.Fatalf("GetKeys: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("got keys %v want none: the probe key is internal", keys)
+	}
+
+	s.Close()
+	if err := s.CheckWritable(); err == nil {
+		t.Errorf("expected a closed store to reject writes")
+	}
+}
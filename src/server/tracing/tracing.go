@@ -0,0 +1,112 @@
+package tracing
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+	"math/rand/v2"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Instrumentation scope of the spans of the server
+const scope = "sdle-server"
+
+// Names of the attributes attached to spans, matching the log keys where they overlap
+const (
+	AttrNode      = attribute.Key("sdle.node")
+	AttrPeer      = attribute.Key("sdle.peer")
+	AttrKey       = attribute.Key("sdle.key")
+	AttrListID    = attribute.Key("sdle.list_id")
+	AttrMessageID = attribute.Key("sdle.message_id")
+)
+
+// Starts a span. Without a tracer provider (see Setup) spans are not recorded and cost next to nothing.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(scope).Start(ctx, name, opts...)
+}
+
+// Marks a span as failed because of an error
+func Fail(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Ends a span, marking it as failed if err is not nil
+func Finish(span trace.Span, err error) {
+	if err != nil {
+		Fail(span, err)
+	}
+	span.End()
+}
+
+// Derives the ID of the trace of a client request from its message ID, so a client can find the trace of a request
+func TraceIDFromMessageID(messageID string) trace.TraceID {
+	sum := sha256.Sum256([]byte(messageID))
+	var id trace.TraceID
+	copy(id[:], sum[:])
+	return id
+}
+
+type traceIDKey struct{}
+
+// Makes the next root span started from ctx belong to the given trace, instead of a random one
+func WithTraceID(ctx context.Context, id trace.TraceID) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// Generates random IDs, except for root spans whose trace ID was set with WithTraceID
+type idGenerator struct{}
+
+func (idGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	traceID, _ := ctx.Value(traceIDKey{}).(trace.TraceID)
+	for !traceID.IsValid() {
+		randomBytes(traceID[:])
+	}
+	return traceID, idGenerator{}.NewSpanID(ctx, traceID)
+}
+
+func (idGenerator) NewSpanID(ctx context.Context, traceID trace.TraceID) trace.SpanID {
+	var spanID trace.SpanID
+	for !spanID.IsValid() {
+		randomBytes(spanID[:])
+	}
+	return spanID
+}
+
+func randomBytes(b []byte) {
+	for i := range b {
+		b[i] = byte(rand.Uint32())
+	}
+}
+
+// Creates a tracer provider for the spans of a node. opts add the span processors (and exporters).
+func NewProvider(nodeID string, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	res := resource.NewSchemaless(
+		attribute.String("service.name", scope),
+		attribute.String("service.instance.id", nodeID),
+	)
+	return sdktrace.NewTracerProvider(append([]sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithIDGenerator(idGenerator{}),
+	}, opts...)...)
+}
+
+// Records every span of the process, exporting them to w as OpenTelemetry span data (one JSON object per span).
+// Returns a function that flushes the pending spans and stops the export.
+func Setup(w io.Writer, nodeID string) (func(context.Context) error, error) {
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(w))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := NewProvider(nodeID, sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTraceIDFromMessageID(t *testing.T) {
+	id := TraceIDFromMessageID("client1-42")
+	if !id.IsValid() {
+		t.Fatalf("Expected a valid trace ID, got %s", id)
+	}
+	if TraceIDFromMessageID("client1-42") != id {
+		t.Errorf("Expected the same message ID to give the same trace ID")
+	}
+	if TraceIDFromMessageID("client1-43") == id {
+		t.Errorf("Expected different message IDs to give different trace IDs")
+	}
+}
+
+func TestStart_RootSpanUsesTraceID(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := NewProvider("node1:5000", sdktrace.WithSpanProcessor(recorder))
+	tracer := provider.Tracer(scope)
+
+	id := TraceIDFromMessageID("client1-42")
+	ctx, root := tracer.Start(WithTraceID(t.Context(), id), "root")
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+	root.End()
+
+	_, other := tracer.Start(t.Context(), "other")
+	other.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+	if spans[0].SpanContext().TraceID() != id || spans[1].SpanContext().TraceID() != id {
+		t.Errorf("Expected the root span and its child in trace %s, got %s and %s", id,
+			spans[1].SpanContext().TraceID(), spans[0].SpanContext().TraceID())
+	}
+	if spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Errorf("Expected the child span to have the root span as parent")
+	}
+	if other := spans[2].SpanContext().TraceID(); other == id || !other.IsValid() {
+		t.Errorf("Expected a span without trace ID to start a random trace, got %s", other)
+	}
+}
+
+func TestSetup_ExportsJSON(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	var out bytes.Buffer
+	shutdown, err := Setup(&out, "node1:5000")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, span := Start(WithTraceID(t.Context(), TraceIDFromMessageID("client1-42")), "client get_shopping_list")
+	span.SetAttributes(AttrListID.String("list1"))
+	span.End()
+	if err := shutdown(t.Context()); err != nil {
+		t.Fatalf("Expected no error shutting down, got %v", err)
+	}
+
+	var exported struct {
+		Name        string
+		SpanContext struct{ TraceID string }
+	}
+	if err := json.Unmarshal(out.Bytes(), &exported); err != nil {
+		t.Fatalf("Expected a JSON span, got %q", out.String())
+	}
+	if exported.Name != "client get_shopping_list" || exported.SpanContext.TraceID != TraceIDFromMessageID("client1-42").String() {
+		t.Errorf("Expected the exported span to keep its name and trace ID, got %+v", exported)
+	}
+}
@@ -0,0 +1,164 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	pb "sdle-server/proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// In-process network connecting memory transports, so that whole clusters can run inside a single process
+type MemoryNetwork struct {
+	mu    sync.RWMutex
+	peers map[string]*MemoryTransport
+}
+
+func NewMemoryNetwork() *MemoryNetwork {
+	return &MemoryNetwork{peers: make(map[string]*MemoryTransport)}
+}
+
+// Creates the transport of a node in the network
+func (net *MemoryNetwork) Listen(id string) (*MemoryTransport, error) {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+
+	if _, exists := net.peers[id]; exists {
+		return nil, fmt.Errorf("address %s already in use", id)
+	}
+
+	t := &MemoryTransport{
+		id:      id,
+		network: net,
+		inbox:   make(chan memoryRequest),
+		stopCh:  make(chan struct{}),
+		doneCh:  make(chan struct{}),
+	}
+	net.peers[id] = t
+
+	return t, nil
+}
+
+func (net *MemoryNetwork) peer(id string) (*MemoryTransport, bool) {
+	net.mu.RLock()
+	defer net.mu.RUnlock()
+
+	t, ok := net.peers[id]
+	return t, ok
+}
+
+func (net *MemoryNetwork) remove(id string) {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+
+	delete(net.peers, id)
+}
+
+type memoryRequest struct {
+	data   []byte
+	respCh chan []byte
+}
+
+// Transport over a MemoryNetwork. Messages are serialized like on the wire, so peers never share memory.
+type MemoryTransport struct {
+	id      string
+	network *MemoryNetwork
+	inbox   chan memoryRequest
+
+	mu      sync.Mutex
+	serving bool
+	closed  bool
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+}
+
+func (t *MemoryTransport) ID() string {
+	return t.id
+}
+
+func (t *MemoryTransport) Send(ctx context.Context, peerId string, req *pb.Request) (*pb.Response, error) {
+	peer, ok := t.network.peer(peerId)
+	if !ok {
+		return nil, ErrPeerUnreachable
+	}
+
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	request := memoryRequest{data: data, respCh: make(chan []byte, 1)}
+
+	select {
+	case peer.inbox <- request:
+	case <-peer.stopCh:
+		return nil, ErrPeerUnreachable
+	case <-ctx.Done():
+		return nil, ContextError(ctx)
+	}
+
+	var respData []byte
+	select {
+	case respData = <-request.respCh:
+	case <-ctx.Done():
+		return nil, ContextError(ctx)
+	}
+
+	var resp pb.Response
+	if err := proto.Unmarshal(respData, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+func (t *MemoryTransport) Serve(handler Handler) error {
+	t.mu.Lock()
+	if t.closed || t.serving {
+		t.mu.Unlock()
+		return ErrClosed
+	}
+	t.serving = true
+	t.mu.Unlock()
+
+	defer close(t.doneCh)
+
+	for {
+		select {
+		case <-t.stopCh:
+			return nil
+		case request := <-t.inbox:
+			var resp *pb.Response
+			var req pb.Request
+			if err := proto.Unmarshal(request.data, &req); err != nil {
+				resp = ErrorResponse("failed to unmarshal request: " + err.Error())
+			} else {
+				resp = handler(&req)
+			}
+
+			data, _ := proto.Marshal(resp)
+			request.respCh <- data
+		}
+	}
+}
+
+func (t *MemoryTransport) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	serving := t.serving
+	t.mu.Unlock()
+
+	t.network.remove(t.id)
+	close(t.stopCh)
+	if serving {
+		<-t.doneCh
+	}
+
+	return nil
+}
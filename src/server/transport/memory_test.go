@@ -0,0 +1,110 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pb "sdle-server/proto"
+)
+
+func echoHandler(req *pb.Request) *pb.Response {
+	return &pb.Response{Origin: req.GetPut().GetKey(), Ok: true}
+}
+
+func TestMemoryTransport_SendAndServe(t *testing.T) {
+	network := NewMemoryNetwork()
+
+	server, _ := network.Listen("a")
+	client, _ := network.Listen("b")
+	defer server.Close()
+	defer client.Close()
+
+	go server.Serve(echoHandler)
+
+	req := &pb.Request{Origin: "b", RequestType: &pb.Request_Put{Put: &pb.RequestPut{Key: "key1"}}}
+	resp, err := client.Send(context.Background(), "a", req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !resp.Ok || resp.Origin != "key1" {
+		t.Errorf("Expected the handler response, got %v", resp)
+	}
+}
+
+func TestMemoryTransport_DuplicateAddress(t *testing.T) {
+	network := NewMemoryNetwork()
+
+	if _, err := network.Listen("a"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := network.Listen("a"); err == nil {
+		t.Errorf("Expected an error when listening twice on the same address")
+	}
+}
+
+func TestMemoryTransport_UnreachablePeer(t *testing.T) {
+	network := NewMemoryNetwork()
+	client, _ := network.Listen("b")
+
+	if _, err := client.Send(context.Background(), "a", &pb.Request{}); !errors.Is(err, ErrPeerUnreachable) {
+		t.Errorf("Expected ErrPeerUnreachable for an unknown peer, got %v", err)
+	}
+
+	server, _ := network.Listen("a")
+	server.Close()
+
+	if _, err := client.Send(context.Background(), "a", &pb.Request{}); !errors.Is(err, ErrPeerUnreachable) {
+		t.Errorf("Expected ErrPeerUnreachable for a closed peer, got %v", err)
+	}
+}
+
+func TestMemoryTransport_Timeout(t *testing.T) {
+	network := NewMemoryNetwork()
+
+	// Listening but not serving: requests are never picked up
+	server, _ := network.Listen("a")
+	client, _ := network.Listen("b")
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.Send(ctx, "a", &pb.Request{}); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestMemoryTransport_Cancel(t *testing.T) {
+	network := NewMemoryNetwork()
+
+	server, _ := network.Listen("a")
+	client, _ := network.Listen("b")
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if _, err := client.Send(ctx, "a", &pb.Request{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestMemoryTransport_CloseStopsServe(t *testing.T) {
+	network := NewMemoryNetwork()
+	server, _ := network.Listen("a")
+
+	done := make(chan error)
+	go func() { done <- server.Serve(echoHandler) }()
+
+	time.Sleep(10 * time.Millisecond)
+	server.Close()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected Serve to return nil after Close, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected Serve to return after Close")
+	}
+}
@@ -0,0 +1,42 @@
+package transport
+
+import (
+	"context"
+	"errors"
+
+	pb "sdle-server/proto"
+)
+
+var (
+	ErrPeerUnreachable = errors.New("peer unreachable")
+	ErrTimeout         = errors.New("request timed out")
+	ErrClosed          = errors.New("transport closed")
+)
+
+// Handles an inbound request and returns the response sent back to the peer
+type Handler func(req *pb.Request) *pb.Response
+
+// Request/response messaging between nodes, addressed by node ID
+type Transport interface {
+	// Sends a request to a peer and waits for its response, until ctx is done
+	Send(ctx context.Context, peerId string, req *pb.Request) (*pb.Response, error)
+
+	// Serves inbound requests, one at a time, until the transport is closed
+	Serve(handler Handler) error
+
+	// Stops serving and releases the transport. Waits for the request being handled to finish.
+	Close() error
+}
+
+// Error of a send abandoned because its context is done: ErrTimeout once its deadline has passed
+func ContextError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ErrTimeout
+	}
+	return ctx.Err()
+}
+
+// Response of a request that failed before reaching its handler
+func ErrorResponse(errStr string) *pb.Response {
+	return &pb.Response{Ok: false, Error: errStr}
+}
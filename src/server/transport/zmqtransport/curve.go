@@ -0,0 +1,133 @@
+package zmqtransport
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pebbe/zmq4"
+)
+
+// ZAP domain of the sockets nodes receive requests on
+const zapDomain = "sdle"
+
+// Length of a Z85-encoded CurveZMQ key
+const curveKeyLength = 40
+
+// CurveZMQ keys of a node and the public keys of the nodes of its cluster. With them, all traffic between nodes is
+// encrypted, and only the nodes of the cluster can send requests to the node.
+type CurveKeys struct {
+	SecretKey  string            // Z85-encoded secret key of the node
+	PublicKeys map[string]string // Z85-encoded public keys of the nodes of the cluster (including this one), by node ID
+}
+
+// Reads the secret key of a node (a file holding the Z85-encoded key) and the public keys of its cluster (a file
+// with a "<node ID> <Z85-encoded public key>" line per node; blank lines and lines starting with # are ignored)
+func LoadCurveKeys(secretKeyFile string, publicKeysFile string) (*CurveKeys, error) {
+	secretKey, err := os.ReadFile(secretKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(publicKeysFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	publicKeys, err := ParsePublicKeys(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", publicKeysFile, err)
+	}
+
+	keys := &CurveKeys{SecretKey: strings.TrimSpace(string(secretKey)), PublicKeys: publicKeys}
+	if err := keys.validate(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Parses the public keys of a cluster, one "<node ID> <Z85-encoded public key>" line per node
+func ParsePublicKeys(r io.Reader) (map[string]string, error) {
+	keys := make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a node ID and a public key", lineNumber)
+		}
+		if _, ok := keys[fields[0]]; ok {
+			return nil, fmt.Errorf("line %d: duplicate node %s", lineNumber, fields[0])
+		}
+		keys[fields[0]] = fields[1]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (k *CurveKeys) validate() error {
+	if len(k.SecretKey) != curveKeyLength {
+		return fmt.Errorf("secret key must be %d Z85 characters long", curveKeyLength)
+	}
+	if len(k.PublicKeys) == 0 {
+		return fmt.Errorf("no public keys for the cluster")
+	}
+	for nodeId, key := range k.PublicKeys {
+		if len(key) != curveKeyLength {
+			return fmt.Errorf("public key of %s must be %d Z85 characters long", nodeId, curveKeyLength)
+		}
+	}
+	return nil
+}
+
+// Node a public key belongs to
+func (k *CurveKeys) nodeOf(publicKey string) (string, bool) {
+	for nodeId, key := range k.PublicKeys {
+		if key == publicKey {
+			return nodeId, true
+		}
+	}
+	return "", false
+}
+
+var (
+	zapOnce sync.Once
+	zapErr  error
+)
+
+// Starts the ZAP handler of the process, which authenticates the peers connecting to the sockets of every node of the
+// process. Authenticated peers are identified by their public key (the User-Id of their messages).
+func startZAP() error {
+	zapOnce.Do(func() {
+		zmq4.AuthSetMetadataHandler(func(version, requestId, domain, address, identity, mechanism string, credentials ...string) map[string]string {
+			if mechanism == "CURVE" && len(credentials) > 0 {
+				return map[string]string{"User-Id": zmq4.Z85encode(credentials[0])}
+			}
+			return map[string]string{}
+		})
+		zapErr = zmq4.AuthStart()
+	})
+	return zapErr
+}
+
+// Makes a socket accept only the nodes of the cluster, encrypting the traffic with the key of this node
+func (k *CurveKeys) setupServer(sock *zmq4.Socket) error {
+	if err := startZAP(); err != nil {
+		return fmt.Errorf("failed to start ZAP handler: %w", err)
+	}
+	for _, key := range k.PublicKeys {
+		zmq4.AuthCurveAdd(zapDomain, key)
+	}
+	return sock.ServerAuthCurve(zapDomain, k.SecretKey)
+}
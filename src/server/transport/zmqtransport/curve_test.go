@@ -0,0 +1,81 @@
+package zmqtransport
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pb "sdle-server/proto"
+)
+
+const (
+	testPublicKey1 = "rq:rM>}U?@Lns47E1%kR.o@n%FcmmsL/@{H8]yf7"
+	testPublicKey2 = "Yne@$w-vo<fVvi]a<NY6T1ed:M$fCG*[IaLV{hID"
+	testSecretKey  = "JTKVSB%%)wK0E.X)V>+}o?pNmC{O&4W4b!Ni{Lh6"
+)
+
+func TestParsePublicKeys(t *testing.T) {
+	keys, err := ParsePublicKeys(strings.NewReader(`
+# cluster keys
+node1:5000 ` + testPublicKey1 + `
+node2:5001   ` + testPublicKey2 + `
+`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(keys) != 2 || keys["node1:5000"] != testPublicKey1 || keys["node2:5001"] != testPublicKey2 {
+		t.Errorf("Expected the keys of node1 and node2, got %v", keys)
+	}
+
+	if _, err := ParsePublicKeys(strings.NewReader("node1:5000\n")); err == nil {
+		t.Errorf("Expected a line without key to be rejected")
+	}
+	if _, err := ParsePublicKeys(strings.NewReader("node1:5000 " + testPublicKey1 + "\nnode1:5000 " + testPublicKey2 + "\n")); err == nil {
+		t.Errorf("Expected a duplicate node to be rejected")
+	}
+}
+
+func TestLoadCurveKeys(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "secret")
+	keysFile := filepath.Join(dir, "keys")
+	_ = os.WriteFile(secretFile, []byte(testSecretKey+"\n"), 0o600)
+	_ = os.WriteFile(keysFile, []byte("node1:5000 "+testPublicKey1+"\n"), 0o600)
+
+	keys, err := LoadCurveKeys(secretFile, keysFile)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if keys.SecretKey != testSecretKey {
+		t.Errorf("Expected the secret key without trailing newline, got %q", keys.SecretKey)
+	}
+	if nodeId, ok := keys.nodeOf(testPublicKey1); !ok || nodeId != "node1:5000" {
+		t.Errorf("Expected the key of node1 to identify it, got %q", nodeId)
+	}
+
+	_ = os.WriteFile(keysFile, []byte("node1:5000 short\n"), 0o600)
+	if _, err := LoadCurveKeys(secretFile, keysFile); err == nil {
+		t.Errorf("Expected a malformed public key to be rejected")
+	}
+}
+
+func TestTransport_CheckOrigin(t *testing.T) {
+	transport := &Transport{curve: &CurveKeys{
+		SecretKey:  testSecretKey,
+		PublicKeys: map[string]string{"node1:5000": testPublicKey1, "node2:5001": testPublicKey2},
+	}}
+
+	if err := transport.checkOrigin(&pb.Request{Origin: "tcp://node1:5000"}, testPublicKey1); err != nil {
+		t.Errorf("Expected node1 to send under its own ID, got %v", err)
+	}
+	if err := transport.checkOrigin(&pb.Request{Origin: "node2:5001"}, testPublicKey1); err == nil {
+		t.Errorf("Expected node1 not to pose as node2")
+	}
+	if err := transport.checkOrigin(&pb.Request{Origin: "node1:5000"}, ""); err == nil {
+		t.Errorf("Expected an unauthenticated request to be rejected")
+	}
+	if err := (&Transport{}).checkOrigin(&pb.Request{Origin: "node1:5000"}, ""); err != nil {
+		t.Errorf("Expected any origin to be accepted without CurveZMQ, got %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+// Package zmqtransport implements transport.Transport over ZeroMQ. It is the only package that needs cgo and libzmq,
+// so the nodes, the simulator and the checker can be built and tested without them.
+package zmqtransport
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	pb "sdle-server/proto"
+	"sdle-server/transport"
+
+	"github.com/pebbe/zmq4"
+	"google.golang.org/protobuf/proto"
+)
+
+// Transport over ZeroMQ: inbound requests arrive on a REP socket bound to tcp://<id>, and every outbound request
+// uses a fresh REQ socket. With CurveZMQ keys, the traffic is encrypted and only the nodes of the cluster are served.
+type Transport struct {
+	repSock   *zmq4.Socket
+	curve     *CurveKeys // nil if the traffic is neither encrypted nor authenticated
+	publicKey string     // CurveZMQ public key of this node
+
+	mu      sync.Mutex
+	serving bool
+	closed  bool
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+}
+
+// Creates the transport of a node. curve may be nil, in which case any process that can reach the port of the node
+// can send it requests.
+func New(id string, curve *CurveKeys) (*Transport, error) {
+	t := &Transport{
+		curve:  curve,
+		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
+	}
+
+	if curve != nil {
+		if err := curve.validate(); err != nil {
+			return nil, err
+		}
+		publicKey, err := zmq4.AuthCurvePublic(curve.SecretKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CurveZMQ secret key: %w", err)
+		}
+		if key, ok := curve.PublicKeys[id]; ok && key != publicKey {
+			return nil, fmt.Errorf("CurveZMQ secret key does not match the public key of %s", id)
+		}
+		t.publicKey = publicKey
+	}
+
+	rep, err := zmq4.NewSocket(zmq4.REP)
+	if err != nil {
+		return nil, err
+	}
+
+	// Must be set up before binding: peers that fail the CurveZMQ handshake never get a request through
+	if curve != nil {
+		if err := curve.setupServer(rep); err != nil {
+			_ = rep.Close()
+			return nil, err
+		}
+	}
+
+	if err := rep.Bind(ZMQAddr(id)); err != nil {
+		_ = rep.Close()
+		return nil, err
+	}
+
+	t.repSock = rep
+	return t, nil
+}
+
+// Longest a send waits for its response before checking whether its context is done
+const sendPollInterval = 50 * time.Millisecond
+
+func ZMQAddr(id string) string {
+	return "tcp://" + id
+}
+
+func (t *Transport) Send(ctx context.Context, peerId string, req *pb.Request) (*pb.Response, error) {
+	if ctx.Err() != nil {
+		return nil, transport.ContextError(ctx)
+	}
+
+	reqSock, err := zmq4.NewSocket(zmq4.REQ)
+	if err != nil {
+		return nil, err
+	}
+	defer reqSock.Close()
+
+	if t.curve != nil {
+		serverKey, ok := t.curve.PublicKeys[peerId]
+		if !ok {
+			return nil, fmt.Errorf("%w: no CurveZMQ public key for %s", transport.ErrPeerUnreachable, peerId)
+		}
+		if err := reqSock.ClientAuthCurve(serverKey, t.publicKey, t.curve.SecretKey); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := reqSock.Connect(ZMQAddr(peerId)); err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = reqSock.SetSndtimeo(max(time.Until(deadline), time.Millisecond))
+	}
+
+	buffer, err := proto.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := reqSock.SendBytes(buffer, 0); err != nil {
+		return nil, err
+	}
+
+	// Waits for the response in short polls, so that a cancelled context is noticed
+	poller := zmq4.NewPoller()
+	poller.Add(reqSock, zmq4.POLLIN)
+	for {
+		if ctx.Err() != nil {
+			return nil, transport.ContextError(ctx)
+		}
+
+		wait := sendPollInterval
+		if deadline, ok := ctx.Deadline(); ok {
+			wait = max(min(wait, time.Until(deadline)), time.Millisecond)
+		}
+
+		sockets, err := poller.Poll(wait)
+		if err != nil {
+			return nil, err
+		}
+		if len(sockets) > 0 {
+			break
+		}
+	}
+
+	responseBytes, err := reqSock.RecvBytes(0)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp pb.Response
+	if err := proto.Unmarshal(responseBytes, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+func (t *Transport) Serve(handler transport.Handler) error {
+	t.mu.Lock()
+	if t.closed || t.serving {
+		t.mu.Unlock()
+		return transport.ErrClosed
+	}
+	t.serving = true
+	t.mu.Unlock()
+
+	defer close(t.doneCh)
+
+	poller := zmq4.NewPoller()
+	poller.Add(t.repSock, zmq4.POLLIN)
+
+	for {
+		select {
+		case <-t.stopCh:
+			return nil
+		default:
+		}
+
+		sockets, err := poller.Poll(100 * time.Millisecond)
+		if err != nil {
+			// ETERM is expected on shutdown
+			if zmq4.AsErrno(err) != zmq4.ETERM {
+				return fmt.Errorf("ZMQ poll error: %w", err)
+			}
+			return nil
+		}
+
+		if len(sockets) == 0 {
+			continue // Poll timed out, loop again
+		}
+
+		msgBytes, metadata, err := t.repSock.RecvBytesWithMetadata(zmq4.DONTWAIT, "User-Id")
+		if err != nil {
+			// EAGAIN is expected when there's nothing to receive, just continue
+			if zmq4.AsErrno(err) != zmq4.Errno(syscall.EAGAIN) {
+				return fmt.Errorf("ZMQ receive error: %w", err)
+			}
+			continue
+		}
+
+		var resp *pb.Response
+		var req pb.Request
+		if err := proto.Unmarshal(msgBytes, &req); err != nil {
+			resp = transport.ErrorResponse("failed to unmarshal request: " + err.Error())
+		} else if err := t.checkOrigin(&req, metadata["User-Id"]); err != nil {
+			resp = transport.ErrorResponse(err.Error())
+		} else {
+			resp = handler(&req)
+		}
+
+		// A REP socket must answer every request before receiving the next one
+		buffer, _ := proto.Marshal(resp)
+		if _, err := t.repSock.SendBytes(buffer, 0); err != nil {
+			return fmt.Errorf("ZMQ send error: %w", err)
+		}
+	}
+}
+
+// Checks that an authenticated peer sends requests under its own node ID, so a node of the cluster cannot pose as
+// another one
+func (t *Transport) checkOrigin(req *pb.Request, publicKey string) error {
+	if t.curve == nil {
+		return nil
+	}
+
+	nodeId, ok := t.curve.nodeOf(publicKey)
+	if !ok {
+		return fmt.Errorf("unknown CurveZMQ key")
+	}
+	if origin := strings.TrimPrefix(req.GetOrigin(), "tcp://"); origin != nodeId {
+		return fmt.Errorf("origin %s does not match the CurveZMQ key of %s", origin, nodeId)
+	}
+	return nil
+}
+
+func (t *Transport) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	serving := t.serving
+	t.mu.Unlock()
+
+	close(t.stopCh)
+	if serving {
+		<-t.doneCh
+	}
+
+	return t.repSock.Close()
+}